@@ -0,0 +1,116 @@
+package audittrail
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownAction is returned in ActionStrict mode for actions that were not registered.
+var ErrUnknownAction = errors.New("audittrail: unknown action")
+
+// ActionMode controls how entries with unregistered actions are handled.
+type ActionMode int
+
+const (
+	ActionPermissive ActionMode = iota // accept any action (default)
+	ActionFlag                         // accept, but log a warning for unknown actions
+	ActionStrict                       // reject unknown actions with ErrUnknownAction
+)
+
+// builtinActions are the actions the package records itself. They are accepted in every
+// mode without being registered, so ActionStrict cannot silently drop them.
+var builtinActions = map[string]bool{
+	ActionHeartbeat: true,
+	ActionAuditRead: true,
+
+	ActionPipelineStarted:  true,
+	ActionPipelineStopped:  true,
+	ActionPipelineDegraded: true,
+
+	ActionLogin:          true,
+	ActionLoginFailed:    true,
+	ActionLogout:         true,
+	ActionPasswordChange: true,
+
+	ActionAuthzAllow: true,
+	ActionAuthzDeny:  true,
+}
+
+var actionRegistry struct {
+	mu      sync.RWMutex
+	mode    ActionMode
+	actions map[string]bool
+}
+
+// RegisterActions adds actions to the registry that keeps the action vocabulary consistent
+// across services. Patterns understood by path.Match (e.g. "GET /*") are allowed, which is
+// useful for the "METHOD /path" actions generated by the middleware.
+func RegisterActions(actions ...string) {
+	actionRegistry.mu.Lock()
+	defer actionRegistry.mu.Unlock()
+	if actionRegistry.actions == nil {
+		actionRegistry.actions = make(map[string]bool, len(actions))
+	}
+	for _, action := range actions {
+		if action != "" {
+			actionRegistry.actions[action] = true
+		}
+	}
+}
+
+// SetActionMode selects how unregistered actions are treated by every recorder.
+func SetActionMode(mode ActionMode) {
+	actionRegistry.mu.Lock()
+	actionRegistry.mode = mode
+	actionRegistry.mu.Unlock()
+}
+
+// RegisteredActions returns the registered actions and patterns in sorted order.
+func RegisteredActions() []string {
+	actionRegistry.mu.RLock()
+	defer actionRegistry.mu.RUnlock()
+	out := make([]string, 0, len(actionRegistry.actions))
+	for action := range actionRegistry.actions {
+		out = append(out, action)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// IsRegisteredAction reports whether action is registered, matches a registered pattern or
+// is one of the package's own actions.
+func IsRegisteredAction(action string) bool {
+	actionRegistry.mu.RLock()
+	defer actionRegistry.mu.RUnlock()
+	return isRegisteredLocked(action)
+}
+
+func isRegisteredLocked(action string) bool {
+	if builtinActions[action] || actionRegistry.actions[action] {
+		return true
+	}
+	for pattern := range actionRegistry.actions {
+		if matchActionPattern(pattern, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAction applies the configured ActionMode to an entry's action.
+func checkAction(action string) error {
+	actionRegistry.mu.RLock()
+	mode := actionRegistry.mode
+	known := mode == ActionPermissive || isRegisteredLocked(action)
+	actionRegistry.mu.RUnlock()
+	if known {
+		return nil
+	}
+	if mode == ActionStrict {
+		return fmt.Errorf("%w: %q", ErrUnknownAction, action)
+	}
+	logger().Warn("audittrail: unknown action", "action", action)
+	return nil
+}
@@ -0,0 +1,59 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestActionRegistryModes(t *testing.T) {
+	t.Cleanup(func() {
+		SetActionMode(ActionPermissive)
+		actionRegistry.mu.Lock()
+		actionRegistry.actions = nil
+		actionRegistry.mu.Unlock()
+	})
+	RegisterActions("CREATE_ORDER", "CANCEL_ORDER", "GET /*")
+
+	rec, err := NewPubSubRecorder(PublisherFunc(func(context.Context, Entry) error { return nil }), nil)
+	if err != nil {
+		t.Fatalf("NewPubSubRecorder: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := rec.Record(ctx, Entry{Action: "SHIP_ORDER"}); err != nil {
+		t.Fatalf("permissive mode should accept unknown actions: %v", err)
+	}
+	SetActionMode(ActionFlag)
+	if err := rec.Record(ctx, Entry{Action: "SHIP_ORDER"}); err != nil {
+		t.Fatalf("flag mode should accept unknown actions: %v", err)
+	}
+
+	SetActionMode(ActionStrict)
+	if err := rec.Record(ctx, Entry{Action: "SHIP_ORDER"}); !errors.Is(err, ErrUnknownAction) {
+		t.Fatalf("expected ErrUnknownAction, got %v", err)
+	}
+	for _, action := range []string{"CREATE_ORDER", "GET /orders"} {
+		if err := rec.Record(ctx, Entry{Action: action}); err != nil {
+			t.Fatalf("%s: %v", action, err)
+		}
+	}
+	if got := RegisteredActions(); !reflect.DeepEqual(got, []string{"CANCEL_ORDER", "CREATE_ORDER", "GET /*"}) {
+		t.Fatalf("unexpected registry: %v", got)
+	}
+}
+
+// useStrictActions switches to ActionStrict with an empty registry for the rest of the test.
+func useStrictActions(t *testing.T) {
+	t.Helper()
+	actionRegistry.mu.Lock()
+	prevMode, prevActions := actionRegistry.mode, actionRegistry.actions
+	actionRegistry.mode, actionRegistry.actions = ActionStrict, nil
+	actionRegistry.mu.Unlock()
+	t.Cleanup(func() {
+		actionRegistry.mu.Lock()
+		actionRegistry.mode, actionRegistry.actions = prevMode, prevActions
+		actionRegistry.mu.Unlock()
+	})
+}
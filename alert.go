@@ -0,0 +1,149 @@
+package audittrail
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+)
+
+// AlertRule flags an actor who performs more than Threshold matching actions within Window.
+type AlertRule struct {
+	Name      string        // optional label reported in Alert
+	Action    string        // glob pattern matched against Entry.Action (e.g. "DELETE_*"); empty matches all
+	Threshold int           // number of matching entries that triggers the alert
+	Window    time.Duration // sliding window length
+
+	MinSeverity Severity // only entries at least this severe count; empty counts all
+}
+
+// Alert describes a triggered AlertRule.
+type Alert struct {
+	Rule        AlertRule
+	Actor       string
+	Count       int
+	WindowStart time.Time
+	WindowEnd   time.Time
+	Entry       Entry // entry that crossed the threshold
+}
+
+// AlertFunc receives triggered alerts. It is called synchronously; hand off slow work (webhooks) to a goroutine.
+type AlertFunc func(context.Context, Alert)
+
+// Analyzer evaluates alert rules over a stream of entries using per-actor sliding windows.
+// It can be attached to a Consumer (WithAlertRule) or used standalone via Observe.
+type Analyzer struct {
+	mu    sync.Mutex
+	rules []analyzerRule
+}
+
+type analyzerRule struct {
+	rule   AlertRule
+	fn     AlertFunc
+	events map[string][]time.Time // actor -> timestamps inside the window
+	fired  map[string]time.Time   // actor -> window end of the last alert, suppresses repeats
+	seen   int
+}
+
+// analyzerSweepEvery controls how often idle actors are evicted from a rule's state.
+const analyzerSweepEvery = 1024
+
+// NewAnalyzer creates an analyzer without rules.
+func NewAnalyzer() *Analyzer {
+	return &Analyzer{}
+}
+
+// AddRule registers a rule and the callback invoked when it triggers.
+// Rules with a non-positive Threshold or Window are ignored.
+func (a *Analyzer) AddRule(rule AlertRule, fn AlertFunc) {
+	if rule.Threshold <= 0 || rule.Window <= 0 || fn == nil {
+		return
+	}
+	a.mu.Lock()
+	a.rules = append(a.rules, analyzerRule{
+		rule:   rule,
+		fn:     fn,
+		events: make(map[string][]time.Time),
+		fired:  make(map[string]time.Time),
+	})
+	a.mu.Unlock()
+}
+
+// Observe feeds an entry to every rule. Entries are bucketed by actor (see ActorOrCreator) and
+// timed by CreatedDate.
+func (a *Analyzer) Observe(ctx context.Context, entry Entry) {
+	at := entry.CreatedDate
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+
+	var alerts []Alert
+	var fns []AlertFunc
+
+	a.mu.Lock()
+	for i := range a.rules {
+		r := &a.rules[i]
+		if !matchActionPattern(r.rule.Action, entry.Action) || !entry.Severity.AtLeast(r.rule.MinSeverity) {
+			continue
+		}
+		actor := entry.ActorOrCreator()
+		cutoff := at.Add(-r.rule.Window)
+		if r.seen++; r.seen%analyzerSweepEvery == 0 {
+			r.sweep(cutoff)
+		}
+		events := r.events[actor]
+		kept := events[:0]
+		for _, ts := range events {
+			if ts.After(cutoff) {
+				kept = append(kept, ts)
+			}
+		}
+		kept = append(kept, at)
+		r.events[actor] = kept
+
+		if len(kept) < r.rule.Threshold {
+			continue
+		}
+		if last, ok := r.fired[actor]; ok && last.After(cutoff) {
+			continue
+		}
+		r.fired[actor] = at
+		alerts = append(alerts, Alert{
+			Rule:        r.rule,
+			Actor:       actor,
+			Count:       len(kept),
+			WindowStart: kept[0],
+			WindowEnd:   at,
+			Entry:       entry,
+		})
+		fns = append(fns, r.fn)
+	}
+	a.mu.Unlock()
+
+	for i, alert := range alerts {
+		fns[i](ctx, alert)
+	}
+}
+
+func (r *analyzerRule) sweep(cutoff time.Time) {
+	for actor, events := range r.events {
+		if len(events) == 0 || !events[len(events)-1].After(cutoff) {
+			delete(r.events, actor)
+			delete(r.fired, actor)
+		}
+	}
+}
+
+// Record implements Recorder so an Analyzer can sit behind any fan-out point; it never fails.
+func (a *Analyzer) Record(ctx context.Context, entry Entry) error {
+	a.Observe(ctx, entry)
+	return nil
+}
+
+func matchActionPattern(pattern, action string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	ok, err := path.Match(pattern, action)
+	return err == nil && ok
+}
@@ -0,0 +1,79 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+func TestAnalyzerTriggersOncePerWindow(t *testing.T) {
+	var alerts []Alert
+	a := NewAnalyzer()
+	a.AddRule(AlertRule{Name: "mass-delete", Action: "DELETE_*", Threshold: 3, Window: time.Minute}, func(_ context.Context, alert Alert) {
+		alerts = append(alerts, alert)
+	})
+
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	observe := func(action, actor string, offset time.Duration) {
+		a.Observe(context.Background(), Entry{Action: action, CreatedBy: actor, CreatedDate: base.Add(offset)})
+	}
+
+	observe("DELETE_ORDER", "u1", 0)
+	observe("CREATE_ORDER", "u1", time.Second)
+	observe("DELETE_ORDER", "u2", 2*time.Second)
+	observe("DELETE_USER", "u1", 3*time.Second)
+	if len(alerts) != 0 {
+		t.Fatalf("expected no alerts yet, got %d", len(alerts))
+	}
+
+	observe("DELETE_ORDER", "u1", 4*time.Second)
+	observe("DELETE_ORDER", "u1", 5*time.Second)
+	if len(alerts) != 1 {
+		t.Fatalf("expected exactly 1 alert, got %d", len(alerts))
+	}
+	if alerts[0].Actor != "u1" || alerts[0].Count != 3 || alerts[0].Rule.Name != "mass-delete" {
+		t.Fatalf("unexpected alert: %+v", alerts[0])
+	}
+
+	// After the window has passed, a new burst alerts again.
+	observe("DELETE_ORDER", "u1", 2*time.Minute)
+	observe("DELETE_ORDER", "u1", 2*time.Minute+time.Second)
+	observe("DELETE_ORDER", "u1", 2*time.Minute+2*time.Second)
+	if len(alerts) != 2 {
+		t.Fatalf("expected a second alert after the window, got %d", len(alerts))
+	}
+}
+
+func TestConsumerEvaluatesAlertRules(t *testing.T) {
+	db := openStubDB(t, &stubDriver{
+		execFn: func(string, []driver.NamedValue) (driver.Result, error) { return stubResult{}, nil },
+	})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderQuestion})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	sub := SubscriberFunc(func(ctx context.Context, handler func(context.Context, Entry) error) error {
+		for i := 0; i < 2; i++ {
+			if err := handler(ctx, Entry{Action: "DELETE_ORDER", CreatedBy: "u1"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	var fired int
+	consumer, err := NewConsumer(audit, sub, nil,
+		WithAlertRule(AlertRule{Action: "DELETE_*", Threshold: 2, Window: time.Minute}, func(context.Context, Alert) { fired++ }),
+	)
+	if err != nil {
+		t.Fatalf("NewConsumer: %v", err)
+	}
+	if err := consumer.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if fired != 1 {
+		t.Fatalf("expected 1 alert, got %d", fired)
+	}
+}
@@ -0,0 +1,193 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RedactedValue replaces PII values in anonymized payloads.
+const RedactedValue = "[REDACTED]"
+
+// DefaultPIIFields are payload keys redacted by AnonymizeActor when Config.PIIFields is empty.
+var DefaultPIIFields = []string{
+	"name", "first_name", "last_name", "full_name", "username",
+	"email", "phone", "phone_number", "address",
+	"ip", "ip_address", "birth_date", "dob", "ssn", "password",
+}
+
+// AnonymizeActor erases a data subject from the trail (GDPR right to erasure) while keeping
+// the records themselves. Every entry naming the subject in log_actor, log_created_by or
+// log_impersonated_by is updated: those columns are replaced with a random pseudonym shared
+// by all of the subject's entries, log_ip_address, log_session_id and log_meta are cleared,
+// PII keys (Config.PIIFields) in request/response payloads are replaced with RedactedValue,
+// and any payload value equal to actorID is replaced with the pseudonym. Payloads encrypted
+// with Config.Encryption are decrypted, redacted and encrypted again under the current data
+// key. The row hash is recomputed. Entries under legal hold (see Hold) are skipped. It returns the number of
+// entries updated.
+func (r *AuditTrail) AnonymizeActor(ctx context.Context, actorID string) (int64, error) {
+	if r == nil || r.db == nil {
+		return 0, errors.New("audittrail: instance is not initialized")
+	}
+	if strings.TrimSpace(actorID) == "" {
+		return 0, errors.New("audittrail: actorID must not be empty")
+	}
+	if err := r.checkMutable(); err != nil {
+		return 0, err
+	}
+
+	b := &queryBuilder{placeholder: r.placeholder, dialect: r.dialect}
+	// Whole entries are read so the row hash can be recomputed after redaction.
+	subjects, err := r.queryEntries(ctx, fmt.Sprintf(" WHERE (log_actor = %s OR log_created_by = %s OR log_impersonated_by = %s) AND log_on_hold = %s",
+		b.arg(actorID), b.arg(actorID), b.arg(actorID), b.arg(false)), b.args...)
+	if err != nil {
+		return 0, err
+	}
+	if len(subjects) == 0 {
+		return 0, nil
+	}
+
+	a := anonymizer{
+		fields:    r.piiFields,
+		actorID:   actorID,
+		pseudonym: "anonymized-" + RandomIDGenerator.NewID()[:12],
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	ub := &queryBuilder{placeholder: r.placeholder, dialect: r.dialect}
+	update := fmt.Sprintf("UPDATE %s SET log_created_by = %s, log_actor = %s, log_ip_address = %s, log_request = %s, log_response = %s, "+
+		"log_impersonated_by = %s, log_session_id = %s, log_meta = %s, log_row_hash = %s WHERE log_audit_trail_id = %s",
+		r.tableRef, ub.arg(nil), ub.arg(nil), ub.arg(nil), ub.arg(nil), ub.arg(nil), ub.arg(nil), ub.arg(nil), ub.arg(nil), ub.arg(nil), ub.arg(nil))
+
+	for _, e := range subjects {
+		request, err := r.redactPayload(ctx, a, e.ID, "log_request", e.Request)
+		if err != nil {
+			return 0, fmt.Errorf("audittrail: anonymize entry %s failed: %w", e.ID, err)
+		}
+		response, err := r.redactPayload(ctx, a, e.ID, "log_response", e.Response)
+		if err != nil {
+			return 0, fmt.Errorf("audittrail: anonymize entry %s failed: %w", e.ID, err)
+		}
+		if e.CreatedBy == actorID {
+			e.CreatedBy = a.pseudonym
+		}
+		if e.Actor == actorID {
+			e.Actor = a.pseudonym
+		}
+		if e.ImpersonatedBy == actorID {
+			e.ImpersonatedBy = a.pseudonym
+		}
+		e.IPAddress, e.SessionID, e.Meta = "", "", nil
+		e.Request, e.Response = scannedPayload(request), scannedPayload(response)
+		if _, err := tx.ExecContext(ctx, update,
+			nullString(e.CreatedBy),
+			nullString(e.Actor),
+			nil,
+			request,
+			response,
+			nullString(e.ImpersonatedBy),
+			nil,
+			nil,
+			rowHash(e),
+			e.ID,
+		); err != nil {
+			return 0, fmt.Errorf("audittrail: anonymize entry %s failed: %w", e.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int64(len(subjects)), nil
+}
+
+// redactPayload redacts one stored payload, decrypting and re-encrypting it when it was
+// encrypted with Config.Encryption.
+func (r *AuditTrail) redactPayload(ctx context.Context, a anonymizer, entryID, name string, v any) (sql.NullString, error) {
+	if r.encryptor == nil {
+		return a.payload(storedPayload(v)), nil
+	}
+	return r.encryptor.rewrite(ctx, entryID, name, storedPayload(v), a.payload)
+}
+
+type anonymizer struct {
+	fields    map[string]bool
+	actorID   string
+	pseudonym string
+}
+
+// payload redacts a stored JSON payload. Non-JSON text is kept unless it is the subject ID itself.
+func (a anonymizer) payload(raw sql.NullString) sql.NullString {
+	if !raw.Valid {
+		return raw
+	}
+	var decoded any
+	if err := json.Unmarshal([]byte(raw.String), &decoded); err != nil {
+		return sql.NullString{String: strings.ReplaceAll(raw.String, a.actorID, a.pseudonym), Valid: true}
+	}
+	buf, err := json.Marshal(a.value(decoded))
+	if err != nil {
+		return raw
+	}
+	return sql.NullString{String: string(buf), Valid: true}
+}
+
+func (a anonymizer) value(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, child := range val {
+			if a.fields[strings.ToLower(k)] {
+				val[k] = RedactedValue
+				continue
+			}
+			val[k] = a.value(child)
+		}
+		return val
+	case []any:
+		for i, child := range val {
+			val[i] = a.value(child)
+		}
+		return val
+	case string:
+		if val == a.actorID {
+			return a.pseudonym
+		}
+		return val
+	default:
+		return val
+	}
+}
+
+func fieldSet(fields []string) map[string]bool {
+	set := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if f = strings.ToLower(strings.TrimSpace(f)); f != "" {
+			set[f] = true
+		}
+	}
+	return set
+}
+
+// storedPayload returns a payload read by scanEntry as the stored column value.
+func storedPayload(v any) sql.NullString {
+	raw, ok := v.(json.RawMessage)
+	if !ok {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(raw), Valid: true}
+}
+
+// scannedPayload is the inverse of storedPayload.
+func scannedPayload(s sql.NullString) any {
+	if !s.Valid {
+		return nil
+	}
+	return json.RawMessage(s.String)
+}
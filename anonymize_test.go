@@ -0,0 +1,185 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnonymizeActorRedactsPayloads(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var updates []execCall
+	db := openStubDB(t, &stubDriver{
+		queryFn: func(query string, args []driver.NamedValue) (driver.Rows, error) {
+			if !strings.Contains(query, "WHERE (log_actor = $1 OR log_created_by = $2 OR log_impersonated_by = $3)") || args[0].Value != "u1" {
+				t.Fatalf("unexpected select: %s %v", query, args)
+			}
+			return &stubRows{
+				columns: strings.Split(EntryColumns, ", "),
+				values: [][]driver.Value{
+					{"e1", nil, "UPDATE_PROFILE", nil, []byte(`{"email":"a@b.c","user":{"id":"u1","Phone":"123"},"qty":2}`), nil, created, "u1", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil},
+					{"e2", nil, "LOGIN", nil, "plain text", []byte(`["u1","other"]`), created, "order-service", nil, nil, nil, nil, nil, nil, nil, nil, nil, "u1", "203.0.113.7", nil},
+				},
+			}, nil
+		},
+		execFn: func(query string, args []driver.NamedValue) (driver.Result, error) {
+			updates = append(updates, execCall{query: query, args: args})
+			return stubResult{}, nil
+		},
+	})
+
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	n, err := audit.AnonymizeActor(context.Background(), "u1")
+	if err != nil {
+		t.Fatalf("AnonymizeActor: %v", err)
+	}
+	if n != 2 || len(updates) != 2 {
+		t.Fatalf("expected 2 updates, got n=%d calls=%d", n, len(updates))
+	}
+
+	pseudonym := stringArg(updates[0].args, 0)
+	if !strings.HasPrefix(pseudonym, "anonymized-") || stringArg(updates[1].args, 1) != pseudonym {
+		t.Fatalf("expected shared pseudonym, got %q / %q", pseudonym, stringArg(updates[1].args, 1))
+	}
+
+	var req map[string]any
+	if err := json.Unmarshal([]byte(stringArg(updates[0].args, 3)), &req); err != nil {
+		t.Fatalf("request JSON: %v", err)
+	}
+	user := req["user"].(map[string]any)
+	if req["email"] != RedactedValue || user["Phone"] != RedactedValue || user["id"] != pseudonym || req["qty"] != float64(2) {
+		t.Fatalf("unexpected redacted request: %v", req)
+	}
+	if stringArg(updates[1].args, 0) != "order-service" || stringArg(updates[1].args, 1) != pseudonym || updates[1].args[2].Value != nil {
+		t.Fatalf("expected the actor pseudonymized and the IP cleared, got %v", updates[1].args[:3])
+	}
+	if got := stringArg(updates[1].args, 4); got != `["`+pseudonym+`","other"]` {
+		t.Fatalf("unexpected redacted response: %s", got)
+	}
+
+	want := rowHash(Entry{
+		ID: "e2", Action: "LOGIN", Request: json.RawMessage("plain text"),
+		Response: json.RawMessage(`["` + pseudonym + `","other"]`), CreatedDate: created, CreatedBy: "order-service", Actor: pseudonym,
+	})
+	if got := stringArg(updates[1].args, 8); got != want || stringArg(updates[1].args, 9) != "e2" {
+		t.Fatalf("expected row hash of the anonymized entry, got %s", got)
+	}
+}
+
+func TestAnonymizeActorClearsImpersonation(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	columns := strings.Split(EntryColumns, ", ")
+	row := make([]driver.Value, len(columns))
+	row[0], row[2], row[6], row[7] = "e1", "REFUND_ISSUED", created, "billing"
+	row[12] = "session-7"          // log_session_id
+	row[16] = "admin-1"            // log_impersonated_by: the erased subject acted as another user
+	row[17] = "user-9"             // log_actor
+	row[18] = "198.51.100.4"       // log_ip_address
+	row[len(row)-1] = `{"by":"x"}` // log_meta
+	var update execCall
+	db := openStubDB(t, &stubDriver{
+		queryFn: func(string, []driver.NamedValue) (driver.Rows, error) {
+			return &stubRows{columns: columns, values: [][]driver.Value{row}}, nil
+		},
+		execFn: func(query string, args []driver.NamedValue) (driver.Result, error) {
+			update = execCall{query: query, args: args}
+			return stubResult{}, nil
+		},
+	})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	if n, err := audit.AnonymizeActor(context.Background(), "admin-1"); err != nil || n != 1 {
+		t.Fatalf("AnonymizeActor: n=%d err=%v", n, err)
+	}
+	for _, col := range []string{"log_impersonated_by", "log_session_id", "log_meta", "log_ip_address", "log_row_hash"} {
+		if !strings.Contains(update.query, col+" = ") {
+			t.Fatalf("%s not updated: %s", col, update.query)
+		}
+	}
+	pseudonym := stringArg(update.args, 5)
+	if !strings.HasPrefix(pseudonym, "anonymized-") || stringArg(update.args, 1) != "user-9" || stringArg(update.args, 0) != "billing" {
+		t.Fatalf("expected only the impersonator pseudonymized, got %v", update.args[:6])
+	}
+	for _, i := range []int{2, 6, 7} { // ip address, session, meta
+		if v := update.args[i].Value; v != nil {
+			t.Fatalf("identifying column %d not cleared: %v", i, v)
+		}
+	}
+	want := rowHash(Entry{ID: "e1", Action: "REFUND_ISSUED", CreatedDate: created, CreatedBy: "billing", Actor: "user-9", ImpersonatedBy: pseudonym})
+	if got := stringArg(update.args, 8); got != want {
+		t.Fatalf("row hash not recomputed for the scrubbed entry")
+	}
+}
+
+func TestAnonymizeActorRedactsEncryptedPayloads(t *testing.T) {
+	var insert, update []driver.NamedValue
+	keys := map[string][]driver.Value{}
+	db := openStubDB(t, &stubDriver{
+		execFn: func(query string, args []driver.NamedValue) (driver.Result, error) {
+			switch {
+			case strings.HasPrefix(query, "INSERT INTO audit_trail_keys"):
+				keys[args[0].Value.(string)] = []driver.Value{args[1].Value, args[2].Value}
+			case strings.HasPrefix(query, "INSERT INTO audit_trail "):
+				insert = args
+			case strings.HasPrefix(query, "UPDATE audit_trail SET"):
+				update = args
+			}
+			return stubResult{}, nil
+		},
+		queryFn: func(query string, args []driver.NamedValue) (driver.Rows, error) {
+			if strings.HasPrefix(query, "SELECT wrapped_key") {
+				return &stubRows{columns: []string{"wrapped_key", "kek_version"}, values: [][]driver.Value{keys[args[0].Value.(string)]}}, nil
+			}
+			row := make([]driver.Value, insertColumnCount-1)
+			for i := range row {
+				row[i] = insert[i].Value
+			}
+			return &stubRows{columns: strings.Split(EntryColumns, ", "), values: [][]driver.Value{row}}, nil
+		},
+	})
+	cfg := Config{DB: db, Placeholder: PlaceholderDollar, Encryption: &EncryptionConfig{Wrapper: &xorWrapper{current: "v1"}}}
+	audit, err := NewAuditTrail(cfg)
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	ctx := context.Background()
+	if err := audit.Record(ctx, Entry{ID: "e1", Action: "UPDATE_PROFILE", CreatedBy: "u1", Request: map[string]any{"email": "a@b.c", "id": "u1"}}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	// A fresh instance has to unwrap the data key from the key table.
+	eraser, err := NewAuditTrail(cfg)
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	if n, err := eraser.AnonymizeActor(ctx, "u1"); err != nil || n != 1 {
+		t.Fatalf("AnonymizeActor: n=%d err=%v", n, err)
+	}
+	stored := stringArg(update, 3)
+	if !strings.Contains(stored, `"$enc"`) || strings.Contains(stored, "a@b.c") {
+		t.Fatalf("expected the redacted request encrypted again, got %s", stored)
+	}
+	entry, err := eraser.Decrypt(ctx, Entry{ID: "e1", Request: json.RawMessage(stored)})
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	pseudonym := stringArg(update, 0)
+	var req map[string]any
+	if err := json.Unmarshal(entry.Request.(json.RawMessage), &req); err != nil || req["email"] != RedactedValue || req["id"] != pseudonym {
+		t.Fatalf("PII survived anonymization: %s (err=%v)", entry.Request, err)
+	}
+	want := rowHash(Entry{ID: "e1", Action: "UPDATE_PROFILE", CreatedBy: pseudonym, CreatedDate: insert[6].Value.(time.Time), Request: json.RawMessage(stored)})
+	if got := stringArg(update, 8); got != want {
+		t.Fatal("row hash not recomputed over the new ciphertext")
+	}
+}
@@ -0,0 +1,441 @@
+package audittrail
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+)
+
+// ErrAlreadyArchived is returned by ArchiveMonth when the month's manifest already exists and
+// ArchiveConfig.Overwrite is not set.
+var ErrAlreadyArchived = errors.New("audittrail: month is already archived")
+
+// ObjectChecker reports whether an object key exists. The Archiver uses it to skip months
+// archived before a restart and to never overwrite an archive, which matters once Truncate
+// has removed the source rows.
+type ObjectChecker interface {
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+// ObjectCheckerFunc adapts a function to ObjectChecker, e.g. around an S3 HeadObject call
+// that maps a not-found error to false.
+type ObjectCheckerFunc func(ctx context.Context, key string) (bool, error)
+
+func (f ObjectCheckerFunc) Exists(ctx context.Context, key string) (bool, error) {
+	return f(ctx, key)
+}
+
+// ArchiveConfig configures an Archiver.
+type ArchiveConfig struct {
+	Store ObjectStore
+	// SigningKey signs each manifest with HMAC-SHA256, so VerifyArchive can detect a
+	// manifest rewritten to match edited files. Required; keep it outside the bucket.
+	SigningKey []byte
+	// Checker reports whether a month's manifest already exists. It is required unless
+	// Store implements ObjectChecker or Overwrite is set.
+	Checker ObjectChecker
+	// Overwrite skips the existence check, so archiving a month again replaces its files
+	// and manifest. Combined with Truncate this can replace an archive with the rows left
+	// after truncation; only set it to rebuild an archive deliberately.
+	Overwrite  bool
+	Prefix     string        // key prefix; default "archive"
+	Format     ObjectFormat  // default ObjectJSONL (gzipped)
+	MaxEntries int           // entries per archive file; default 100000
+	Truncate   bool          // delete the archived rows, except those under legal hold, once the manifest is written
+	Grace      time.Duration // how long after a month ends Run waits before archiving it; default 24h
+	Interval   time.Duration // how often Run checks for a completed month; default 1h
+}
+
+// ArchiveManifest describes one archived month. It is written as manifest.json next to the
+// data files so auditors can check the archive is complete and unmodified. Signature is
+// the hex HMAC-SHA256, under ArchiveConfig.SigningKey, of the manifest's JSON encoding with
+// Signature empty.
+type ArchiveManifest struct {
+	Version      int           `json:"version"`
+	Table        string        `json:"table"`
+	Month        string        `json:"month"` // e.g. "2024-05"
+	From         time.Time     `json:"from"`  // inclusive
+	To           time.Time     `json:"to"`    // exclusive
+	Format       string        `json:"format"`
+	Rows         int64         `json:"rows"`
+	FirstCreated time.Time     `json:"first_created,omitempty"`
+	LastCreated  time.Time     `json:"last_created,omitempty"`
+	Files        []ArchiveFile `json:"files"`
+	CreatedAt    time.Time     `json:"created_at"`
+	Signature    string        `json:"signature"`
+}
+
+// ArchiveFile is one data file of an archived month.
+type ArchiveFile struct {
+	Key    string `json:"key"`
+	Rows   int64  `json:"rows"`
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256"` // hex digest of the stored object
+	// RowHashes is the hex SHA-256 of the rows' log_row_hash values, one per line in
+	// archive order, as stored in the table when the month was archived.
+	RowHashes string `json:"row_hashes"`
+}
+
+// Archiver exports completed months from the audit table to object storage, e.g.
+// archive/month=2024-05/part-0001.jsonl.gz plus archive/month=2024-05/manifest.json.
+// Object keys are deterministic, so a month that already has a manifest is refused with
+// ErrAlreadyArchived rather than overwritten, unless ArchiveConfig.Overwrite is set.
+type Archiver struct {
+	trail *AuditTrail
+	cfg   ArchiveConfig
+
+	mu   sync.Mutex
+	last time.Time // start of the newest month archived by this Archiver
+}
+
+// NewArchiver returns an Archiver that reads from trail.
+func NewArchiver(trail *AuditTrail, cfg ArchiveConfig) (*Archiver, error) {
+	if trail == nil || trail.db == nil {
+		return nil, errors.New("audittrail: instance is not initialized")
+	}
+	if cfg.Store == nil {
+		return nil, errors.New("audittrail: archive store is required")
+	}
+	if len(cfg.SigningKey) == 0 {
+		return nil, errors.New("audittrail: archive signing key is required")
+	}
+	if cfg.Checker == nil {
+		cfg.Checker, _ = cfg.Store.(ObjectChecker)
+	}
+	if cfg.Checker == nil && !cfg.Overwrite {
+		return nil, errors.New("audittrail: archive checker is required unless the store implements ObjectChecker or Overwrite is set")
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "archive"
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 100000
+	}
+	if cfg.Grace <= 0 {
+		cfg.Grace = 24 * time.Hour
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Hour
+	}
+	return &Archiver{trail: trail, cfg: cfg}, nil
+}
+
+// ManifestKey returns the object key of the manifest for the month containing t.
+func (a *Archiver) ManifestKey(t time.Time) string {
+	return path.Join(a.monthDir(monthStart(t)), "manifest.json")
+}
+
+// ArchiveMonth exports every entry created in the month containing t (in UTC), uploads the
+// data files and then the manifest, and, with Truncate, deletes the archived rows that are
+// not under legal hold. The manifest is uploaded last, so its presence means the archive is
+// complete; a month whose manifest exists returns ErrAlreadyArchived unless Overwrite is
+// set. Truncation is skipped with an error if rows were added to the month while it
+// was being exported.
+func (a *Archiver) ArchiveMonth(ctx context.Context, t time.Time) (*ArchiveManifest, error) {
+	from := monthStart(t)
+	to := from.AddDate(0, 1, 0)
+	if !a.cfg.Overwrite {
+		exists, err := a.cfg.Checker.Exists(ctx, a.ManifestKey(from))
+		if err != nil {
+			return nil, fmt.Errorf("audittrail: archive check failed: %w", err)
+		}
+		if exists {
+			return nil, ErrAlreadyArchived
+		}
+	}
+
+	manifest := &ArchiveManifest{
+		Version: 2,
+		Table:   a.trail.table,
+		Month:   from.Format("2006-01"),
+		From:    from,
+		To:      to,
+		Format:  "jsonl",
+		Files:   []ArchiveFile{},
+	}
+	if a.cfg.Format == ObjectParquet {
+		manifest.Format = "parquet"
+	}
+
+	part := &objectPart{}
+	rowHashes := sha256.New()
+	f := Filter{From: from, To: to}
+	ctx = a.trail.auditRead(ctx, "archive", f, nil)
+	err := a.trail.iterateHashed(ctx, f, func(entry Entry, stored sql.NullString) error {
+		// The stored hash is archived as is: a row edited in the table without rewriting its
+		// hash fails VerifyArchive. Rows written before hashing get their computed hash.
+		hash := stored.String
+		if !stored.Valid || hash == "" {
+			hash = rowHash(entry)
+		} else if hash != rowHash(entry) {
+			logger().Error("audittrail: archiving entry that does not match its row hash", "entry_id", entry.ID)
+		}
+		rowHashes.Write([]byte(hash + "\n"))
+		if a.cfg.Format == ObjectParquet {
+			part.entries = append(part.entries, entry)
+		} else {
+			line, err := JSONCodec.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			part.lines.Write(line)
+			part.lines.WriteByte('\n')
+		}
+		part.count++
+		manifest.Rows++
+		if manifest.FirstCreated.IsZero() {
+			manifest.FirstCreated = entry.CreatedDate.UTC()
+		}
+		manifest.LastCreated = entry.CreatedDate.UTC()
+		if part.count < a.cfg.MaxEntries {
+			return nil
+		}
+		if err := a.writePart(ctx, manifest, part, rowHashes.Sum(nil)); err != nil {
+			return err
+		}
+		part = &objectPart{}
+		rowHashes.Reset()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if part.count > 0 {
+		if err := a.writePart(ctx, manifest, part, rowHashes.Sum(nil)); err != nil {
+			return nil, err
+		}
+	}
+
+	manifest.CreatedAt = a.trail.now().UTC()
+	mac, err := manifestMAC(manifest, a.cfg.SigningKey)
+	if err != nil {
+		return nil, err
+	}
+	manifest.Signature = hex.EncodeToString(mac)
+	body, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, marshalFailed("archive manifest", err)
+	}
+	if err := a.cfg.Store.Put(ctx, a.ManifestKey(from), body, "application/json"); err != nil {
+		return nil, fmt.Errorf("audittrail: archive upload failed: %w", err)
+	}
+
+	if a.cfg.Truncate {
+		if err := a.truncate(ctx, f, manifest.Rows); err != nil {
+			return manifest, err
+		}
+	}
+	return manifest, nil
+}
+
+// Run archives each month once it has ended and Grace has passed, checking every Interval
+// until ctx is canceled. It only handles the most recent completed month; use ArchiveMonth
+// to backfill older ones. Errors are logged and retried on the next check.
+func (a *Archiver) Run(ctx context.Context) error {
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if err := a.archiveDue(ctx); err != nil {
+			logger().Warn("audittrail: archive failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// archiveDue archives the most recent month that ended at least Grace ago, unless this
+// Archiver already did.
+func (a *Archiver) archiveDue(ctx context.Context) error {
+	month := monthStart(a.trail.now().Add(-a.cfg.Grace)).AddDate(0, -1, 0)
+	a.mu.Lock()
+	done := !a.last.Before(month)
+	a.mu.Unlock()
+	if done {
+		return nil
+	}
+	manifest, err := a.ArchiveMonth(ctx, month)
+	if err != nil && !errors.Is(err, ErrAlreadyArchived) {
+		return err
+	}
+	if manifest != nil {
+		logger().Info("audittrail: archived month", "month", manifest.Month, "rows", manifest.Rows, "files", len(manifest.Files))
+	}
+	a.mu.Lock()
+	a.last = month
+	a.mu.Unlock()
+	return nil
+}
+
+// writePart encodes and uploads one data file and records it in the manifest with the
+// digest of its rows' hashes.
+func (a *Archiver) writePart(ctx context.Context, manifest *ArchiveManifest, part *objectPart, rowHashes []byte) error {
+	body, contentType, err := encodeObject(part, a.cfg.Format, false)
+	if err != nil {
+		return err
+	}
+	key := path.Join(a.monthDir(manifest.From),
+		fmt.Sprintf("part-%04d%s", len(manifest.Files)+1, objectExt(a.cfg.Format, false)))
+	if err := a.cfg.Store.Put(ctx, key, body, contentType); err != nil {
+		return fmt.Errorf("audittrail: archive upload failed: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	manifest.Files = append(manifest.Files, ArchiveFile{
+		Key:       key,
+		Rows:      int64(part.count),
+		Bytes:     int64(len(body)),
+		SHA256:    hex.EncodeToString(sum[:]),
+		RowHashes: hex.EncodeToString(rowHashes),
+	})
+	return nil
+}
+
+// manifestMAC returns the HMAC-SHA256 of the manifest with Signature empty.
+func manifestMAC(manifest *ArchiveManifest, key []byte) ([]byte, error) {
+	unsigned := *manifest
+	unsigned.Signature = ""
+	body, err := json.Marshal(unsigned)
+	if err != nil {
+		return nil, marshalFailed("archive manifest", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return mac.Sum(nil), nil
+}
+
+// truncate deletes the archived rows that are not under legal hold, after checking the
+// month still holds exactly the rows that were exported.
+func (a *Archiver) truncate(ctx context.Context, f Filter, archived int64) error {
+	count, err := a.trail.Count(ctx, f)
+	if err != nil {
+		return err
+	}
+	if count != archived {
+		return fmt.Errorf("audittrail: archive truncation skipped: month has %d rows, archived %d", count, archived)
+	}
+	b := &queryBuilder{placeholder: a.trail.placeholder, dialect: a.trail.dialect}
+	clause := andWhere(b.where(f), "log_on_hold = "+b.arg(false))
+	query := fmt.Sprintf("DELETE FROM %s%s", a.trail.tableRef, clause)
+	if _, err := a.trail.execDelete(ctx, query, b.args...); err != nil {
+		return fmt.Errorf("audittrail: archive truncation failed: %w", err)
+	}
+	return nil
+}
+
+func (a *Archiver) monthDir(from time.Time) string {
+	return path.Join(a.cfg.Prefix, "month="+from.Format("2006-01"))
+}
+
+// monthStart returns the first instant of t's month in UTC.
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// ErrArchiveMismatch is returned by VerifyArchive when an archive does not match its
+// manifest.
+var ErrArchiveMismatch = errors.New("audittrail: archive does not match manifest")
+
+// ObjectReader downloads an object. It is the read side of ObjectStore and is used to verify
+// archives after they were restored.
+type ObjectReader interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// ObjectReaderFunc adapts a function to ObjectReader.
+type ObjectReaderFunc func(ctx context.Context, key string) ([]byte, error)
+
+func (f ObjectReaderFunc) Get(ctx context.Context, key string) ([]byte, error) {
+	return f(ctx, key)
+}
+
+// VerifyArchive reads the manifest at manifestKey, checks its signature with signingKey
+// (ArchiveConfig.SigningKey), and checks every file it lists: the size and SHA-256 checksum
+// must match, and each file must decode to the recorded number of entries, all created
+// within the manifest's time range, in archive order (created date, then ID) and matching
+// the row hashes they had in the table.
+// It returns the manifest; any mismatch is reported as an error wrapping ErrArchiveMismatch.
+func VerifyArchive(ctx context.Context, reader ObjectReader, manifestKey string, signingKey []byte) (*ArchiveManifest, error) {
+	if reader == nil {
+		return nil, errors.New("audittrail: archive reader is required")
+	}
+	if len(signingKey) == 0 {
+		return nil, errors.New("audittrail: archive signing key is required")
+	}
+	data, err := reader.Get(ctx, manifestKey)
+	if err != nil {
+		return nil, fmt.Errorf("audittrail: archive download failed: %w", err)
+	}
+	var manifest ArchiveManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: manifest %s: %v", ErrArchiveMismatch, manifestKey, err)
+	}
+	want, err := manifestMAC(&manifest, signingKey)
+	if err != nil {
+		return nil, err
+	}
+	got, err := hex.DecodeString(manifest.Signature)
+	if err != nil || !hmac.Equal(got, want) {
+		return nil, fmt.Errorf("%w: manifest %s signature is invalid", ErrArchiveMismatch, manifestKey)
+	}
+
+	var rows int64
+	var prev keysetPosition
+	for _, file := range manifest.Files {
+		body, err := reader.Get(ctx, file.Key)
+		if err != nil {
+			return nil, fmt.Errorf("audittrail: archive download failed: %w", err)
+		}
+		sum := sha256.Sum256(body)
+		if int64(len(body)) != file.Bytes || hex.EncodeToString(sum[:]) != file.SHA256 {
+			return nil, fmt.Errorf("%w: %s checksum differs", ErrArchiveMismatch, file.Key)
+		}
+		rows += file.Rows
+
+		var entries []Entry
+		if manifest.Format == "parquet" {
+			entries, err = readParquet(body)
+		} else {
+			err = readJSONL(bytes.NewReader(body), func(entry Entry) error {
+				entries = append(entries, entry)
+				return nil
+			})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrArchiveMismatch, file.Key, err)
+		}
+		if int64(len(entries)) != file.Rows {
+			return nil, fmt.Errorf("%w: %s has %d entries, manifest lists %d", ErrArchiveMismatch, file.Key, len(entries), file.Rows)
+		}
+		rowHashes := sha256.New()
+		for _, entry := range entries {
+			rowHashes.Write([]byte(rowHash(entry) + "\n"))
+			created := entry.CreatedDate.UTC()
+			if created.Before(manifest.From) || !created.Before(manifest.To) {
+				return nil, fmt.Errorf("%w: %s: entry %s is outside %s", ErrArchiveMismatch, file.Key, entry.ID, manifest.Month)
+			}
+			if prev.id != "" && (created.Before(prev.createdDate) || created.Equal(prev.createdDate) && entry.ID <= prev.id) {
+				return nil, fmt.Errorf("%w: %s: entry %s is out of order", ErrArchiveMismatch, file.Key, entry.ID)
+			}
+			prev = keysetPosition{createdDate: created, id: entry.ID}
+		}
+		if hex.EncodeToString(rowHashes.Sum(nil)) != file.RowHashes {
+			return nil, fmt.Errorf("%w: %s: entries do not match their row hashes", ErrArchiveMismatch, file.Key)
+		}
+	}
+	if rows != manifest.Rows {
+		return nil, fmt.Errorf("%w: files hold %d entries, manifest lists %d", ErrArchiveMismatch, rows, manifest.Rows)
+	}
+	return &manifest, nil
+}
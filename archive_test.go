@@ -0,0 +1,326 @@
+package audittrail
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type checkedStore struct {
+	objects map[string][]byte
+}
+
+func (s *checkedStore) Put(_ context.Context, key string, body []byte, _ string) error {
+	s.objects[key] = body
+	return nil
+}
+
+func (s *checkedStore) Exists(_ context.Context, key string) (bool, error) {
+	_, ok := s.objects[key]
+	return ok, nil
+}
+
+var archiveKey = []byte("archive-signing-key")
+
+func archiveStubDB(t *testing.T, rows int, execs *[]execCall) *AuditTrail {
+	return archiveStubDBHashed(t, rows, execs, rowHash)
+}
+
+// archiveStubDBHashed stores hash(entry) as each row's log_row_hash.
+func archiveStubDBHashed(t *testing.T, rows int, execs *[]execCall, hash func(Entry) string) *AuditTrail {
+	t.Helper()
+	base := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	db := openStubDB(t, &stubDriver{
+		queryFn: func(query string, _ []driver.NamedValue) (driver.Rows, error) {
+			if strings.HasPrefix(query, "SELECT COUNT(*)") {
+				return &stubRows{columns: []string{"n"}, values: [][]driver.Value{{int64(rows)}}}, nil
+			}
+			values := make([][]driver.Value, rows)
+			for i := range values {
+				e := Entry{ID: fmt.Sprintf("e%d", i), Action: "EXPORT", CreatedDate: base.Add(time.Duration(i) * time.Hour), CreatedBy: "u1"}
+				values[i] = make([]driver.Value, insertColumnCount)
+				values[i][0], values[i][2], values[i][6], values[i][7] = e.ID, e.Action, e.CreatedDate, e.CreatedBy
+				values[i][insertColumnCount-1] = hash(e)
+			}
+			return &stubRows{columns: strings.Split(insertColumns, ", "), values: values}, nil
+		},
+		execFn: func(query string, args []driver.NamedValue) (driver.Result, error) {
+			*execs = append(*execs, execCall{query: query, args: args})
+			return stubResult{}, nil
+		},
+	})
+	now := time.Date(2024, 7, 2, 12, 0, 0, 0, time.UTC)
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar, Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	return audit
+}
+
+func TestArchiveMonthWritesFilesAndManifest(t *testing.T) {
+	var execs []execCall
+	audit := archiveStubDB(t, 3, &execs)
+	store := &checkedStore{objects: map[string][]byte{}}
+	archiver, err := NewArchiver(audit, ArchiveConfig{Store: store, SigningKey: archiveKey, MaxEntries: 2, Truncate: true})
+	if err != nil {
+		t.Fatalf("NewArchiver: %v", err)
+	}
+
+	ctx := context.Background()
+	manifest, err := archiver.ArchiveMonth(ctx, time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("ArchiveMonth: %v", err)
+	}
+	if manifest.Month != "2024-06" || manifest.Rows != 3 || len(manifest.Files) != 2 ||
+		!manifest.From.Equal(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)) ||
+		!manifest.To.Equal(time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected manifest %+v", manifest)
+	}
+	if manifest.Files[0].Key != "archive/month=2024-06/part-0001.jsonl.gz" || manifest.Files[1].Rows != 1 {
+		t.Fatalf("unexpected files %+v", manifest.Files)
+	}
+	for _, file := range manifest.Files {
+		body := store.objects[file.Key]
+		sum := sha256.Sum256(body)
+		if hex.EncodeToString(sum[:]) != file.SHA256 || int64(len(body)) != file.Bytes {
+			t.Fatalf("checksum mismatch for %s", file.Key)
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("gzip: %v", err)
+		}
+		data, _ := io.ReadAll(zr)
+		if got := int64(strings.Count(string(data), "\n")); got != file.Rows {
+			t.Fatalf("%s has %d lines, manifest says %d", file.Key, got, file.Rows)
+		}
+	}
+
+	var stored ArchiveManifest
+	if err := json.Unmarshal(store.objects["archive/month=2024-06/manifest.json"], &stored); err != nil || stored.Rows != 3 {
+		t.Fatalf("unexpected stored manifest %+v, err=%v", stored, err)
+	}
+
+	if len(execs) != 1 || execs[0].query !=
+		"DELETE FROM audit_trail WHERE log_created_date >= $1 AND log_created_date < $2 AND log_on_hold = $3" {
+		t.Fatalf("unexpected truncation %+v", execs)
+	}
+
+	if _, err := archiver.ArchiveMonth(ctx, manifest.From); !errors.Is(err, ErrAlreadyArchived) {
+		t.Fatalf("expected ErrAlreadyArchived, got %v", err)
+	}
+}
+
+func TestArchiveTruncationRequiresMatchingCount(t *testing.T) {
+	var execs []execCall
+	audit := archiveStubDB(t, 2, &execs)
+	store := &checkedStore{objects: map[string][]byte{}}
+	archiver, err := NewArchiver(audit, ArchiveConfig{Store: store, SigningKey: archiveKey, Truncate: true})
+	if err != nil {
+		t.Fatalf("NewArchiver: %v", err)
+	}
+	f := Filter{From: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)}
+	if err := archiver.truncate(context.Background(), f, 5); err == nil || len(execs) != 0 {
+		t.Fatalf("expected truncation to be skipped, err=%v execs=%d", err, len(execs))
+	}
+}
+
+func TestArchiverArchivesCompletedMonthOnce(t *testing.T) {
+	var execs []execCall
+	audit := archiveStubDB(t, 1, &execs)
+	var keys []string
+	store := ObjectStoreFunc(func(_ context.Context, key string, _ []byte, _ string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if _, err := NewArchiver(audit, ArchiveConfig{Store: store, SigningKey: archiveKey}); err == nil {
+		t.Fatal("expected an error for a store that cannot check for existing archives")
+	}
+	checker := ObjectCheckerFunc(func(_ context.Context, key string) (bool, error) {
+		for _, k := range keys {
+			if k == key {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	archiver, err := NewArchiver(audit, ArchiveConfig{Store: store, SigningKey: archiveKey, Checker: checker})
+	if err != nil {
+		t.Fatalf("NewArchiver: %v", err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := archiver.archiveDue(ctx); err != nil {
+			t.Fatalf("archiveDue: %v", err)
+		}
+	}
+	if len(keys) != 2 || keys[1] != "archive/month=2024-06/manifest.json" || len(execs) != 0 {
+		t.Fatalf("unexpected uploads %v, execs %d", keys, len(execs))
+	}
+}
+
+func TestArchiverRestartDoesNotOverwriteTruncatedMonth(t *testing.T) {
+	var execs []execCall
+	store := &checkedStore{objects: map[string][]byte{}}
+	ctx := context.Background()
+	first, err := NewArchiver(archiveStubDB(t, 3, &execs), ArchiveConfig{Store: store, SigningKey: archiveKey, Truncate: true})
+	if err != nil {
+		t.Fatalf("NewArchiver: %v", err)
+	}
+	if err := first.archiveDue(ctx); err != nil {
+		t.Fatalf("archiveDue: %v", err)
+	}
+	key := first.ManifestKey(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	original := string(store.objects[key])
+	if original == "" || len(execs) != 1 {
+		t.Fatalf("month not archived and truncated: %d objects, %d execs", len(store.objects), len(execs))
+	}
+
+	// After a restart the truncated month only holds its held rows; archiving it again
+	// would replace the archive with them.
+	restarted, err := NewArchiver(archiveStubDB(t, 1, &execs), ArchiveConfig{Store: store, SigningKey: archiveKey, Truncate: true})
+	if err != nil {
+		t.Fatalf("NewArchiver: %v", err)
+	}
+	if err := restarted.archiveDue(ctx); err != nil {
+		t.Fatalf("archiveDue: %v", err)
+	}
+	if string(store.objects[key]) != original || len(execs) != 1 {
+		t.Fatalf("archive overwritten after restart: %d execs\n%s", len(execs), store.objects[key])
+	}
+
+	rebuild, err := NewArchiver(archiveStubDB(t, 1, &execs), ArchiveConfig{Store: store, SigningKey: archiveKey, Overwrite: true})
+	if err != nil {
+		t.Fatalf("NewArchiver: %v", err)
+	}
+	if manifest, err := rebuild.ArchiveMonth(ctx, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)); err != nil || manifest.Rows != 1 {
+		t.Fatalf("Overwrite did not rebuild the archive: %+v, %v", manifest, err)
+	}
+}
+
+func TestVerifyArchiveDetectsTampering(t *testing.T) {
+	var execs []execCall
+	audit := archiveStubDB(t, 3, &execs)
+	store := &checkedStore{objects: map[string][]byte{}}
+	archiver, err := NewArchiver(audit, ArchiveConfig{Store: store, SigningKey: archiveKey, MaxEntries: 2})
+	if err != nil {
+		t.Fatalf("NewArchiver: %v", err)
+	}
+	ctx := context.Background()
+	month := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := archiver.ArchiveMonth(ctx, month); err != nil {
+		t.Fatalf("ArchiveMonth: %v", err)
+	}
+	reader := ObjectReaderFunc(func(_ context.Context, key string) ([]byte, error) {
+		body, ok := store.objects[key]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		return body, nil
+	})
+	key := archiver.ManifestKey(month)
+
+	manifest, err := VerifyArchive(ctx, reader, key, archiveKey)
+	if err != nil || manifest.Rows != 3 {
+		t.Fatalf("VerifyArchive = %+v, %v", manifest, err)
+	}
+
+	part := "archive/month=2024-06/part-0002.jsonl.gz"
+	original := store.objects[part]
+	tampered := append([]byte(nil), original...)
+	tampered[len(tampered)-1] ^= 0xff
+	store.objects[part] = tampered
+	if _, err := VerifyArchive(ctx, reader, key, archiveKey); !errors.Is(err, ErrArchiveMismatch) {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	store.objects[part] = original
+
+	var edited ArchiveManifest
+	_ = json.Unmarshal(store.objects[key], &edited)
+	edited.Files[1].Rows = 2
+	edited.Rows = 4
+	store.objects[key], _ = json.Marshal(edited)
+	if _, err := VerifyArchive(ctx, reader, key, archiveKey); !errors.Is(err, ErrArchiveMismatch) {
+		t.Fatalf("expected row count mismatch, got %v", err)
+	}
+}
+
+func TestVerifyArchiveAuthenticatesManifestAndRows(t *testing.T) {
+	ctx := context.Background()
+	month := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	archive := func(audit *AuditTrail, format ObjectFormat) (*checkedStore, ObjectReader, string) {
+		t.Helper()
+		store := &checkedStore{objects: map[string][]byte{}}
+		archiver, err := NewArchiver(audit, ArchiveConfig{Store: store, SigningKey: archiveKey, Format: format})
+		if err != nil {
+			t.Fatalf("NewArchiver: %v", err)
+		}
+		if _, err := archiver.ArchiveMonth(ctx, month); err != nil {
+			t.Fatalf("ArchiveMonth: %v", err)
+		}
+		reader := ObjectReaderFunc(func(_ context.Context, key string) ([]byte, error) { return store.objects[key], nil })
+		return store, reader, archiver.ManifestKey(month)
+	}
+	var execs []execCall
+	for _, format := range []ObjectFormat{ObjectJSONL, ObjectParquet} {
+		_, reader, key := archive(archiveStubDB(t, 2, &execs), format)
+		if _, err := VerifyArchive(ctx, reader, key, archiveKey); err != nil {
+			t.Fatalf("VerifyArchive(format %v): %v", format, err)
+		}
+	}
+
+	store, reader, key := archive(archiveStubDB(t, 2, &execs), ObjectJSONL)
+	if _, err := VerifyArchive(ctx, reader, key, []byte("another-key")); !errors.Is(err, ErrArchiveMismatch) {
+		t.Fatalf("expected a signature mismatch under another key, got %v", err)
+	}
+
+	// Someone with write access to the bucket edits a row and fixes up the checksums.
+	var manifest ArchiveManifest
+	_ = json.Unmarshal(store.objects[key], &manifest)
+	file := &manifest.Files[0]
+	edited, _ := JSONCodec.Marshal(Entry{ID: "e0", Action: "EXPORT", CreatedDate: manifest.From.Add(48 * time.Hour), CreatedBy: "someone-else"})
+	next, _ := JSONCodec.Marshal(Entry{ID: "e1", Action: "EXPORT", CreatedDate: manifest.From.Add(49 * time.Hour), CreatedBy: "u1"})
+	part := &objectPart{count: 2}
+	part.lines.Write(append(append(edited, '\n'), append(next, '\n')...))
+	body, _, err := encodeObject(part, ObjectJSONL, false)
+	if err != nil {
+		t.Fatalf("encodeObject: %v", err)
+	}
+	store.objects[file.Key] = body
+	sum := sha256.Sum256(body)
+	file.SHA256, file.Bytes = hex.EncodeToString(sum[:]), int64(len(body))
+	store.objects[key], _ = json.Marshal(manifest)
+	if _, err := VerifyArchive(ctx, reader, key, archiveKey); !errors.Is(err, ErrArchiveMismatch) || !strings.Contains(err.Error(), "signature") {
+		t.Fatalf("expected the unsigned manifest to be rejected, got %v", err)
+	}
+
+	// Even re-signed with the key, the edited row no longer matches its stored hash.
+	mac, _ := manifestMAC(&manifest, archiveKey)
+	manifest.Signature = hex.EncodeToString(mac)
+	store.objects[key], _ = json.Marshal(manifest)
+	if _, err := VerifyArchive(ctx, reader, key, archiveKey); !errors.Is(err, ErrArchiveMismatch) || !strings.Contains(err.Error(), "row hashes") {
+		t.Fatalf("expected a row hash mismatch, got %v", err)
+	}
+
+	// A row edited in the table before archiving is archived with its stored hash and flagged.
+	tampered := archiveStubDBHashed(t, 2, &execs, func(e Entry) string {
+		if e.ID == "e1" {
+			e.CreatedBy = "original-author"
+		}
+		return rowHash(e)
+	})
+	_, reader, key = archive(tampered, ObjectJSONL)
+	if _, err := VerifyArchive(ctx, reader, key, archiveKey); !errors.Is(err, ErrArchiveMismatch) || !strings.Contains(err.Error(), "row hashes") {
+		t.Fatalf("expected the edited row to fail verification, got %v", err)
+	}
+}
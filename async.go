@@ -0,0 +1,89 @@
+package audittrail
+
+import (
+	"context"
+	goruntime "runtime"
+	"sync"
+	"time"
+)
+
+const (
+	// asyncQueueSize bounds the entries waiting for the async record workers.
+	asyncQueueSize = 1024
+	// defaultAsyncTimeout bounds an async Record, as it no longer has the request's deadline.
+	defaultAsyncTimeout = 10 * time.Second
+)
+
+// asyncRecord is a group of entries queued for the global Record.
+type asyncRecord struct {
+	ctx      context.Context
+	recorder Recorder // nil for the global Record
+	entries  []Entry
+	timeout  time.Duration // 0 means no deadline
+	onError  func(error)
+}
+
+var async struct {
+	once  sync.Once
+	queue chan asyncRecord
+}
+
+// recordAsync hands entries to a fixed pool of workers that call recorder (the global
+// Record when nil), instead of spawning a goroutine per request. The entries are recorded
+// together, see recordEntries. When the queue is full it falls back to a goroutine so
+// bursts are not dropped. ctx keeps its values but not its cancellation, as the request has
+// usually finished by the time the entries are recorded; timeout gives the detached context
+// its own deadline.
+func recordAsync(ctx context.Context, recorder Recorder, entries []Entry, timeout time.Duration, onError func(error)) {
+	async.once.Do(startAsyncWorkers)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	rec := asyncRecord{ctx: context.WithoutCancel(ctx), recorder: recorder, entries: entries, timeout: timeout, onError: onError}
+	select {
+	case async.queue <- rec:
+	default:
+		go rec.run()
+	}
+}
+
+func startAsyncWorkers() {
+	async.queue = make(chan asyncRecord, asyncQueueSize)
+	for range max(2, goruntime.GOMAXPROCS(0)) {
+		go func() {
+			for rec := range async.queue {
+				rec.run()
+			}
+		}()
+	}
+}
+
+func (a asyncRecord) run() {
+	recorder := a.recorder
+	var onPublishError func(error)
+	if recorder == nil {
+		if recorder, onPublishError = defaultRecorder(); recorder == nil {
+			for _, e := range a.entries {
+				reportDrop(e, errNotInitialized)
+			}
+			if a.onError != nil {
+				a.onError(errNotInitialized)
+			}
+			return
+		}
+	}
+	ctx := a.ctx
+	if a.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.timeout)
+		defer cancel()
+	}
+	if err := recordEntries(ctx, recorder, a.entries); err != nil {
+		if onPublishError != nil {
+			onPublishError(err)
+		}
+		if a.onError != nil {
+			a.onError(err)
+		}
+	}
+}
@@ -2,14 +2,13 @@ package audittrail
 
 import (
 	"context"
-	"crypto/rand"
 	"database/sql"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -26,6 +25,30 @@ type Config struct {
 	TableName   string
 	Placeholder PlaceholderStyle
 	Now         func() time.Time
+	Clock       Clock       // takes precedence over Now
+	IDGenerator IDGenerator // IDs for entries recorded without one; default DefaultIDGenerator
+	PIIFields   []string    // payload keys redacted by AnonymizeActor; default DefaultPIIFields
+	Retention   []RetentionRule
+
+	// PrepareStatements caches a prepared statement for the single-entry INSERT, saving a
+	// parse per insert. Leave it off behind poolers that do not support prepared
+	// statements (e.g. PgBouncer in transaction mode).
+	PrepareStatements bool
+
+	// CopyFrom bulk-loads large batches (Postgres COPY) instead of multi-row INSERTs.
+	// CopyThreshold is the minimum batch size that uses it (default 1000).
+	CopyFrom      CopyFromFunc
+	CopyThreshold int
+
+	// AppendOnly makes the package refuse to update or delete entries (WORM). Retention
+	// deletes are still allowed when PurgeToken is set; InstallAppendOnlyTrigger enforces
+	// the same rule inside Postgres.
+	AppendOnly bool
+	PurgeToken string
+
+	// Encryption encrypts request and response payloads with data keys wrapped by a KMS.
+	// Read them back with Decrypt.
+	Encryption *EncryptionConfig
 }
 
 type Recorder interface {
@@ -37,6 +60,8 @@ type RecorderFunc func(context.Context, Entry) error
 func (f RecorderFunc) Record(ctx context.Context, entry Entry) error { return f(ctx, entry) }
 
 type Entry struct {
+	SchemaVersion int `json:"log_schema_version,omitempty"` // wire schema version, set by Codec
+
 	ID          string    `json:"log_audit_trail_id"`
 	RequestID   string    `json:"log_req_id,omitempty"`
 	Action      string    `json:"log_action"`
@@ -44,17 +69,79 @@ type Entry struct {
 	Request     any       `json:"log_request,omitempty"`
 	Response    any       `json:"log_response,omitempty"`
 	CreatedDate time.Time `json:"log_created_date"`
-	CreatedBy   string    `json:"log_created_by,omitempty"`
+	CreatedBy   string    `json:"log_created_by,omitempty"` // legacy creator, user or service; prefer Actor and ServiceName
+	ExpiresAt   time.Time `json:"log_expires_at,omitzero"`  // purge after this time; zero means the retention rules or global cutoff apply
+	Severity    Severity  `json:"log_severity,omitempty"`   // INFO, WARN or CRITICAL; empty is stored as NULL
+
+	ParentID      string `json:"log_parent_id,omitempty"`      // entry that caused this one (see DerivedFrom)
+	CorrelationID string `json:"log_correlation_id,omitempty"` // ID of the root entry of the causal chain
+	SessionID     string `json:"log_session_id,omitempty"`
+
+	AppVersion string `json:"log_app_version,omitempty"` // build of the service that recorded the entry
+	Hostname   string `json:"log_hostname,omitempty"`
+	InstanceID string `json:"log_instance_id,omitempty"` // pod or instance name
+
+	// ImpersonatedBy is the real actor when Actor acted through someone else's identity,
+	// e.g. a support admin acting as a user.
+	ImpersonatedBy string `json:"log_impersonated_by,omitempty"`
+
+	// Actor is the end user who performed the action and IPAddress the client address the
+	// request came from. Entries recorded before Actor existed carry the user in CreatedBy
+	// (see ActorOrCreator and MigrateIdentity).
+	Actor     string `json:"log_actor,omitempty"`
+	IPAddress string `json:"log_ip_address,omitempty"`
+
+	// ServiceName is the service that recorded the entry, e.g. from WithHTTPServiceName or
+	// AUDIT_SERVICE_NAME (see InstanceEnricher).
+	ServiceName string `json:"log_service_name,omitempty"`
+
+	// StartedAt and EndedAt bound the request the entry describes and DurationMS is the time
+	// between them, computed when both are set; the middlewares fill them.
+	StartedAt  time.Time `json:"log_started_at,omitzero"`
+	EndedAt    time.Time `json:"log_ended_at,omitzero"`
+	DurationMS int64     `json:"log_duration_ms,omitempty"`
+
+	// EntityType and EntityID name the record the entry is about, e.g. "order" and "42",
+	// so its change history can be read back with History.
+	EntityType string `json:"log_entity_type,omitempty"`
+	EntityID   string `json:"log_entity_id,omitempty"`
+
+	// Meta holds free-form details about the action, e.g. an order total, stored as a JSON
+	// object in log_meta.
+	Meta map[string]any `json:"log_meta,omitempty"`
+}
+
+// ActorOrCreator returns Actor, or CreatedBy for entries that have no Actor.
+func (e Entry) ActorOrCreator() string {
+	if e.Actor != "" {
+		return e.Actor
+	}
+	return e.CreatedBy
 }
 
 type AuditTrail struct {
-	db          *sql.DB
-	table       string
-	placeholder PlaceholderStyle
-	now         func() time.Time
+	db            *sql.DB
+	table         string
+	tableRef      string // table as written in SQL, quoted for the dialect
+	columnList    string // insertColumns, quoted for the dialect
+	placeholder   PlaceholderStyle
+	now           func() time.Time
+	ownsDB        bool
+	piiFields     map[string]bool
+	dialect       sqlDialect
+	retention     []RetentionRule
+	hooks         recorderHooks
+	prepare       bool
+	copyFrom      CopyFromFunc
+	copyThreshold int
+	appendOnly    bool
+	purgeToken    string
+	encryptor     *encryptor
+	stmtMu        sync.Mutex
+	stmts         map[string]*sql.Stmt
 }
 
-func NewAuditTrail(cfg Config) (*AuditTrail, error) {
+func NewAuditTrail(cfg Config, opts ...RecorderOption) (*AuditTrail, error) {
 	if cfg.DB == nil {
 		return nil, errors.New("audittrail: DB must not be nil")
 	}
@@ -76,46 +163,130 @@ func NewAuditTrail(cfg Config) (*AuditTrail, error) {
 	}
 
 	nowFn := cfg.Now
+	if cfg.Clock != nil {
+		nowFn = cfg.Clock.Now
+	}
 	if nowFn == nil {
 		nowFn = time.Now
 	}
+	if cfg.IDGenerator != nil {
+		opts = append([]RecorderOption{WithIDGenerator(cfg.IDGenerator)}, opts...)
+	}
 
-	return &AuditTrail{
-		db:          cfg.DB,
-		table:       table,
-		placeholder: placeholder,
-		now:         nowFn,
-	}, nil
+	piiFields := cfg.PIIFields
+	if len(piiFields) == 0 {
+		piiFields = DefaultPIIFields
+	}
+
+	copyThreshold := cfg.CopyThreshold
+	if copyThreshold <= 0 {
+		copyThreshold = defaultCopyThreshold
+	}
+
+	r := &AuditTrail{
+		db:            cfg.DB,
+		table:         table,
+		placeholder:   placeholder,
+		now:           nowFn,
+		piiFields:     fieldSet(piiFields),
+		dialect:       detectDialect(cfg.DB),
+		retention:     cfg.Retention,
+		hooks:         newRecorderHooks(opts),
+		prepare:       cfg.PrepareStatements,
+		copyFrom:      cfg.CopyFrom,
+		copyThreshold: copyThreshold,
+		appendOnly:    cfg.AppendOnly,
+		purgeToken:    cfg.PurgeToken,
+	}
+	r.tableRef = r.quote(table)
+	r.columnList = r.quoteList(insertColumns)
+	if cfg.Encryption != nil {
+		enc, err := newEncryptor(r, cfg.Encryption)
+		if err != nil {
+			return nil, err
+		}
+		r.encryptor = enc
+	}
+	return r, nil
 }
 
 func (r *AuditTrail) Record(ctx context.Context, entry Entry) error {
+	return r.insert(ctx, entry, false)
+}
+
+// recordOnce inserts an entry unless an entry with the same ID already exists.
+// Consumers use it so redelivered or replayed messages are not persisted twice.
+func (r *AuditTrail) recordOnce(ctx context.Context, entry Entry) error {
+	return r.insert(ctx, entry, true)
+}
+
+func (r *AuditTrail) insert(ctx context.Context, entry Entry, ignoreDuplicate bool) error {
 	if r == nil || r.db == nil {
 		return errors.New("audittrail: instance is not initialized")
 	}
-	normalized, err := normalizeEntry(entry, r.now)
+	args, err := r.insertArgs(ctx, entry)
+	if errors.Is(err, errQuarantined) {
+		return nil
+	}
 	if err != nil {
 		return err
 	}
+	_, err = r.execInsert(ctx, r.insertQuery(1, ignoreDuplicate), args...)
+	return err
+}
+
+// entryColumns lists the columns read back into an Entry, in scan order.
+const entryColumns = "log_audit_trail_id, log_req_id, log_action, log_endpoint, log_request, log_response, log_created_date, log_created_by, log_expires_at, log_severity, log_parent_id, log_correlation_id, log_session_id, log_app_version, log_hostname, log_instance_id, log_impersonated_by, log_actor, log_ip_address, log_service_name, log_started_at, log_ended_at, log_duration_ms, log_entity_type, log_entity_id, log_meta"
+
+// insertColumns lists the columns written for each entry, in argument order.
+const insertColumns = entryColumns + ", log_row_hash"
+
+const insertColumnCount = 27
+
+// insertArgs runs the recorder hooks, normalizes and encrypts an entry and returns its
+// values in insertColumns order.
+func (r *AuditTrail) insertArgs(ctx context.Context, entry Entry) ([]any, error) {
+	entry, err := r.hooks.apply(ctx, entry)
+	if err != nil {
+		return nil, err
+	}
+	if r.encryptor != nil {
+		// Payloads are encrypted last, after validation, and bound to the final entry ID.
+		if entry, err = normalizeEntry(entry, r.now); err != nil {
+			return nil, err
+		}
+		if entry, err = r.encryptor.encrypt(ctx, entry); err != nil {
+			return nil, err
+		}
+	}
+	return r.rowArgs(entry)
+}
+
+// rowArgs normalizes an entry and returns its values in insertColumns order, without
+// running the recorder hooks.
+func (r *AuditTrail) rowArgs(entry Entry) ([]any, error) {
+	normalized, err := normalizeEntry(entry, r.now)
+	if err != nil {
+		return nil, err
+	}
 
 	requestValue, err := marshalJSONValue(normalized.Request)
 	if err != nil {
-		return fmt.Errorf("audittrail: marshal request failed: %w", err)
+		return nil, marshalFailed("request", err)
 	}
 	responseValue, err := marshalJSONValue(normalized.Response)
 	if err != nil {
-		return fmt.Errorf("audittrail: marshal response failed: %w", err)
+		return nil, marshalFailed("response", err)
 	}
+	metaValue, err := marshalMeta(normalized.Meta)
+	if err != nil {
+		return nil, marshalFailed("meta", err)
+	}
+	expiresAt := r.expiresAt(normalized)
+	stored := normalized
+	stored.ExpiresAt = expiresAt.Time
 
-	placeholders := r.buildPlaceholders(8)
-	query := fmt.Sprintf(
-		"INSERT INTO %s (log_audit_trail_id, log_req_id, log_action, log_endpoint, log_request, log_response, log_created_date, log_created_by) VALUES (%s)",
-		r.table,
-		placeholders,
-	)
-
-	_, err = r.db.ExecContext(
-		ctx,
-		query,
+	return []any{
 		normalized.ID,
 		nullString(normalized.RequestID),
 		normalized.Action,
@@ -124,37 +295,75 @@ func (r *AuditTrail) Record(ctx context.Context, entry Entry) error {
 		responseValue,
 		normalized.CreatedDate,
 		nullString(normalized.CreatedBy),
-	)
-	return err
+		expiresAt,
+		nullString(string(normalized.Severity)),
+		nullString(normalized.ParentID),
+		nullString(normalized.CorrelationID),
+		nullString(normalized.SessionID),
+		nullString(normalized.AppVersion),
+		nullString(normalized.Hostname),
+		nullString(normalized.InstanceID),
+		nullString(normalized.ImpersonatedBy),
+		nullString(normalized.Actor),
+		nullString(normalized.IPAddress),
+		nullString(normalized.ServiceName),
+		nullTime(normalized.StartedAt),
+		nullTime(normalized.EndedAt),
+		sql.NullInt64{Int64: normalized.DurationMS, Valid: normalized.DurationMS != 0},
+		nullString(normalized.EntityType),
+		nullString(normalized.EntityID),
+		metaValue,
+		rowHash(stored),
+	}, nil
 }
 
-func (r *AuditTrail) EnsureTable(ctx context.Context) error {
-	if r == nil || r.db == nil {
-		return errors.New("audittrail: instance is not initialized")
+// insertQuery builds an INSERT of rows entries. With ignoreDuplicate, rows whose ID already
+// exists are skipped instead of failing the statement.
+func (r *AuditTrail) insertQuery(rows int, ignoreDuplicate bool) string {
+	verb, suffix := "INSERT", ""
+	if ignoreDuplicate {
+		switch r.dialect {
+		case dialectMySQL:
+			verb = "INSERT IGNORE"
+		case dialectSpanner:
+			verb = "INSERT OR IGNORE"
+		default:
+			suffix = " ON CONFLICT (log_audit_trail_id) DO NOTHING"
+		}
 	}
 
-	query := fmt.Sprintf(`
-		CREATE TABLE IF NOT EXISTS %s (
-			log_audit_trail_id VARCHAR(64) PRIMARY KEY,
-			log_req_id VARCHAR(128) NULL,
-			log_action VARCHAR(255) NOT NULL,
-			log_endpoint TEXT NULL,
-			log_request JSON NULL,
-			log_response JSON NULL,
-			log_created_date TIMESTAMP NOT NULL,
-			log_created_by VARCHAR(255) NULL
-		);`, r.table)
+	columns, extra := r.columnList, ""
+	if r.dialect == dialectSpanner {
+		columns += ", " + r.quote(spannerCommitColumn.name)
+		extra = ", PENDING_COMMIT_TIMESTAMP()"
+	}
+	values := make([]string, rows)
+	for i := range values {
+		values[i] = "(" + r.buildPlaceholdersFrom(i*insertColumnCount, insertColumnCount) + extra + ")"
+	}
+	return fmt.Sprintf("%s INTO %s (%s) VALUES %s%s", verb, r.tableRef, columns, strings.Join(values, ", "), suffix)
+}
 
-	_, err := r.db.ExecContext(ctx, query)
-	return err
+// Close releases cached statements, and the database if it was opened by this package
+// (e.g. NewLocal). A DB passed in through Config is left open for the caller to manage.
+func (r *AuditTrail) Close() error {
+	if r == nil || r.db == nil {
+		return nil
+	}
+	r.closeStmts()
+	if !r.ownsDB {
+		return nil
+	}
+	return r.db.Close()
 }
 
-func (r *AuditTrail) buildPlaceholders(n int) string {
+// buildPlaceholdersFrom renders n placeholders numbered after the first offset arguments.
+func (r *AuditTrail) buildPlaceholdersFrom(offset, n int) string {
 	switch r.placeholder {
 	case PlaceholderDollar:
 		parts := make([]string, n)
 		for i := 0; i < n; i++ {
-			parts[i] = fmt.Sprintf("$%d", i+1)
+			parts[i] = fmt.Sprintf("$%d", offset+i+1)
 		}
 		return strings.Join(parts, ", ")
 	default:
@@ -166,6 +375,31 @@ func (r *AuditTrail) buildPlaceholders(n int) string {
 	}
 }
 
+// marshalMeta encodes Entry.Meta as a JSON object; an empty map is stored as NULL.
+func marshalMeta(m map[string]any) (sql.NullString, error) {
+	if len(m) == 0 {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// unmarshalMeta decodes a stored log_meta value. Empty values and anything but a JSON
+// object read back as nil.
+func unmarshalMeta(data []byte) map[string]any {
+	if len(data) == 0 {
+		return nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil || len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
 func marshalJSONValue(v any) (sql.NullString, error) {
 	if v == nil {
 		return sql.NullString{}, nil
@@ -185,7 +419,7 @@ func marshalJSONValue(v any) (sql.NullString, error) {
 		}
 		return sql.NullString{String: val, Valid: true}, nil
 	default:
-		buf, err := json.Marshal(v)
+		buf, err := jsonMarshal(v)
 		if err != nil {
 			return sql.NullString{}, fmt.Errorf("audittrail: marshal JSON failed: %w", err)
 		}
@@ -197,6 +431,9 @@ func normalizeEntry(entry Entry, now func() time.Time) (Entry, error) {
 	if strings.TrimSpace(entry.Action) == "" {
 		return Entry{}, errors.New("audittrail: field Action is required")
 	}
+	if err := checkAction(entry.Action); err != nil {
+		return Entry{}, err
+	}
 	if entry.ID == "" {
 		entry.ID = newID()
 	}
@@ -204,11 +441,37 @@ func normalizeEntry(entry Entry, now func() time.Time) (Entry, error) {
 		if now == nil {
 			now = time.Now
 		}
-		entry.CreatedDate = now().UTC()
+		entry.CreatedDate = now()
+	}
+	// Timestamps are stored in UTC: columns without a zone (Postgres TIMESTAMP, MySQL
+	// DATETIME) would otherwise keep the caller's wall clock.
+	entry.CreatedDate = entry.CreatedDate.UTC()
+	for _, t := range []*time.Time{&entry.ExpiresAt, &entry.StartedAt, &entry.EndedAt} {
+		if !t.IsZero() {
+			*t = t.UTC()
+		}
+	}
+	if entry.DurationMS == 0 {
+		entry.DurationMS = durationMS(entry.StartedAt, entry.EndedAt)
 	}
 	return entry, nil
 }
 
+// durationMS returns the milliseconds from start to end, or 0 unless both are set.
+func durationMS(start, end time.Time) int64 {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return end.Sub(start).Milliseconds()
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t, Valid: true}
+}
+
 func nullString(s string) sql.NullString {
 	if strings.TrimSpace(s) == "" {
 		return sql.NullString{}
@@ -216,12 +479,9 @@ func nullString(s string) sql.NullString {
 	return sql.NullString{String: s, Valid: true}
 }
 
+// newID returns an ID from DefaultIDGenerator.
 func newID() string {
-	var b [16]byte
-	if _, err := rand.Read(b[:]); err == nil {
-		return hex.EncodeToString(b[:])
-	}
-	return fmt.Sprintf("%d", time.Now().UnixNano())
+	return DefaultIDGenerator.NewID()
 }
 
 func isSafeIdentifier(name string) bool {
@@ -6,6 +6,7 @@ import (
 	"database/sql/driver"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
 	"time"
@@ -17,20 +18,22 @@ type execCall struct {
 }
 
 type stubDriver struct {
-	execFn func(query string, args []driver.NamedValue) (driver.Result, error)
+	execFn  func(query string, args []driver.NamedValue) (driver.Result, error)
+	queryFn func(query string, args []driver.NamedValue) (driver.Rows, error)
 }
 
 func (d *stubDriver) Open(_ string) (driver.Conn, error) {
-	return &stubConn{execFn: d.execFn}, nil
+	return &stubConn{execFn: d.execFn, queryFn: d.queryFn}, nil
 }
 
 type stubConn struct {
-	execFn func(query string, args []driver.NamedValue) (driver.Result, error)
+	execFn  func(query string, args []driver.NamedValue) (driver.Result, error)
+	queryFn func(query string, args []driver.NamedValue) (driver.Rows, error)
 }
 
 func (c *stubConn) Prepare(_ string) (driver.Stmt, error) { return nil, errors.New("not implemented") }
 func (c *stubConn) Close() error                          { return nil }
-func (c *stubConn) Begin() (driver.Tx, error)             { return nil, errors.New("not implemented") }
+func (c *stubConn) Begin() (driver.Tx, error)             { return stubTx{}, nil }
 
 // ExecContext captures query execution without using Prepare.
 func (c *stubConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
@@ -45,6 +48,37 @@ func (c *stubConn) Exec(_ string, _ []driver.Value) (driver.Result, error) {
 	return nil, driver.ErrSkip
 }
 
+// QueryContext serves SELECT statements from queryFn without using Prepare.
+func (c *stubConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if c.queryFn == nil {
+		return nil, errors.New("queryFn missing")
+	}
+	return c.queryFn(query, args)
+}
+
+// stubRows returns a fixed result set.
+type stubRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *stubRows) Columns() []string { return r.columns }
+func (r *stubRows) Close() error      { return nil }
+
+func (r *stubRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+type stubTx struct{}
+
+func (stubTx) Commit() error   { return nil }
+func (stubTx) Rollback() error { return nil }
+
 type stubResult struct{}
 
 func (stubResult) LastInsertId() (int64, error) { return 0, nil }
@@ -83,8 +117,8 @@ func TestRecordInsertsData(t *testing.T) {
 	if !strings.Contains(calls[0].query, "INSERT INTO audit_trail") {
 		t.Fatalf("unexpected query: %s", calls[0].query)
 	}
-	if len(calls[0].args) != 8 {
-		t.Fatalf("expected 8 args, got %d", len(calls[0].args))
+	if len(calls[0].args) != 27 {
+		t.Fatalf("expected 27 args, got %d", len(calls[0].args))
 	}
 }
 
@@ -101,3 +135,25 @@ func TestInvalidTableName(t *testing.T) {
 		t.Fatal("expected error for invalid table name")
 	}
 }
+
+// openStubDB registers a fresh stub driver and opens a DB on it.
+func openStubDB(t *testing.T, d *stubDriver) *sql.DB {
+	t.Helper()
+	driverName := fmt.Sprintf("audittrail_stub_%s_%d", t.Name(), time.Now().UnixNano())
+	sql.Register(driverName, d)
+	db, err := sql.Open(driverName, "")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+// auditColumnNames returns the current schema, as reported by an up-to-date table.
+func auditColumnNames() []string {
+	names := make([]string, len(auditColumns))
+	for i, col := range auditColumns {
+		names[i] = col.name
+	}
+	return names
+}
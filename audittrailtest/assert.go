@@ -0,0 +1,110 @@
+package audittrailtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	audittrail "github.com/ahsansandiah/audit-trail"
+)
+
+// Matcher checks a single property of an entry.
+type Matcher struct {
+	desc  string
+	match func(audittrail.Entry) bool
+}
+
+// String describes the matcher in failure messages.
+func (m Matcher) String() string { return m.desc }
+
+// MatchFunc builds a custom matcher with a description used in failure messages.
+func MatchFunc(desc string, fn func(audittrail.Entry) bool) Matcher {
+	return Matcher{desc: desc, match: fn}
+}
+
+// MatchAction matches entries with the given action.
+func MatchAction(action string) Matcher {
+	return MatchFunc(fmt.Sprintf("action=%q", action), func(e audittrail.Entry) bool {
+		return e.Action == action
+	})
+}
+
+// MatchActor matches entries recorded for the given user ID.
+func MatchActor(actor string) Matcher {
+	return MatchFunc(fmt.Sprintf("actor=%q", actor), func(e audittrail.Entry) bool {
+		return e.ActorOrCreator() == actor
+	})
+}
+
+// MatchEndpoint matches entries with the given endpoint.
+func MatchEndpoint(endpoint string) Matcher {
+	return MatchFunc(fmt.Sprintf("endpoint=%q", endpoint), func(e audittrail.Entry) bool {
+		return e.Endpoint == endpoint
+	})
+}
+
+// MatchRequestID matches entries with the given request ID.
+func MatchRequestID(requestID string) Matcher {
+	return MatchFunc(fmt.Sprintf("request_id=%q", requestID), func(e audittrail.Entry) bool {
+		return e.RequestID == requestID
+	})
+}
+
+// AssertRecorded fails the test unless at least one entry matches all matchers.
+// It returns the first matching entry for further inspection.
+func AssertRecorded(t testing.TB, rec *InMemoryRecorder, matchers ...Matcher) audittrail.Entry {
+	t.Helper()
+	found := rec.Find(matchers...)
+	if len(found) == 0 {
+		t.Errorf("audittrailtest: no entry matching %s; recorded:\n%s", describe(matchers), dump(rec.Entries()))
+		return audittrail.Entry{}
+	}
+	return found[0]
+}
+
+// AssertNotRecorded fails the test if any entry matches all matchers.
+func AssertNotRecorded(t testing.TB, rec *InMemoryRecorder, matchers ...Matcher) {
+	t.Helper()
+	if found := rec.Find(matchers...); len(found) > 0 {
+		t.Errorf("audittrailtest: expected no entry matching %s, found %d:\n%s", describe(matchers), len(found), dump(found))
+	}
+}
+
+// AssertCount fails the test unless exactly n entries match all matchers.
+func AssertCount(t testing.TB, rec *InMemoryRecorder, n int, matchers ...Matcher) {
+	t.Helper()
+	if found := rec.Find(matchers...); len(found) != n {
+		t.Errorf("audittrailtest: expected %d entries matching %s, found %d:\n%s", n, describe(matchers), len(found), dump(rec.Entries()))
+	}
+}
+
+func matchAll(entry audittrail.Entry, matchers []Matcher) bool {
+	for _, m := range matchers {
+		if m.match != nil && !m.match(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+func describe(matchers []Matcher) string {
+	if len(matchers) == 0 {
+		return "(any)"
+	}
+	parts := make([]string, len(matchers))
+	for i, m := range matchers {
+		parts[i] = m.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+func dump(entries []audittrail.Entry) string {
+	if len(entries) == 0 {
+		return "  (none)"
+	}
+	var b strings.Builder
+	for _, e := range entries {
+		fmt.Fprintf(&b, "  - action=%q actor=%q endpoint=%q request_id=%q\n", e.Action, e.ActorOrCreator(), e.Endpoint, e.RequestID)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,55 @@
+package audittrailtest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	audittrail "github.com/ahsansandiah/audit-trail"
+)
+
+type fakeTB struct {
+	testing.TB
+	failed bool
+}
+
+func (f *fakeTB) Helper()               {}
+func (f *fakeTB) Errorf(string, ...any) { f.failed = true }
+
+func TestAssertRecordedWithMiddleware(t *testing.T) {
+	rec := NewInMemoryRecorder()
+	handler := audittrail.HTTPMiddleware(rec)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/orders", nil)
+	req.Header.Set("X-User-Id", "u1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	entry := AssertRecorded(t, rec, MatchAction("POST /api/orders"), MatchActor("u1"))
+	if entry.ID == "" {
+		t.Fatalf("expected normalized entry with ID")
+	}
+	AssertCount(t, rec, 1)
+	AssertNotRecorded(t, rec, MatchActor("u2"))
+}
+
+func TestAssertRecordedReportsMissingEntry(t *testing.T) {
+	rec := NewInMemoryRecorder()
+	if err := rec.Record(context.Background(), audittrail.Entry{Action: "CANCEL_ORDER"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	tb := &fakeTB{}
+	AssertRecorded(tb, rec, MatchAction("CREATE_ORDER"))
+	if !tb.failed {
+		t.Fatalf("expected assertion failure for missing action")
+	}
+
+	if err := rec.Record(context.Background(), audittrail.Entry{}); err == nil {
+		t.Fatalf("expected error for entry without action")
+	}
+	rec.Reset()
+	AssertCount(t, rec, 0)
+}
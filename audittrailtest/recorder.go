@@ -0,0 +1,66 @@
+// Package audittrailtest provides an in-memory Recorder and assertion helpers
+// for unit-testing code that produces audit trail entries.
+package audittrailtest
+
+import (
+	"context"
+	"sync"
+
+	audittrail "github.com/ahsansandiah/audit-trail"
+)
+
+// InMemoryRecorder stores recorded entries in memory instead of a database or queue.
+// Entries are normalized (ID, CreatedDate, required Action) exactly like the real recorders.
+type InMemoryRecorder struct {
+	mu       sync.Mutex
+	entries  []audittrail.Entry
+	recorder audittrail.Recorder
+}
+
+// NewInMemoryRecorder creates an empty in-memory recorder.
+func NewInMemoryRecorder() *InMemoryRecorder {
+	r := &InMemoryRecorder{}
+	// PubSubRecorder applies the same normalization the production recorders use.
+	recorder, _ := audittrail.NewPubSubRecorder(audittrail.PublisherFunc(r.store), nil)
+	r.recorder = recorder
+	return r
+}
+
+// Record validates and stores an entry.
+func (r *InMemoryRecorder) Record(ctx context.Context, entry audittrail.Entry) error {
+	return r.recorder.Record(ctx, entry)
+}
+
+// Entries returns a copy of all recorded entries in recording order.
+func (r *InMemoryRecorder) Entries() []audittrail.Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]audittrail.Entry, len(r.entries))
+	copy(out, r.entries)
+	return out
+}
+
+// Find returns all recorded entries matching every matcher.
+func (r *InMemoryRecorder) Find(matchers ...Matcher) []audittrail.Entry {
+	var out []audittrail.Entry
+	for _, entry := range r.Entries() {
+		if matchAll(entry, matchers) {
+			out = append(out, entry)
+		}
+	}
+	return out
+}
+
+// Reset removes all recorded entries.
+func (r *InMemoryRecorder) Reset() {
+	r.mu.Lock()
+	r.entries = nil
+	r.mu.Unlock()
+}
+
+func (r *InMemoryRecorder) store(_ context.Context, entry audittrail.Entry) error {
+	r.mu.Lock()
+	r.entries = append(r.entries, entry)
+	r.mu.Unlock()
+	return nil
+}
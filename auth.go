@@ -0,0 +1,66 @@
+package audittrail
+
+import "context"
+
+// Actions of identity events, shared by every service that records them.
+const (
+	ActionLogin          = "AUTH_LOGIN"
+	ActionLoginFailed    = "AUTH_LOGIN_FAILED"
+	ActionLogout         = "AUTH_LOGOUT"
+	ActionPasswordChange = "AUTH_PASSWORD_CHANGE"
+)
+
+// LoginEvent describes a sign-in attempt.
+type LoginEvent struct {
+	UserID    string `json:"user_id"`          // account the attempt was for, even if it failed
+	Method    string `json:"method,omitempty"` // e.g. "password", "sso", "passkey", "mfa"
+	Success   bool   `json:"success"`
+	IP        string `json:"ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	Reason    string `json:"reason,omitempty"` // why it failed, e.g. "invalid_password", "locked"
+	SessionID string `json:"-"`                // session started by the login; stored as SessionID
+}
+
+// LogoutEvent describes the end of a session.
+type LogoutEvent struct {
+	UserID    string `json:"user_id"`
+	IP        string `json:"ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	Reason    string `json:"reason,omitempty"` // e.g. "user", "expired", "revoked"
+	SessionID string `json:"-"`
+}
+
+// PasswordChangeEvent describes a password change or reset.
+type PasswordChangeEvent struct {
+	UserID    string `json:"user_id"`
+	ChangedBy string `json:"changed_by,omitempty"` // administrator who reset it; empty for self-service
+	Reset     bool   `json:"reset,omitempty"`      // set through a reset flow rather than with the old password
+	IP        string `json:"ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	SessionID string `json:"-"`
+}
+
+// RecordLogin records a sign-in attempt as ActionLogin, or ActionLoginFailed with
+// SeverityWarn when it failed.
+func (r *AuditTrail) RecordLogin(ctx context.Context, ev LoginEvent) error {
+	entry := Entry{Action: ActionLogin, Request: ev, Actor: ev.UserID, SessionID: ev.SessionID, Severity: SeverityInfo}
+	if !ev.Success {
+		entry.Action, entry.Severity = ActionLoginFailed, SeverityWarn
+	}
+	return r.Record(ctx, entry)
+}
+
+// RecordLogout records the end of a session as ActionLogout.
+func (r *AuditTrail) RecordLogout(ctx context.Context, ev LogoutEvent) error {
+	return r.Record(ctx, Entry{Action: ActionLogout, Request: ev, Actor: ev.UserID, SessionID: ev.SessionID, Severity: SeverityInfo})
+}
+
+// RecordPasswordChange records a password change as ActionPasswordChange with
+// SeverityWarn. Actor is the administrator for resets done on a user's behalf.
+func (r *AuditTrail) RecordPasswordChange(ctx context.Context, ev PasswordChangeEvent) error {
+	actor := ev.UserID
+	if ev.ChangedBy != "" {
+		actor = ev.ChangedBy
+	}
+	return r.Record(ctx, Entry{Action: ActionPasswordChange, Request: ev, Actor: actor, SessionID: ev.SessionID, Severity: SeverityWarn})
+}
@@ -0,0 +1,75 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+)
+
+func TestAuthEventHelpers(t *testing.T) {
+	var calls [][]driver.NamedValue
+	db := openStubDB(t, &stubDriver{
+		execFn: func(_ string, args []driver.NamedValue) (driver.Result, error) {
+			calls = append(calls, args)
+			return stubResult{}, nil
+		},
+	})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	ctx := context.Background()
+	if err := audit.RecordLogin(ctx, LoginEvent{UserID: "u1", Method: "password", IP: "10.0.0.1", Reason: "invalid_password"}); err != nil {
+		t.Fatalf("RecordLogin: %v", err)
+	}
+	if err := audit.RecordLogin(ctx, LoginEvent{UserID: "u1", Method: "sso", Success: true, SessionID: "s1"}); err != nil {
+		t.Fatalf("RecordLogin: %v", err)
+	}
+	if err := audit.RecordLogout(ctx, LogoutEvent{UserID: "u1", Reason: "user", SessionID: "s1"}); err != nil {
+		t.Fatalf("RecordLogout: %v", err)
+	}
+	if err := audit.RecordPasswordChange(ctx, PasswordChangeEvent{UserID: "u1", ChangedBy: "admin", Reset: true}); err != nil {
+		t.Fatalf("RecordPasswordChange: %v", err)
+	}
+
+	want := []struct{ action, request, actor, severity, session string }{
+		{ActionLoginFailed, `{"user_id":"u1","method":"password","success":false,"ip":"10.0.0.1","reason":"invalid_password"}`, "u1", "WARN", ""},
+		{ActionLogin, `{"user_id":"u1","method":"sso","success":true}`, "u1", "INFO", "s1"},
+		{ActionLogout, `{"user_id":"u1","reason":"user"}`, "u1", "INFO", "s1"},
+		{ActionPasswordChange, `{"user_id":"u1","changed_by":"admin","reset":true}`, "admin", "WARN", ""},
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("expected %d inserts, got %d", len(want), len(calls))
+	}
+	for i, w := range want {
+		args := calls[i]
+		if stringArg(args, 2) != w.action || stringArg(args, 4) != w.request || stringArg(args, 17) != w.actor || stringArg(args, 9) != w.severity || stringArg(args, 12) != w.session {
+			t.Errorf("entry %d: got action=%s request=%s actor=%s severity=%s session=%s", i,
+				stringArg(args, 2), stringArg(args, 4), stringArg(args, 17), stringArg(args, 9), stringArg(args, 12))
+		}
+	}
+}
+
+func TestAuthEventHelpersStrictActions(t *testing.T) {
+	useStrictActions(t)
+	db := openStubDB(t, &stubDriver{execFn: func(string, []driver.NamedValue) (driver.Result, error) {
+		return stubResult{}, nil
+	}})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	ctx := context.Background()
+	if err := audit.RecordLogin(ctx, LoginEvent{UserID: "u1"}); err != nil {
+		t.Fatalf("RecordLogin: %v", err)
+	}
+	if err := audit.RecordLogin(ctx, LoginEvent{UserID: "u1", Success: true}); err != nil {
+		t.Fatalf("RecordLogin: %v", err)
+	}
+	if err := audit.RecordLogout(ctx, LogoutEvent{UserID: "u1"}); err != nil {
+		t.Fatalf("RecordLogout: %v", err)
+	}
+	if err := audit.RecordPasswordChange(ctx, PasswordChangeEvent{UserID: "u1"}); err != nil {
+		t.Fatalf("RecordPasswordChange: %v", err)
+	}
+}
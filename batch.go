@@ -0,0 +1,130 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// BatchRecorder is implemented by recorders that can store many entries in one call.
+type BatchRecorder interface {
+	RecordBatch(ctx context.Context, entries []Entry) error
+}
+
+// BatchPublisher is implemented by publishers that can send many entries at once.
+type BatchPublisher interface {
+	PublishBatch(ctx context.Context, entries []Entry) error
+}
+
+// maxBatchRows bounds the rows per INSERT so statements stay under driver placeholder
+// limits (SQLite historically allows 999).
+const maxBatchRows = 999 / insertColumnCount
+
+// RecordBatch validates every entry, then inserts them with multi-row INSERT statements in
+// one transaction: either all entries are stored or none are. Batches of at least
+// Config.CopyThreshold entries use Config.CopyFrom when it is set.
+func (r *AuditTrail) RecordBatch(ctx context.Context, entries []Entry) error {
+	return r.recordBatch(ctx, entries, false, nil)
+}
+
+// recordBatch is RecordBatch; with ignoreDuplicate, entries whose ID already exists are
+// skipped (see recordOnce). A non-nil inTx runs in the insert transaction before it commits.
+func (r *AuditTrail) recordBatch(ctx context.Context, entries []Entry, ignoreDuplicate bool, inTx func(*sql.Tx) error) error {
+	if r == nil || r.db == nil {
+		return errors.New("audittrail: instance is not initialized")
+	}
+	if len(entries) == 0 && inTx == nil {
+		return nil
+	}
+	args := make([]any, 0, len(entries)*insertColumnCount)
+	for i, entry := range entries {
+		row, err := r.insertArgs(ctx, entry)
+		if errors.Is(err, errQuarantined) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("audittrail: entry %d: %w", i, err)
+		}
+		args = append(args, row...)
+	}
+	return r.insertRows(ctx, args, ignoreDuplicate, inTx)
+}
+
+// insertRows stores rows of insertColumns values, flattened into args, in one transaction,
+// then runs inTx, if set, in the same transaction. Large batches go through Config.CopyFrom
+// when it is set and there is no inTx, since COPY runs on its own connection.
+func (r *AuditTrail) insertRows(ctx context.Context, args []any, ignoreDuplicate bool, inTx func(*sql.Tx) error) error {
+	total := len(args) / insertColumnCount
+	if total == 0 && inTx == nil {
+		return nil
+	}
+	if r.copyFrom != nil && total >= r.copyThreshold && inTx == nil {
+		err := r.copyRows(ctx, args)
+		if err == nil {
+			return nil
+		}
+		// COPY is all-or-nothing, so nothing was stored; duplicates from redelivery are
+		// the usual cause and are handled by the INSERT path.
+		logger().Warn("audittrail: copy failed, falling back to INSERT", "entries", total, "error", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	for start := 0; start < total; start += maxBatchRows {
+		rows := min(maxBatchRows, total-start)
+		chunk := args[start*insertColumnCount : (start+rows)*insertColumnCount]
+		if _, err := tx.ExecContext(ctx, r.insertQuery(rows, ignoreDuplicate), chunk...); err != nil {
+			return err
+		}
+	}
+	if inTx != nil {
+		if err := inTx(tx); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RecordBatch validates every entry before publishing any of them, then publishes the batch
+// through PublishBatch when the publisher supports it, or entry by entry otherwise.
+func (p *PubSubRecorder) RecordBatch(ctx context.Context, entries []Entry) error {
+	normalized := make([]Entry, 0, len(entries))
+	for i, entry := range entries {
+		entry, err := p.hooks.apply(ctx, entry)
+		if errors.Is(err, errQuarantined) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("audittrail: entry %d: %w", i, err)
+		}
+		n, err := normalizeEntry(entry, p.now)
+		if err != nil {
+			return fmt.Errorf("audittrail: entry %d: %w", i, err)
+		}
+		normalized = append(normalized, n)
+	}
+	if len(normalized) == 0 {
+		return nil
+	}
+	if bp, ok := p.publisher.(BatchPublisher); ok {
+		return bp.PublishBatch(ctx, normalized)
+	}
+	for _, entry := range normalized {
+		if err := p.publisher.Publish(ctx, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordBatch sends entries to the collector in a single call.
+func (g *GRPCRecorder) RecordBatch(ctx context.Context, entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return g.send(ctx, entries)
+}
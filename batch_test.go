@@ -0,0 +1,72 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+)
+
+func TestAuditTrailRecordBatchChunksInsert(t *testing.T) {
+	var calls []execCall
+	db := openStubDB(t, &stubDriver{
+		execFn: func(query string, args []driver.NamedValue) (driver.Result, error) {
+			calls = append(calls, execCall{query: query, args: args})
+			return stubResult{}, nil
+		},
+	})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	entries := make([]Entry, maxBatchRows+5)
+	for i := range entries {
+		entries[i] = Entry{Action: "IMPORT"}
+	}
+	if err := audit.RecordBatch(context.Background(), entries); err != nil {
+		t.Fatalf("RecordBatch: %v", err)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 chunked inserts, got %d", len(calls))
+	}
+	if len(calls[0].args) != maxBatchRows*insertColumnCount || len(calls[1].args) != 5*insertColumnCount {
+		t.Fatalf("unexpected arg counts %d, %d", len(calls[0].args), len(calls[1].args))
+	}
+	if !strings.Contains(calls[1].query, "($109, $110") || strings.Contains(calls[1].query, "$136") {
+		t.Fatalf("unexpected placeholders: %s", calls[1].query)
+	}
+
+	calls = nil
+	entries[3].Action = ""
+	if err := audit.RecordBatch(context.Background(), entries); err == nil || len(calls) != 0 {
+		t.Fatalf("expected validation to fail before any insert, err=%v calls=%d", err, len(calls))
+	}
+}
+
+type batchPublisher struct {
+	batches [][]Entry
+}
+
+func (b *batchPublisher) Publish(ctx context.Context, e Entry) error {
+	return b.PublishBatch(ctx, []Entry{e})
+}
+
+func (b *batchPublisher) PublishBatch(_ context.Context, entries []Entry) error {
+	b.batches = append(b.batches, entries)
+	return nil
+}
+
+func TestPubSubRecorderRecordBatchUsesBatchPublisher(t *testing.T) {
+	pub := &batchPublisher{}
+	rec, err := NewPubSubRecorder(pub, nil)
+	if err != nil {
+		t.Fatalf("NewPubSubRecorder: %v", err)
+	}
+	if err := rec.RecordBatch(context.Background(), []Entry{{Action: "A"}, {Action: "B"}}); err != nil {
+		t.Fatalf("RecordBatch: %v", err)
+	}
+	if len(pub.batches) != 1 || len(pub.batches[0]) != 2 || pub.batches[0][1].ID == "" {
+		t.Fatalf("unexpected batches: %+v", pub.batches)
+	}
+}
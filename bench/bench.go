@@ -0,0 +1,109 @@
+// Package bench holds reproducible benchmarks and the load harness for audittrail.
+//
+// The benchmarks run against an in-process database driver that accepts every statement
+// and stores nothing, so they measure the library's own overhead (normalization,
+// argument building, encoding, middleware) rather than a database:
+//
+//	go test ./bench -bench . -benchmem
+//
+// For end-to-end load against a deployed collector, see cmd/auditload.
+package bench
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	audittrail "github.com/ahsansandiah/audit-trail"
+)
+
+// DiscardDriverName is the database/sql driver registered by OpenDiscardDB.
+const DiscardDriverName = "audittrail_discard"
+
+var (
+	registerOnce sync.Once
+	discard      = &discardDriver{}
+)
+
+// OpenDiscardDB opens a database that accepts every statement and stores nothing.
+func OpenDiscardDB() (*sql.DB, error) {
+	registerOnce.Do(func() { sql.Register(DiscardDriverName, discard) })
+	return sql.Open(DiscardDriverName, "")
+}
+
+// Executed returns the number of statements executed through discard databases.
+func Executed() int64 {
+	return discard.executed.Load()
+}
+
+// SampleEntry returns a representative entry with small JSON payloads.
+func SampleEntry(i int) audittrail.Entry {
+	return audittrail.Entry{
+		RequestID: fmt.Sprintf("req-%d", i),
+		Action:    "UPDATE_ORDER",
+		Endpoint:  "/api/orders/789",
+		Request:   map[string]any{"order_id": "order-789", "items": []map[string]any{{"sku": "A-1", "qty": 2}}, "note": "gift wrap"},
+		Response:  map[string]any{"status": "updated", "total": 42.5},
+		Actor:     fmt.Sprintf("user-%d", i%100),
+		Severity:  audittrail.SeverityWarn,
+	}
+}
+
+type discardDriver struct {
+	executed atomic.Int64
+}
+
+func (d *discardDriver) Open(string) (driver.Conn, error) { return &discardConn{d: d}, nil }
+
+type discardConn struct{ d *discardDriver }
+
+func (c *discardConn) Prepare(string) (driver.Stmt, error) { return &discardStmt{d: c.d}, nil }
+func (c *discardConn) Close() error                        { return nil }
+func (c *discardConn) Begin() (driver.Tx, error)           { return discardTx{}, nil }
+
+func (c *discardConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	c.d.executed.Add(1)
+	return driver.RowsAffected(1), nil
+}
+
+func (c *discardConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return emptyRows{}, nil
+}
+
+type discardStmt struct{ d *discardDriver }
+
+func (s *discardStmt) Close() error  { return nil }
+func (s *discardStmt) NumInput() int { return -1 }
+func (s *discardStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.d.executed.Add(1)
+	return driver.RowsAffected(1), nil
+}
+func (s *discardStmt) Query([]driver.Value) (driver.Rows, error) { return emptyRows{}, nil }
+
+type discardTx struct{}
+
+func (discardTx) Commit() error   { return nil }
+func (discardTx) Rollback() error { return nil }
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string         { return nil }
+func (emptyRows) Close() error              { return nil }
+func (emptyRows) Next([]driver.Value) error { return io.EOF }
+
+// WaitExecuted blocks until at least n statements have been executed or timeout elapses.
+func WaitExecuted(n int64, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for Executed() < n {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return true
+}
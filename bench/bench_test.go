@@ -0,0 +1,111 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	audittrail "github.com/ahsansandiah/audit-trail"
+)
+
+func newAuditTrail(b *testing.B, cfg audittrail.Config) *audittrail.AuditTrail {
+	b.Helper()
+	db, err := OpenDiscardDB()
+	if err != nil {
+		b.Fatalf("OpenDiscardDB: %v", err)
+	}
+	b.Cleanup(func() { _ = db.Close() })
+	cfg.DB = db
+	audit, err := audittrail.NewAuditTrail(cfg)
+	if err != nil {
+		b.Fatalf("NewAuditTrail: %v", err)
+	}
+	return audit
+}
+
+func BenchmarkRecord(b *testing.B) {
+	for _, prepared := range []bool{false, true} {
+		b.Run(fmt.Sprintf("prepared=%v", prepared), func(b *testing.B) {
+			audit := newAuditTrail(b, audittrail.Config{Placeholder: audittrail.PlaceholderDollar, PrepareStatements: prepared})
+			ctx := context.Background()
+			entry := SampleEntry(0)
+			b.ReportAllocs()
+			for b.Loop() {
+				if err := audit.Record(ctx, entry); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRecordBatch(b *testing.B) {
+	for _, size := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			audit := newAuditTrail(b, audittrail.Config{Placeholder: audittrail.PlaceholderDollar})
+			entries := make([]audittrail.Entry, size)
+			for i := range entries {
+				entries[i] = SampleEntry(i)
+			}
+			ctx := context.Background()
+			b.ReportAllocs()
+			for b.Loop() {
+				if err := audit.RecordBatch(ctx, entries); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.N*size)/b.Elapsed().Seconds(), "entries/s")
+		})
+	}
+}
+
+func BenchmarkHTTPMiddleware(b *testing.B) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	body := `{"order_id":"order-789","items":[{"sku":"A-1","qty":2}]}`
+	run := func(b *testing.B, h http.Handler) {
+		b.ReportAllocs()
+		for b.Loop() {
+			req := httptest.NewRequest(http.MethodPost, "/api/orders", strings.NewReader(body))
+			req.Header.Set("X-User-Id", "u1")
+			h.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	}
+
+	b.Run("baseline", func(b *testing.B) { run(b, ok) })
+	b.Run("audited", func(b *testing.B) {
+		audit := newAuditTrail(b, audittrail.Config{Placeholder: audittrail.PlaceholderDollar})
+		run(b, audittrail.HTTPMiddleware(audit, audittrail.WithRequestBody(64*1024))(ok))
+	})
+}
+
+func BenchmarkConsumerThroughput(b *testing.B) {
+	audit := newAuditTrail(b, audittrail.Config{Placeholder: audittrail.PlaceholderDollar})
+	ps := audittrail.NewMemoryPubSub(1024)
+	consumer, err := audittrail.NewConsumer(audit, ps, nil)
+	if err != nil {
+		b.Fatalf("NewConsumer: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = consumer.Run(ctx) }()
+
+	entry := SampleEntry(0)
+	start := Executed()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e := entry
+		e.ID = fmt.Sprintf("e-%d", i)
+		if err := ps.Publish(ctx, e); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if !WaitExecuted(start+int64(b.N), time.Minute) {
+		b.Fatal("consumer did not drain the queue")
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "entries/s")
+}
@@ -0,0 +1,154 @@
+// Command auditload generates audit entries at a configurable concurrency and reports
+// throughput and Record latency.
+//
+//	go run ./bench/cmd/auditload -mode pipeline -n 200000 -c 32
+//	go run ./bench/cmd/auditload -mode http -url https://audit.internal -key $AUDIT_API_KEY -rate 5000
+//
+// Modes:
+//   - record:   AuditTrail.Record against an in-process database that stores nothing
+//   - pipeline: PubSubRecorder -> MemoryPubSub -> Consumer -> in-process database
+//   - http:     HTTPRecorder against a running collector (CollectorServer.HTTPHandler)
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	audittrail "github.com/ahsansandiah/audit-trail"
+	"github.com/ahsansandiah/audit-trail/bench"
+)
+
+func main() {
+	mode := flag.String("mode", "pipeline", "record, pipeline or http")
+	total := flag.Int("n", 100000, "entries to record")
+	concurrency := flag.Int("c", 16, "concurrent producers")
+	rate := flag.Int("rate", 0, "max entries per second across producers (0 = unlimited)")
+	url := flag.String("url", "", "collector base URL (http mode)")
+	key := flag.String("key", "", "collector API key (http mode)")
+	batch := flag.Int("batch", 100, "entries per request (http mode)")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	rec, wait, err := newRecorder(ctx, *mode, *url, *key, *batch)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	latencies := make([][]time.Duration, *concurrency)
+	var failed atomic.Int64
+	var next atomic.Int64
+	var ticker <-chan time.Time
+	if *rate > 0 {
+		t := time.NewTicker(time.Second / time.Duration(*rate))
+		defer t.Stop()
+		ticker = t.C
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for w := range *concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1)) - 1
+				if i >= *total || ctx.Err() != nil {
+					return
+				}
+				if ticker != nil {
+					<-ticker
+				}
+				t0 := time.Now()
+				if err := rec.Record(ctx, bench.SampleEntry(i)); err != nil {
+					failed.Add(1)
+				}
+				latencies[w] = append(latencies[w], time.Since(t0))
+			}
+		}()
+	}
+	wg.Wait()
+	produced := time.Since(start)
+	if err := wait(ctx); err != nil {
+		log.Printf("drain: %v", err)
+	}
+	elapsed := time.Since(start)
+
+	all := slices.Concat(latencies...)
+	slices.Sort(all)
+	fmt.Printf("mode=%s entries=%d failed=%d concurrency=%d\n", *mode, len(all), failed.Load(), *concurrency)
+	fmt.Printf("produce: %v (%.0f entries/s)\n", produced.Round(time.Millisecond), float64(len(all))/produced.Seconds())
+	fmt.Printf("end-to-end: %v (%.0f entries/s)\n", elapsed.Round(time.Millisecond), float64(len(all))/elapsed.Seconds())
+	fmt.Printf("record latency: p50=%v p95=%v p99=%v max=%v\n",
+		percentile(all, 0.50), percentile(all, 0.95), percentile(all, 0.99), percentile(all, 1))
+}
+
+// newRecorder builds the recorder for mode and a function that waits until everything
+// recorded has been delivered.
+func newRecorder(ctx context.Context, mode, url, key string, batch int) (audittrail.Recorder, func(context.Context) error, error) {
+	switch mode {
+	case "record", "pipeline":
+		db, err := bench.OpenDiscardDB()
+		if err != nil {
+			return nil, nil, err
+		}
+		audit, err := audittrail.NewAuditTrail(audittrail.Config{DB: db, Placeholder: audittrail.PlaceholderDollar})
+		if err != nil {
+			return nil, nil, err
+		}
+		if mode == "record" {
+			return audit, func(context.Context) error { return nil }, nil
+		}
+		ps := audittrail.NewMemoryPubSub(4096)
+		consumer, err := audittrail.NewConsumer(audit, ps, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		go func() { _ = consumer.Run(ctx) }()
+		rec, err := audittrail.NewPubSubRecorder(ps, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		base := bench.Executed()
+		var recorded atomic.Int64
+		counting := audittrail.RecorderFunc(func(ctx context.Context, e audittrail.Entry) error {
+			recorded.Add(1)
+			return rec.Record(ctx, e)
+		})
+		return counting, func(context.Context) error {
+			if !bench.WaitExecuted(base+recorded.Load(), time.Minute) {
+				return fmt.Errorf("consumer did not drain within a minute")
+			}
+			return nil
+		}, nil
+	case "http":
+		if url == "" {
+			return nil, nil, fmt.Errorf("-url is required in http mode")
+		}
+		rec, err := audittrail.NewHTTPRecorder(audittrail.HTTPRecorderConfig{BaseURL: url, APIKey: key, BatchSize: batch})
+		if err != nil {
+			return nil, nil, err
+		}
+		go func() { _ = rec.Run(ctx) }()
+		return rec, rec.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown mode %q", mode)
+	}
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p*float64(len(sorted))) - 1
+	return sorted[max(0, min(i, len(sorted)-1))]
+}
@@ -0,0 +1,204 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the backend while the circuit breaker is open.
+var ErrCircuitOpen = errors.New("audittrail: circuit breaker is open")
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // calls pass through
+	BreakerOpen                         // calls fail fast with ErrCircuitOpen
+	BreakerHalfOpen                     // a single trial call is allowed through
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerConfig configures a CircuitBreaker.
+type BreakerConfig struct {
+	FailureThreshold int                         // consecutive failures that trip the breaker; default 5
+	OpenTimeout      time.Duration               // how long to fail fast before a trial call; default 30s
+	OnStateChange    func(from, to BreakerState) // optional, called on transitions after the breaker's lock is released
+	Now              func() time.Time            // optional clock override (useful in tests)
+
+	// LifecycleRecorder, when set, receives an ActionPipelineDegraded entry when the breaker
+	// trips (Meta phase "tripped") and another when it closes again (phase "recovered"),
+	// spanning the outage with the number of calls it failed fast. The backend behind the
+	// breaker is failing when it trips, so use a recorder that does not go through it (e.g.
+	// another store, or a RecorderFunc appending to a FileSpool) for the first entry to
+	// survive a crash during the outage.
+	LifecycleRecorder Recorder
+}
+
+// CircuitBreaker stops calling a failing audit backend (DB insert or publish) so request
+// latency is protected while it is down. After OpenTimeout it lets one trial call through
+// (half-open); success closes the circuit, failure re-opens it.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	cfg       BreakerConfig
+	state     BreakerState
+	failures  int
+	openedAt  time.Time
+	trial     bool
+	trippedAt time.Time // when the breaker left the closed state
+	rejected  int       // calls failed fast since then
+}
+
+// NewCircuitBreaker creates a closed circuit breaker.
+func NewCircuitBreaker(cfg BreakerConfig) *CircuitBreaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &CircuitBreaker{cfg: cfg}
+}
+
+// State returns the current state.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Do runs fn unless the circuit is open. Errors caused by the caller's context being
+// canceled do not count as backend failures; a panic in fn counts as a failure and is
+// propagated.
+func (b *CircuitBreaker) Do(ctx context.Context, fn func(context.Context) error) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	finished := false
+	defer func() {
+		if !finished {
+			b.done(false) // fn panicked: end the trial so the breaker is not stuck half-open
+		}
+	}()
+	err := fn(ctx)
+	finished = true
+	b.done(err == nil || (ctx.Err() != nil && errors.Is(err, ctx.Err())))
+	return err
+}
+
+// Recorder wraps rec so every Record call goes through the breaker.
+func (b *CircuitBreaker) Recorder(rec Recorder) Recorder {
+	return RecorderFunc(func(ctx context.Context, entry Entry) error {
+		return b.Do(ctx, func(ctx context.Context) error { return rec.Record(ctx, entry) })
+	})
+}
+
+// Publisher wraps pub so every Publish call goes through the breaker.
+func (b *CircuitBreaker) Publisher(pub Publisher) Publisher {
+	return PublisherFunc(func(ctx context.Context, entry Entry) error {
+		return b.Do(ctx, func(ctx context.Context) error { return pub.Publish(ctx, entry) })
+	})
+}
+
+func (b *CircuitBreaker) allow() error {
+	var notify func()
+	b.mu.Lock()
+	defer func() {
+		b.mu.Unlock()
+		if notify != nil {
+			notify()
+		}
+	}()
+	switch b.state {
+	case BreakerOpen:
+		if b.cfg.Now().Sub(b.openedAt) < b.cfg.OpenTimeout {
+			b.rejected++
+			return ErrCircuitOpen
+		}
+		notify = b.transition(BreakerHalfOpen)
+		b.trial = true
+		return nil
+	case BreakerHalfOpen:
+		if b.trial {
+			b.rejected++
+			return ErrCircuitOpen
+		}
+		b.trial = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+func (b *CircuitBreaker) done(success bool) {
+	var notify func()
+	b.mu.Lock()
+	defer func() {
+		b.mu.Unlock()
+		if notify != nil {
+			notify()
+		}
+	}()
+	b.trial = false
+	if success {
+		b.failures = 0
+		if b.state != BreakerClosed {
+			notify = b.transition(BreakerClosed)
+		}
+		return
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.cfg.FailureThreshold {
+		b.openedAt = b.cfg.Now()
+		if b.state != BreakerOpen {
+			notify = b.transition(BreakerOpen)
+		}
+	}
+}
+
+// transition changes the state with b.mu held and returns the notifications to run once it
+// is released, so OnStateChange may call the breaker and a slow callback does not block
+// other callers.
+func (b *CircuitBreaker) transition(to BreakerState) func() {
+	from := b.state
+	b.state = to
+	var entry *Entry
+	switch {
+	case from == BreakerClosed:
+		b.trippedAt, b.rejected = b.cfg.Now(), 0
+		if b.cfg.LifecycleRecorder != nil {
+			meta := map[string]any{"reason": "circuit_open", "phase": "tripped", "failures": b.failures}
+			e := lifecycleEntry(ActionPipelineDegraded, SeverityWarn, time.Time{}, b.trippedAt.UTC(), meta)
+			entry = &e
+		}
+	case to == BreakerClosed && b.cfg.LifecycleRecorder != nil:
+		meta := map[string]any{"reason": "circuit_open", "phase": "recovered", "rejected": b.rejected}
+		e := lifecycleEntry(ActionPipelineDegraded, SeverityWarn, b.trippedAt.UTC(), b.cfg.Now().UTC(), meta)
+		entry = &e
+	}
+	return func() {
+		if entry != nil {
+			// In the background: the recorder may itself go through this breaker.
+			go recordLifecycle(context.Background(), b.cfg.LifecycleRecorder, *entry)
+		}
+		if b.cfg.OnStateChange != nil {
+			b.cfg.OnStateChange(from, to)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTripsAndRecovers(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var transitions []string
+	breaker := NewCircuitBreaker(BreakerConfig{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		Now:              func() time.Time { return now },
+		OnStateChange: func(from, to BreakerState) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	})
+
+	failing := true
+	calls := 0
+	rec := breaker.Recorder(RecorderFunc(func(context.Context, Entry) error {
+		calls++
+		if failing {
+			return errors.New("db down")
+		}
+		return nil
+	}))
+
+	ctx := context.Background()
+	_ = rec.Record(ctx, Entry{Action: "a"})
+	_ = rec.Record(ctx, Entry{Action: "a"})
+	if breaker.State() != BreakerOpen {
+		t.Fatalf("expected open breaker, got %s", breaker.State())
+	}
+	if err := rec.Record(ctx, Entry{Action: "a"}); !errors.Is(err, ErrCircuitOpen) || calls != 2 {
+		t.Fatalf("expected fast failure without backend call, got err=%v calls=%d", err, calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	failing = false
+	if err := rec.Record(ctx, Entry{Action: "a"}); err != nil {
+		t.Fatalf("expected trial call to succeed: %v", err)
+	}
+	if breaker.State() != BreakerClosed {
+		t.Fatalf("expected closed breaker, got %s", breaker.State())
+	}
+	want := []string{"closed->open", "open->half-open", "half-open->closed"}
+	if len(transitions) != len(want) {
+		t.Fatalf("unexpected transitions: %v", transitions)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Fatalf("unexpected transitions: %v", transitions)
+		}
+	}
+}
+
+func TestCircuitBreakerCallbackMayUseBreaker(t *testing.T) {
+	var breaker *CircuitBreaker
+	var states []BreakerState
+	breaker = NewCircuitBreaker(BreakerConfig{
+		FailureThreshold: 1,
+		OnStateChange: func(_, _ BreakerState) {
+			states = append(states, breaker.State()) // deadlocks if called with the lock held
+		},
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = breaker.Do(context.Background(), func(context.Context) error { return errors.New("db down") })
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnStateChange deadlocked calling the breaker")
+	}
+	if len(states) != 1 || states[0] != BreakerOpen {
+		t.Fatalf("unexpected states seen by the callback: %v", states)
+	}
+}
+
+func TestCircuitBreakerRecoversFromPanickingTrial(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	breaker := NewCircuitBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, Now: func() time.Time { return now }})
+	ctx := context.Background()
+	_ = breaker.Do(ctx, func(context.Context) error { return errors.New("db down") })
+	now = now.Add(2 * time.Minute)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected the trial's panic to propagate")
+			}
+		}()
+		_ = breaker.Do(ctx, func(context.Context) error { panic("driver bug") })
+	}()
+	if breaker.State() != BreakerOpen {
+		t.Fatalf("expected the panicking trial to re-open the breaker, got %s", breaker.State())
+	}
+
+	now = now.Add(2 * time.Minute)
+	if err := breaker.Do(ctx, func(context.Context) error { return nil }); err != nil || breaker.State() != BreakerClosed {
+		t.Fatalf("breaker stuck after a panicking trial: err=%v state=%s", err, breaker.State())
+	}
+}
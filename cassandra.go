@@ -0,0 +1,251 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CassandraExecer runs one CQL statement. Adapt a gocql session with CassandraExecFunc:
+//
+//	audittrail.CassandraExecFunc(func(ctx context.Context, stmt string, args ...any) error {
+//		return session.Query(stmt, args...).WithContext(ctx).Exec()
+//	})
+type CassandraExecer interface {
+	Exec(ctx context.Context, stmt string, args ...any) error
+}
+
+// CassandraExecFunc adapts a function to CassandraExecer.
+type CassandraExecFunc func(ctx context.Context, stmt string, args ...any) error
+
+func (f CassandraExecFunc) Exec(ctx context.Context, stmt string, args ...any) error {
+	return f(ctx, stmt, args...)
+}
+
+// CassandraConfig configures a CassandraRecorder.
+type CassandraConfig struct {
+	Session     CassandraExecer
+	Table       string          // table, optionally keyspace-qualified; default "audit_trail"
+	Service     string          // service partition key component; default "default"
+	Retention   []RetentionRule // expiry for entries without ExpiresAt, as in Config.Retention
+	DefaultTTL  time.Duration   // TTL for entries without an expiry; zero keeps them forever
+	Concurrency int             // parallel inserts in RecordBatch; default 8
+}
+
+// CassandraRecorder writes entries to Cassandra or ScyllaDB for very high ingest rates.
+// Rows are partitioned by (log_day, log_service), so each partition holds one service's
+// day and stays bounded, and clustered by creation time and ID. Retention uses per-row
+// TTLs instead of deletes, and EnsureTable picks time-window compaction so expired data is
+// dropped a whole SSTable at a time.
+type CassandraRecorder struct {
+	cfg    CassandraConfig
+	insert string
+}
+
+// NewCassandraRecorder creates a Cassandra recorder.
+func NewCassandraRecorder(cfg CassandraConfig) (*CassandraRecorder, error) {
+	if cfg.Session == nil {
+		return nil, errors.New("audittrail: Cassandra session is required")
+	}
+	if cfg.Table == "" {
+		cfg.Table = "audit_trail"
+	}
+	for _, part := range strings.Split(cfg.Table, ".") {
+		if !isSafeIdentifier(part) {
+			return nil, fmt.Errorf("audittrail: invalid table name: %s", cfg.Table)
+		}
+	}
+	if cfg.Service == "" {
+		cfg.Service = "default"
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 8
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(cassandraColumns)), ", ")
+	return &CassandraRecorder{
+		cfg: cfg,
+		insert: fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) USING TTL ?",
+			cfg.Table, cassandraColumnList(), placeholders),
+	}, nil
+}
+
+// cassandraColumns lists the table schema; the partition key comes first.
+var cassandraColumns = []column{
+	{name: "log_day", ddl: "text"},
+	{name: "log_service", ddl: "text"},
+	{name: "log_created_date", ddl: "timestamp"},
+	{name: "log_audit_trail_id", ddl: "text"},
+	{name: "log_req_id", ddl: "text"},
+	{name: "log_action", ddl: "text"},
+	{name: "log_endpoint", ddl: "text"},
+	{name: "log_request", ddl: "text"},
+	{name: "log_response", ddl: "text"},
+	{name: "log_created_by", ddl: "text"},
+	{name: "log_expires_at", ddl: "timestamp"},
+	{name: "log_severity", ddl: "text"},
+	{name: "log_parent_id", ddl: "text"},
+	{name: "log_correlation_id", ddl: "text"},
+	{name: "log_session_id", ddl: "text"},
+	{name: "log_app_version", ddl: "text"},
+	{name: "log_hostname", ddl: "text"},
+	{name: "log_instance_id", ddl: "text"},
+	{name: "log_impersonated_by", ddl: "text"},
+	{name: "log_actor", ddl: "text"},
+	{name: "log_ip_address", ddl: "text"},
+	{name: "log_service_name", ddl: "text"},
+	{name: "log_started_at", ddl: "timestamp"},
+	{name: "log_ended_at", ddl: "timestamp"},
+	{name: "log_duration_ms", ddl: "bigint"},
+	{name: "log_entity_type", ddl: "text"},
+	{name: "log_entity_id", ddl: "text"},
+	{name: "log_meta", ddl: "text"},
+}
+
+func cassandraColumnList() string {
+	names := make([]string, len(cassandraColumns))
+	for i, col := range cassandraColumns {
+		names[i] = col.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// EnsureTable creates the table if it does not exist. The keyspace must already exist.
+func (c *CassandraRecorder) EnsureTable(ctx context.Context) error {
+	defs := make([]string, len(cassandraColumns))
+	for i, col := range cassandraColumns {
+		defs[i] = col.name + " " + col.ddl
+	}
+	stmt := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s, "+
+		"PRIMARY KEY ((log_day, log_service), log_created_date, log_audit_trail_id)) "+
+		"WITH CLUSTERING ORDER BY (log_created_date DESC, log_audit_trail_id ASC) "+
+		"AND compaction = {'class': 'TimeWindowCompactionStrategy', 'compaction_window_unit': 'DAYS', 'compaction_window_size': 1}",
+		c.cfg.Table, strings.Join(defs, ", "))
+	return c.cfg.Session.Exec(ctx, stmt)
+}
+
+// Record inserts one entry.
+func (c *CassandraRecorder) Record(ctx context.Context, entry Entry) error {
+	args, err := c.args(entry)
+	if err != nil {
+		return err
+	}
+	if err := c.cfg.Session.Exec(ctx, c.insert, args...); err != nil {
+		return fmt.Errorf("audittrail: Cassandra insert failed: %w", err)
+	}
+	return nil
+}
+
+// RecordBatch inserts entries concurrently rather than in a CQL batch, which would span
+// partitions and slow the coordinator down. It is not atomic; the first error is returned.
+func (c *CassandraRecorder) RecordBatch(ctx context.Context, entries []Entry) error {
+	sem := make(chan struct{}, c.cfg.Concurrency)
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		first error
+	)
+	for _, entry := range entries {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(entry Entry) {
+			defer func() { <-sem; wg.Done() }()
+			if err := c.Record(ctx, entry); err != nil {
+				mu.Lock()
+				if first == nil {
+					first = err
+				}
+				mu.Unlock()
+			}
+		}(entry)
+	}
+	wg.Wait()
+	return first
+}
+
+// args returns the insert values in cassandraColumns order, followed by the TTL in seconds
+// (0 means no TTL).
+func (c *CassandraRecorder) args(entry Entry) ([]any, error) {
+	normalized, err := normalizeEntry(entry, nil)
+	if err != nil {
+		return nil, err
+	}
+	request, err := marshalJSONValue(normalized.Request)
+	if err != nil {
+		return nil, marshalFailed("request", err)
+	}
+	response, err := marshalJSONValue(normalized.Response)
+	if err != nil {
+		return nil, marshalFailed("response", err)
+	}
+	meta, err := marshalMeta(normalized.Meta)
+	if err != nil {
+		return nil, marshalFailed("meta", err)
+	}
+
+	created := normalized.CreatedDate.UTC()
+	expires := normalized.ExpiresAt
+	if expires.IsZero() {
+		expires = ExpiryFor(c.cfg.Retention, normalized)
+	}
+	ttl := 0
+	var expiresAt any
+	switch {
+	case !expires.IsZero():
+		expiresAt = expires.UTC()
+		// A TTL of 0 means "keep forever" in CQL, so already expired entries get one second.
+		ttl = max(1, int(time.Until(expires).Seconds()))
+	case c.cfg.DefaultTTL > 0:
+		ttl = int(c.cfg.DefaultTTL.Seconds())
+	}
+
+	text := func(s string) any {
+		if s == "" {
+			return nil
+		}
+		return s
+	}
+	timestamp := func(t time.Time) any {
+		if t.IsZero() {
+			return nil
+		}
+		return t.UTC()
+	}
+	var duration any
+	if normalized.DurationMS != 0 {
+		duration = normalized.DurationMS
+	}
+	return []any{
+		created.Format("2006-01-02"),
+		c.cfg.Service,
+		created,
+		normalized.ID,
+		text(normalized.RequestID),
+		normalized.Action,
+		text(normalized.Endpoint),
+		text(request.String),
+		text(response.String),
+		text(normalized.CreatedBy),
+		expiresAt,
+		text(string(normalized.Severity)),
+		text(normalized.ParentID),
+		text(normalized.CorrelationID),
+		text(normalized.SessionID),
+		text(normalized.AppVersion),
+		text(normalized.Hostname),
+		text(normalized.InstanceID),
+		text(normalized.ImpersonatedBy),
+		text(normalized.Actor),
+		text(normalized.IPAddress),
+		text(normalized.ServiceName),
+		timestamp(normalized.StartedAt),
+		timestamp(normalized.EndedAt),
+		duration,
+		text(normalized.EntityType),
+		text(normalized.EntityID),
+		text(meta.String),
+		ttl,
+	}, nil
+}
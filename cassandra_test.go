@@ -0,0 +1,76 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCassandraRecorderInsertAndTTL(t *testing.T) {
+	var mu sync.Mutex
+	var stmts []string
+	var calls [][]any
+	session := CassandraExecFunc(func(_ context.Context, stmt string, args ...any) error {
+		mu.Lock()
+		defer mu.Unlock()
+		stmts = append(stmts, stmt)
+		calls = append(calls, args)
+		return nil
+	})
+	rec, err := NewCassandraRecorder(CassandraConfig{
+		Session:    session,
+		Table:      "audit.events",
+		Service:    "billing",
+		Retention:  []RetentionRule{{Action: "LOGIN_*", TTL: time.Hour}},
+		DefaultTTL: 24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewCassandraRecorder: %v", err)
+	}
+	ctx := context.Background()
+	if err := rec.EnsureTable(ctx); err != nil {
+		t.Fatalf("EnsureTable: %v", err)
+	}
+	if !strings.Contains(stmts[0], "CREATE TABLE IF NOT EXISTS audit.events (log_day text, ") ||
+		!strings.Contains(stmts[0], "PRIMARY KEY ((log_day, log_service), log_created_date, log_audit_trail_id))") ||
+		!strings.Contains(stmts[0], "TimeWindowCompactionStrategy") {
+		t.Fatalf("unexpected DDL: %s", stmts[0])
+	}
+
+	now := time.Now().UTC()
+	if err := rec.Record(ctx, Entry{ID: "e1", Action: "LOGIN_OK", CreatedBy: "u1", CreatedDate: now}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	args := calls[1]
+	if !strings.HasSuffix(stmts[1], "USING TTL ?") || len(args) != len(cassandraColumns)+1 {
+		t.Fatalf("unexpected insert %s with %d args", stmts[1], len(args))
+	}
+	if args[0] != now.Format("2006-01-02") || args[1] != "billing" || args[3] != "e1" || args[4] != nil || args[9] != "u1" {
+		t.Fatalf("unexpected args %v", args)
+	}
+	if ttl := args[len(args)-1].(int); ttl < 3590 || ttl > 3600 {
+		t.Fatalf("expected retention TTL of about an hour, got %d", ttl)
+	}
+
+	calls = nil
+	if err := rec.RecordBatch(ctx, []Entry{{Action: "EXPORT"}, {Action: "EXPORT"}, {Action: "EXPORT"}}); err != nil {
+		t.Fatalf("RecordBatch: %v", err)
+	}
+	if len(calls) != 3 || calls[0][len(calls[0])-1] != int((24*time.Hour).Seconds()) {
+		t.Fatalf("unexpected batch calls %v", calls)
+	}
+}
+
+func TestCassandraRecorderErrors(t *testing.T) {
+	if _, err := NewCassandraRecorder(CassandraConfig{Session: CassandraExecFunc(nil), Table: "a;b"}); err == nil {
+		t.Fatal("expected invalid table name error")
+	}
+	failing := CassandraExecFunc(func(context.Context, string, ...any) error { return errors.New("unavailable") })
+	rec, _ := NewCassandraRecorder(CassandraConfig{Session: failing})
+	if err := rec.RecordBatch(context.Background(), []Entry{{Action: "EXPORT"}}); err == nil {
+		t.Fatal("expected insert error")
+	}
+}
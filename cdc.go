@@ -0,0 +1,250 @@
+package audittrail
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Change is one row change decoded from the Postgres write-ahead log.
+type Change struct {
+	Kind   string         // INSERT, UPDATE or DELETE
+	Schema string         // e.g. "public"
+	Table  string         // e.g. "orders"
+	Old    map[string]any // UPDATE and DELETE: the replica identity (the whole row with REPLICA IDENTITY FULL)
+	New    map[string]any // INSERT and UPDATE: the new row
+	LSN    string         // log position of the change, e.g. "0/16B3748"
+	Time   time.Time      // commit time; zero if unknown
+}
+
+// CDCConfig configures a CDCListener.
+type CDCConfig struct {
+	DB   *sql.DB // Postgres connection; the role needs the REPLICATION attribute
+	Slot string  // logical replication slot using the wal2json plugin
+	// Tables limits decoding to these tables, written "schema.table". Never include the
+	// audit table itself.
+	Tables       []string
+	PollInterval time.Duration // used by Run; default 1s
+	BatchSize    int           // changes read per poll; default 1000
+	// Action names entries; default "DB <KIND> <schema>.<table>", e.g. "DB UPDATE public.orders".
+	Action func(Change) string
+	// Actor fills Actor, e.g. from an updated_by column. Changes carry no session user.
+	Actor func(Change) string
+}
+
+// CDCListener records changes made to Postgres tables, including those made outside the
+// application (psql sessions, migrations, other services), by tailing a logical
+// replication slot. Each INSERT, UPDATE or DELETE becomes an entry with the old row as
+// Request and the new row as Response.
+//
+// It reads the slot with pg_logical_slot_peek_changes over a regular connection, so the
+// slot must use the wal2json output plugin (format version 2). Changes decoded elsewhere,
+// e.g. from pgoutput with a replication-protocol client, can be passed to RecordChange.
+//
+// The slot is only advanced past a transaction after all of its changes were recorded,
+// and entry IDs are derived from the slot and LSN, so a change is recorded at least once
+// and an *AuditTrail stores it only once.
+type CDCListener struct {
+	cfg      CDCConfig
+	recorder Recorder
+}
+
+// NewCDCListener creates a listener that records changes in rec.
+func NewCDCListener(cfg CDCConfig, rec Recorder) (*CDCListener, error) {
+	if cfg.DB == nil {
+		return nil, errors.New("audittrail: CDC database must not be nil")
+	}
+	if cfg.Slot == "" {
+		return nil, errors.New("audittrail: CDC slot name is required")
+	}
+	if rec == nil {
+		return nil, errors.New("audittrail: CDC recorder must not be nil")
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1000
+	}
+	if cfg.Action == nil {
+		cfg.Action = func(c Change) string { return "DB " + c.Kind + " " + c.Schema + "." + c.Table }
+	}
+	return &CDCListener{cfg: cfg, recorder: rec}, nil
+}
+
+// EnsureSlot creates the replication slot with the wal2json plugin if it does not exist.
+// Postgres must run with wal_level = logical.
+func (l *CDCListener) EnsureSlot(ctx context.Context) error {
+	_, err := l.cfg.DB.ExecContext(ctx,
+		"SELECT pg_create_logical_replication_slot($1, 'wal2json') WHERE NOT EXISTS (SELECT 1 FROM pg_replication_slots WHERE slot_name = $1)",
+		l.cfg.Slot)
+	return err
+}
+
+// wal2jsonChange is one format-version 2 record.
+type wal2jsonChange struct {
+	Action    string `json:"action"` // B(egin), C(ommit), I, U, D, T(runcate), M(essage)
+	Schema    string `json:"schema"`
+	Table     string `json:"table"`
+	Timestamp string `json:"timestamp"`
+	Columns   []struct {
+		Name  string          `json:"name"`
+		Value json.RawMessage `json:"value"`
+	} `json:"columns"`
+	Identity []struct {
+		Name  string          `json:"name"`
+		Value json.RawMessage `json:"value"`
+	} `json:"identity"`
+}
+
+var wal2jsonKinds = map[string]string{"I": "INSERT", "U": "UPDATE", "D": "DELETE"}
+
+// Poll records the changes committed since the last poll, up to about BatchSize, and
+// returns how many were recorded.
+func (l *CDCListener) Poll(ctx context.Context) (int, error) {
+	query := "SELECT lsn::text, data FROM pg_logical_slot_peek_changes($1, NULL, $2, 'format-version', '2', 'include-timestamp', '1'"
+	args := []any{l.cfg.Slot, l.cfg.BatchSize}
+	if len(l.cfg.Tables) > 0 {
+		query += ", 'add-tables', $3"
+		args = append(args, strings.Join(l.cfg.Tables, ","))
+	}
+	rows, err := l.cfg.DB.QueryContext(ctx, query+")", args...)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		pending   []Change // changes of the current transaction
+		batch     []Change // changes of complete transactions
+		commitLSN string   // end of the last complete transaction
+		committed time.Time
+	)
+	for rows.Next() {
+		var lsn, data string
+		if err := rows.Scan(&lsn, &data); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		var rec wal2jsonChange
+		if err := json.Unmarshal([]byte(data), &rec); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("audittrail: decode wal2json change at %s: %w", lsn, err)
+		}
+		switch rec.Action {
+		case "B":
+			pending, committed = pending[:0], parseWal2jsonTime(rec.Timestamp)
+		case "C":
+			if t := parseWal2jsonTime(rec.Timestamp); !t.IsZero() {
+				committed = t
+			}
+			for i := range pending {
+				pending[i].Time = committed
+			}
+			batch = append(batch, pending...)
+			pending, commitLSN = nil, lsn
+		case "I", "U", "D":
+			change := Change{Kind: wal2jsonKinds[rec.Action], Schema: rec.Schema, Table: rec.Table, LSN: lsn}
+			if len(rec.Columns) > 0 {
+				change.New = make(map[string]any, len(rec.Columns))
+				for _, c := range rec.Columns {
+					change.New[c.Name] = c.Value
+				}
+			}
+			if len(rec.Identity) > 0 {
+				change.Old = make(map[string]any, len(rec.Identity))
+				for _, c := range rec.Identity {
+					change.Old[c.Name] = c.Value
+				}
+			}
+			pending = append(pending, change)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for i, change := range batch {
+		if err := l.RecordChange(ctx, change); err != nil {
+			return i, err
+		}
+	}
+	if commitLSN != "" {
+		if _, err := l.cfg.DB.ExecContext(ctx, "SELECT pg_replication_slot_advance($1, $2::pg_lsn)", l.cfg.Slot, commitLSN); err != nil {
+			return len(batch), err
+		}
+	}
+	return len(batch), nil
+}
+
+// RecordChange records one change. Its entry ID is derived from the slot and LSN, so
+// recording a change again after a failure does not duplicate it in an *AuditTrail.
+func (l *CDCListener) RecordChange(ctx context.Context, c Change) error {
+	entry := l.entry(c)
+	if audit, ok := l.recorder.(*AuditTrail); ok {
+		return audit.recordOnce(ctx, entry)
+	}
+	return l.recorder.Record(ctx, entry)
+}
+
+func (l *CDCListener) entry(c Change) Entry {
+	sum := sha256.Sum256([]byte(l.cfg.Slot + "/" + c.LSN + "/" + c.Schema + "." + c.Table))
+	entry := Entry{
+		ID:          hex.EncodeToString(sum[:16]),
+		Action:      l.cfg.Action(c),
+		Endpoint:    c.Schema + "." + c.Table,
+		CreatedDate: c.Time,
+		Severity:    SeverityInfo,
+	}
+	if c.Old != nil {
+		entry.Request = c.Old
+	}
+	if c.New != nil {
+		entry.Response = c.New
+	}
+	if c.Kind == "DELETE" {
+		entry.Severity = SeverityCritical
+	}
+	if l.cfg.Actor != nil {
+		entry.Actor = l.cfg.Actor(c)
+	}
+	return entry
+}
+
+// Run polls every PollInterval until ctx is canceled. Poll errors are logged and the
+// unrecorded changes retried on the next tick.
+func (l *CDCListener) Run(ctx context.Context) error {
+	ticker := time.NewTicker(l.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := l.Poll(ctx)
+			if err != nil && ctx.Err() == nil {
+				logger().Warn("audittrail: CDC poll failed", "slot", l.cfg.Slot, "error", err)
+			}
+			if err != nil || n < l.cfg.BatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// parseWal2jsonTime parses timestamps like "2024-03-01 10:48:34.517917+00".
+func parseWal2jsonTime(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04:05.999999999-07", s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t.UTC()
+}
@@ -0,0 +1,73 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCDCListenerPoll(t *testing.T) {
+	var queries, advanced []string
+	db := openStubDB(t, &stubDriver{
+		queryFn: func(query string, args []driver.NamedValue) (driver.Rows, error) {
+			queries = append(queries, query)
+			if args[2].Value != "public.orders" {
+				t.Errorf("unexpected table filter: %v", args[2].Value)
+			}
+			return &stubRows{columns: []string{"lsn", "data"}, values: [][]driver.Value{
+				{"0/10", `{"action":"B","timestamp":"2024-03-01 10:48:34.517917+00"}`},
+				{"0/11", `{"action":"U","schema":"public","table":"orders","columns":[{"name":"id","type":"integer","value":7},{"name":"status","type":"text","value":"paid"}],"identity":[{"name":"id","type":"integer","value":7},{"name":"status","type":"text","value":"open"}]}`},
+				{"0/12", `{"action":"D","schema":"public","table":"orders","identity":[{"name":"id","type":"integer","value":8}]}`},
+				{"0/13", `{"action":"C","timestamp":"2024-03-01 10:48:34.517917+00"}`},
+				// A transaction cut off by the batch limit is left for the next poll.
+				{"0/14", `{"action":"B"}`},
+				{"0/15", `{"action":"I","schema":"public","table":"orders","columns":[{"name":"id","type":"integer","value":9}]}`},
+			}}, nil
+		},
+		execFn: func(query string, args []driver.NamedValue) (driver.Result, error) {
+			if strings.Contains(query, "pg_replication_slot_advance") {
+				advanced = append(advanced, args[1].Value.(string))
+			}
+			return stubResult{}, nil
+		},
+	})
+
+	var got []Entry
+	rec := RecorderFunc(func(_ context.Context, e Entry) error {
+		got = append(got, e)
+		return nil
+	})
+	l, err := NewCDCListener(CDCConfig{DB: db, Slot: "audit", Tables: []string{"public.orders"}}, rec)
+	if err != nil {
+		t.Fatalf("NewCDCListener: %v", err)
+	}
+	n, err := l.Poll(context.Background())
+	if err != nil || n != 2 {
+		t.Fatalf("Poll = %d, %v", n, err)
+	}
+	if len(queries) != 1 || !strings.Contains(queries[0], "pg_logical_slot_peek_changes($1, NULL, $2, 'format-version', '2', 'include-timestamp', '1', 'add-tables', $3)") {
+		t.Fatalf("unexpected query: %v", queries)
+	}
+	if len(advanced) != 1 || advanced[0] != "0/13" {
+		t.Fatalf("slot should advance to the last commit: %v", advanced)
+	}
+
+	update, del := got[0], got[1]
+	old, _ := json.Marshal(update.Request)
+	row, _ := json.Marshal(update.Response)
+	if update.Action != "DB UPDATE public.orders" || update.Endpoint != "public.orders" || string(old) != `{"id":7,"status":"open"}` || string(row) != `{"id":7,"status":"paid"}` {
+		t.Fatalf("unexpected update entry: %+v", update)
+	}
+	if !update.CreatedDate.Equal(time.Date(2024, 3, 1, 10, 48, 34, 517917000, time.UTC)) || update.Severity != SeverityInfo {
+		t.Fatalf("unexpected update metadata: %v %s", update.CreatedDate, update.Severity)
+	}
+	if del.Action != "DB DELETE public.orders" || del.Response != nil || del.Severity != SeverityCritical {
+		t.Fatalf("unexpected delete entry: %+v", del)
+	}
+	if update.ID == "" || update.ID == del.ID || update.ID != l.entry(Change{Schema: "public", Table: "orders", LSN: "0/11"}).ID {
+		t.Fatalf("entry IDs must be stable per change: %q %q", update.ID, del.ID)
+	}
+}
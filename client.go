@@ -0,0 +1,177 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Client is an audit pipeline owned by the application instead of package state: it bundles
+// a recorder, an optional consumer and the middlewares bound to them, so it can be injected
+// with fx or wire and replaced in tests. Several clients can live in one process.
+type Client struct {
+	recorder Recorder
+	consumer *Consumer
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	closers []func() error
+}
+
+// ClientOption configures NewClient.
+type ClientOption func(*Client)
+
+// WithClientConsumer runs consumer from Start until Close.
+func WithClientConsumer(consumer *Consumer) ClientOption {
+	return func(c *Client) {
+		c.consumer = consumer
+	}
+}
+
+// WithClientCloser registers fn to run on Close after the consumer stopped, e.g. to close
+// the database or Pub/Sub client the pipeline uses.
+func WithClientCloser(fn func() error) ClientOption {
+	return func(c *Client) {
+		if fn != nil {
+			c.closers = append(c.closers, fn)
+		}
+	}
+}
+
+// NewClient creates a client recording through recorder.
+//
+//	fx.Provide(func(lc fx.Lifecycle, audit *audittrail.AuditTrail, sub audittrail.Subscriber, pub audittrail.Publisher) (*audittrail.Client, error) {
+//		rec, _ := audittrail.NewPubSubRecorder(pub, nil)
+//		consumer, _ := audittrail.NewConsumer(audit, sub, nil)
+//		client, err := audittrail.NewClient(rec, audittrail.WithClientConsumer(consumer))
+//		lc.Append(fx.Hook{OnStart: client.Start, OnStop: client.Close})
+//		return client, err
+//	})
+func NewClient(recorder Recorder, opts ...ClientOption) (*Client, error) {
+	if recorder == nil {
+		return nil, errors.New("audittrail: recorder must not be nil")
+	}
+	c := &Client{recorder: recorder}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(c)
+		}
+	}
+	return c, nil
+}
+
+// NewClientFromEnv builds a client configured like InitFromEnv, without touching the global
+// pipeline. Its consumer is already running; Close stops it and closes the connections.
+func NewClientFromEnv(ctx context.Context, opts ...InitOption) (*Client, error) {
+	options := &InitOptions{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(options)
+		}
+	}
+	if options.Resilient {
+		return nil, errors.New("audittrail: resilient mode is not supported for clients")
+	}
+	c := &Client{}
+	p, err := startPipeline(ctx, options, pipelineResources{}, &c.wg)
+	if err != nil {
+		return nil, err
+	}
+	c.recorder = p.recorder
+	c.cancel = p.cancel
+	if p.client != nil {
+		c.closers = append(c.closers, p.client.Close)
+	}
+	c.closers = append(c.closers, p.db.Close)
+	return c, nil
+}
+
+// Record records an entry synchronously.
+func (c *Client) Record(ctx context.Context, entry Entry) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return c.recorder.Record(ctx, entry)
+}
+
+// RecordAsync records an entry on the shared worker pool without blocking, with ctx's values
+// but a deadline of its own (10s); failures are reported to OnDrop hooks.
+func (c *Client) RecordAsync(ctx context.Context, entry Entry) {
+	recordAsync(ctx, c.recorder, []Entry{entry}, defaultAsyncTimeout, nil)
+}
+
+// Consumer returns the consumer run by the client, or nil.
+func (c *Client) Consumer() *Consumer {
+	return c.consumer
+}
+
+// Start runs the consumer in the background until Close or until ctx is canceled. It does
+// nothing without a consumer.
+func (c *Client) Start(ctx context.Context) error {
+	if c.consumer == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancel != nil {
+		return errors.New("audittrail: client already started")
+	}
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	c.cancel = cancel
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		if err := c.consumer.Run(runCtx); err != nil && !errors.Is(err, context.Canceled) {
+			logger().Error("audittrail: consumer stopped", "error", err)
+		}
+	}()
+	return nil
+}
+
+// Close stops the consumer, waiting for it until ctx is done, then runs the closers.
+func (c *Client) Close(ctx context.Context) error {
+	c.mu.Lock()
+	cancel := c.cancel
+	c.cancel = nil
+	c.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	c.mu.Lock()
+	closers := c.closers
+	c.closers = nil
+	c.mu.Unlock()
+	var errs []error
+	for _, fn := range closers {
+		errs = append(errs, fn())
+	}
+	return errors.Join(errs...)
+}
+
+// GinMiddleware is GinMiddleware recording through the client.
+func (c *Client) GinMiddleware(opts ...GinMiddlewareOption) gin.HandlerFunc {
+	return GinMiddleware(append([]GinMiddlewareOption{func(cfg *ginMiddlewareConfig) {
+		cfg.recorder = c.recorder
+	}}, opts...)...)
+}
+
+// HTTPMiddleware is HTTPMiddleware recording through the client.
+func (c *Client) HTTPMiddleware(opts ...HTTPMiddlewareOption) func(http.Handler) http.Handler {
+	return HTTPMiddleware(c.recorder, opts...)
+}
@@ -0,0 +1,93 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestClientRunsPipelineWithoutGlobals(t *testing.T) {
+	inserted := make(chan string, 1)
+	db := openStubDB(t, &stubDriver{execFn: func(_ string, args []driver.NamedValue) (driver.Result, error) {
+		inserted <- stringArg(args, 0)
+		return stubResult{}, nil
+	}})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	bus := NewMemoryPubSub(8)
+	rec, _ := NewPubSubRecorder(bus, nil)
+	consumer, _ := NewConsumer(audit, bus, nil)
+	closed := false
+	client, err := NewClient(rec, WithClientConsumer(consumer), WithClientCloser(func() error {
+		closed = true
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	ctx := context.Background()
+	if err := client.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := client.Start(ctx); err == nil {
+		t.Fatal("expected error starting twice")
+	}
+
+	if err := client.Record(ctx, Entry{ID: "e1", Action: "LOGIN"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	select {
+	case id := <-inserted:
+		if id != "e1" {
+			t.Fatalf("unexpected entry %q", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("entry was not persisted by the client's consumer")
+	}
+	if err := Record(ctx, Entry{Action: "LOGIN"}); err == nil {
+		t.Fatal("the client must not initialize the global pipeline")
+	}
+	if err := client.Close(ctx); err != nil || !closed {
+		t.Fatalf("Close: %v closed=%v", err, closed)
+	}
+}
+
+func TestClientGinMiddlewareUsesClientRecorder(t *testing.T) {
+	entries := make(chan Entry, 1)
+	client, _ := NewClient(RecorderFunc(func(_ context.Context, e Entry) error {
+		entries <- e
+		return nil
+	}))
+	useGlobalRecorder(t, RecorderFunc(func(context.Context, Entry) error {
+		return errors.New("global recorder must not be used")
+	}))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(client.GinMiddleware(WithCaptureRequestBody(false)))
+	r.GET("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders", nil))
+
+	select {
+	case e := <-entries:
+		if e.Endpoint != "/orders" {
+			t.Fatalf("unexpected entry: %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("middleware did not record through the client")
+	}
+
+	h := client.HTTPMiddleware()(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/orders/1", nil))
+	if e := <-entries; e.Endpoint != "/orders/1" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+}
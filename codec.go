@@ -0,0 +1,130 @@
+package audittrail
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// CurrentSchemaVersion is the Entry wire schema version written by this release.
+// Version 0 denotes payloads published before versioning was introduced.
+const CurrentSchemaVersion = 1
+
+// Codec encodes entries for transport between publishers and consumers.
+type Codec interface {
+	// Name identifies the encoding on the wire (e.g. as a message attribute).
+	Name() string
+	Marshal(entry Entry) ([]byte, error)
+	Unmarshal(data []byte) (Entry, error)
+}
+
+var codecs = struct {
+	sync.RWMutex
+	byName map[string]Codec
+}{byName: map[string]Codec{}}
+
+func init() {
+	RegisterCodec(JSONCodec)
+	RegisterCodec(ProtobufCodec)
+	RegisterCodec(AvroCodec)
+}
+
+// RegisterCodec makes a codec available to subscribers that select the decoder by name.
+func RegisterCodec(c Codec) {
+	if c == nil {
+		return
+	}
+	codecs.Lock()
+	codecs.byName[c.Name()] = c
+	codecs.Unlock()
+}
+
+// CodecByName returns a registered codec. An empty name selects JSONCodec.
+func CodecByName(name string) (Codec, bool) {
+	if name == "" {
+		return JSONCodec, true
+	}
+	codecs.RLock()
+	defer codecs.RUnlock()
+	c, ok := codecs.byName[name]
+	return c, ok
+}
+
+// schemaUpgrades converts a decoded payload of version N (the key) to version N+1 in place.
+// When a change to Entry alters the wire layout, bump CurrentSchemaVersion and add the
+// upgrade from the previous version here so older publishers stay readable.
+var schemaUpgrades = map[int]func(map[string]any) error{
+	// Unversioned payloads share the v1 layout; only the version marker is new.
+	0: func(map[string]any) error { return nil },
+}
+
+// JSONCodec is the default Codec. Marshal stamps CurrentSchemaVersion; Unmarshal upgrades
+// older payloads and decodes newer ones best-effort (unknown fields are ignored).
+var JSONCodec Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) Marshal(entry Entry) ([]byte, error) {
+	entry.SchemaVersion = CurrentSchemaVersion
+	data, err := jsonMarshal(entry)
+	if err != nil {
+		return nil, marshalFailed("entry", err)
+	}
+	return data, nil
+}
+
+func (jsonCodec) Unmarshal(data []byte) (Entry, error) {
+	var probe struct {
+		SchemaVersion int `json:"log_schema_version"`
+	}
+	if err := jsonUnmarshal(data, &probe); err != nil {
+		return Entry{}, fmt.Errorf("audittrail: decode entry failed: %w", err)
+	}
+
+	if probe.SchemaVersion < CurrentSchemaVersion {
+		upgraded, err := upgradeSchema(data, probe.SchemaVersion)
+		if err != nil {
+			return Entry{}, err
+		}
+		data = upgraded
+	}
+
+	// Payloads are kept as raw JSON instead of being decoded into maps, so the consumer
+	// stores them as received without a decode/encode round trip.
+	type plainEntry Entry
+	var entry Entry
+	wire := struct {
+		*plainEntry
+		Request  json.RawMessage `json:"log_request"`
+		Response json.RawMessage `json:"log_response"`
+	}{plainEntry: (*plainEntry)(&entry)}
+	if err := jsonUnmarshal(data, &wire); err != nil {
+		return Entry{}, fmt.Errorf("audittrail: decode entry failed: %w", err)
+	}
+	entry.Request = decodePayload(wire.Request)
+	entry.Response = decodePayload(wire.Response)
+	if entry.SchemaVersion < CurrentSchemaVersion {
+		entry.SchemaVersion = CurrentSchemaVersion
+	}
+	return entry, nil
+}
+
+func upgradeSchema(data []byte, from int) ([]byte, error) {
+	var fields map[string]any
+	if err := jsonUnmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("audittrail: decode entry failed: %w", err)
+	}
+	for v := from; v < CurrentSchemaVersion; v++ {
+		upgrade, ok := schemaUpgrades[v]
+		if !ok {
+			return nil, fmt.Errorf("audittrail: no upgrade from schema version %d", v)
+		}
+		if err := upgrade(fields); err != nil {
+			return nil, fmt.Errorf("audittrail: upgrade from schema version %d failed: %w", v, err)
+		}
+	}
+	fields["log_schema_version"] = CurrentSchemaVersion
+	return jsonMarshal(fields)
+}
@@ -0,0 +1,239 @@
+package audittrail
+
+import (
+	_ "embed"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AvroSchema is the Avro record schema implemented by AvroCodec.
+//
+//go:embed entry.avsc
+var AvroSchema string
+
+// AvroCodec encodes entries as Avro binary datums following AvroSchema.
+// Request and Response payloads are carried as embedded JSON strings. Avro has no
+// self-describing field tags, so producers and consumers must share the schema version.
+var AvroCodec Codec = avroCodec{}
+
+type avroCodec struct{}
+
+func (avroCodec) Name() string { return "avro" }
+
+func (avroCodec) Marshal(entry Entry) ([]byte, error) {
+	request, err := encodePayload(entry.Request)
+	if err != nil {
+		return nil, marshalFailed("request", err)
+	}
+	response, err := encodePayload(entry.Response)
+	if err != nil {
+		return nil, marshalFailed("response", err)
+	}
+	meta, err := marshalMeta(entry.Meta)
+	if err != nil {
+		return nil, marshalFailed("meta", err)
+	}
+
+	var b []byte
+	b = binary.AppendVarint(b, CurrentSchemaVersion)
+	b = appendAvroString(b, entry.ID)
+	b = appendAvroOptional(b, entry.RequestID)
+	b = appendAvroString(b, entry.Action)
+	b = appendAvroOptional(b, entry.Endpoint)
+	b = appendAvroOptional(b, string(request))
+	b = appendAvroOptional(b, string(response))
+	b = binary.AppendVarint(b, entry.CreatedDate.UnixMicro())
+	b = appendAvroOptional(b, entry.CreatedBy)
+	if entry.ExpiresAt.IsZero() {
+		b = binary.AppendVarint(b, 0)
+	} else {
+		b = binary.AppendVarint(b, 1)
+		b = binary.AppendVarint(b, entry.ExpiresAt.UnixMicro())
+	}
+	b = appendAvroOptional(b, string(entry.Severity))
+	b = appendAvroOptional(b, entry.ParentID)
+	b = appendAvroOptional(b, entry.CorrelationID)
+	b = appendAvroOptional(b, entry.SessionID)
+	b = appendAvroOptional(b, entry.AppVersion)
+	b = appendAvroOptional(b, entry.Hostname)
+	b = appendAvroOptional(b, entry.InstanceID)
+	b = appendAvroOptional(b, entry.ImpersonatedBy)
+	b = appendAvroOptional(b, entry.Actor)
+	b = appendAvroOptional(b, entry.IPAddress)
+	b = appendAvroOptional(b, entry.ServiceName)
+	b = appendAvroOptionalTime(b, entry.StartedAt)
+	b = appendAvroOptionalTime(b, entry.EndedAt)
+	b = appendAvroOptionalLong(b, entry.DurationMS)
+	b = appendAvroOptional(b, entry.EntityType)
+	b = appendAvroOptional(b, entry.EntityID)
+	b = appendAvroOptional(b, meta.String)
+	return b, nil
+}
+
+func (avroCodec) Unmarshal(data []byte) (Entry, error) {
+	d := avroDecoder{data: data}
+	entry := Entry{SchemaVersion: int(d.long())}
+	entry.ID = d.string()
+	entry.RequestID = d.optional()
+	entry.Action = d.string()
+	entry.Endpoint = d.optional()
+	entry.Request = decodePayload([]byte(d.optional()))
+	entry.Response = decodePayload([]byte(d.optional()))
+	entry.CreatedDate = time.UnixMicro(d.long()).UTC()
+	entry.CreatedBy = d.optional()
+	// Fields appended to the schema later; datums written before them end early.
+	if len(d.data) > 0 && d.long() == 1 {
+		entry.ExpiresAt = time.UnixMicro(d.long()).UTC()
+	}
+	if len(d.data) > 0 {
+		entry.Severity = Severity(d.optional())
+	}
+	if len(d.data) > 0 {
+		entry.ParentID = d.optional()
+		entry.CorrelationID = d.optional()
+	}
+	if len(d.data) > 0 {
+		entry.SessionID = d.optional()
+	}
+	if len(d.data) > 0 {
+		entry.AppVersion = d.optional()
+		entry.Hostname = d.optional()
+		entry.InstanceID = d.optional()
+	}
+	if len(d.data) > 0 {
+		entry.ImpersonatedBy = d.optional()
+	}
+	if len(d.data) > 0 {
+		entry.Actor = d.optional()
+		entry.IPAddress = d.optional()
+	}
+	if len(d.data) > 0 {
+		entry.ServiceName = d.optional()
+	}
+	if len(d.data) > 0 {
+		entry.StartedAt = d.optionalTime()
+		entry.EndedAt = d.optionalTime()
+		entry.DurationMS = d.optionalLong()
+	}
+	if len(d.data) > 0 {
+		entry.EntityType = d.optional()
+		entry.EntityID = d.optional()
+	}
+	if len(d.data) > 0 {
+		entry.Meta = unmarshalMeta([]byte(d.optional()))
+	}
+	if d.err != nil {
+		return Entry{}, d.err
+	}
+	if entry.SchemaVersion < CurrentSchemaVersion {
+		entry.SchemaVersion = CurrentSchemaVersion
+	}
+	return entry, nil
+}
+
+func appendAvroString(b []byte, s string) []byte {
+	b = binary.AppendVarint(b, int64(len(s)))
+	return append(b, s...)
+}
+
+// appendAvroOptional writes a ["null", "string"] union; empty strings are encoded as null.
+func appendAvroOptional(b []byte, s string) []byte {
+	if s == "" {
+		return binary.AppendVarint(b, 0)
+	}
+	b = binary.AppendVarint(b, 1)
+	return appendAvroString(b, s)
+}
+
+// appendAvroOptionalLong writes a ["null", "long"] union; zero is encoded as null.
+func appendAvroOptionalLong(b []byte, v int64) []byte {
+	if v == 0 {
+		return binary.AppendVarint(b, 0)
+	}
+	b = binary.AppendVarint(b, 1)
+	return binary.AppendVarint(b, v)
+}
+
+// appendAvroOptionalTime writes a ["null", timestamp-micros] union.
+func appendAvroOptionalTime(b []byte, t time.Time) []byte {
+	if t.IsZero() {
+		return binary.AppendVarint(b, 0)
+	}
+	b = binary.AppendVarint(b, 1)
+	return binary.AppendVarint(b, t.UnixMicro())
+}
+
+var errAvroShortBuffer = errors.New("audittrail: decode avro entry failed: unexpected end of data")
+
+// avroDecoder reads Avro primitives, remembering the first error.
+type avroDecoder struct {
+	data []byte
+	err  error
+}
+
+func (d *avroDecoder) long() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.data)
+	if n <= 0 {
+		d.err = errAvroShortBuffer
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *avroDecoder) string() string {
+	size := d.long()
+	if d.err != nil {
+		return ""
+	}
+	if size < 0 || int64(len(d.data)) < size {
+		d.err = errAvroShortBuffer
+		return ""
+	}
+	s := string(d.data[:size])
+	d.data = d.data[size:]
+	return s
+}
+
+func (d *avroDecoder) optionalLong() int64 {
+	switch branch := d.long(); {
+	case d.err != nil, branch == 0:
+		return 0
+	case branch == 1:
+		return d.long()
+	default:
+		d.err = fmt.Errorf("audittrail: decode avro entry failed: invalid union branch %d", branch)
+		return 0
+	}
+}
+
+func (d *avroDecoder) optionalTime() time.Time {
+	switch branch := d.long(); {
+	case d.err != nil, branch == 0:
+		return time.Time{}
+	case branch == 1:
+		return time.UnixMicro(d.long()).UTC()
+	default:
+		d.err = fmt.Errorf("audittrail: decode avro entry failed: invalid union branch %d", branch)
+		return time.Time{}
+	}
+}
+
+func (d *avroDecoder) optional() string {
+	switch branch := d.long(); {
+	case d.err != nil:
+		return ""
+	case branch == 0:
+		return ""
+	case branch == 1:
+		return d.string()
+	default:
+		d.err = fmt.Errorf("audittrail: decode avro entry failed: invalid union branch %d", branch)
+		return ""
+	}
+}
@@ -0,0 +1,298 @@
+package audittrail
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ProtobufCodec encodes entries using the audittrail.v1.Entry message defined in entry.proto.
+// Request and Response payloads are carried as embedded JSON. Unknown fields written by newer
+// schema versions are skipped on decode.
+var ProtobufCodec Codec = protobufCodec{}
+
+type protobufCodec struct{}
+
+const (
+	pbSchemaVersion  protowire.Number = 1
+	pbID             protowire.Number = 2
+	pbRequestID      protowire.Number = 3
+	pbAction         protowire.Number = 4
+	pbEndpoint       protowire.Number = 5
+	pbRequestJSON    protowire.Number = 6
+	pbResponseJSON   protowire.Number = 7
+	pbCreatedDate    protowire.Number = 8
+	pbCreatedBy      protowire.Number = 9
+	pbExpiresAt      protowire.Number = 10
+	pbSeverity       protowire.Number = 11
+	pbParentID       protowire.Number = 12
+	pbCorrelationID  protowire.Number = 13
+	pbSessionID      protowire.Number = 14
+	pbAppVersion     protowire.Number = 15
+	pbHostname       protowire.Number = 16
+	pbInstanceID     protowire.Number = 17
+	pbImpersonatedBy protowire.Number = 18
+	pbActor          protowire.Number = 19
+	pbIPAddress      protowire.Number = 20
+	pbServiceName    protowire.Number = 21
+	pbStartedAt      protowire.Number = 22
+	pbEndedAt        protowire.Number = 23
+	pbDurationMS     protowire.Number = 24
+	pbEntityType     protowire.Number = 25
+	pbEntityID       protowire.Number = 26
+	pbMetaJSON       protowire.Number = 27
+
+	pbTimestampSeconds protowire.Number = 1
+	pbTimestampNanos   protowire.Number = 2
+)
+
+func (protobufCodec) Name() string { return "protobuf" }
+
+func (protobufCodec) Marshal(entry Entry) ([]byte, error) {
+	request, err := encodePayload(entry.Request)
+	if err != nil {
+		return nil, marshalFailed("request", err)
+	}
+	response, err := encodePayload(entry.Response)
+	if err != nil {
+		return nil, marshalFailed("response", err)
+	}
+	meta, err := marshalMeta(entry.Meta)
+	if err != nil {
+		return nil, marshalFailed("meta", err)
+	}
+
+	var b []byte
+	b = protowire.AppendTag(b, pbSchemaVersion, protowire.VarintType)
+	b = protowire.AppendVarint(b, CurrentSchemaVersion)
+	b = appendPBString(b, pbID, entry.ID)
+	b = appendPBString(b, pbRequestID, entry.RequestID)
+	b = appendPBString(b, pbAction, entry.Action)
+	b = appendPBString(b, pbEndpoint, entry.Endpoint)
+	b = appendPBBytes(b, pbRequestJSON, request)
+	b = appendPBBytes(b, pbResponseJSON, response)
+	b = appendPBTimestamp(b, pbCreatedDate, entry.CreatedDate)
+	b = appendPBString(b, pbCreatedBy, entry.CreatedBy)
+	b = appendPBTimestamp(b, pbExpiresAt, entry.ExpiresAt)
+	b = appendPBString(b, pbSeverity, string(entry.Severity))
+	b = appendPBString(b, pbParentID, entry.ParentID)
+	b = appendPBString(b, pbCorrelationID, entry.CorrelationID)
+	b = appendPBString(b, pbSessionID, entry.SessionID)
+	b = appendPBString(b, pbAppVersion, entry.AppVersion)
+	b = appendPBString(b, pbHostname, entry.Hostname)
+	b = appendPBString(b, pbInstanceID, entry.InstanceID)
+	b = appendPBString(b, pbImpersonatedBy, entry.ImpersonatedBy)
+	b = appendPBString(b, pbActor, entry.Actor)
+	b = appendPBString(b, pbIPAddress, entry.IPAddress)
+	b = appendPBString(b, pbServiceName, entry.ServiceName)
+	b = appendPBTimestamp(b, pbStartedAt, entry.StartedAt)
+	b = appendPBTimestamp(b, pbEndedAt, entry.EndedAt)
+	if entry.DurationMS != 0 {
+		b = protowire.AppendTag(b, pbDurationMS, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(entry.DurationMS))
+	}
+	b = appendPBString(b, pbEntityType, entry.EntityType)
+	b = appendPBString(b, pbEntityID, entry.EntityID)
+	b = appendPBString(b, pbMetaJSON, meta.String)
+	return b, nil
+}
+
+func (protobufCodec) Unmarshal(data []byte) (Entry, error) {
+	var entry Entry
+	err := consumePBFields(data, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == pbSchemaVersion && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			entry.SchemaVersion = int(v)
+			return n, nil
+		case num == pbDurationMS && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			entry.DurationMS = int64(v)
+			return n, nil
+		case num == pbCreatedDate && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			created, err := decodePBTimestamp(v)
+			entry.CreatedDate = created
+			return n, err
+		case num == pbExpiresAt && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			expires, err := decodePBTimestamp(v)
+			entry.ExpiresAt = expires
+			return n, err
+		case (num == pbStartedAt || num == pbEndedAt) && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			ts, err := decodePBTimestamp(v)
+			if num == pbStartedAt {
+				entry.StartedAt = ts
+			} else {
+				entry.EndedAt = ts
+			}
+			return n, err
+		case typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			switch num {
+			case pbID:
+				entry.ID = string(v)
+			case pbRequestID:
+				entry.RequestID = string(v)
+			case pbAction:
+				entry.Action = string(v)
+			case pbEndpoint:
+				entry.Endpoint = string(v)
+			case pbRequestJSON:
+				entry.Request = decodePayload(v)
+			case pbResponseJSON:
+				entry.Response = decodePayload(v)
+			case pbCreatedBy:
+				entry.CreatedBy = string(v)
+			case pbSeverity:
+				entry.Severity = Severity(v)
+			case pbParentID:
+				entry.ParentID = string(v)
+			case pbCorrelationID:
+				entry.CorrelationID = string(v)
+			case pbSessionID:
+				entry.SessionID = string(v)
+			case pbAppVersion:
+				entry.AppVersion = string(v)
+			case pbHostname:
+				entry.Hostname = string(v)
+			case pbInstanceID:
+				entry.InstanceID = string(v)
+			case pbImpersonatedBy:
+				entry.ImpersonatedBy = string(v)
+			case pbActor:
+				entry.Actor = string(v)
+			case pbIPAddress:
+				entry.IPAddress = string(v)
+			case pbServiceName:
+				entry.ServiceName = string(v)
+			case pbEntityType:
+				entry.EntityType = string(v)
+			case pbEntityID:
+				entry.EntityID = string(v)
+			case pbMetaJSON:
+				entry.Meta = unmarshalMeta(v)
+			}
+			return n, nil
+		default:
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+	})
+	if err != nil {
+		return Entry{}, err
+	}
+	if entry.SchemaVersion < CurrentSchemaVersion {
+		entry.SchemaVersion = CurrentSchemaVersion
+	}
+	return entry, nil
+}
+
+// consumePBFields walks a protobuf message, calling fn with the bytes following each tag.
+// fn returns how many bytes it consumed (negative on malformed input).
+func consumePBFields(data []byte, fn func(protowire.Number, protowire.Type, []byte) (int, error)) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return fmt.Errorf("audittrail: decode protobuf entry failed: %w", protowire.ParseError(n))
+		}
+		data = data[n:]
+		m, err := fn(num, typ, data)
+		if err != nil {
+			return err
+		}
+		if m < 0 {
+			return fmt.Errorf("audittrail: decode protobuf entry failed: %w", protowire.ParseError(m))
+		}
+		data = data[m:]
+	}
+	return nil
+}
+
+func decodePBTimestamp(data []byte) (time.Time, error) {
+	var seconds, nanos int64
+	err := consumePBFields(data, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if typ != protowire.VarintType {
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+		v, n := protowire.ConsumeVarint(b)
+		switch num {
+		case pbTimestampSeconds:
+			seconds = int64(v)
+		case pbTimestampNanos:
+			nanos = int64(v)
+		}
+		return n, nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(seconds, nanos).UTC(), nil
+}
+
+// appendPBTimestamp writes t as a google.protobuf.Timestamp; the zero time is omitted.
+func appendPBTimestamp(b []byte, num protowire.Number, t time.Time) []byte {
+	if t.IsZero() {
+		return b
+	}
+	var ts []byte
+	ts = protowire.AppendTag(ts, pbTimestampSeconds, protowire.VarintType)
+	ts = protowire.AppendVarint(ts, uint64(t.Unix()))
+	ts = protowire.AppendTag(ts, pbTimestampNanos, protowire.VarintType)
+	ts = protowire.AppendVarint(ts, uint64(t.Nanosecond()))
+	return appendPBBytes(b, num, ts)
+}
+
+func appendPBString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendPBBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+// encodePayload renders a Request/Response value as JSON for binary codecs.
+func encodePayload(v any) ([]byte, error) {
+	switch val := v.(type) {
+	case nil:
+		return nil, nil
+	case json.RawMessage:
+		return val, nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// decodePayload restores a payload encoded by encodePayload. JSON strings become Go strings
+// so they are stored as plain text; null is nil; everything else stays raw JSON.
+func decodePayload(data []byte) any {
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+	if data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err == nil {
+			return s
+		}
+	}
+	return json.RawMessage(append([]byte(nil), data...))
+}
@@ -0,0 +1,147 @@
+package audittrail
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestJSONCodecStampsSchemaVersion(t *testing.T) {
+	data, err := MarshalEntryJSON(Entry{ID: "e1", Action: "login"})
+	if err != nil {
+		t.Fatalf("MarshalEntryJSON: %v", err)
+	}
+	if !strings.Contains(string(data), `"log_schema_version":1`) {
+		t.Fatalf("expected schema version in payload: %s", data)
+	}
+
+	entry, err := UnmarshalEntryJSON(data)
+	if err != nil {
+		t.Fatalf("UnmarshalEntryJSON: %v", err)
+	}
+	if entry.ID != "e1" || entry.SchemaVersion != CurrentSchemaVersion {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+}
+
+func TestJSONCodecDecodesOlderAndNewerPayloads(t *testing.T) {
+	legacy := []byte(`{"log_audit_trail_id":"e1","log_action":"login","log_created_date":"2024-01-02T03:04:05Z"}`)
+	entry, err := JSONCodec.Unmarshal(legacy)
+	if err != nil {
+		t.Fatalf("decode legacy: %v", err)
+	}
+	if entry.Action != "login" || entry.SchemaVersion != CurrentSchemaVersion || entry.CreatedDate.Year() != 2024 {
+		t.Fatalf("unexpected legacy entry: %+v", entry)
+	}
+
+	future := []byte(`{"log_schema_version":99,"log_audit_trail_id":"e2","log_action":"logout","log_new_field":{"x":1}}`)
+	entry, err = JSONCodec.Unmarshal(future)
+	if err != nil {
+		t.Fatalf("decode future: %v", err)
+	}
+	if entry.ID != "e2" || entry.SchemaVersion != 99 {
+		t.Fatalf("unexpected future entry: %+v", entry)
+	}
+
+	if _, err := JSONCodec.Unmarshal([]byte("not json")); err == nil {
+		t.Fatal("expected error for invalid payload")
+	}
+}
+
+func TestBinaryCodecsRoundTrip(t *testing.T) {
+	created := time.Date(2024, 6, 1, 13, 4, 5, 123456000, time.UTC)
+	in := Entry{
+		ID:          "e1",
+		RequestID:   "req-1",
+		Action:      "CREATE_ORDER",
+		Endpoint:    "/api/orders",
+		Request:     map[string]any{"qty": 2},
+		Response:    "created",
+		CreatedDate: created,
+		CreatedBy:   "u1",
+
+		ImpersonatedBy: "admin-7",
+		Actor:          "u2",
+		IPAddress:      "203.0.113.7",
+		ServiceName:    "orders",
+		StartedAt:      created,
+		EndedAt:        created.Add(1500 * time.Millisecond),
+		DurationMS:     1500,
+		EntityType:     "order",
+		EntityID:       "42",
+		Meta:           map[string]any{"total": 99.5},
+	}
+
+	for _, codec := range []Codec{ProtobufCodec, AvroCodec} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			data, err := codec.Marshal(in)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			out, err := codec.Unmarshal(data)
+			if err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if out.ID != in.ID || out.RequestID != in.RequestID || out.Action != in.Action ||
+				out.Endpoint != in.Endpoint || out.CreatedBy != in.CreatedBy || !out.CreatedDate.Equal(created) || out.ImpersonatedBy != in.ImpersonatedBy ||
+				out.Actor != in.Actor || out.IPAddress != in.IPAddress || out.ServiceName != in.ServiceName ||
+				!out.StartedAt.Equal(in.StartedAt) || !out.EndedAt.Equal(in.EndedAt) || out.DurationMS != in.DurationMS ||
+				out.EntityType != in.EntityType || out.EntityID != in.EntityID || out.Meta["total"] != 99.5 {
+				t.Fatalf("round trip mismatch: %+v", out)
+			}
+			if raw, ok := out.Request.(json.RawMessage); !ok || string(raw) != `{"qty":2}` {
+				t.Fatalf("unexpected request payload: %#v", out.Request)
+			}
+			if out.Response != "created" || out.SchemaVersion != CurrentSchemaVersion {
+				t.Fatalf("unexpected response/version: %#v %d", out.Response, out.SchemaVersion)
+			}
+			if got, ok := CodecByName(codec.Name()); !ok || got != codec {
+				t.Fatalf("codec %s not registered", codec.Name())
+			}
+			if _, err := codec.Unmarshal(data[:len(data)-1]); err == nil {
+				t.Fatal("expected error for truncated payload")
+			}
+		})
+	}
+}
+
+func TestProtobufCodecSkipsUnknownFields(t *testing.T) {
+	data, err := ProtobufCodec.Marshal(Entry{ID: "e1", Action: "login"})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	// A field added by a newer schema version.
+	data = protowire.AppendTag(data, 99, protowire.BytesType)
+	data = protowire.AppendString(data, "future")
+
+	entry, err := ProtobufCodec.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if entry.ID != "e1" || entry.Action != "login" {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+}
+
+func TestJSONCodecKeepsPayloadsRaw(t *testing.T) {
+	data := []byte(`{"log_schema_version":1,"log_audit_trail_id":"e1","log_action":"A","log_created_date":"2024-06-01T00:00:00Z",` +
+		`"log_request":{"b":1,"a":[true,null]},"log_response":"ok"}`)
+	entry, err := JSONCodec.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if raw, ok := entry.Request.(json.RawMessage); !ok || string(raw) != `{"b":1,"a":[true,null]}` {
+		t.Fatalf("request should stay raw JSON: %#v", entry.Request)
+	}
+	if entry.Response != "ok" || entry.ID != "e1" || entry.Action != "A" {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+
+	entry, err = JSONCodec.Unmarshal([]byte(`{"log_schema_version":1,"log_audit_trail_id":"e2","log_action":"A","log_created_date":"2024-06-01T00:00:00Z","log_request":null}`))
+	if err != nil || entry.Request != nil || entry.Response != nil {
+		t.Fatalf("null payloads should decode to nil: %#v, %v", entry, err)
+	}
+}
@@ -0,0 +1,138 @@
+package audittrail
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// WithBatchInsert makes the Consumer persist entries in batches of up to size, flushed at
+// least every wait. Each message is acknowledged only after its batch is stored. Batches
+// use multi-row INSERTs, or COPY when the AuditTrail has Config.CopyFrom, which speeds up
+// backfills considerably. The subscriber must deliver messages concurrently (GCP Pub/Sub
+// does; raise ReceiveSettings.MaxOutstandingMessages to at least size), otherwise every
+// message waits for the flush interval.
+func WithBatchInsert(size int, wait time.Duration) ConsumerOption {
+	return func(c *Consumer) {
+		if wait <= 0 {
+			wait = time.Second
+		}
+		c.batchSize = size
+		c.batchWait = wait
+	}
+}
+
+// pendingEntry is a received message waiting for its batch to be stored.
+type pendingEntry struct {
+	ctx   context.Context
+	entry Entry
+	done  chan error
+}
+
+func (c *Consumer) runBatched(ctx context.Context) error {
+	queue := make(chan pendingEntry)
+	loopCtx, stop := context.WithCancel(context.WithoutCancel(ctx))
+	loopDone := make(chan struct{})
+	go func() {
+		defer close(loopDone)
+		c.batchLoop(loopCtx, queue)
+	}()
+	defer func() {
+		stop()
+		<-loopDone
+	}()
+
+	return c.subscriber.Receive(ctx, func(ctx context.Context, entry Entry) error {
+		if err := c.gate.acquire(ctx); err != nil {
+			return err
+		}
+		defer c.gate.release()
+		p := pendingEntry{ctx: ctx, entry: entry, done: make(chan error, 1)}
+		select {
+		case queue <- p:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		// Wait for the flush even if ctx is canceled, so the message is not
+		// negatively acknowledged after it was stored.
+		return <-p.done
+	})
+}
+
+// batchLoop collects pending entries and flushes them when the batch is full, the wait
+// elapses, or ctx is canceled.
+func (c *Consumer) batchLoop(ctx context.Context, queue <-chan pendingEntry) {
+	batch := make([]pendingEntry, 0, c.batchSize)
+	timer := time.NewTimer(c.batchWait)
+	timer.Stop()
+	for {
+		select {
+		case p := <-queue:
+			if len(batch) == 0 {
+				timer.Reset(c.batchWait)
+			}
+			batch = append(batch, p)
+			if len(batch) < c.batchSize {
+				continue
+			}
+			timer.Stop()
+		case <-timer.C:
+		case <-ctx.Done():
+			c.flushBatch(batch)
+			return
+		}
+		c.flushBatch(batch)
+		batch = batch[:0]
+	}
+}
+
+// flushBatch stores a batch in one statement and checkpoints its highest position. If that
+// fails, entries are stored one by one in position order so a single bad entry does not
+// fail the whole batch; the checkpoint then stops before the first entry that failed, so a
+// resumed subscriber receives it again.
+func (c *Consumer) flushBatch(batch []pendingEntry) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx := context.WithoutCancel(batch[0].ctx)
+	entries := make([]Entry, len(batch))
+	var position string
+	for i, p := range batch {
+		entries[i] = p.entry
+		if pos := MessagePosition(p.ctx); pos != "" && (position == "" || positionLess(position, pos)) {
+			position = pos
+		}
+	}
+	if err := c.store(ctx, entries, position); err == nil {
+		for _, p := range batch {
+			c.observed(p.ctx, p.entry)
+			p.done <- nil
+		}
+		c.checkpoint(ctx, position)
+		return
+	}
+
+	sort.SliceStable(batch, func(i, j int) bool {
+		return positionLess(MessagePosition(batch[i].ctx), MessagePosition(batch[j].ctx))
+	})
+	var safe string // position of the last entry stored before any failure
+	failed := false
+	for _, p := range batch {
+		pos := MessagePosition(p.ctx)
+		if failed {
+			pos = ""
+		}
+		err := c.store(ctx, []Entry{p.entry}, pos)
+		if err != nil {
+			failed = true
+			c.failed(err)
+		} else {
+			c.observed(p.ctx, p.entry)
+			if pos != "" {
+				safe = pos
+			}
+		}
+		p.done <- err
+	}
+	c.checkpoint(ctx, safe)
+}
@@ -0,0 +1,210 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// concurrentSubscriber delivers every entry on its own goroutine, like GCP Pub/Sub.
+func concurrentSubscriber(entries []Entry, results chan<- error) Subscriber {
+	return SubscriberFunc(func(ctx context.Context, handler func(context.Context, Entry) error) error {
+		var wg sync.WaitGroup
+		for _, e := range entries {
+			wg.Add(1)
+			go func(e Entry) {
+				defer wg.Done()
+				results <- handler(ctx, e)
+			}(e)
+		}
+		wg.Wait()
+		return nil
+	})
+}
+
+func TestConsumerBatchInsert(t *testing.T) {
+	var mu sync.Mutex
+	var calls []execCall
+	db := openStubDB(t, &stubDriver{execFn: func(query string, args []driver.NamedValue) (driver.Result, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, execCall{query: query, args: args})
+		return stubResult{}, nil
+	}})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	entries := []Entry{{Action: "A"}, {Action: "B"}, {Action: "C"}, {Action: "D"}}
+	results := make(chan error, len(entries))
+	consumer, err := NewConsumer(audit, concurrentSubscriber(entries, results), nil, WithBatchInsert(4, time.Minute))
+	if err != nil {
+		t.Fatalf("NewConsumer: %v", err)
+	}
+	if err := consumer.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for range entries {
+		if err := <-results; err != nil {
+			t.Fatalf("handler: %v", err)
+		}
+	}
+	if len(calls) != 1 || len(calls[0].args) != 4*insertColumnCount || !strings.HasSuffix(calls[0].query, "ON CONFLICT (log_audit_trail_id) DO NOTHING") {
+		t.Fatalf("expected one batched insert, got %d calls", len(calls))
+	}
+}
+
+func TestConsumerBatchIsolatesFailures(t *testing.T) {
+	db := openStubDB(t, &stubDriver{execFn: func(query string, args []driver.NamedValue) (driver.Result, error) {
+		if len(args) > insertColumnCount || args[2].Value == "BAD" {
+			return nil, errors.New("constraint violation")
+		}
+		return stubResult{}, nil
+	}})
+	audit, err := NewAuditTrail(Config{DB: db})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	entries := []Entry{{Action: "GOOD"}, {Action: "BAD"}}
+	results := make(chan error, len(entries))
+	consumer, err := NewConsumer(audit, concurrentSubscriber(entries, results), func(error) {}, WithBatchInsert(10, 10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewConsumer: %v", err)
+	}
+	if err := consumer.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	var failed int
+	for range entries {
+		if <-results != nil {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Fatalf("expected only the bad entry to fail, got %d failures", failed)
+	}
+}
+
+func TestConsumerBatchCheckpointsHighestStoredPosition(t *testing.T) {
+	db := openStubDB(t, &stubDriver{execFn: func(query string, args []driver.NamedValue) (driver.Result, error) {
+		if len(args) > insertColumnCount || args[2].Value == "BAD" {
+			return nil, errors.New("constraint violation")
+		}
+		return stubResult{}, nil
+	}})
+	audit, err := NewAuditTrail(Config{DB: db})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	// Positions "9" < "10" < "11" in stream order, though "9" sorts last as a string.
+	deliver := func(actions ...string) Subscriber {
+		return SubscriberFunc(func(ctx context.Context, handler func(context.Context, Entry) error) error {
+			var wg sync.WaitGroup
+			for i, action := range actions {
+				wg.Add(1)
+				go func(pos string, e Entry) {
+					defer wg.Done()
+					_ = handler(WithMessagePosition(ctx, pos), e)
+				}(fmt.Sprint(9+i), Entry{Action: action})
+			}
+			wg.Wait()
+			return nil
+		})
+	}
+	cp := &memoryCheckpointer{}
+	run := func(sub Subscriber) {
+		t.Helper()
+		consumer, err := NewConsumer(audit, sub, func(error) {}, WithBatchInsert(3, time.Minute), WithCheckpointer(cp))
+		if err != nil {
+			t.Fatalf("NewConsumer: %v", err)
+		}
+		if err := consumer.Run(context.Background()); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	}
+
+	run(deliver("GOOD", "GOOD", "GOOD"))
+	if len(cp.positions) != 1 || cp.positions[0] != "11" {
+		t.Fatalf("expected the highest position of the batch, got %v", cp.positions)
+	}
+
+	// Entry 1 of 3 fails: the entries after it are stored, but the checkpoint must not move
+	// past it or a resumed subscriber would never redeliver it.
+	cp.positions = nil
+	run(deliver("BAD", "GOOD", "GOOD"))
+	if len(cp.positions) != 0 {
+		t.Fatalf("checkpoint advanced past the failed entry: %v", cp.positions)
+	}
+
+	cp.positions = nil
+	run(deliver("GOOD", "BAD", "GOOD"))
+	if len(cp.positions) != 1 || cp.positions[0] != "9" {
+		t.Fatalf("expected the checkpoint to stop before the failed entry, got %v", cp.positions)
+	}
+}
+
+func TestPositionLess(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		less bool
+	}{
+		{"9", "10", true},
+		{"10", "9", false},
+		{"1700000000000-9", "1700000000000-10", true},
+		{"p0:041", "p0:42", true},
+		{"p0:42", "p0:42", false},
+		{"a", "b", true},
+		{"", "1", true},
+	} {
+		if got := positionLess(c.a, c.b); got != c.less {
+			t.Errorf("positionLess(%q, %q) = %v, want %v", c.a, c.b, got, c.less)
+		}
+	}
+}
+
+func TestRecordBatchUsesCopyFrom(t *testing.T) {
+	var inserts int
+	db := openStubDB(t, &stubDriver{execFn: func(string, []driver.NamedValue) (driver.Result, error) {
+		inserts++
+		return stubResult{}, nil
+	}})
+	var copied [][]any
+	var columns []string
+	copyErr := error(nil)
+	audit, err := NewAuditTrail(Config{
+		DB:            db,
+		CopyThreshold: 3,
+		CopyFrom: func(_ context.Context, table string, cols []string, rows [][]any) (int64, error) {
+			if copyErr != nil {
+				return 0, copyErr
+			}
+			columns, copied = cols, rows
+			return int64(len(rows)), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := audit.RecordBatch(ctx, []Entry{{Action: "A"}, {Action: "B"}}); err != nil || inserts != 1 || copied != nil {
+		t.Fatalf("small batch should INSERT: err=%v inserts=%d", err, inserts)
+	}
+	if err := audit.RecordBatch(ctx, []Entry{{Action: "A", CreatedBy: "u1"}, {Action: "B"}, {Action: "C"}}); err != nil {
+		t.Fatalf("RecordBatch: %v", err)
+	}
+	if inserts != 1 || len(copied) != 3 || len(columns) != insertColumnCount || copied[0][7] != "u1" || copied[1][7] != nil {
+		t.Fatalf("expected COPY, inserts=%d rows=%v", inserts, copied)
+	}
+
+	copyErr = errors.New("duplicate key")
+	if err := audit.RecordBatch(ctx, []Entry{{Action: "A"}, {Action: "B"}, {Action: "C"}}); err != nil || inserts != 2 {
+		t.Fatalf("expected INSERT fallback, err=%v inserts=%d", err, inserts)
+	}
+}
@@ -0,0 +1,92 @@
+package audittrail
+
+import (
+	"context"
+	"sync"
+)
+
+// consumerGate holds messages back while the consumer is paused or at its concurrency limit.
+type consumerGate struct {
+	mu      sync.Mutex
+	paused  bool
+	limit   int // messages persisted at once; 0 means unlimited
+	active  int
+	changed chan struct{} // closed and replaced whenever the state changes
+}
+
+// acquire waits until the gate is open and takes a slot.
+func (g *consumerGate) acquire(ctx context.Context) error {
+	for {
+		g.mu.Lock()
+		if !g.paused && (g.limit <= 0 || g.active < g.limit) {
+			g.active++
+			g.mu.Unlock()
+			return nil
+		}
+		changed := g.wait()
+		g.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (g *consumerGate) release() {
+	g.update(func() { g.active-- })
+}
+
+// update applies fn under the lock and wakes the waiters.
+func (g *consumerGate) update(fn func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	fn()
+	if g.changed != nil {
+		close(g.changed)
+		g.changed = nil
+	}
+}
+
+// wait returns the channel closed on the next state change; g.mu must be held.
+func (g *consumerGate) wait() <-chan struct{} {
+	if g.changed == nil {
+		g.changed = make(chan struct{})
+	}
+	return g.changed
+}
+
+// WithConcurrency limits how many messages the consumer persists at once (see
+// SetConcurrency). Default: as many as the subscriber delivers.
+func WithConcurrency(n int) ConsumerOption {
+	return func(c *Consumer) { c.gate.limit = max(n, 0) }
+}
+
+// Pause stops persisting entries, e.g. during database maintenance. Messages delivered while
+// paused wait in their handler, unacknowledged, until Resume, so nothing is dropped; the
+// subscriber's flow control (ReceiveSettings.MaxOutstandingMessages for Pub/Sub) then stops
+// further deliveries. Pub/Sub extends the leases of waiting messages for up to
+// ReceiveSettings.MaxExtension, after which they are redelivered.
+func (c *Consumer) Pause() {
+	c.gate.update(func() { c.gate.paused = true })
+}
+
+// Resume continues persisting after Pause.
+func (c *Consumer) Resume() {
+	c.gate.update(func() { c.gate.paused = false })
+}
+
+// Paused reports whether the consumer is paused.
+func (c *Consumer) Paused() bool {
+	c.gate.mu.Lock()
+	defer c.gate.mu.Unlock()
+	return c.gate.paused
+}
+
+// SetConcurrency changes how many messages are persisted at once while the consumer runs;
+// n <= 0 removes the limit. Lowering it lets messages in progress finish, then holds new
+// ones back until fewer than n remain. With WithBatchInsert it bounds the messages waiting
+// for a batch, so keep it at least the batch size.
+func (c *Consumer) SetConcurrency(n int) {
+	c.gate.update(func() { c.gate.limit = max(n, 0) })
+}
@@ -0,0 +1,117 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// payEntries returns n entries with distinct IDs.
+func payEntries(n int) []Entry {
+	entries := make([]Entry, n)
+	for i := range entries {
+		entries[i] = Entry{ID: fmt.Sprint("e", i), Action: "PAY"}
+	}
+	return entries
+}
+
+func TestConsumerPauseHoldsMessagesUntilResume(t *testing.T) {
+	var inserted atomic.Int32
+	db := openStubDB(t, &stubDriver{execFn: func(string, []driver.NamedValue) (driver.Result, error) {
+		inserted.Add(1)
+		return stubResult{}, nil
+	}})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	c, err := NewConsumer(audit, concurrentSubscriber(payEntries(2), make(chan error, 2)), nil)
+	if err != nil {
+		t.Fatalf("NewConsumer: %v", err)
+	}
+
+	c.Pause()
+	if !c.Paused() {
+		t.Fatal("expected consumer to be paused")
+	}
+	done := make(chan error, 1)
+	go func() { done <- c.Run(context.Background()) }()
+
+	time.Sleep(20 * time.Millisecond)
+	if n := inserted.Load(); n != 0 {
+		t.Fatalf("expected no inserts while paused, got %d", n)
+	}
+	c.Resume()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("consumer did not resume")
+	}
+	if n := inserted.Load(); n != 2 {
+		t.Fatalf("expected 2 inserts after resume, got %d", n)
+	}
+}
+
+func TestConsumerConcurrencyLimit(t *testing.T) {
+	var active, peak atomic.Int32
+	db := openStubDB(t, &stubDriver{execFn: func(string, []driver.NamedValue) (driver.Result, error) {
+		n := active.Add(1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(2 * time.Millisecond)
+		active.Add(-1)
+		return stubResult{}, nil
+	}})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	c, err := NewConsumer(audit, concurrentSubscriber(payEntries(8), make(chan error, 16)), nil, WithConcurrency(2))
+	if err != nil {
+		t.Fatalf("NewConsumer: %v", err)
+	}
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if p := peak.Load(); p > 2 {
+		t.Fatalf("expected at most 2 concurrent inserts, got %d", p)
+	}
+
+	peak.Store(0)
+	c.SetConcurrency(1)
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if p := peak.Load(); p != 1 {
+		t.Fatalf("expected serial inserts after SetConcurrency(1), got peak %d", p)
+	}
+	if s := c.Stats(); s.Processed != 16 {
+		t.Fatalf("expected 16 processed, got %d", s.Processed)
+	}
+}
+
+func TestConsumerPausedHandlerHonorsCancel(t *testing.T) {
+	db := openStubDB(t, &stubDriver{})
+	audit, _ := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	var handlerErr error
+	c, _ := NewConsumer(audit, SubscriberFunc(func(ctx context.Context, handler func(context.Context, Entry) error) error {
+		handlerErr = handler(ctx, Entry{ID: "e1", Action: "PAY"})
+		return nil
+	}), nil)
+	c.Pause()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if handlerErr != context.DeadlineExceeded {
+		t.Fatalf("expected the held message to be released on cancel, got %v", handlerErr)
+	}
+}
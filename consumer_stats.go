@@ -0,0 +1,116 @@
+package audittrail
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// BacklogReporter is implemented by subscribers that can tell how many messages are waiting
+// to be delivered.
+type BacklogReporter interface {
+	Backlog() (int64, error)
+}
+
+// ConsumerStats is a snapshot of a Consumer's progress since it was created.
+type ConsumerStats struct {
+	Processed   uint64 // entries persisted
+	Errors      uint64 // entries that failed to persist and were left for redelivery
+	Quarantined uint64 // undecodable messages stored by WithPoisonQuarantine
+
+	LastMessageAt time.Time     // when the last entry was persisted; zero before the first
+	LastEntryAt   time.Time     // CreatedDate of that entry
+	Lag           time.Duration // LastMessageAt - LastEntryAt: how far persistence trails producers
+
+	// Backlog is the number of messages waiting in the broker, when the subscriber
+	// implements BacklogReporter; -1 otherwise or when the broker could not be asked.
+	Backlog int64
+}
+
+// consumerCounters holds the values behind Consumer.Stats.
+type consumerCounters struct {
+	processed   atomic.Uint64
+	errors      atomic.Uint64
+	quarantined atomic.Uint64
+	lastMessage atomic.Int64 // unix nanoseconds
+	lastEntry   atomic.Int64
+}
+
+// Stats returns the consumer's counters, lag and, where supported, broker backlog.
+func (c *Consumer) Stats() ConsumerStats {
+	s := ConsumerStats{
+		Processed:   c.stats.processed.Load(),
+		Errors:      c.stats.errors.Load(),
+		Quarantined: c.stats.quarantined.Load(),
+		Backlog:     -1,
+	}
+	if ns := c.stats.lastMessage.Load(); ns != 0 {
+		s.LastMessageAt = time.Unix(0, ns).UTC()
+		s.LastEntryAt = time.Unix(0, c.stats.lastEntry.Load()).UTC()
+		s.Lag = s.LastMessageAt.Sub(s.LastEntryAt)
+	}
+	if br, ok := c.subscriber.(BacklogReporter); ok {
+		if n, err := br.Backlog(); err == nil {
+			s.Backlog = n
+		}
+	}
+	return s
+}
+
+// WithStatsReport calls fn with the consumer's Stats every interval while Run is active, e.g.
+// to export them as metrics or alert when Lag grows. A nil fn logs them.
+func WithStatsReport(interval time.Duration, fn func(ConsumerStats)) ConsumerOption {
+	return func(c *Consumer) {
+		if fn == nil {
+			fn = func(s ConsumerStats) {
+				logger().Info("audittrail: consumer stats", "processed", s.Processed, "errors", s.Errors,
+					"quarantined", s.Quarantined, "lag", s.Lag, "backlog", s.Backlog)
+			}
+		}
+		c.statsInterval = interval
+		c.statsReport = fn
+	}
+}
+
+// reportStats calls the stats report until ctx is done.
+func (c *Consumer) reportStats(ctx context.Context) {
+	ticker := time.NewTicker(c.statsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.statsReport(c.Stats())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// observe counts a persisted entry.
+func (c *Consumer) observe(entry Entry) {
+	c.stats.processed.Add(1)
+	c.stats.lastEntry.Store(entry.CreatedDate.UnixNano())
+	c.stats.lastMessage.Store(c.audit.now().UnixNano())
+}
+
+// failed counts an entry that could not be persisted and reports err.
+func (c *Consumer) failed(err error) {
+	c.stats.errors.Add(1)
+	if c.onError != nil {
+		c.onError(err)
+	}
+}
+
+// countingPoisonStore counts the messages the consumer quarantines.
+type countingPoisonStore struct {
+	PoisonStore
+	counters *consumerCounters
+}
+
+func (s countingPoisonStore) Store(ctx context.Context, msg PoisonMessage) error {
+	err := s.PoisonStore.Store(ctx, msg)
+	if err == nil {
+		s.counters.quarantined.Add(1)
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+package audittrail
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsumerStats(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 30, 0, time.UTC)
+	db := openStubDB(t, &stubDriver{execFn: func(_ string, args []driver.NamedValue) (driver.Result, error) {
+		if stringArg(args, 0) == "bad" {
+			return nil, errors.New("constraint violation")
+		}
+		return stubResult{}, nil
+	}})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar, Clock: ClockFunc(func() time.Time { return now })})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	mem := NewMemoryPubSub(8)
+	ctx, cancel := context.WithCancel(context.Background())
+	created := now.Add(-30 * time.Second)
+	for _, id := range []string{"e1", "bad", "e2"} {
+		_ = mem.Publish(ctx, Entry{ID: id, Action: "PAY", CreatedDate: created})
+	}
+	_ = mem.Publish(ctx, Entry{ID: "stop", Action: "PAY", CreatedDate: created})
+
+	reports := make(chan ConsumerStats, 16)
+	c, err := NewConsumer(audit, SubscriberFunc(func(ctx context.Context, handler func(context.Context, Entry) error) error {
+		return mem.Receive(ctx, func(ctx context.Context, e Entry) error {
+			if e.ID == "stop" {
+				cancel()
+				return nil
+			}
+			return handler(ctx, e)
+		})
+	}), func(error) {}, WithStatsReport(time.Millisecond, func(s ConsumerStats) {
+		select {
+		case reports <- s:
+		default:
+		}
+	}))
+	if err != nil {
+		t.Fatalf("NewConsumer: %v", err)
+	}
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	s := c.Stats()
+	if s.Processed != 2 || s.Errors != 1 || s.Quarantined != 0 {
+		t.Fatalf("unexpected counts: %+v", s)
+	}
+	if !s.LastMessageAt.Equal(now) || !s.LastEntryAt.Equal(created) || s.Lag != 30*time.Second {
+		t.Fatalf("unexpected lag: %+v", s)
+	}
+	if s.Backlog != -1 {
+		t.Fatalf("expected unknown backlog for SubscriberFunc, got %d", s.Backlog)
+	}
+
+	direct, _ := NewConsumer(audit, mem, nil)
+	_ = mem.Publish(context.Background(), Entry{ID: "e3", Action: "PAY"})
+	if got := direct.Stats().Backlog; got != 1 {
+		t.Fatalf("expected backlog 1, got %d", got)
+	}
+}
+
+func TestConsumerStatsReportLogsByDefault(t *testing.T) {
+	c := &Consumer{audit: &AuditTrail{}, subscriber: NewMemoryPubSub(1)}
+	WithStatsReport(time.Second, nil)(c)
+	var buf bytes.Buffer
+	SetLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer SetLogger(nil)
+	c.statsReport(c.Stats())
+	if !strings.Contains(buf.String(), "consumer stats") || !strings.Contains(buf.String(), "backlog=0") {
+		t.Fatalf("unexpected log: %s", buf.String())
+	}
+}
@@ -0,0 +1,45 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+)
+
+// defaultCopyThreshold is the batch size from which RecordBatch prefers CopyFrom.
+const defaultCopyThreshold = 1000
+
+// CopyFromFunc bulk-loads rows into table, e.g. with Postgres COPY FROM. Columns are the
+// audit table columns in row order; values are nil, strings, []byte and time.Time. It must
+// store all rows or none and return the number stored. With pgx:
+//
+//	CopyFrom: func(ctx context.Context, table string, columns []string, rows [][]any) (int64, error) {
+//	    return pool.CopyFrom(ctx, pgx.Identifier{table}, columns, pgx.CopyFromRows(rows))
+//	}
+//
+// This package does not import pgx itself, so the function is supplied by the application.
+type CopyFromFunc func(ctx context.Context, table string, columns []string, rows [][]any) (int64, error)
+
+// copyRows sends insert arguments (insertColumnCount per row) through the CopyFrom function.
+func (r *AuditTrail) copyRows(ctx context.Context, args []any) error {
+	rows := make([][]any, 0, len(args)/insertColumnCount)
+	for start := 0; start < len(args); start += insertColumnCount {
+		row := make([]any, insertColumnCount)
+		for i, v := range args[start : start+insertColumnCount] {
+			row[i] = copyValue(v)
+		}
+		rows = append(rows, row)
+	}
+	_, err := r.copyFrom(ctx, r.table, strings.Split(insertColumns, ", "), rows)
+	return err
+}
+
+// copyValue unwraps sql.Null* arguments, which COPY encoders do not all understand.
+func copyValue(v any) any {
+	if valuer, ok := v.(driver.Valuer); ok {
+		if val, err := valuer.Value(); err == nil {
+			return val
+		}
+	}
+	return v
+}
@@ -0,0 +1,89 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// EnsureID assigns a new ID if the entry has none and returns it, so derived entries can
+// reference an entry before it is recorded.
+func (e *Entry) EnsureID() string {
+	if e.ID == "" {
+		e.ID = newID()
+	}
+	return e.ID
+}
+
+// DerivedFrom links child to the entry that caused it, e.g. a refund triggered by a
+// cancellation. The child gets ParentID = parent.ID and inherits the chain's CorrelationID;
+// a parent without one starts a chain identified by its own ID. parent is updated in place,
+// so record it after calling DerivedFrom.
+//
+//	cancel := audittrail.Entry{Action: "CANCEL_ORDER"}
+//	refund := audittrail.DerivedFrom(&cancel, audittrail.Entry{Action: "REFUND_PAYMENT"})
+//	_ = rec.Record(ctx, cancel)
+//	_ = rec.Record(ctx, refund)
+func DerivedFrom(parent *Entry, child Entry) Entry {
+	parent.EnsureID()
+	if parent.CorrelationID == "" {
+		parent.CorrelationID = parent.ID
+	}
+	child.ParentID = parent.ID
+	child.CorrelationID = parent.CorrelationID
+	return child
+}
+
+// EntryNode is an entry with the entries it caused.
+type EntryNode struct {
+	Entry
+	Children []*EntryNode
+}
+
+// BuildTree arranges entries into causal trees using ParentID. Entries whose parent is not
+// in the slice become roots. Roots and children are ordered by CreatedDate.
+func BuildTree(entries []Entry) []*EntryNode {
+	nodes := make(map[string]*EntryNode, len(entries))
+	ordered := make([]*EntryNode, len(entries))
+	for i, e := range entries {
+		n := &EntryNode{Entry: e}
+		ordered[i] = n
+		if e.ID != "" {
+			nodes[e.ID] = n
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].CreatedDate.Before(ordered[j].CreatedDate)
+	})
+
+	var roots []*EntryNode
+	for _, n := range ordered {
+		if parent, ok := nodes[n.ParentID]; ok && n.ParentID != "" && parent != n {
+			parent.Children = append(parent.Children, n)
+			continue
+		}
+		roots = append(roots, n)
+	}
+	return roots
+}
+
+// Chain loads every entry of a causal chain (the root entry and all entries carrying its
+// CorrelationID) and returns it as a tree.
+func (r *AuditTrail) Chain(ctx context.Context, correlationID string) ([]*EntryNode, error) {
+	if r == nil || r.db == nil {
+		return nil, errors.New("audittrail: instance is not initialized")
+	}
+	if correlationID == "" {
+		return nil, errors.New("audittrail: correlation ID must not be empty")
+	}
+	ctx = r.auditRead(ctx, "chain", Filter{CorrelationID: correlationID}, nil)
+	b := &queryBuilder{placeholder: r.placeholder, dialect: r.dialect}
+	clause := fmt.Sprintf(" WHERE log_correlation_id = %s OR log_audit_trail_id = %s ORDER BY log_created_date",
+		b.arg(correlationID), b.arg(correlationID))
+	entries, err := r.queryEntries(ctx, clause, b.args...)
+	if err != nil {
+		return nil, err
+	}
+	return BuildTree(entries), nil
+}
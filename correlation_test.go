@@ -0,0 +1,68 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDerivedFromBuildsChain(t *testing.T) {
+	cancel := Entry{Action: "CANCEL_ORDER"}
+	refund := DerivedFrom(&cancel, Entry{Action: "REFUND_PAYMENT"})
+	refund.EnsureID()
+	email := DerivedFrom(&refund, Entry{Action: "SEND_EMAIL"})
+
+	if cancel.ID == "" || cancel.CorrelationID != cancel.ID {
+		t.Fatalf("root should start the chain: %+v", cancel)
+	}
+	if refund.ParentID != cancel.ID || email.ParentID != refund.ID || email.CorrelationID != cancel.ID {
+		t.Fatalf("unexpected links: refund=%+v email=%+v", refund, email)
+	}
+}
+
+func TestChainQueriesAndBuildsTree(t *testing.T) {
+	base := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	row := func(id, action, parent string, offset time.Duration) []driver.Value {
+		var p driver.Value
+		if parent != "" {
+			p = parent
+		}
+		return []driver.Value{id, nil, action, nil, nil, nil, base.Add(offset), "u1", nil, "INFO", p, "root", nil, nil, nil, nil}
+	}
+	var gotQuery string
+	db := openStubDB(t, &stubDriver{
+		queryFn: func(query string, _ []driver.NamedValue) (driver.Rows, error) {
+			gotQuery = query
+			return &stubRows{
+				columns: strings.Split(EntryColumns, ", "),
+				values: [][]driver.Value{
+					row("root", "CANCEL_ORDER", "", 0),
+					row("refund", "REFUND_PAYMENT", "root", time.Second),
+					row("restock", "RESTOCK", "root", 2*time.Second),
+					row("email", "SEND_EMAIL", "refund", 3*time.Second),
+				},
+			}, nil
+		},
+	})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	roots, err := audit.Chain(context.Background(), "root")
+	if err != nil {
+		t.Fatalf("Chain: %v", err)
+	}
+	if !strings.Contains(gotQuery, "WHERE log_correlation_id = $1 OR log_audit_trail_id = $2") {
+		t.Fatalf("unexpected query: %s", gotQuery)
+	}
+	if len(roots) != 1 || roots[0].ID != "root" || len(roots[0].Children) != 2 {
+		t.Fatalf("unexpected tree: %+v", roots)
+	}
+	refund := roots[0].Children[0]
+	if refund.ID != "refund" || len(refund.Children) != 1 || refund.Children[0].Action != "SEND_EMAIL" {
+		t.Fatalf("unexpected refund branch: %+v", refund)
+	}
+}
@@ -0,0 +1,43 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+)
+
+func TestCountAndExists(t *testing.T) {
+	var queries []string
+	var result [][]driver.Value
+	db := openStubDB(t, &stubDriver{queryFn: func(query string, _ []driver.NamedValue) (driver.Rows, error) {
+		queries = append(queries, query)
+		return &stubRows{columns: []string{"n"}, values: result}, nil
+	}})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	ctx := context.Background()
+	f := Filter{Actor: "u1", Actions: []string{"CHANGE_SETTING"}}
+
+	result = [][]driver.Value{{int64(42)}}
+	if n, err := audit.Count(ctx, f); err != nil || n != 42 {
+		t.Fatalf("Count = %d, %v", n, err)
+	}
+	if queries[0] != "SELECT COUNT(*) FROM audit_trail WHERE log_action IN ($1) AND (log_actor = $2 OR (log_actor IS NULL AND log_created_by = $3))" {
+		t.Fatalf("unexpected query: %s", queries[0])
+	}
+
+	result = [][]driver.Value{{int64(1)}}
+	if ok, err := audit.Exists(ctx, f); err != nil || !ok {
+		t.Fatalf("Exists = %v, %v", ok, err)
+	}
+	if queries[1] != "SELECT 1 FROM audit_trail WHERE log_action IN ($1) AND (log_actor = $2 OR (log_actor IS NULL AND log_created_by = $3)) LIMIT 1" {
+		t.Fatalf("unexpected query: %s", queries[1])
+	}
+
+	result = nil
+	if ok, err := audit.Exists(ctx, f); err != nil || ok {
+		t.Fatalf("Exists on empty result = %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,230 @@
+package audittrail
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CoverageRecorder wraps a Recorder during tests or in development and tracks which
+// registered routes produced audit entries, so teams can check that every sensitive
+// endpoint is actually audited:
+//
+//	cov := audittrail.NewCoverageRecorder(rec, "POST /orders", "DELETE /orders/{id}")
+//	handler := audittrail.HTTPMiddleware(cov)(mux)
+//	// ... run the tests ...
+//	fmt.Print(cov.Report())
+//	if err := cov.Check(); err != nil { ... }
+//
+// Routes are "METHOD /path" or "/path" for any method, with ServeMux ({id}, {rest...}) or
+// Gin (:id, *rest) wildcards. Entries are attributed through the request recorded by
+// HTTPMiddleware or GinMiddleware, including those added with FromContext; entries
+// recorded outside an audited request are counted separately.
+type CoverageRecorder struct {
+	next Recorder
+
+	mu         sync.Mutex
+	routes     []coverageRoute
+	hits       map[string]int
+	unmatched  map[string]int // observed "METHOD route" that no registered route covers
+	unattached int
+}
+
+type coverageRoute struct {
+	method string // empty matches any method
+	path   string
+}
+
+func (r coverageRoute) String() string {
+	return strings.TrimSpace(r.method + " " + r.path)
+}
+
+// NewCoverageRecorder returns a CoverageRecorder that passes entries on to next (nil only
+// tracks them) and expects the given routes to be audited.
+func NewCoverageRecorder(next Recorder, routes ...string) *CoverageRecorder {
+	c := &CoverageRecorder{next: next, hits: make(map[string]int), unmatched: make(map[string]int)}
+	c.AddRoutes(routes...)
+	return c
+}
+
+// AddRoutes registers more routes expected to be audited; duplicates are ignored.
+func (c *CoverageRecorder) AddRoutes(routes ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, route := range routes {
+		r := parseCoverageRoute(route)
+		if r.path == "" {
+			continue
+		}
+		if _, ok := c.hits[r.String()]; ok {
+			continue
+		}
+		c.routes = append(c.routes, r)
+		c.hits[r.String()] = 0
+	}
+}
+
+// GinRoutes returns the routes registered on a Gin engine, for AddRoutes.
+func GinRoutes(engine *gin.Engine) []string {
+	var routes []string
+	for _, r := range engine.Routes() {
+		routes = append(routes, r.Method+" "+r.Path)
+	}
+	return routes
+}
+
+// Record attributes the entry to the route of its request and passes it on.
+func (c *CoverageRecorder) Record(ctx context.Context, entry Entry) error {
+	c.observe(ctx)
+	if c.next == nil {
+		return nil
+	}
+	return c.next.Record(ctx, entry)
+}
+
+func (c *CoverageRecorder) observe(ctx context.Context) {
+	method, route, p := FromContext(ctx).routeInfo()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if method == "" {
+		c.unattached++
+		return
+	}
+	matched := false
+	for _, r := range c.routes {
+		if r.method != "" && !strings.EqualFold(r.method, method) {
+			continue
+		}
+		// The template that served the request is exact; the path is matched against the
+		// registered templates only for routers that do not expose it (e.g. chi).
+		if (route != "" && r.path == route) || (route == "" && matchRouteTemplate(r.path, p)) {
+			c.hits[r.String()]++
+			matched = true
+		}
+	}
+	if !matched {
+		if route == "" {
+			route = p
+		}
+		c.unmatched[method+" "+route]++
+	}
+}
+
+// RouteCoverage is the number of entries one registered route produced.
+type RouteCoverage struct {
+	Route   string
+	Entries int
+}
+
+// CoverageReport is the result of a CoverageRecorder.
+type CoverageReport struct {
+	Routes []RouteCoverage // every registered route, in registration order
+	// Unregistered lists audited requests that matched no registered route, with their
+	// entry counts, e.g. routes missing from the list passed to NewCoverageRecorder.
+	Unregistered map[string]int
+	// Unattached counts entries recorded outside an audited request.
+	Unattached int
+}
+
+// Report returns the coverage so far.
+func (c *CoverageRecorder) Report() CoverageReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rep := CoverageReport{Unregistered: make(map[string]int, len(c.unmatched)), Unattached: c.unattached}
+	for _, r := range c.routes {
+		rep.Routes = append(rep.Routes, RouteCoverage{Route: r.String(), Entries: c.hits[r.String()]})
+	}
+	for route, n := range c.unmatched {
+		rep.Unregistered[route] = n
+	}
+	return rep
+}
+
+// Uncovered returns the registered routes that produced no entries.
+func (r CoverageReport) Uncovered() []string {
+	var out []string
+	for _, rc := range r.Routes {
+		if rc.Entries == 0 {
+			out = append(out, rc.Route)
+		}
+	}
+	return out
+}
+
+// String renders the report as text, one route per line.
+func (r CoverageReport) String() string {
+	var b strings.Builder
+	covered := len(r.Routes) - len(r.Uncovered())
+	fmt.Fprintf(&b, "audit coverage: %d/%d routes\n", covered, len(r.Routes))
+	for _, rc := range r.Routes {
+		mark := "ok  "
+		if rc.Entries == 0 {
+			mark = "MISS"
+		}
+		fmt.Fprintf(&b, "  %s %s (%d entries)\n", mark, rc.Route, rc.Entries)
+	}
+	unregistered := make([]string, 0, len(r.Unregistered))
+	for route := range r.Unregistered {
+		unregistered = append(unregistered, route)
+	}
+	sort.Strings(unregistered)
+	for _, route := range unregistered {
+		fmt.Fprintf(&b, "  ??   %s (%d entries, not registered)\n", route, r.Unregistered[route])
+	}
+	if r.Unattached > 0 {
+		fmt.Fprintf(&b, "  %d entries recorded outside an audited request\n", r.Unattached)
+	}
+	return b.String()
+}
+
+// Check is the lint mode of the recorder: it returns an error naming every registered
+// route that produced no entries, e.g. to fail a test run from TestMain.
+func (c *CoverageRecorder) Check() error {
+	if missing := c.Report().Uncovered(); len(missing) > 0 {
+		return fmt.Errorf("audittrail: %d routes produced no audit entries: %s", len(missing), strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// parseCoverageRoute splits "METHOD /path" or a ServeMux pattern ("GET example.com/path")
+// into method and path.
+func parseCoverageRoute(route string) coverageRoute {
+	route = strings.TrimSpace(route)
+	var r coverageRoute
+	if method, rest, ok := strings.Cut(route, " "); ok {
+		r.method, route = strings.ToUpper(method), strings.TrimSpace(rest)
+	}
+	if i := strings.Index(route, "/"); i > 0 {
+		route = route[i:] // drop a ServeMux host
+	}
+	r.path = route
+	return r
+}
+
+// matchRouteTemplate reports whether a request path matches a route template with
+// ServeMux or Gin wildcards.
+func matchRouteTemplate(template, p string) bool {
+	if p == "" {
+		return false
+	}
+	template = strings.TrimSuffix(template, "{$}")
+	tseg := strings.Split(strings.Trim(template, "/"), "/")
+	pseg := strings.Split(strings.Trim(p, "/"), "/")
+	for i, t := range tseg {
+		if strings.HasPrefix(t, "*") || (strings.HasPrefix(t, "{") && strings.HasSuffix(t, "...}")) {
+			return true
+		}
+		if i >= len(pseg) {
+			return false
+		}
+		wildcard := strings.HasPrefix(t, ":") || (strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") && t != "{$}")
+		if !wildcard && t != pseg[i] {
+			return false
+		}
+	}
+	return len(tseg) == len(pseg)
+}
@@ -0,0 +1,90 @@
+package audittrail
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCoverageRecorderServeMux(t *testing.T) {
+	cov := NewCoverageRecorder(nil, "POST /orders", "DELETE /orders/{id}", "GET /orders/{id}", "/health")
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /orders", func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Add(Entry{Action: "STOCK_RESERVED"})
+	})
+	mux.HandleFunc("DELETE /orders/{id}", func(http.ResponseWriter, *http.Request) {})
+	mux.HandleFunc("GET /orders/{id}", func(http.ResponseWriter, *http.Request) {})
+	mux.HandleFunc("GET /admin", func(http.ResponseWriter, *http.Request) {})
+	h := HTTPMiddleware(cov)(mux)
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/orders", nil),
+		httptest.NewRequest(http.MethodDelete, "/orders/42", nil),
+		httptest.NewRequest(http.MethodGet, "/admin", nil),
+	} {
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	_ = cov.Record(context.Background(), Entry{Action: "JOB_RAN"})
+
+	rep := cov.Report()
+	want := []RouteCoverage{{"POST /orders", 2}, {"DELETE /orders/{id}", 1}, {"GET /orders/{id}", 0}, {"/health", 0}}
+	for i, rc := range want {
+		if rep.Routes[i] != rc {
+			t.Fatalf("route %d: got %+v, want %+v", i, rep.Routes[i], rc)
+		}
+	}
+	if rep.Unregistered["GET /admin"] != 1 || rep.Unattached != 1 {
+		t.Fatalf("unexpected unregistered/unattached: %v %d", rep.Unregistered, rep.Unattached)
+	}
+	if s := rep.String(); !strings.Contains(s, "audit coverage: 2/4 routes") || !strings.Contains(s, "MISS GET /orders/{id}") {
+		t.Fatalf("unexpected report:\n%s", s)
+	}
+	if err := cov.Check(); err == nil || !strings.Contains(err.Error(), "GET /orders/{id}, /health") {
+		t.Fatalf("expected lint error for uncovered routes, got %v", err)
+	}
+}
+
+func TestCoverageRecorderGinAndPaths(t *testing.T) {
+	entries := make(chan Entry, 1)
+	cov := NewCoverageRecorder(RecorderFunc(func(_ context.Context, e Entry) error {
+		entries <- e
+		return nil
+	}))
+	useGlobalRecorder(t, cov)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinMiddleware())
+	r.PUT("/orders/:id", func(c *gin.Context) {})
+	r.GET("/orders/new", func(c *gin.Context) {})
+	cov.AddRoutes(GinRoutes(r)...)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/new", nil))
+	select {
+	case <-entries:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for entry")
+	}
+	if missing := cov.Report().Uncovered(); len(missing) != 1 || missing[0] != "PUT /orders/:id" {
+		t.Fatalf("unexpected uncovered routes: %v", missing)
+	}
+
+	for _, tc := range []struct {
+		template, path string
+		want           bool
+	}{
+		{"/orders/{id}", "/orders/42", true},
+		{"/orders/:id", "/orders/42/items", false},
+		{"/files/*path", "/files/a/b", true},
+		{"/files/{rest...}", "/files/a/b", true},
+		{"/{$}", "/", true},
+		{"/orders", "/users", false},
+	} {
+		if got := matchRouteTemplate(tc.template, tc.path); got != tc.want {
+			t.Errorf("matchRouteTemplate(%q, %q) = %v", tc.template, tc.path, got)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package audittrail
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Actions of authorization decision entries.
+const (
+	ActionAuthzAllow = "AUTHZ_ALLOW"
+	ActionAuthzDeny  = "AUTHZ_DENY"
+)
+
+// Decision is the request payload of an authorization decision entry.
+type Decision struct {
+	Subject  string `json:"subject"`
+	Action   string `json:"action"`
+	Resource string `json:"resource"`
+	Allowed  bool   `json:"allowed"`
+	Policy   string `json:"policy,omitempty"` // policy or rule that decided, e.g. "data.http.authz.allow"
+	Reason   string `json:"reason,omitempty"`
+	Input    any    `json:"input,omitempty"` // policy input, when logged
+}
+
+// Entry maps the decision to an entry: Action is ActionAuthzAllow or ActionAuthzDeny, the
+// subject becomes Actor and the resource Endpoint. Denials are SeverityWarn.
+func (d Decision) Entry() Entry {
+	entry := Entry{
+		Action:   ActionAuthzAllow,
+		Endpoint: d.Resource,
+		Request:  d,
+		Actor:    d.Subject,
+		Severity: SeverityInfo,
+	}
+	if !d.Allowed {
+		entry.Action = ActionAuthzDeny
+		entry.Severity = SeverityWarn
+	}
+	return entry
+}
+
+// RecordDecision records an authorization decision, allowed or denied, e.g. from an
+// authorization middleware:
+//
+//	allowed := enforcer.Allow(user, "orders:refund", orderID)
+//	_ = audit.RecordDecision(ctx, user, "orders:refund", orderID, allowed, "rbac/v3")
+func (r *AuditTrail) RecordDecision(ctx context.Context, subject, action, resource string, allowed bool, policy string) error {
+	return r.Record(ctx, Decision{Subject: subject, Action: action, Resource: resource, Allowed: allowed, Policy: policy}.Entry())
+}
+
+// OPADecisionConfig configures OPADecisionHandler.
+type OPADecisionConfig struct {
+	// Tokens are accepted as "Authorization: Bearer <token>" (services[_].credentials.bearer
+	// in the OPA configuration). With no tokens every request is rejected.
+	Tokens []string
+	// Map turns a policy input into subject, action and resource. By default they are
+	// read from the input's "subject" (or "user"), "action" (or "method") and "resource"
+	// (or "path") keys.
+	Map func(input map[string]any) (subject, action, resource string)
+	// OnlyDenied records denials only.
+	OnlyDenied bool
+}
+
+// opaDecision is one event of the OPA decision log API.
+type opaDecision struct {
+	DecisionID string         `json:"decision_id"`
+	Path       string         `json:"path"`
+	Input      map[string]any `json:"input"`
+	Result     any            `json:"result"`
+	Timestamp  time.Time      `json:"timestamp"`
+}
+
+// OPADecisionHandler receives the decision logs Open Policy Agent uploads
+// (decision_logs.service) and records each decision like RecordDecision. The decision
+// ID becomes the entry ID, so uploads retried by OPA are stored once by an *AuditTrail.
+// A result counts as allowed when it is true or an object with "allow": true; its "reason"
+// is recorded.
+func OPADecisionHandler(rec Recorder, cfg OPADecisionConfig) (http.Handler, error) {
+	if rec == nil {
+		return nil, errors.New("audittrail: decision recorder must not be nil")
+	}
+	if cfg.Map == nil {
+		cfg.Map = func(input map[string]any) (string, string, string) {
+			first := func(keys ...string) string {
+				for _, k := range keys {
+					if s, ok := input[k].(string); ok && s != "" {
+						return s
+					}
+				}
+				return ""
+			}
+			return first("subject", "user"), first("action", "method"), first("resource", "path")
+		}
+	}
+	collector := &CollectorServer{recorder: rec}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !validAPIKey(r, cfg.Tokens) {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		body := io.Reader(r.Body)
+		if strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, "invalid gzip body", http.StatusBadRequest)
+				return
+			}
+			defer zr.Close()
+			body = zr
+		}
+		var events []opaDecision
+		if err := json.NewDecoder(io.LimitReader(body, maxCollectorBody)).Decode(&events); err != nil {
+			http.Error(w, "invalid decision log: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		entries := make([]Entry, 0, len(events))
+		for _, ev := range events {
+			d := Decision{Policy: ev.Path, Input: ev.Input}
+			d.Allowed, d.Reason = opaResult(ev.Result)
+			if cfg.OnlyDenied && d.Allowed {
+				continue
+			}
+			d.Subject, d.Action, d.Resource = cfg.Map(ev.Input)
+			entry := d.Entry()
+			entry.ID, entry.RequestID, entry.CreatedDate = ev.DecisionID, ev.DecisionID, ev.Timestamp
+			entries = append(entries, entry)
+		}
+		if accepted, err := collector.store(r.Context(), entries); err != nil {
+			logger().Error("audittrail: failed to store OPA decisions", "accepted", accepted, "error", err)
+			http.Error(w, "failed to store decisions", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}), nil
+}
+
+// opaResult reads a policy result: true, or an object with "allow" and optionally "reason".
+func opaResult(result any) (allowed bool, reason string) {
+	switch v := result.(type) {
+	case bool:
+		return v, ""
+	case map[string]any:
+		allowed, _ = v["allow"].(bool)
+		reason, _ = v["reason"].(string)
+	}
+	return allowed, reason
+}
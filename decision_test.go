@@ -0,0 +1,87 @@
+package audittrail
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecordDecision(t *testing.T) {
+	var args []driver.NamedValue
+	db := openStubDB(t, &stubDriver{
+		execFn: func(_ string, a []driver.NamedValue) (driver.Result, error) {
+			args = a
+			return stubResult{}, nil
+		},
+	})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	if err := audit.RecordDecision(context.Background(), "u1", "orders:refund", "orders/42", false, "rbac/v3"); err != nil {
+		t.Fatalf("RecordDecision: %v", err)
+	}
+	if stringArg(args, 2) != ActionAuthzDeny || stringArg(args, 3) != "orders/42" || stringArg(args, 17) != "u1" || stringArg(args, 9) != string(SeverityWarn) {
+		t.Fatalf("unexpected args: %v", args)
+	}
+	if got := stringArg(args, 4); got != `{"subject":"u1","action":"orders:refund","resource":"orders/42","allowed":false,"policy":"rbac/v3"}` {
+		t.Fatalf("unexpected decision payload: %s", got)
+	}
+}
+
+func TestRecordDecisionStrictActions(t *testing.T) {
+	useStrictActions(t)
+	db := openStubDB(t, &stubDriver{execFn: func(string, []driver.NamedValue) (driver.Result, error) {
+		return stubResult{}, nil
+	}})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	for _, allowed := range []bool{true, false} {
+		if err := audit.RecordDecision(context.Background(), "u1", "orders:read", "orders/42", allowed, ""); err != nil {
+			t.Fatalf("RecordDecision(allowed=%v): %v", allowed, err)
+		}
+	}
+}
+
+func TestOPADecisionHandler(t *testing.T) {
+	var got []Entry
+	rec := RecorderFunc(func(_ context.Context, e Entry) error {
+		got = append(got, e)
+		return nil
+	})
+	h, err := OPADecisionHandler(rec, OPADecisionConfig{Tokens: []string{"t1"}})
+	if err != nil {
+		t.Fatalf("OPADecisionHandler: %v", err)
+	}
+
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	_, _ = zw.Write([]byte(`[
+{"decision_id":"d1","path":"http/authz/allow","input":{"user":"alice","method":"GET","path":"/reports"},"result":true,"timestamp":"2024-05-01T09:30:00Z"},
+{"decision_id":"d2","path":"http/authz","input":{"subject":"bob","action":"delete","resource":"orders/7"},"result":{"allow":false,"reason":"not owner"},"timestamp":"2024-05-01T09:31:00Z"}
+]`))
+	_ = zw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/logs", &body)
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Authorization", "Bearer t1")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || len(got) != 2 {
+		t.Fatalf("unexpected result: %d, %d entries", w.Code, len(got))
+	}
+
+	allow, deny := got[0], got[1]
+	if allow.ID != "d1" || allow.Action != ActionAuthzAllow || allow.Actor != "alice" || allow.Endpoint != "/reports" {
+		t.Fatalf("unexpected allow entry: %+v", allow)
+	}
+	d := deny.Request.(Decision)
+	if deny.Action != ActionAuthzDeny || deny.Severity != SeverityWarn || d.Subject != "bob" || d.Action != "delete" || d.Policy != "http/authz" || d.Reason != "not owner" {
+		t.Fatalf("unexpected deny entry: %+v", deny)
+	}
+}
@@ -0,0 +1,106 @@
+package audittrail
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// sqlDialect selects the SQL variations a database needs.
+type sqlDialect int
+
+const (
+	dialectStandard sqlDialect = iota // Postgres, SQLite and others
+	dialectMySQL                      // INSERT IGNORE, DATETIME(6), backtick-quoted identifiers
+	dialectSpanner                    // GoogleSQL types, INSERT OR IGNORE, commit timestamps
+)
+
+// detectDialect infers the dialect from the driver type, e.g. *mysql.MySQLDriver or
+// *spannerdriver.Driver.
+func detectDialect(db *sql.DB) sqlDialect {
+	name := strings.ToLower(fmt.Sprintf("%T", db.Driver()))
+	switch {
+	case strings.Contains(name, "mysql"):
+		return dialectMySQL
+	case strings.Contains(name, "spanner"):
+		return dialectSpanner
+	}
+	return dialectStandard
+}
+
+// spannerCommitColumn is added to the table on Spanner and set to the transaction's commit
+// timestamp, which orders entries consistently across regions.
+var spannerCommitColumn = column{name: "log_commit_ts", ddl: "TIMESTAMP OPTIONS (allow_commit_timestamp=true)"}
+
+// spannerTypes maps the portable column types onto GoogleSQL. Payloads are stored as JSON
+// text in STRING(MAX) so they scan like on the other databases; query them with
+// PARSE_JSON. The primary key is declared after the column list.
+var spannerTypes = strings.NewReplacer(
+	"VARCHAR(", "STRING(",
+	"TEXT", "STRING(MAX)",
+	"JSON", "STRING(MAX)",
+	"BOOLEAN", "BOOL",
+	"BIGINT", "INT64",
+	"DEFAULT FALSE", "DEFAULT (FALSE)",
+	" PRIMARY KEY", " NOT NULL",
+)
+
+// quote returns an identifier as written in SQL: backtick-quoted on MySQL and Spanner, so
+// names that are reserved words still work, and unchanged elsewhere. Identifiers are
+// validated by isSafeIdentifier or are package constants, so they never contain a backtick.
+func (r *AuditTrail) quote(name string) string {
+	if r.dialect == dialectStandard {
+		return name
+	}
+	return "`" + name + "`"
+}
+
+// quoteList quotes each name of a comma-separated column list.
+func (r *AuditTrail) quoteList(columns string) string {
+	if r.dialect == dialectStandard {
+		return columns
+	}
+	names := strings.Split(columns, ", ")
+	for i, name := range names {
+		names[i] = r.quote(name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// timestampType is the column type for timestamps. MySQL's TIMESTAMP truncates to seconds,
+// ends in 2038 and may update itself on every write, so DATETIME(6) is used there; it has
+// no zone, so entries are written in UTC and Init connects with loc=UTC (see mysqlDSN).
+// Postgres gets TIMESTAMPTZ, which stores an instant and reads back correctly whatever the
+// session time zone is.
+func (r *AuditTrail) timestampType() string {
+	switch {
+	case r.dialect == dialectMySQL:
+		return "DATETIME(6)"
+	case r.dialect == dialectStandard && r.placeholder == PlaceholderDollar:
+		return "TIMESTAMPTZ"
+	}
+	return "TIMESTAMP"
+}
+
+// tableColumns returns the columns EnsureTable creates for the dialect.
+func (r *AuditTrail) tableColumns() []column {
+	if r.dialect != dialectSpanner {
+		return auditColumns
+	}
+	return append(auditColumns[:len(auditColumns):len(auditColumns)], spannerCommitColumn)
+}
+
+// columnDDL returns the column definition for the dialect.
+func (r *AuditTrail) columnDDL(col column) string {
+	switch r.dialect {
+	case dialectMySQL, dialectStandard:
+		return strings.Replace(col.ddl, "TIMESTAMP", r.timestampType(), 1)
+	case dialectSpanner:
+		ddl := spannerTypes.Replace(col.ddl)
+		if !strings.HasSuffix(ddl, "NOT NULL") {
+			ddl = strings.TrimSuffix(ddl, " NULL") // columns are nullable unless NOT NULL
+		}
+		return ddl
+	}
+	return col.ddl
+}
@@ -0,0 +1,254 @@
+package audittrail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"mime/multipart"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Periods for DigestConfig.Period.
+const (
+	DigestDaily  = 24 * time.Hour
+	DigestWeekly = 7 * 24 * time.Hour
+)
+
+// DigestConfig configures BuildDigest and RunDigest.
+type DigestConfig struct {
+	Title  string        // default "Audit digest"
+	Filter Filter        // restricts the entries summarized; From and To are set per digest
+	Period time.Duration // length of the window summarized; default DigestDaily
+	TopN   int           // actors and actions listed; default 10
+	// MaxFailures caps the failures listed individually; all of them are counted. Default 50.
+	MaxFailures int
+	// Failure reports whether an entry counts as a failure. Default: WARN severity or above,
+	// which covers denied requests, server errors and failed logins.
+	Failure func(Entry) bool
+	// Send delivers each digest built by RunDigest, e.g. MailDigest(smtp).
+	Send func(context.Context, Digest) error
+}
+
+// Digest summarizes the entries of one period for compliance reports.
+type Digest struct {
+	Title      string
+	From       time.Time // inclusive
+	To         time.Time // exclusive
+	Total      int64
+	TopActors  []StatsRow
+	TopActions []StatsRow
+	// FailureCount is the number of failures; Failures lists the first MaxFailures of them,
+	// oldest first.
+	FailureCount int64
+	Failures     []Entry
+}
+
+func (c *DigestConfig) defaults() {
+	if c.Title == "" {
+		c.Title = "Audit digest"
+	}
+	if c.Period <= 0 {
+		c.Period = DigestDaily
+	}
+	if c.TopN <= 0 {
+		c.TopN = 10
+	}
+	if c.MaxFailures <= 0 {
+		c.MaxFailures = 50
+	}
+	if c.Failure == nil {
+		c.Failure = func(e Entry) bool { return e.Severity != "" && e.Severity.AtLeast(SeverityWarn) }
+	}
+}
+
+// BuildDigest summarizes the entries matching cfg.Filter in the Period ending at to: the
+// total, the most active actors and the most frequent actions, and the failures. Entries
+// are read in a single pass with Iterate.
+func (r *AuditTrail) BuildDigest(ctx context.Context, cfg DigestConfig, to time.Time) (Digest, error) {
+	cfg.defaults()
+	d := Digest{Title: cfg.Title, From: to.Add(-cfg.Period).UTC(), To: to.UTC()}
+	f := cfg.Filter
+	f.From, f.To = d.From, d.To
+	ctx = r.auditRead(ctx, "digest", f, nil)
+	actors := make(map[string]int64)
+	actions := make(map[string]int64)
+	err := r.Iterate(ctx, f, func(e Entry) error {
+		d.Total++
+		actors[e.ActorOrCreator()]++
+		actions[e.Action]++
+		if cfg.Failure(e) {
+			d.FailureCount++
+			if len(d.Failures) < cfg.MaxFailures {
+				d.Failures = append(d.Failures, e)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Digest{}, err
+	}
+	d.TopActors = topCounts(actors, cfg.TopN)
+	d.TopActions = topCounts(actions, cfg.TopN)
+	return d, nil
+}
+
+// RunDigest builds and sends a digest at the end of every Period until ctx is canceled.
+// Periods are aligned to UTC: a daily digest covers midnight to midnight and a weekly one
+// starts on Monday, so restarts neither skip nor repeat a period. Run it on one instance
+// only, e.g. with RunAsLeader. Failures are logged and the period is skipped.
+func (r *AuditTrail) RunDigest(ctx context.Context, cfg DigestConfig) error {
+	if r == nil || r.db == nil {
+		return errors.New("audittrail: instance is not initialized")
+	}
+	if cfg.Send == nil {
+		return errors.New("audittrail: digest Send must not be nil")
+	}
+	cfg.defaults()
+	for {
+		end := r.now().UTC().Truncate(cfg.Period).Add(cfg.Period)
+		timer := time.NewTimer(end.Sub(r.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+		d, err := r.BuildDigest(ctx, cfg, end)
+		if err == nil {
+			err = cfg.Send(ctx, d)
+		}
+		if err != nil && ctx.Err() == nil {
+			logger().Error("audittrail: digest not sent", "from", d.From, "to", end, "error", err)
+		}
+	}
+}
+
+// topCounts returns the n largest counts, ties ordered by key.
+func topCounts(counts map[string]int64, n int) []StatsRow {
+	rows := make([]StatsRow, 0, len(counts))
+	for k, c := range counts {
+		rows = append(rows, StatsRow{Key: k, Count: c})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Count != rows[j].Count {
+			return rows[i].Count > rows[j].Count
+		}
+		return rows[i].Key < rows[j].Key
+	})
+	if len(rows) > n {
+		rows = rows[:n]
+	}
+	return rows
+}
+
+// Subject returns the title and the period, for an email subject.
+func (d Digest) Subject() string {
+	return fmt.Sprintf("%s: %s to %s", d.Title, d.From.Format("2006-01-02 15:04"), d.To.Format("2006-01-02 15:04 MST"))
+}
+
+// Text renders the digest as plain text.
+func (d Digest) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n%d entries, %d failures\n", d.Subject(), d.Total, d.FailureCount)
+	for _, section := range []struct {
+		title string
+		rows  []StatsRow
+	}{{"Top actors", d.TopActors}, {"Top actions", d.TopActions}} {
+		fmt.Fprintf(&b, "\n%s:\n", section.title)
+		for _, row := range section.rows {
+			fmt.Fprintf(&b, "  %6d  %s\n", row.Count, digestKey(row.Key))
+		}
+	}
+	if len(d.Failures) > 0 {
+		b.WriteString("\nFailures:\n")
+		for _, e := range d.Failures {
+			fmt.Fprintf(&b, "  %s  %-8s %s by %s (%s)\n", e.CreatedDate.UTC().Format(time.RFC3339), e.Severity, e.Action, digestKey(e.ActorOrCreator()), e.ID)
+		}
+		if more := d.FailureCount - int64(len(d.Failures)); more > 0 {
+			fmt.Fprintf(&b, "  ... and %d more\n", more)
+		}
+	}
+	return b.String()
+}
+
+var digestHTML = template.Must(template.New("digest").Funcs(template.FuncMap{
+	"key":  digestKey,
+	"time": func(t time.Time) string { return t.UTC().Format(time.RFC3339) },
+}).Parse(`<!DOCTYPE html>
+<html><body style="font-family: sans-serif">
+<h2>{{.Subject}}</h2>
+<p>{{.Total}} entries, {{.FailureCount}} failures</p>
+<h3>Top actors</h3>
+<table>{{range .TopActors}}<tr><td align="right">{{.Count}}</td><td>{{key .Key}}</td></tr>{{end}}</table>
+<h3>Top actions</h3>
+<table>{{range .TopActions}}<tr><td align="right">{{.Count}}</td><td>{{key .Key}}</td></tr>{{end}}</table>
+{{if .Failures}}<h3>Failures</h3>
+<table><tr><th>Time</th><th>Severity</th><th>Action</th><th>Actor</th><th>Entry</th></tr>
+{{range .Failures}}<tr><td>{{time .CreatedDate}}</td><td>{{.Severity}}</td><td>{{.Action}}</td><td>{{key .ActorOrCreator}}</td><td>{{.ID}}</td></tr>
+{{end}}</table>{{end}}
+</body></html>
+`))
+
+// HTML renders the digest as an HTML document.
+func (d Digest) HTML() string {
+	var b strings.Builder
+	if err := digestHTML.Execute(&b, d); err != nil {
+		return "" // the template only fails on write errors, which strings.Builder never returns
+	}
+	return b.String()
+}
+
+func digestKey(k string) string {
+	if k == "" {
+		return "(none)"
+	}
+	return k
+}
+
+// MailDigest returns a DigestConfig.Send that emails the digest through an SMTP server, with
+// text and HTML parts.
+func MailDigest(cfg SMTPConfig) func(context.Context, Digest) error {
+	return func(_ context.Context, d Digest) error {
+		if cfg.Addr == "" || cfg.From == "" || len(cfg.To) == 0 {
+			return errors.New("audittrail: SMTP address, sender and recipients must not be empty")
+		}
+		msg, err := mailAlternative(cfg, d.Subject(), d.Text(), d.HTML())
+		if err != nil {
+			return err
+		}
+		if err := smtpSendMail(cfg.Addr, cfg.Auth, cfg.From, cfg.To, msg); err != nil {
+			return fmt.Errorf("audittrail: send digest: %w", err)
+		}
+		return nil
+	}
+}
+
+// mailAlternative renders a multipart/alternative message with a text and an HTML part.
+func mailAlternative(cfg SMTPConfig, subject, text, html string) ([]byte, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		pw, err := w.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := pw.Write([]byte(strings.ReplaceAll(part.content, "\n", "\r\n"))); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	writeMailHeader(&b, cfg, subject, "multipart/alternative; boundary="+w.Boundary())
+	b.Write(body.Bytes())
+	return b.Bytes(), nil
+}
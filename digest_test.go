@@ -0,0 +1,80 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildDigest(t *testing.T) {
+	t0 := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	columns := strings.Split(EntryColumns, ", ")
+	row := func(id, action, actor string, sev Severity) []driver.Value {
+		r := make([]driver.Value, len(columns))
+		r[0], r[2], r[6], r[9], r[17] = id, action, t0.Add(time.Hour), string(sev), actor
+		return r
+	}
+	var query string
+	var args []driver.NamedValue
+	db := openStubDB(t, &stubDriver{queryFn: func(q string, a []driver.NamedValue) (driver.Rows, error) {
+		query, args = q, a
+		return &stubRows{columns: columns, values: [][]driver.Value{
+			row("1", "ORDER_CREATED", "alice", SeverityInfo),
+			row("2", "ORDER_CREATED", "bob", SeverityInfo),
+			row("3", "ORDER_CREATED", "alice", SeverityInfo),
+			row("4", "AUTH_LOGIN_FAILED", "<mallory>", SeverityWarn),
+		}}, nil
+	}})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	d, err := audit.BuildDigest(context.Background(), DigestConfig{Filter: Filter{ServiceName: "orders"}, TopN: 1}, t0.Add(DigestDaily))
+	if err != nil {
+		t.Fatalf("BuildDigest: %v", err)
+	}
+	if d.Total != 4 || d.FailureCount != 1 || len(d.Failures) != 1 || d.Failures[0].ID != "4" {
+		t.Fatalf("unexpected digest: %+v", d)
+	}
+	if len(d.TopActors) != 1 || d.TopActors[0] != (StatsRow{Key: "alice", Count: 2}) || d.TopActions[0] != (StatsRow{Key: "ORDER_CREATED", Count: 3}) {
+		t.Fatalf("unexpected top lists: %+v %+v", d.TopActors, d.TopActions)
+	}
+	if !strings.Contains(query, "log_service_name = $1") || args[1].Value != t0 {
+		t.Fatalf("unexpected query: %s %v", query, args)
+	}
+	if text := d.Text(); !strings.Contains(text, "4 entries, 1 failures") || !strings.Contains(text, "AUTH_LOGIN_FAILED by <mallory>") {
+		t.Fatalf("unexpected text:\n%s", text)
+	}
+	if html := d.HTML(); !strings.Contains(html, "&lt;mallory&gt;") || strings.Contains(html, "<mallory>") {
+		t.Fatalf("actor not escaped in HTML:\n%s", html)
+	}
+
+	var msg string
+	prev := smtpSendMail
+	smtpSendMail = func(_ string, _ smtp.Auth, _ string, _ []string, m []byte) error {
+		msg = string(m)
+		return nil
+	}
+	defer func() { smtpSendMail = prev }()
+	if err := MailDigest(SMTPConfig{Addr: "smtp:25", From: "a@example.com", To: []string{"c@example.com"}})(context.Background(), d); err != nil {
+		t.Fatalf("MailDigest: %v", err)
+	}
+	if !strings.Contains(msg, "Subject: Audit digest: 2024-06-03 00:00 to 2024-06-04 00:00 UTC\r\n") ||
+		!strings.Contains(msg, "multipart/alternative") || !strings.Contains(msg, "text/html") {
+		t.Fatalf("unexpected message:\n%s", msg)
+	}
+}
+
+func TestDigestPeriodsAlignToUTC(t *testing.T) {
+	now := time.Date(2024, 6, 5, 15, 4, 0, 0, time.UTC) // a Wednesday
+	if got := now.Truncate(DigestDaily); !got.Equal(time.Date(2024, 6, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("daily period starts at %v", got)
+	}
+	if got := now.Truncate(DigestWeekly); got.Weekday() != time.Monday || got.Day() != 3 {
+		t.Fatalf("weekly period starts at %v", got)
+	}
+}
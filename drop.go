@@ -0,0 +1,92 @@
+package audittrail
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrMarshalFailed matches (via errors.Is) any failure to serialize an entry or its payloads.
+var ErrMarshalFailed = errors.New("audittrail: marshal failed")
+
+type marshalError struct {
+	what string
+	err  error
+}
+
+func (e *marshalError) Error() string {
+	return "audittrail: marshal " + e.what + " failed: " + e.err.Error()
+}
+func (e *marshalError) Unwrap() error        { return e.err }
+func (e *marshalError) Is(target error) bool { return target == ErrMarshalFailed }
+
+func marshalFailed(what string, err error) error {
+	return &marshalError{what: what, err: err}
+}
+
+// DropReason classifies why an entry was lost.
+type DropReason string
+
+const (
+	DropQueueFull     DropReason = "queue_full"     // an async buffer or spool had no room
+	DropMarshalFailed DropReason = "marshal_failed" // the entry could not be serialized
+	DropRecordFailed  DropReason = "record_failed"  // publish/insert failed and nobody retries it
+)
+
+var drops struct {
+	mu       sync.RWMutex
+	handlers []func(Entry, error)
+	counts   [3]atomic.Uint64
+}
+
+// OnDrop registers a hook called whenever an entry is lost for good: an async Record
+// (Gin middleware, RecordAsync, HTTPMiddleware) fails, a spool is full, or marshaling
+// fails. Use DropReasonOf to classify the error. Hooks must be fast and must not block.
+func OnDrop(fn func(Entry, error)) {
+	if fn == nil {
+		return
+	}
+	drops.mu.Lock()
+	drops.handlers = append(drops.handlers, fn)
+	drops.mu.Unlock()
+}
+
+// DropStats returns how many entries were dropped, per reason, since process start.
+func DropStats() map[DropReason]uint64 {
+	return map[DropReason]uint64{
+		DropQueueFull:     drops.counts[0].Load(),
+		DropMarshalFailed: drops.counts[1].Load(),
+		DropRecordFailed:  drops.counts[2].Load(),
+	}
+}
+
+// DropReasonOf classifies an error passed to an OnDrop hook.
+func DropReasonOf(err error) DropReason {
+	switch {
+	case errors.Is(err, ErrSpoolFull), errors.Is(err, ErrBufferFull):
+		return DropQueueFull
+	case errors.Is(err, ErrMarshalFailed):
+		return DropMarshalFailed
+	default:
+		return DropRecordFailed
+	}
+}
+
+// reportDrop counts a lost entry and notifies OnDrop hooks.
+func reportDrop(entry Entry, err error) {
+	switch DropReasonOf(err) {
+	case DropQueueFull:
+		drops.counts[0].Add(1)
+	case DropMarshalFailed:
+		drops.counts[1].Add(1)
+	default:
+		drops.counts[2].Add(1)
+	}
+
+	drops.mu.RLock()
+	handlers := drops.handlers
+	drops.mu.RUnlock()
+	for _, fn := range handlers {
+		fn(entry, err)
+	}
+}
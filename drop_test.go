@@ -0,0 +1,47 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestDropHookReportsLostEntries(t *testing.T) {
+	var mu sync.Mutex
+	var dropped []DropReason
+	OnDrop(func(entry Entry, err error) {
+		if entry.Action != "POST /api/orders" {
+			return
+		}
+		mu.Lock()
+		dropped = append(dropped, DropReasonOf(err))
+		mu.Unlock()
+	})
+	before := DropStats()[DropRecordFailed]
+
+	failing := RecorderFunc(func(context.Context, Entry) error { return errors.New("publish failed after retries") })
+	handler := HTTPMiddleware(failing, WithErrorHandler(nil))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/orders", nil))
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dropped) != 1 || dropped[0] != DropRecordFailed {
+		t.Fatalf("unexpected drops: %v", dropped)
+	}
+	if got := DropStats()[DropRecordFailed]; got != before+1 {
+		t.Fatalf("expected drop counter to increase, got %d -> %d", before, got)
+	}
+}
+
+func TestDropReasonClassifiesMarshalFailures(t *testing.T) {
+	_, err := JSONCodec.Marshal(Entry{Action: "a", Request: make(chan int)})
+	if !errors.Is(err, ErrMarshalFailed) || DropReasonOf(err) != DropMarshalFailed {
+		t.Fatalf("expected marshal failure, got %v", err)
+	}
+	if DropReasonOf(ErrSpoolFull) != DropQueueFull {
+		t.Fatal("expected spool full to classify as queue_full")
+	}
+}
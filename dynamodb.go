@@ -0,0 +1,216 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// dynamoBatchLimit is the most items DynamoDB accepts in one BatchWriteItem call.
+const dynamoBatchLimit = 25
+
+// DynamoItem is one DynamoDB item as attribute name to value. Values are strings, or int64
+// for the TTL attribute; absent fields are left out.
+type DynamoItem map[string]any
+
+// DynamoWriter writes a batch of at most 25 items and returns the items DynamoDB left
+// unprocessed. Adapt the AWS SDK with DynamoWriterFunc:
+//
+//	// aws-sdk-go-v2: dynamodb, dynamodb/types and feature/dynamodb/attributevalue
+//	audittrail.DynamoWriterFunc(func(ctx context.Context, table string, items []audittrail.DynamoItem) ([]audittrail.DynamoItem, error) {
+//		reqs := make([]types.WriteRequest, len(items))
+//		for i, item := range items {
+//			av, err := attributevalue.MarshalMap(item)
+//			if err != nil {
+//				return nil, err
+//			}
+//			reqs[i] = types.WriteRequest{PutRequest: &types.PutRequest{Item: av}}
+//		}
+//		out, err := client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+//			RequestItems: map[string][]types.WriteRequest{table: reqs},
+//		})
+//		if err != nil {
+//			return nil, err
+//		}
+//		var left []audittrail.DynamoItem
+//		for _, req := range out.UnprocessedItems[table] {
+//			var item audittrail.DynamoItem
+//			if err := attributevalue.UnmarshalMap(req.PutRequest.Item, &item); err != nil {
+//				return nil, err
+//			}
+//			left = append(left, item)
+//		}
+//		return left, nil
+//	})
+type DynamoWriter interface {
+	BatchWrite(ctx context.Context, table string, items []DynamoItem) ([]DynamoItem, error)
+}
+
+// DynamoWriterFunc adapts a function to DynamoWriter.
+type DynamoWriterFunc func(ctx context.Context, table string, items []DynamoItem) ([]DynamoItem, error)
+
+func (f DynamoWriterFunc) BatchWrite(ctx context.Context, table string, items []DynamoItem) ([]DynamoItem, error) {
+	return f(ctx, table, items)
+}
+
+// DynamoConfig configures a DynamoRecorder.
+type DynamoConfig struct {
+	Writer       DynamoWriter
+	Table        string
+	PartitionKey func(Entry) string // pk value; default "ACTOR#" + Entry.ActorOrCreator()
+	TTLAttribute string             // epoch-seconds expiry attribute; default "ttl", "-" disables it
+	Retention    []RetentionRule    // expiry for entries without ExpiresAt, as in Config.Retention
+	MaxRetries   int                // retries for unprocessed items; default 5
+	RetryBackoff time.Duration      // first retry delay, doubled per retry; default 50ms
+}
+
+// DynamoRecorder writes entries to a DynamoDB table keyed for per-actor (or per-entity)
+// timelines: the partition key "pk" comes from PartitionKey and the sort key "sk" is the
+// creation time followed by the entry ID, so a Query on pk returns entries in order and
+// can be bounded by time with begins_with or BETWEEN on sk. The other attributes use the
+// SQL column names. Enable TTL on the table's TTL attribute to let DynamoDB expire entries.
+type DynamoRecorder struct {
+	cfg DynamoConfig
+}
+
+// NewDynamoRecorder creates a DynamoDB recorder.
+func NewDynamoRecorder(cfg DynamoConfig) (*DynamoRecorder, error) {
+	if cfg.Writer == nil {
+		return nil, errors.New("audittrail: DynamoDB writer is required")
+	}
+	if cfg.Table == "" {
+		return nil, errors.New("audittrail: DynamoDB table must not be empty")
+	}
+	if cfg.PartitionKey == nil {
+		cfg.PartitionKey = func(e Entry) string { return "ACTOR#" + e.ActorOrCreator() }
+	}
+	if cfg.TTLAttribute == "" {
+		cfg.TTLAttribute = "ttl"
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 5
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 50 * time.Millisecond
+	}
+	return &DynamoRecorder{cfg: cfg}, nil
+}
+
+// Record writes one entry.
+func (d *DynamoRecorder) Record(ctx context.Context, entry Entry) error {
+	return d.RecordBatch(ctx, []Entry{entry})
+}
+
+// RecordBatch writes entries in batches of 25, retrying items DynamoDB leaves unprocessed
+// (throttling) with exponential backoff. Unlike AuditTrail.RecordBatch it is not atomic:
+// batches written before an error stay written.
+func (d *DynamoRecorder) RecordBatch(ctx context.Context, entries []Entry) error {
+	items := make([]DynamoItem, len(entries))
+	for i, entry := range entries {
+		item, err := d.Item(entry)
+		if err != nil {
+			return fmt.Errorf("audittrail: entry %d: %w", i, err)
+		}
+		items[i] = item
+	}
+	for start := 0; start < len(items); start += dynamoBatchLimit {
+		if err := d.write(ctx, items[start:min(start+dynamoBatchLimit, len(items))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *DynamoRecorder) write(ctx context.Context, items []DynamoItem) error {
+	backoff := d.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		left, err := d.cfg.Writer.BatchWrite(ctx, d.cfg.Table, items)
+		if err != nil {
+			return fmt.Errorf("audittrail: DynamoDB write failed: %w", err)
+		}
+		if len(left) == 0 {
+			return nil
+		}
+		if attempt >= d.cfg.MaxRetries {
+			return fmt.Errorf("audittrail: DynamoDB left %d items unprocessed after %d retries", len(left), attempt)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		items, backoff = left, backoff*2
+	}
+}
+
+// Item returns the DynamoDB item written for entry.
+func (d *DynamoRecorder) Item(entry Entry) (DynamoItem, error) {
+	normalized, err := normalizeEntry(entry, nil)
+	if err != nil {
+		return nil, err
+	}
+	item := DynamoItem{
+		"pk":                 d.cfg.PartitionKey(normalized),
+		"sk":                 normalized.CreatedDate.UTC().Format(dynamoTimeLayout) + "#" + normalized.ID,
+		"log_audit_trail_id": normalized.ID,
+		"log_action":         normalized.Action,
+		"log_created_date":   normalized.CreatedDate.UTC().Format(dynamoTimeLayout),
+	}
+	set := func(name, value string) {
+		if value != "" {
+			item[name] = value
+		}
+	}
+	set("log_req_id", normalized.RequestID)
+	set("log_endpoint", normalized.Endpoint)
+	set("log_created_by", normalized.CreatedBy)
+	set("log_severity", string(normalized.Severity))
+	set("log_parent_id", normalized.ParentID)
+	set("log_correlation_id", normalized.CorrelationID)
+	set("log_session_id", normalized.SessionID)
+	set("log_app_version", normalized.AppVersion)
+	set("log_hostname", normalized.Hostname)
+	set("log_instance_id", normalized.InstanceID)
+	set("log_impersonated_by", normalized.ImpersonatedBy)
+	set("log_actor", normalized.Actor)
+	set("log_ip_address", normalized.IPAddress)
+	set("log_service_name", normalized.ServiceName)
+	set("log_entity_type", normalized.EntityType)
+	set("log_entity_id", normalized.EntityID)
+	meta, err := marshalMeta(normalized.Meta)
+	if err != nil {
+		return nil, marshalFailed("meta", err)
+	}
+	set("log_meta", meta.String)
+	for name, t := range map[string]time.Time{"log_started_at": normalized.StartedAt, "log_ended_at": normalized.EndedAt} {
+		if !t.IsZero() {
+			set(name, t.UTC().Format(dynamoTimeLayout))
+		}
+	}
+	if normalized.DurationMS != 0 {
+		item["log_duration_ms"] = normalized.DurationMS
+	}
+	for name, payload := range map[string]any{"log_request": normalized.Request, "log_response": normalized.Response} {
+		v, err := marshalJSONValue(payload)
+		if err != nil {
+			return nil, marshalFailed(name, err)
+		}
+		set(name, v.String)
+	}
+
+	expires := normalized.ExpiresAt
+	if expires.IsZero() {
+		expires = ExpiryFor(d.cfg.Retention, normalized)
+	}
+	if !expires.IsZero() {
+		set("log_expires_at", expires.UTC().Format(dynamoTimeLayout))
+		if d.cfg.TTLAttribute != "-" {
+			item[d.cfg.TTLAttribute] = expires.Unix()
+		}
+	}
+	return item, nil
+}
+
+// dynamoTimeLayout is a fixed-width UTC timestamp, so sort keys order chronologically.
+const dynamoTimeLayout = "2006-01-02T15:04:05.000000000Z"
@@ -0,0 +1,73 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDynamoRecorderItemKeysAndTTL(t *testing.T) {
+	rec, err := NewDynamoRecorder(DynamoConfig{
+		Writer:    DynamoWriterFunc(func(context.Context, string, []DynamoItem) ([]DynamoItem, error) { return nil, nil }),
+		Table:     "audit",
+		Retention: []RetentionRule{{Action: "LOGIN_*", TTL: time.Hour}},
+	})
+	if err != nil {
+		t.Fatalf("NewDynamoRecorder: %v", err)
+	}
+	created := time.Date(2024, 6, 1, 13, 0, 0, 5, time.UTC)
+	item, err := rec.Item(Entry{ID: "e1", Action: "LOGIN_OK", CreatedBy: "u1", CreatedDate: created, Request: map[string]any{"ip": "10.0.0.1"}})
+	if err != nil {
+		t.Fatalf("Item: %v", err)
+	}
+	if item["pk"] != "ACTOR#u1" || item["sk"] != "2024-06-01T13:00:00.000000005Z#e1" ||
+		item["log_request"] != `{"ip":"10.0.0.1"}` || item["ttl"] != created.Add(time.Hour).Unix() {
+		t.Fatalf("unexpected item %v", item)
+	}
+	if _, ok := item["log_endpoint"]; ok {
+		t.Fatal("empty attributes must be omitted")
+	}
+
+	item, _ = rec.Item(Entry{ID: "e2", Action: "EXPORT", CreatedDate: created})
+	if _, ok := item["ttl"]; ok {
+		t.Fatal("entries without expiry must not get a TTL")
+	}
+}
+
+func TestDynamoRecorderBatchesAndRetries(t *testing.T) {
+	var sizes []int
+	retried := false
+	writer := DynamoWriterFunc(func(_ context.Context, table string, items []DynamoItem) ([]DynamoItem, error) {
+		if table != "audit" {
+			return nil, errors.New("wrong table")
+		}
+		sizes = append(sizes, len(items))
+		if !retried {
+			retried = true
+			return items[:1], nil
+		}
+		return nil, nil
+	})
+	rec, err := NewDynamoRecorder(DynamoConfig{Writer: writer, Table: "audit", RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewDynamoRecorder: %v", err)
+	}
+	entries := make([]Entry, 30)
+	for i := range entries {
+		entries[i] = Entry{ID: fmt.Sprintf("e%d", i), Action: "EXPORT"}
+	}
+	if err := rec.RecordBatch(context.Background(), entries); err != nil {
+		t.Fatalf("RecordBatch: %v", err)
+	}
+	if fmt.Sprint(sizes) != "[25 1 5]" {
+		t.Fatalf("unexpected batch sizes %v", sizes)
+	}
+
+	stuck := DynamoWriterFunc(func(_ context.Context, _ string, items []DynamoItem) ([]DynamoItem, error) { return items, nil })
+	rec, _ = NewDynamoRecorder(DynamoConfig{Writer: stuck, Table: "audit", MaxRetries: 2, RetryBackoff: time.Millisecond})
+	if err := rec.Record(context.Background(), Entry{Action: "EXPORT"}); err == nil {
+		t.Fatal("expected error for items that stay unprocessed")
+	}
+}
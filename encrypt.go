@@ -0,0 +1,357 @@
+package audittrail
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// KeyWrapper wraps and unwraps data keys with a key-encryption key (KEK) held in a KMS.
+// Wrap uses the KEK's current (primary) version and returns it; Unwrap is given the version
+// the key was wrapped with. With AWS KMS:
+//
+//	func (w awsKMS) Wrap(ctx context.Context, key []byte) ([]byte, string, error) {
+//		out, err := w.client.Encrypt(ctx, &kms.EncryptInput{KeyId: &w.keyID, Plaintext: key})
+//		if err != nil {
+//			return nil, "", err
+//		}
+//		return out.CiphertextBlob, *out.KeyId, nil
+//	}
+//
+//	func (w awsKMS) Unwrap(ctx context.Context, wrapped []byte, _ string) ([]byte, error) {
+//		out, err := w.client.Decrypt(ctx, &kms.DecryptInput{CiphertextBlob: wrapped})
+//		if err != nil {
+//			return nil, err
+//		}
+//		return out.Plaintext, nil
+//	}
+//
+// With Cloud KMS, Encrypt's response Name is the crypto key version used.
+type KeyWrapper interface {
+	Wrap(ctx context.Context, key []byte) (wrapped []byte, version string, err error)
+	Unwrap(ctx context.Context, wrapped []byte, version string) ([]byte, error)
+}
+
+// EncryptionConfig enables envelope encryption of request and response payloads.
+type EncryptionConfig struct {
+	Wrapper KeyWrapper
+	// DataKeyTTL is how long a data key encrypts new entries before a fresh one is
+	// generated; default 24h.
+	DataKeyTTL time.Duration
+}
+
+// encryptedPayload is the JSON stored in place of an encrypted payload.
+type encryptedPayload struct {
+	Enc struct {
+		Alg   string `json:"alg"`
+		KeyID string `json:"kid"` // data key in the "<table>_keys" table
+		Nonce string `json:"iv"`
+		Data  string `json:"ct"`
+	} `json:"$enc"`
+}
+
+// keyColumns is the schema of the data key table.
+var keyColumns = []column{
+	{name: "key_id", ddl: "VARCHAR(64) PRIMARY KEY"},
+	{name: "wrapped_key", ddl: "TEXT NOT NULL"},
+	{name: "kek_version", ddl: "VARCHAR(255) NOT NULL"},
+	{name: "created_date", ddl: "TIMESTAMP NOT NULL"},
+}
+
+// encryptor encrypts payloads with AES-256-GCM data keys that are stored wrapped by the KMS
+// in a table next to the audit table, so rotating the KEK only re-wraps those keys.
+type encryptor struct {
+	trail   *AuditTrail
+	wrapper KeyWrapper
+	ttl     time.Duration
+	table   string
+
+	mu      sync.Mutex
+	current string            // ID of the data key used for new entries
+	expires time.Time         // when current is replaced
+	keys    map[string][]byte // unwrapped data keys by ID
+}
+
+func newEncryptor(trail *AuditTrail, cfg *EncryptionConfig) (*encryptor, error) {
+	if cfg.Wrapper == nil {
+		return nil, errors.New("audittrail: encryption needs a key wrapper")
+	}
+	ttl := cfg.DataKeyTTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	return &encryptor{
+		trail:   trail,
+		wrapper: cfg.Wrapper,
+		ttl:     ttl,
+		table:   trail.quote(trail.table + "_keys"),
+		keys:    map[string][]byte{},
+	}, nil
+}
+
+// dataKey returns the current data key, generating, wrapping and storing a new one when
+// there is none yet or it has expired.
+func (e *encryptor) dataKey(ctx context.Context) (string, []byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := e.trail.now()
+	if e.current != "" && now.Before(e.expires) {
+		return e.current, e.keys[e.current], nil
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", nil, err
+	}
+	wrapped, version, err := e.wrapper.Wrap(ctx, key)
+	if err != nil {
+		return "", nil, fmt.Errorf("audittrail: wrap data key failed: %w", err)
+	}
+	id := newID()
+	b := &queryBuilder{placeholder: e.trail.placeholder, dialect: e.trail.dialect}
+	query := fmt.Sprintf("INSERT INTO %s (key_id, wrapped_key, kek_version, created_date) VALUES (%s, %s, %s, %s)",
+		e.table, b.arg(id), b.arg(base64.StdEncoding.EncodeToString(wrapped)), b.arg(version), b.arg(now.UTC()))
+	if _, err := e.trail.db.ExecContext(ctx, query, b.args...); err != nil {
+		return "", nil, fmt.Errorf("audittrail: store data key failed: %w", err)
+	}
+	e.current, e.expires, e.keys[id] = id, now.Add(e.ttl), key
+	return id, key, nil
+}
+
+// lookup returns the unwrapped data key with the given ID.
+func (e *encryptor) lookup(ctx context.Context, id string) ([]byte, error) {
+	e.mu.Lock()
+	key, ok := e.keys[id]
+	e.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	b := &queryBuilder{placeholder: e.trail.placeholder, dialect: e.trail.dialect}
+	query := fmt.Sprintf("SELECT wrapped_key, kek_version FROM %s WHERE key_id = %s", e.table, b.arg(id))
+	var encoded, version string
+	if err := e.trail.db.QueryRowContext(ctx, query, b.args...).Scan(&encoded, &version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("audittrail: unknown data key %s", id)
+		}
+		return nil, err
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	key, err = e.wrapper.Unwrap(ctx, wrapped, version)
+	if err != nil {
+		return nil, fmt.Errorf("audittrail: unwrap data key %s failed: %w", id, err)
+	}
+	e.mu.Lock()
+	e.keys[id] = key
+	e.mu.Unlock()
+	return key, nil
+}
+
+// encrypt replaces the payloads of a normalized entry with encrypted envelopes. The entry ID
+// and field name are bound as associated data, so a ciphertext cannot be moved to another
+// entry or field.
+func (e *encryptor) encrypt(ctx context.Context, entry Entry) (Entry, error) {
+	if entry.Request == nil && entry.Response == nil {
+		return entry, nil
+	}
+	id, key, err := e.dataKey(ctx)
+	if err != nil {
+		return Entry{}, err
+	}
+	for _, p := range []struct {
+		name  string
+		value *any
+	}{{"log_request", &entry.Request}, {"log_response", &entry.Response}} {
+		plain, err := marshalJSONValue(*p.value)
+		if err != nil {
+			return Entry{}, marshalFailed(p.name, err)
+		}
+		if !plain.Valid {
+			*p.value = nil
+			continue
+		}
+		sealed, err := seal(id, key, entry.ID, p.name, plain.String)
+		if err != nil {
+			return Entry{}, err
+		}
+		*p.value = sealed
+	}
+	return entry, nil
+}
+
+// seal encrypts one JSON payload into an envelope under the data key id.
+func seal(id string, key []byte, entryID, name, plain string) (json.RawMessage, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	var env encryptedPayload
+	env.Enc.Alg, env.Enc.KeyID = "A256GCM", id
+	env.Enc.Nonce = base64.StdEncoding.EncodeToString(nonce)
+	env.Enc.Data = base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, []byte(plain), []byte(entryID+"/"+name)))
+	buf, err := json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(buf), nil
+}
+
+// rewrite applies fn to a stored payload. An encrypted envelope is decrypted first and the
+// result encrypted again under the current data key, so AnonymizeActor can redact
+// encrypted payloads; other values are passed to fn as stored.
+func (e *encryptor) rewrite(ctx context.Context, entryID, name string, stored sql.NullString, fn func(sql.NullString) sql.NullString) (sql.NullString, error) {
+	var env encryptedPayload
+	if !stored.Valid || json.Unmarshal([]byte(stored.String), &env) != nil || env.Enc.KeyID == "" {
+		return fn(stored), nil
+	}
+	plain, err := e.decrypt(ctx, entryID, name, json.RawMessage(stored.String))
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	rewritten := fn(storedPayload(plain))
+	if !rewritten.Valid {
+		return rewritten, nil
+	}
+	id, key, err := e.dataKey(ctx)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	sealed, err := seal(id, key, entryID, name, rewritten.String)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(sealed), Valid: true}, nil
+}
+
+// decrypt restores one payload; values that are not encrypted envelopes are returned as is.
+func (e *encryptor) decrypt(ctx context.Context, entryID, name string, v any) (any, error) {
+	raw, err := marshalJSONValue(v)
+	if err != nil || !raw.Valid {
+		return v, err
+	}
+	var env encryptedPayload
+	if json.Unmarshal([]byte(raw.String), &env) != nil || env.Enc.KeyID == "" {
+		return v, nil
+	}
+	if env.Enc.Alg != "A256GCM" {
+		return nil, fmt.Errorf("audittrail: unsupported payload algorithm %q", env.Enc.Alg)
+	}
+	key, err := e.lookup(ctx, env.Enc.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := base64.StdEncoding.DecodeString(env.Enc.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(env.Enc.Data)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, nonce, data, []byte(entryID+"/"+name))
+	if err != nil {
+		return nil, fmt.Errorf("audittrail: decrypt %s of entry %s failed: %w", name, entryID, err)
+	}
+	return json.RawMessage(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Decrypt returns entry with payloads encrypted by Config.Encryption decrypted, as
+// json.RawMessage. Payloads that are not encrypted are returned unchanged, so it is safe
+// on tables written before encryption was enabled.
+func (r *AuditTrail) Decrypt(ctx context.Context, entry Entry) (Entry, error) {
+	if r == nil || r.encryptor == nil {
+		return Entry{}, errors.New("audittrail: encryption is not configured")
+	}
+	var err error
+	if entry.Request, err = r.encryptor.decrypt(ctx, entry.ID, "log_request", entry.Request); err != nil {
+		return Entry{}, err
+	}
+	if entry.Response, err = r.encryptor.decrypt(ctx, entry.ID, "log_response", entry.Response); err != nil {
+		return Entry{}, err
+	}
+	return entry, nil
+}
+
+// RotateKeys re-wraps every stored data key with the KEK's current version, e.g. after
+// rotating the key in KMS or before disabling an old version. Payloads are not touched:
+// entries reference their data key by ID, and only the wrapped copy of that key changes.
+// It returns the number of data keys whose KEK version changed.
+func (r *AuditTrail) RotateKeys(ctx context.Context) (int64, error) {
+	if r == nil || r.encryptor == nil {
+		return 0, errors.New("audittrail: encryption is not configured")
+	}
+	e := r.encryptor
+
+	type storedKey struct{ id, wrapped, version string }
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf("SELECT key_id, wrapped_key, kek_version FROM %s", e.table))
+	if err != nil {
+		return 0, err
+	}
+	var stored []storedKey
+	for rows.Next() {
+		var k storedKey
+		if err := rows.Scan(&k.id, &k.wrapped, &k.version); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		stored = append(stored, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var rotated int64
+	for _, k := range stored {
+		wrapped, err := base64.StdEncoding.DecodeString(k.wrapped)
+		if err != nil {
+			return rotated, fmt.Errorf("audittrail: data key %s: %w", k.id, err)
+		}
+		key, err := e.wrapper.Unwrap(ctx, wrapped, k.version)
+		if err != nil {
+			return rotated, fmt.Errorf("audittrail: unwrap data key %s failed: %w", k.id, err)
+		}
+		rewrapped, version, err := e.wrapper.Wrap(ctx, key)
+		if err != nil {
+			return rotated, fmt.Errorf("audittrail: wrap data key %s failed: %w", k.id, err)
+		}
+		if version == k.version {
+			continue
+		}
+		b := &queryBuilder{placeholder: r.placeholder, dialect: r.dialect}
+		query := fmt.Sprintf("UPDATE %s SET wrapped_key = %s, kek_version = %s WHERE key_id = %s",
+			e.table, b.arg(base64.StdEncoding.EncodeToString(rewrapped)), b.arg(version), b.arg(k.id))
+		if _, err := r.db.ExecContext(ctx, query, b.args...); err != nil {
+			return rotated, fmt.Errorf("audittrail: store data key %s failed: %w", k.id, err)
+		}
+		rotated++
+	}
+	return rotated, nil
+}
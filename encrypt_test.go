@@ -0,0 +1,106 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// xorWrapper is a KeyWrapper whose KEK versions are single XOR bytes.
+type xorWrapper struct {
+	current string
+}
+
+func (w *xorWrapper) xor(key []byte, version string) []byte {
+	out := make([]byte, len(key))
+	for i, b := range key {
+		out[i] = b ^ version[len(version)-1]
+	}
+	return out
+}
+
+func (w *xorWrapper) Wrap(_ context.Context, key []byte) ([]byte, string, error) {
+	return w.xor(key, w.current), w.current, nil
+}
+
+func (w *xorWrapper) Unwrap(_ context.Context, wrapped []byte, version string) ([]byte, error) {
+	return w.xor(wrapped, version), nil
+}
+
+func TestPayloadEncryptionAndKeyRotation(t *testing.T) {
+	var calls []execCall
+	keys := map[string][]driver.Value{} // key_id -> wrapped_key, kek_version
+	db := openStubDB(t, &stubDriver{
+		execFn: func(query string, args []driver.NamedValue) (driver.Result, error) {
+			calls = append(calls, execCall{query: query, args: args})
+			switch {
+			case strings.HasPrefix(query, "INSERT INTO audit_trail_keys"):
+				keys[args[0].Value.(string)] = []driver.Value{args[1].Value, args[2].Value}
+			case strings.HasPrefix(query, "UPDATE audit_trail_keys"):
+				keys[args[2].Value.(string)] = []driver.Value{args[0].Value, args[1].Value}
+			}
+			return stubResult{}, nil
+		},
+		queryFn: func(query string, args []driver.NamedValue) (driver.Rows, error) {
+			var values [][]driver.Value
+			if len(args) == 1 {
+				if k, ok := keys[args[0].Value.(string)]; ok {
+					values = append(values, k)
+				}
+				return &stubRows{columns: []string{"wrapped_key", "kek_version"}, values: values}, nil
+			}
+			for id, k := range keys {
+				values = append(values, []driver.Value{id, k[0], k[1]})
+			}
+			return &stubRows{columns: []string{"key_id", "wrapped_key", "kek_version"}, values: values}, nil
+		},
+	})
+	wrapper := &xorWrapper{current: "v1"}
+	cfg := Config{DB: db, Placeholder: PlaceholderDollar, Encryption: &EncryptionConfig{Wrapper: wrapper}}
+	audit, err := NewAuditTrail(cfg)
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	ctx := context.Background()
+	for _, id := range []string{"e1", "e2"} {
+		if err := audit.Record(ctx, Entry{ID: id, Action: "UPDATE_PROFILE", Request: map[string]any{"email": "a@b.c"}}); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	if len(calls) != 3 || len(keys) != 1 {
+		t.Fatalf("expected one data key for both entries, got %d calls and %d keys", len(calls), len(keys))
+	}
+	stored := stringArg(calls[1].args, 4)
+	if strings.Contains(stored, "a@b.c") || !strings.Contains(stored, `"$enc"`) || stringArg(calls[1].args, 5) != "" {
+		t.Fatalf("unexpected stored payloads: %s / %s", stored, stringArg(calls[1].args, 5))
+	}
+
+	wrapper.current = "v2"
+	if n, err := audit.RotateKeys(ctx); err != nil || n != 1 {
+		t.Fatalf("RotateKeys: n=%d err=%v", n, err)
+	}
+
+	// A fresh instance unwraps the re-wrapped data key from the table.
+	reader, err := NewAuditTrail(cfg)
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	entry, err := reader.Decrypt(ctx, Entry{ID: "e1", Request: json.RawMessage(stored), Response: json.RawMessage(`{"plain":true}`)})
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if string(entry.Request.(json.RawMessage)) != `{"email":"a@b.c"}` || string(entry.Response.(json.RawMessage)) != `{"plain":true}` {
+		t.Fatalf("unexpected decrypted entry: %s / %s", entry.Request, entry.Response)
+	}
+	if n, err := audit.RotateKeys(ctx); err != nil || n != 0 {
+		t.Fatalf("expected no keys to re-wrap under the same version, n=%d err=%v", n, err)
+	}
+
+	// The ciphertext is bound to its entry.
+	if _, err := reader.Decrypt(ctx, Entry{ID: "e2", Request: json.RawMessage(stored)}); err == nil {
+		t.Fatal("expected error decrypting a payload moved to another entry")
+	}
+}
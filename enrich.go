@@ -0,0 +1,107 @@
+package audittrail
+
+import (
+	"context"
+	"fmt"
+)
+
+// Enricher adds data to an entry before it is validated and stored, e.g. hostname, pod
+// name, app version or region. Returning an error rejects the entry.
+type Enricher func(ctx context.Context, entry *Entry) error
+
+// RecorderOption configures hooks shared by the built-in recorders (AuditTrail and
+// PubSubRecorder): enrichers, then transformers, then audit struct tags, then sensitive
+// data screening, then validators.
+type RecorderOption func(*recorderHooks)
+
+// recorderHooks holds the per-recorder pipeline applied to every entry.
+type recorderHooks struct {
+	enrichers    []Enricher
+	transformers []Transformer
+	validators   []Validator
+	policy       ValidationPolicy
+	quarantine   Recorder
+	ids          IDGenerator
+	sensitive    SensitiveMode
+	readAudit    bool
+	readRecorder Recorder // nil records reads to the AuditTrail itself
+}
+
+// WithEnrichers runs fns, in order, on every entry the recorder receives.
+func WithEnrichers(fns ...Enricher) RecorderOption {
+	return func(h *recorderHooks) {
+		for _, fn := range fns {
+			if fn != nil {
+				h.enrichers = append(h.enrichers, fn)
+			}
+		}
+	}
+}
+
+// Transformer rewrites an entry before it is stored, e.g. to map legacy action names,
+// normalize field values or strip fields at the storage boundary.
+type Transformer func(entry Entry) Entry
+
+// WithTransformer runs fns, in order, on every entry after the enrichers. Set it on the
+// AuditTrail passed to NewConsumer to transform entries as they are persisted.
+func WithTransformer(fns ...Transformer) RecorderOption {
+	return func(h *recorderHooks) {
+		for _, fn := range fns {
+			if fn != nil {
+				h.transformers = append(h.transformers, fn)
+			}
+		}
+	}
+}
+
+func newRecorderHooks(opts []RecorderOption) recorderHooks {
+	var h recorderHooks
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&h)
+		}
+	}
+	return h
+}
+
+// apply assigns an ID from the generator, runs the enrichers and transformers on a copy of
+// entry, screens its payloads for sensitive data, then runs the validators. It returns errQuarantined when the entry was diverted and
+// must not be stored.
+func (h *recorderHooks) apply(ctx context.Context, entry Entry) (Entry, error) {
+	if entry.ID == "" && h.ids != nil {
+		entry.ID = h.ids.NewID()
+	}
+	for _, fn := range h.enrichers {
+		if err := fn(ctx, &entry); err != nil {
+			return Entry{}, fmt.Errorf("audittrail: enrich entry failed: %w", err)
+		}
+	}
+	for _, fn := range h.transformers {
+		entry = fn(entry)
+	}
+	var err error
+	if entry.Request, err = applyAuditTags(entry.Request); err != nil {
+		return Entry{}, marshalFailed("request", err)
+	}
+	if entry.Response, err = applyAuditTags(entry.Response); err != nil {
+		return Entry{}, marshalFailed("response", err)
+	}
+	entry = h.screenSensitive(entry)
+	if err := h.validate(ctx, entry); err != nil {
+		return Entry{}, err
+	}
+	return entry, nil
+}
+
+// Enrich wraps any Recorder so fns run on every entry before it is passed on; use it for
+// recorders that do not take RecorderOptions (Loki, object storage, ...).
+func Enrich(rec Recorder, fns ...Enricher) Recorder {
+	hooks := newRecorderHooks([]RecorderOption{WithEnrichers(fns...)})
+	return RecorderFunc(func(ctx context.Context, entry Entry) error {
+		enriched, err := hooks.apply(ctx, entry)
+		if err != nil {
+			return err
+		}
+		return rec.Record(ctx, enriched)
+	})
+}
@@ -0,0 +1,94 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+func TestEnrichersRunBeforeRecording(t *testing.T) {
+	var published Entry
+	region := func(_ context.Context, e *Entry) error {
+		e.Request = map[string]any{"region": "eu-west-1"}
+		return nil
+	}
+	host := func(_ context.Context, e *Entry) error {
+		e.CreatedBy = "host-a/" + e.CreatedBy
+		return nil
+	}
+	rec, err := NewPubSubRecorder(PublisherFunc(func(_ context.Context, e Entry) error {
+		published = e
+		return nil
+	}), nil, WithEnrichers(region, host))
+	if err != nil {
+		t.Fatalf("NewPubSubRecorder: %v", err)
+	}
+	if err := rec.Record(context.Background(), Entry{Action: "A", CreatedBy: "u1"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if published.CreatedBy != "host-a/u1" || published.Request.(map[string]any)["region"] != "eu-west-1" {
+		t.Fatalf("entry not enriched: %+v", published)
+	}
+
+	var calls int
+	db := openStubDB(t, &stubDriver{execFn: func(string, []driver.NamedValue) (driver.Result, error) {
+		calls++
+		return stubResult{}, nil
+	}})
+	reject := errors.New("missing tenant")
+	audit, err := NewAuditTrail(Config{DB: db}, WithEnrichers(func(context.Context, *Entry) error { return reject }))
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	if err := audit.Record(context.Background(), Entry{Action: "A"}); !errors.Is(err, reject) || calls != 0 {
+		t.Fatalf("expected enricher error before insert, err=%v calls=%d", err, calls)
+	}
+
+	var got Entry
+	wrapped := Enrich(RecorderFunc(func(_ context.Context, e Entry) error { got = e; return nil }), host)
+	_ = wrapped.Record(context.Background(), Entry{Action: "A", CreatedBy: "u2"})
+	if got.CreatedBy != "host-a/u2" {
+		t.Fatalf("Enrich did not apply: %+v", got)
+	}
+}
+
+func TestTransformerRunsAtStorageBoundary(t *testing.T) {
+	var args []driver.NamedValue
+	db := openStubDB(t, &stubDriver{execFn: func(_ string, a []driver.NamedValue) (driver.Result, error) {
+		args = a
+		return stubResult{}, nil
+	}})
+	legacy := map[string]string{"user.login": "USER_LOGIN"}
+	audit, err := NewAuditTrail(Config{DB: db}, WithTransformer(func(e Entry) Entry {
+		if mapped, ok := legacy[e.Action]; ok {
+			e.Action = mapped
+		}
+		e.Response = nil
+		return e
+	}))
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	entries := make(chan Entry, 1)
+	entries <- Entry{ID: "e1", Action: "user.login", Response: map[string]any{"token": "secret"}}
+	close(entries)
+	consumer, err := NewConsumer(audit, SubscriberFunc(func(ctx context.Context, handler func(context.Context, Entry) error) error {
+		for e := range entries {
+			if err := handler(ctx, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}), nil)
+	if err != nil {
+		t.Fatalf("NewConsumer: %v", err)
+	}
+	if err := consumer.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(args) != insertColumnCount || args[2].Value != "USER_LOGIN" || args[5].Value != nil {
+		t.Fatalf("entry not transformed: %+v", args)
+	}
+}
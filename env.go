@@ -4,10 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"log"
+	"fmt"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"cloud.google.com/go/pubsub"
 )
@@ -25,8 +28,26 @@ const (
 	envDBDriver           = "AUDIT_DB_DRIVER"
 	envDBDSN              = "AUDIT_DB_DSN"
 	envAuditTable         = "AUDIT_TABLE"
+	envDBMaxOpenConns     = "AUDIT_DB_MAX_OPEN_CONNS"
+	envDBMaxIdleConns     = "AUDIT_DB_MAX_IDLE_CONNS"
+	envDBConnMaxLifetime  = "AUDIT_DB_CONN_MAX_LIFETIME"
+	envEnsureTopology     = "AUDIT_ENSURE_TOPOLOGY"
+	envTransport          = "AUDIT_TRANSPORT"
+	envPubSubEmulator     = "PUBSUB_EMULATOR_HOST"
 )
 
+// Transports selectable with AUDIT_TRANSPORT.
+const (
+	TransportPubSub = "pubsub" // GCP Pub/Sub, or the emulator when PUBSUB_EMULATOR_HOST is set
+	TransportMemory = "memory" // in-process MemoryPubSub, for local development without GCP
+)
+
+// memoryTransportBuffer is the queue size of the memory transport.
+const memoryTransportBuffer = 1024
+
+// defaultStartupTimeout bounds the startup checks enabled by WithStartupPing and WithEnsureTable.
+const defaultStartupTimeout = 10 * time.Second
+
 // InitOptions configures audit trail initialization with custom handlers
 type InitOptions struct {
 	// OnConsumerError is called when consumer fails to process a message (e.g., DB insert error)
@@ -37,6 +58,72 @@ type InitOptions struct {
 
 	// SecretProvider for fetching config from GCP Secret Manager (optional)
 	SecretProvider SecretProvider
+
+	// Logger receives the package's diagnostics (default: slog.Default()), see SetLogger.
+	Logger Logger
+
+	// OnDrop is registered with OnDrop and called whenever an entry is lost for good
+	// (e.g. async publish failure), so audit data loss can be alerted on.
+	OnDrop func(entry Entry, err error)
+
+	// Enrichers run on every entry recorded through the global Record (see WithEnrichers),
+	// after InstanceEnricher.
+	Enrichers []Enricher
+
+	// Transformers run on every entry the consumer persists (see WithTransformer).
+	Transformers []Transformer
+
+	// MaxOpenConns, MaxIdleConns and ConnMaxLifetime tune the connection pool of the
+	// database opened by Init (see sql.DB). Zero values fall back to AUDIT_DB_MAX_OPEN_CONNS,
+	// AUDIT_DB_MAX_IDLE_CONNS and AUDIT_DB_CONN_MAX_LIFETIME, then to database/sql defaults.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+
+	// PrepareStatements caches the prepared INSERT used by the consumer (see
+	// Config.PrepareStatements).
+	PrepareStatements bool
+
+	// EnsureTopology creates the Pub/Sub topic and subscription when they are missing (also
+	// enabled by AUDIT_ENSURE_TOPOLOGY=true), with TopicRetention and AckDeadline (see
+	// GCPTopology).
+	EnsureTopology bool
+	TopicRetention time.Duration
+	AckDeadline    time.Duration
+
+	// StartupPing makes Init fail fast when the database is unreachable or, unless
+	// EnsureTopology is set, the Pub/Sub topic or subscription does not exist, instead of
+	// surfacing the problem on the first entry. StartupTimeout bounds these checks and
+	// EnsureTable (default 10s).
+	StartupPing    bool
+	StartupTimeout time.Duration
+
+	// EnsureTable creates or migrates the audit table at Init (see AuditTrail.EnsureTable).
+	EnsureTable bool
+
+	// Resilient makes Init return at once even when the database or broker is down (e.g.
+	// briefly at boot): Record holds entries in memory, up to PendingLimit (default 10000),
+	// or durably in Spool, and the pipeline is retried in the background, backing off from
+	// RetryInterval (default 1s) to a minute, until the database answers a ping. The held
+	// entries are then published in order. Entries beyond the limit, and those in memory at
+	// Shutdown, are reported to OnDrop.
+	Resilient     bool
+	PendingLimit  int
+	Spool         *FileSpool
+	RetryInterval time.Duration
+
+	// DisableInstanceFields stops Init from stamping AppVersion, Hostname and InstanceID
+	// on entries (see InstanceEnricher).
+	DisableInstanceFields bool
+
+	// LifecycleEvents records entries about the pipeline itself: ActionPipelineStarted when
+	// Init brings it up, ActionPipelineStopped at Shutdown (spanning the uptime), and, in
+	// resilient mode, ActionPipelineDegraded spanning the time Init waited for the backends.
+	LifecycleEvents bool
+
+	// HeartbeatInterval, when positive, makes Init record an ActionHeartbeat entry at this
+	// interval until Shutdown (see RunHeartbeat and HeartbeatGaps).
+	HeartbeatInterval time.Duration
 }
 
 var runtime struct {
@@ -48,14 +135,48 @@ var runtime struct {
 	wg           sync.WaitGroup
 	db           *sql.DB
 	pubsub       *pubsub.Client
+	pending      *pendingRecorder // set in resilient mode
+	pipelines    map[string]*namedPipeline
 	options      *InitOptions
+	startedAt    time.Time // when the pipeline came up, for ActionPipelineStopped
 }
 
 // InitFromEnv initializes a global recorder and consumer using GCP Pub/Sub + DB.
-// Configuration is loaded from environment variables.
+// Configuration is loaded from environment variables; AUDIT_TRANSPORT=memory replaces
+// Pub/Sub with an in-process queue for local development, and PUBSUB_EMULATOR_HOST
+// points the client at the Pub/Sub emulator.
 // It is safe to call multiple times; only the first call will initialize.
-func InitFromEnv(ctx context.Context) error {
-	return InitWithOptions(ctx, nil)
+func InitFromEnv(ctx context.Context, opts ...InitOption) error {
+	options := &InitOptions{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(options)
+		}
+	}
+	return InitWithOptions(ctx, options)
+}
+
+// WithStartupPing makes InitFromEnv verify the database and Pub/Sub resources before
+// returning (see InitOptions.StartupPing).
+func WithStartupPing() InitOption {
+	return func(o *InitOptions) {
+		o.StartupPing = true
+	}
+}
+
+// WithEnsureTable makes InitFromEnv create or migrate the audit table.
+func WithEnsureTable() InitOption {
+	return func(o *InitOptions) {
+		o.EnsureTable = true
+	}
+}
+
+// WithEnsureTopology makes InitFromEnv create the Pub/Sub topic and subscription when they
+// are missing (see GCPTopology).
+func WithEnsureTopology() InitOption {
+	return func(o *InitOptions) {
+		o.EnsureTopology = true
+	}
 }
 
 // InitFromEnvOrSecrets initializes using environment variables with optional secret provider fallback.
@@ -84,7 +205,6 @@ func InitWithOptions(ctx context.Context, opts *InitOptions) error {
 	if opts == nil {
 		opts = &InitOptions{}
 	}
-	provider := opts.SecretProvider
 	runtime.mu.Lock()
 	if runtime.initialized {
 		runtime.mu.Unlock()
@@ -96,16 +216,68 @@ func InitWithOptions(ctx context.Context, opts *InitOptions) error {
 	}
 	runtime.initializing = true
 	runtime.mu.Unlock()
-	ok := false
-	defer func() {
-		if ok {
-			return
-		}
+
+	if opts.Logger != nil {
+		SetLogger(opts.Logger)
+	}
+	if opts.Resilient {
+		startResilient(ctx, opts)
+		return nil
+	}
+
+	p, err := startPipeline(ctx, opts, pipelineResources{}, &runtime.wg)
+	if err != nil {
 		runtime.mu.Lock()
 		runtime.initializing = false
 		runtime.mu.Unlock()
-	}()
+		return err
+	}
+	runtime.mu.Lock()
+	runtime.initialized = true
+	runtime.initializing = false
+	runtime.recorder = p.recorder
+	runtime.cancel = p.cancel
+	runtime.db = p.db
+	runtime.pubsub = p.client
+	runtime.options = opts
+	runtime.startedAt = time.Now().UTC()
+	startHeartbeat(ctx, opts)
+	runtime.mu.Unlock()
+	OnDrop(opts.OnDrop)
+	if opts.LifecycleEvents {
+		recordLifecycle(ctx, p.recorder, lifecycleEntry(ActionPipelineStarted, SeverityInfo, time.Time{}, time.Now().UTC(), p.describe()))
+	}
+	return nil
+}
+
+// pipeline is the recorder and running consumer set up by Init*.
+type pipeline struct {
+	recorder  Recorder
+	cancel    context.CancelFunc
+	db        *sql.DB
+	client    *pubsub.Client
+	transport string
+	table     string
+}
+
+// describe returns the Meta of the pipeline's lifecycle entries.
+func (p *pipeline) describe() map[string]any {
+	return map[string]any{"transport": p.transport, "table": p.table}
+}
+
+// pipelineResources overrides the topic, subscription and table read from the environment.
+// With a name, the environment values get "-<name>" (topic, subscription) or "_<name>"
+// (table) appended.
+type pipelineResources struct {
+	name         string
+	topic        string
+	subscription string
+	table        string
+}
 
+// startPipeline opens the database and transport and starts the consumer, tracked by wg.
+func startPipeline(ctx context.Context, opts *InitOptions, res pipelineResources, wg *sync.WaitGroup) (*pipeline, error) {
+	provider := opts.SecretProvider
 	// Helper to get config from env var or secret provider
 	getConfig := func(envKey, secretKey, defaultVal string) string {
 		return getEnvOrSecret(ctx, provider, envKey, secretKey, defaultVal)
@@ -117,112 +289,149 @@ func InitWithOptions(ctx context.Context, opts *InitOptions) error {
 	dbDriver := getConfig(envDBDriver, "audit-db-driver", defaultDBDriver)
 	dbDSN := getConfig(envDBDSN, "audit-db-dsn", defaultDBDSN)
 	table := getConfig(envAuditTable, "audit-table", defaultAuditTable)
+	if res.name != "" {
+		topicName += "-" + res.name
+		subscriptionName += "-" + res.name
+		table += "_" + res.name
+	}
+	if res.topic != "" {
+		topicName = res.topic
+	}
+	if res.subscription != "" {
+		subscriptionName = res.subscription
+	}
+	if res.table != "" {
+		table = res.table
+	}
 
+	if strings.Contains(strings.ToLower(dbDriver), "mysql") {
+		dbDSN = mysqlDSN(dbDSN)
+	}
 	db, err := sql.Open(dbDriver, dbDSN)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	configurePool(db, opts)
 
 	audit, err := NewAuditTrail(Config{
-		DB:          db,
-		TableName:   table,
-		Placeholder: detectPlaceholderFromDriver(dbDriver),
-	})
+		DB:                db,
+		TableName:         table,
+		Placeholder:       detectPlaceholderFromDriver(dbDriver),
+		PrepareStatements: opts.PrepareStatements,
+	}, WithTransformer(opts.Transformers...))
 	if err != nil {
 		_ = db.Close()
-		return err
+		return nil, err
+	}
+	if err := prepareDatabase(ctx, db, audit, dbDriver, dbDSN, table, opts); err != nil {
+		_ = db.Close()
+		return nil, err
 	}
 
-	client, err := pubsub.NewClient(ctx, projectID)
+	transport := getConfig(envTransport, "audit-transport", TransportPubSub)
+	publisher, subscriber, client, err := openTransport(ctx, transport, projectID, topicName, subscriptionName, opts)
 	if err != nil {
 		_ = db.Close()
-		return err
+		return nil, err
+	}
+	closeAll := func() {
+		if client != nil {
+			_ = client.Close()
+		}
+		_ = db.Close()
 	}
 
-	recorder, err := NewPubSubRecorder(NewGCPPublisher(client.Topic(topicName)), nil)
+	enrichers := opts.Enrichers
+	if !opts.DisableInstanceFields {
+		enrichers = append([]Enricher{InstanceEnricher()}, enrichers...)
+	}
+	recorder, err := NewPubSubRecorder(publisher, nil, WithEnrichers(enrichers...))
 	if err != nil {
-		_ = client.Close()
-		_ = db.Close()
-		return err
+		closeAll()
+		return nil, err
 	}
 
 	// Use custom error handler if provided, otherwise use default logger
 	consumerErrorHandler := opts.OnConsumerError
 	if consumerErrorHandler == nil {
 		consumerErrorHandler = func(err error) {
-			log.Printf("audittrail consumer error: %v", err)
+			logger().Error("audittrail: consumer error", "error", err)
 		}
 	}
 
-	consumer, err := NewConsumer(audit, NewGCPSubscriber(client.Subscription(subscriptionName)), consumerErrorHandler)
+	consumer, err := NewConsumer(audit, subscriber, consumerErrorHandler)
 	if err != nil {
-		_ = client.Close()
-		_ = db.Close()
-		return err
+		closeAll()
+		return nil, err
 	}
 
 	runCtx, cancel := context.WithCancel(ctx)
-	runtime.wg.Add(1)
+	wg.Add(1)
 	go func() {
-		defer runtime.wg.Done()
+		defer wg.Done()
 		if err := consumer.Run(runCtx); err != nil && !errors.Is(err, context.Canceled) {
 			if opts.OnConsumerError != nil {
 				opts.OnConsumerError(err)
 			} else {
-				log.Printf("audittrail consumer stopped: %v", err)
+				logger().Error("audittrail: consumer stopped", "error", err)
 			}
 		}
 	}()
-
-	runtime.mu.Lock()
-	runtime.initialized = true
-	runtime.initializing = false
-	runtime.recorder = recorder
-	runtime.cancel = cancel
-	runtime.db = db
-	runtime.pubsub = client
-	runtime.options = opts
-	runtime.mu.Unlock()
-
-	ok = true
-	return nil
+	return &pipeline{recorder: recorder, cancel: cancel, db: db, client: client, transport: transport, table: table}, nil
 }
 
+var errNotInitialized = errors.New("audittrail: not initialized, call InitFromEnv first")
+
 // Record publishes an audit entry using the default recorder.
 func Record(ctx context.Context, entry Entry) error {
-	runtime.mu.Lock()
-	recorder := runtime.recorder
-	opts := runtime.options
-	runtime.mu.Unlock()
+	recorder, onError := defaultRecorder()
 	if recorder == nil {
-		return errors.New("audittrail: not initialized, call InitFromEnv first")
+		return errNotInitialized
 	}
 	if ctx == nil {
 		ctx = context.Background()
 	}
 	err := recorder.Record(ctx, entry)
-	if err != nil && opts != nil && opts.OnPublishError != nil {
-		opts.OnPublishError(err)
+	if err != nil && onError != nil {
+		onError(err)
 	}
 	return err
 }
 
-// Shutdown stops the consumer and closes resources initialized by InitFromEnv.
+// defaultRecorder returns the recorder behind Record, or nil before InitFromEnv, and the
+// InitOptions.OnPublishError handler.
+func defaultRecorder() (Recorder, func(error)) {
+	runtime.mu.Lock()
+	defer runtime.mu.Unlock()
+	if runtime.options == nil {
+		return runtime.recorder, nil
+	}
+	return runtime.recorder, runtime.options.OnPublishError
+}
+
+// Shutdown stops the consumers and closes resources initialized by InitFromEnv and Register.
 func Shutdown(ctx context.Context) error {
 	runtime.mu.Lock()
-	if !runtime.initialized {
+	if !runtime.initialized && len(runtime.pipelines) == 0 {
 		runtime.initializing = false
 		runtime.mu.Unlock()
 		return nil
 	}
 	cancel := runtime.cancel
-	db := runtime.db
-	client := runtime.pubsub
+	pipelines := runtime.pipelines
+	recorder, opts, startedAt := runtime.recorder, runtime.options, runtime.startedAt
 	runtime.mu.Unlock()
 
+	// Recorded before the consumer stops, so it can still be delivered.
+	if opts != nil && opts.LifecycleEvents && !startedAt.IsZero() {
+		recordLifecycle(ctx, recorder, lifecycleEntry(ActionPipelineStopped, SeverityInfo, startedAt, time.Now().UTC(), nil))
+	}
 	if cancel != nil {
 		cancel()
 	}
+	for _, named := range pipelines {
+		named.stop()
+	}
 
 	done := make(chan struct{})
 	go func() {
@@ -236,6 +445,18 @@ func Shutdown(ctx context.Context) error {
 		return ctx.Err()
 	}
 
+	// Read after the wait: a resilient Init may have opened them in the background.
+	runtime.mu.Lock()
+	db := runtime.db
+	client := runtime.pubsub
+	pending := runtime.pending
+	runtime.mu.Unlock()
+	if pending != nil {
+		pending.close()
+	}
+	for _, named := range pipelines {
+		named.close()
+	}
 	if client != nil {
 		_ = client.Close()
 	}
@@ -249,10 +470,180 @@ func Shutdown(ctx context.Context) error {
 	runtime.cancel = nil
 	runtime.db = nil
 	runtime.pubsub = nil
+	runtime.pending = nil
+	runtime.pipelines = nil
+	runtime.startedAt = time.Time{}
 	runtime.mu.Unlock()
 	return nil
 }
 
+// openTransport connects the publisher and subscriber used by Init*. The Pub/Sub client
+// connects to the emulator by itself when PUBSUB_EMULATOR_HOST is set; as emulators start
+// empty, the topology is then always ensured.
+func openTransport(ctx context.Context, transport, projectID, topic, subscription string, opts *InitOptions) (Publisher, Subscriber, *pubsub.Client, error) {
+	switch transport {
+	case TransportMemory:
+		logger().Warn("audittrail: using the in-memory transport; entries are lost if the process exits before they are stored")
+		mem := NewMemoryPubSub(memoryTransportBuffer)
+		return mem, mem, nil, nil
+	case TransportPubSub:
+	default:
+		return nil, nil, nil, fmt.Errorf("audittrail: unknown transport %q (want %q or %q)", transport, TransportPubSub, TransportMemory)
+	}
+
+	client, err := pubsub.NewClient(ctx, projectID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ensure, _ := strconv.ParseBool(getenv(envEnsureTopology, "false"))
+	if emulator := getenv(envPubSubEmulator, ""); emulator != "" {
+		logger().Info("audittrail: using the Pub/Sub emulator", "host", emulator)
+		ensure = true
+	}
+	if ensure || opts.EnsureTopology {
+		topology := GCPTopology{
+			Client:           client,
+			Topic:            topic,
+			Subscription:     subscription,
+			MessageRetention: opts.TopicRetention,
+			AckDeadline:      opts.AckDeadline,
+		}
+		if err := topology.EnsureTopology(ctx); err != nil {
+			_ = client.Close()
+			return nil, nil, nil, fmt.Errorf("%w (project %q; creating resources needs the Pub/Sub Editor role)", err, projectID)
+		}
+	} else if opts.StartupPing {
+		if err := checkTopology(ctx, client, projectID, topic, subscription, startupTimeout(opts)); err != nil {
+			_ = client.Close()
+			return nil, nil, nil, err
+		}
+	}
+	return NewGCPPublisher(client.Topic(topic)), NewGCPSubscriber(client.Subscription(subscription)), client, nil
+}
+
+// prepareDatabase runs the startup checks requested by opts.StartupPing and opts.EnsureTable.
+func prepareDatabase(ctx context.Context, db *sql.DB, audit *AuditTrail, driverName, dsn, table string, opts *InitOptions) error {
+	if !opts.StartupPing && !opts.EnsureTable {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, startupTimeout(opts))
+	defer cancel()
+	if opts.StartupPing {
+		if err := db.PingContext(ctx); err != nil {
+			return fmt.Errorf("audittrail: cannot reach database (driver %q, dsn %s): %w; check %s and that the database accepts connections",
+				driverName, redactDSN(dsn), err, envDBDSN)
+		}
+	}
+	if opts.EnsureTable {
+		if err := audit.EnsureTable(ctx); err != nil {
+			return fmt.Errorf("audittrail: ensure table %q: %w; the database user needs CREATE and ALTER on it, or create it with EnsureTable from a migration", table, err)
+		}
+	}
+	return nil
+}
+
+// checkTopology reports a missing topic or subscription, which would otherwise only fail on
+// the first publish or inside the consumer.
+func checkTopology(ctx context.Context, client *pubsub.Client, projectID, topic, subscription string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	exists, err := client.Topic(topic).Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("audittrail: cannot reach Pub/Sub (project %q): %w; check %s and the Application Default Credentials", projectID, err, envGCPProject)
+	}
+	if !exists {
+		return fmt.Errorf("audittrail: pubsub topic %q does not exist in project %q; create it, or set %s=true or WithEnsureTopology", topic, projectID, envEnsureTopology)
+	}
+	if exists, err = client.Subscription(subscription).Exists(ctx); err != nil {
+		return fmt.Errorf("audittrail: check subscription %q: %w", subscription, err)
+	}
+	if !exists {
+		return fmt.Errorf("audittrail: pubsub subscription %q does not exist in project %q; create it, or set %s=true or WithEnsureTopology", subscription, projectID, envEnsureTopology)
+	}
+	return nil
+}
+
+func startupTimeout(opts *InitOptions) time.Duration {
+	if opts.StartupTimeout > 0 {
+		return opts.StartupTimeout
+	}
+	return defaultStartupTimeout
+}
+
+// redactDSN hides the password of URL-style DSNs; other forms are not shown.
+func redactDSN(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil || u.Scheme == "" {
+		return "(redacted)"
+	}
+	return u.Redacted()
+}
+
+// mysqlDSN adds parseTime=true, loc=UTC and time_zone='+00:00' to a go-sql-driver/mysql
+// DSN unless they are set, so DATETIME values are written and read back in UTC whatever the
+// host's zone is, and the server converts TIMESTAMP columns of older tables from UTC too.
+// Explicit settings are kept; a non-UTC loc is logged because writers using different
+// zones store different wall clocks for the same instant.
+func mysqlDSN(dsn string) string {
+	slash := strings.LastIndex(dsn, "/")
+	if slash < 0 {
+		return dsn
+	}
+	base, query, _ := strings.Cut(dsn[slash:], "?")
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return dsn
+	}
+	if loc := params.Get("loc"); loc != "" && loc != "UTC" {
+		logger().Warn("audittrail: MySQL DSN sets loc to a zone other than UTC; every writer must use the same zone", "loc", loc)
+	}
+	if params.Get("parseTime") == "false" {
+		logger().Warn("audittrail: MySQL DSN disables parseTime; reading entries needs parseTime=true")
+	}
+	var extra []string
+	for _, p := range []struct{ key, value string }{
+		{"parseTime", "true"},
+		{"loc", "UTC"},
+		{"time_zone", url.QueryEscape("'+00:00'")},
+	} {
+		if !params.Has(p.key) {
+			extra = append(extra, p.key+"="+p.value)
+		}
+	}
+	if len(extra) == 0 {
+		return dsn
+	}
+	if query != "" {
+		query += "&"
+	}
+	return dsn[:slash] + base + "?" + query + strings.Join(extra, "&")
+}
+
+// configurePool applies the pool settings from opts, falling back to the environment.
+func configurePool(db *sql.DB, opts *InitOptions) {
+	maxOpen := opts.MaxOpenConns
+	if maxOpen == 0 {
+		maxOpen, _ = strconv.Atoi(getenv(envDBMaxOpenConns, "0"))
+	}
+	if maxOpen > 0 {
+		db.SetMaxOpenConns(maxOpen)
+	}
+	maxIdle := opts.MaxIdleConns
+	if maxIdle == 0 {
+		maxIdle, _ = strconv.Atoi(getenv(envDBMaxIdleConns, "0"))
+	}
+	if maxIdle > 0 {
+		db.SetMaxIdleConns(maxIdle)
+	}
+	lifetime := opts.ConnMaxLifetime
+	if lifetime == 0 {
+		lifetime, _ = time.ParseDuration(getenv(envDBConnMaxLifetime, "0s"))
+	}
+	if lifetime > 0 {
+		db.SetConnMaxLifetime(lifetime)
+	}
+}
+
 func getenv(key, def string) string {
 	val := strings.TrimSpace(os.Getenv(key))
 	if val == "" {
@@ -0,0 +1,144 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+)
+
+func TestInitFromEnvMemoryTransport(t *testing.T) {
+	inserted := make(chan string, 1)
+	driverName := fmt.Sprintf("audittrail_stub_%s_%d", t.Name(), time.Now().UnixNano())
+	sql.Register(driverName, &stubDriver{execFn: func(_ string, args []driver.NamedValue) (driver.Result, error) {
+		inserted <- stringArg(args, 0)
+		return stubResult{}, nil
+	}})
+	t.Setenv(envTransport, TransportMemory)
+	t.Setenv(envDBDriver, driverName)
+
+	ctx := context.Background()
+	if err := InitFromEnv(ctx, func(o *InitOptions) { o.DisableInstanceFields = true }); err != nil {
+		t.Fatalf("InitFromEnv: %v", err)
+	}
+	defer func() { _ = Shutdown(ctx) }()
+
+	if err := Record(ctx, Entry{ID: "local-1", Action: "LOGIN", CreatedBy: "dev"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	select {
+	case id := <-inserted:
+		if id != "local-1" {
+			t.Fatalf("unexpected entry %q", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("entry was not persisted through the memory transport")
+	}
+}
+
+// unreachableDriver fails every connection attempt, like a database that is down.
+type unreachableDriver struct{}
+
+func (unreachableDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("dial tcp 127.0.0.1:5432: connect: connection refused")
+}
+
+func TestInitFromEnvStartupPing(t *testing.T) {
+	driverName := fmt.Sprintf("audittrail_down_%d", time.Now().UnixNano())
+	sql.Register(driverName, unreachableDriver{})
+	t.Setenv(envTransport, TransportMemory)
+	t.Setenv(envDBDriver, driverName)
+	t.Setenv(envDBDSN, "postgres://audit:s3cret@db:5432/audit")
+
+	ctx := context.Background()
+	if err := InitFromEnv(ctx); err != nil {
+		t.Fatalf("expected lazy init without WithStartupPing, got %v", err)
+	}
+	_ = Shutdown(ctx)
+
+	err := InitFromEnv(ctx, WithStartupPing())
+	if err == nil {
+		_ = Shutdown(ctx)
+		t.Fatal("expected InitFromEnv to fail when the database is down")
+	}
+	if !strings.Contains(err.Error(), "connection refused") || !strings.Contains(err.Error(), envDBDSN) {
+		t.Fatalf("expected an actionable error, got %v", err)
+	}
+	if strings.Contains(err.Error(), "s3cret") {
+		t.Fatalf("error leaks the DSN password: %v", err)
+	}
+}
+
+func TestInitFromEnvEnsureTable(t *testing.T) {
+	var created bool
+	driverName := fmt.Sprintf("audittrail_stub_%s_%d", t.Name(), time.Now().UnixNano())
+	sql.Register(driverName, &stubDriver{
+		execFn: func(query string, _ []driver.NamedValue) (driver.Result, error) {
+			created = created || strings.Contains(query, "CREATE TABLE IF NOT EXISTS audit_trail")
+			return stubResult{}, nil
+		},
+		queryFn: func(string, []driver.NamedValue) (driver.Rows, error) {
+			return &stubRows{columns: []string{"log_audit_trail_id"}}, nil
+		},
+	})
+	t.Setenv(envTransport, TransportMemory)
+	t.Setenv(envDBDriver, driverName)
+
+	ctx := context.Background()
+	if err := InitFromEnv(ctx, WithEnsureTable(), WithStartupPing()); err != nil {
+		t.Fatalf("InitFromEnv: %v", err)
+	}
+	defer func() { _ = Shutdown(ctx) }()
+	if !created {
+		t.Fatal("expected the audit table to be created at init")
+	}
+}
+
+func TestCheckTopologyReportsMissingTopic(t *testing.T) {
+	srv := pstest.NewServer()
+	defer srv.Close()
+	t.Setenv(envPubSubEmulator, srv.Addr)
+
+	client, err := pubsub.NewClient(context.Background(), "local-project")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer client.Close()
+	err = checkTopology(context.Background(), client, "local-project", "audit", "audit-sub", time.Second)
+	if err == nil || !strings.Contains(err.Error(), envEnsureTopology) {
+		t.Fatalf("expected a missing topic error pointing at %s, got %v", envEnsureTopology, err)
+	}
+}
+
+func TestOpenTransportUsesEmulator(t *testing.T) {
+	srv := pstest.NewServer()
+	defer srv.Close()
+	t.Setenv(envPubSubEmulator, srv.Addr)
+
+	ctx := context.Background()
+	pub, _, client, err := openTransport(ctx, TransportPubSub, "local-project", "audit", "audit-sub", &InitOptions{})
+	if err != nil {
+		t.Fatalf("openTransport: %v", err)
+	}
+	defer client.Close()
+	if ok, err := client.Subscription("audit-sub").Exists(ctx); err != nil || !ok {
+		t.Fatalf("expected the emulator subscription to be created: %v %v", ok, err)
+	}
+	if err := pub.Publish(ctx, Entry{ID: "e1", Action: "LOGIN"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if len(srv.Messages()) != 1 {
+		t.Fatalf("expected 1 message on the emulator, got %d", len(srv.Messages()))
+	}
+
+	if _, _, _, err := openTransport(ctx, "kafka", "p", "t", "s", &InitOptions{}); err == nil {
+		t.Fatal("expected error for unknown transport")
+	}
+}
@@ -0,0 +1,125 @@
+package audittrail
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+)
+
+// EventOption configures a RecordEvent call.
+type EventOption func(*eventConfig)
+
+type eventConfig struct {
+	entry    Entry
+	recorder Recorder
+}
+
+// WithEventActor sets Actor.
+func WithEventActor(id string) EventOption {
+	return func(c *eventConfig) { c.entry.Actor = id }
+}
+
+// WithEventEndpoint sets Endpoint, e.g. the entity the event is about ("orders/42").
+func WithEventEndpoint(endpoint string) EventOption {
+	return func(c *eventConfig) { c.entry.Endpoint = endpoint }
+}
+
+// WithEventEntity sets EntityType and EntityID, e.g. "order" and "42" (see History).
+func WithEventEntity(entityType, id string) EventOption {
+	return func(c *eventConfig) { c.entry.EntityType, c.entry.EntityID = entityType, id }
+}
+
+// WithEventRequestID sets RequestID.
+func WithEventRequestID(id string) EventOption {
+	return func(c *eventConfig) { c.entry.RequestID = id }
+}
+
+// WithEventSeverity sets Severity. Default: SeverityInfo.
+func WithEventSeverity(s Severity) EventOption {
+	return func(c *eventConfig) { c.entry.Severity = s }
+}
+
+// WithEventParent links the event to the entry that caused it (see DerivedFrom).
+func WithEventParent(parent *Entry) EventOption {
+	return func(c *eventConfig) {
+		if parent != nil {
+			c.entry = DerivedFrom(parent, c.entry)
+		}
+	}
+}
+
+// WithEventRecorder records the event with rec instead of the default recorder set up by
+// InitFromEnv.
+func WithEventRecorder(rec Recorder) EventOption {
+	return func(c *eventConfig) { c.recorder = rec }
+}
+
+// typeRedactions holds the redactions registered per payload type.
+var typeRedactions struct {
+	mu   sync.RWMutex
+	byTy map[reflect.Type]Redaction
+}
+
+// RegisterRedaction sets the redaction RecordEvent applies to payloads of type T, so the
+// rule lives next to the type rather than at every call site:
+//
+//	audittrail.RegisterRedaction[PaymentCaptured](audittrail.RedactFields("card_number"))
+//
+// Registering again replaces the previous redaction; nil removes it.
+func RegisterRedaction[T any](r Redaction) {
+	ty := reflect.TypeFor[T]()
+	typeRedactions.mu.Lock()
+	defer typeRedactions.mu.Unlock()
+	if r == nil {
+		delete(typeRedactions.byTy, ty)
+		return
+	}
+	if typeRedactions.byTy == nil {
+		typeRedactions.byTy = make(map[reflect.Type]Redaction)
+	}
+	typeRedactions.byTy[ty] = r
+}
+
+func redactionForType(ty reflect.Type) Redaction {
+	typeRedactions.mu.RLock()
+	defer typeRedactions.mu.RUnlock()
+	return typeRedactions.byTy[ty]
+}
+
+// RecordEvent records a domain event from the service layer: payload is marshaled to JSON
+// as the request payload, honoring audit:"omit" and audit:"mask" struct tags, the
+// redaction registered for T is applied, and the entry is recorded with the default
+// recorder (or WithEventRecorder):
+//
+//	err := audittrail.RecordEvent(ctx, "ORDER_REFUNDED", OrderRefunded{OrderID: id, Amount: amt},
+//		audittrail.WithEventActor(userID), audittrail.WithEventEndpoint("orders/"+id))
+//
+// A payload that cannot be marshaled fails with an error matching ErrMarshalFailed.
+func RecordEvent[T any](ctx context.Context, action string, payload T, opts ...EventOption) error {
+	cfg := eventConfig{entry: Entry{Action: action, Severity: SeverityInfo}}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+
+	tagged, err := applyAuditTags(payload)
+	if err != nil {
+		return marshalFailed("event payload", err)
+	}
+	data, err := json.Marshal(tagged)
+	if err != nil {
+		return marshalFailed("event payload", err)
+	}
+	var request any = json.RawMessage(data)
+	if redact := redactionForType(reflect.TypeFor[T]()); redact != nil {
+		request = redact(request)
+	}
+	cfg.entry.Request = request
+
+	if cfg.recorder != nil {
+		return cfg.recorder.Record(ctx, cfg.entry)
+	}
+	return Record(ctx, cfg.entry)
+}
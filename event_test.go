@@ -0,0 +1,50 @@
+package audittrail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type orderRefunded struct {
+	OrderID    string  `json:"order_id"`
+	Amount     float64 `json:"amount"`
+	CardNumber string  `json:"card_number"`
+}
+
+func TestRecordEvent(t *testing.T) {
+	var got Entry
+	rec := RecorderFunc(func(_ context.Context, e Entry) error {
+		got = e
+		return nil
+	})
+	RegisterRedaction[orderRefunded](RedactFields("card_number"))
+	t.Cleanup(func() { RegisterRedaction[orderRefunded](nil) })
+
+	parent := Entry{ID: "p1", Action: "ORDER_CANCELLED"}
+	err := RecordEvent(context.Background(), "ORDER_REFUNDED", orderRefunded{OrderID: "o1", Amount: 12.5, CardNumber: "4111"},
+		WithEventRecorder(rec), WithEventActor("u1"), WithEventEndpoint("orders/o1"), WithEventParent(&parent))
+	if err != nil {
+		t.Fatalf("RecordEvent: %v", err)
+	}
+	if got.Action != "ORDER_REFUNDED" || got.Actor != "u1" || got.Endpoint != "orders/o1" || got.ParentID != "p1" || got.Severity != SeverityInfo {
+		t.Fatalf("unexpected entry: %+v", got)
+	}
+	if string(got.Request.(json.RawMessage)) != `{"amount":12.5,"card_number":"[REDACTED]","order_id":"o1"}` {
+		t.Fatalf("unexpected payload: %s", got.Request)
+	}
+
+	// Redactions are per type: other payloads are recorded as marshaled.
+	if err := RecordEvent(context.Background(), "NOTE", map[string]string{"card_number": "x"}, WithEventRecorder(rec)); err != nil {
+		t.Fatalf("RecordEvent: %v", err)
+	}
+	if string(got.Request.(json.RawMessage)) != `{"card_number":"x"}` {
+		t.Fatalf("unexpected payload: %s", got.Request)
+	}
+
+	err = RecordEvent(context.Background(), "BAD", func() {}, WithEventRecorder(rec))
+	if !errors.Is(err, ErrMarshalFailed) {
+		t.Fatalf("expected ErrMarshalFailed, got %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package audittrail
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// newFakePubSub starts an in-process Pub/Sub server and returns a client connected to it.
+func newFakePubSub(t *testing.T) (*pstest.Server, *pubsub.Client) {
+	t.Helper()
+	srv := pstest.NewServer()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	conn, err := grpc.NewClient(srv.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.NewClient: %v", err)
+	}
+	client, err := pubsub.NewClient(context.Background(), "test-project", option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("pubsub.NewClient: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return srv, client
+}
+
+func TestGCPPublisherSetsOrderingKeyAndCodec(t *testing.T) {
+	ctx := context.Background()
+	srv, client := newFakePubSub(t)
+	topic, err := client.CreateTopic(ctx, "audit")
+	if err != nil {
+		t.Fatalf("CreateTopic: %v", err)
+	}
+	defer topic.Stop()
+
+	pub := NewGCPPublisher(topic, WithOrderingKey(nil), WithCodec(ProtobufCodec))
+	if err := pub.Publish(ctx, Entry{ID: "e1", Action: "login", CreatedBy: "u1"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if err := pub.Publish(ctx, Entry{ID: "e2", Action: "login", RequestID: "req-1", CreatedBy: "u1"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	msgs := srv.Messages()
+	if len(msgs) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(msgs))
+	}
+	keys := map[string]bool{msgs[0].OrderingKey: true, msgs[1].OrderingKey: true}
+	if !keys["u1"] || !keys["req-1"] {
+		t.Fatalf("unexpected ordering keys: %v", keys)
+	}
+	if msgs[0].Attributes[codecAttribute] != "protobuf" {
+		t.Fatalf("expected codec attribute, got %v", msgs[0].Attributes)
+	}
+	entry, err := ProtobufCodec.Unmarshal(msgs[0].Data)
+	if err != nil || entry.Action != "login" {
+		t.Fatalf("decode published entry: %+v %v", entry, err)
+	}
+}
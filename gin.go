@@ -1,13 +1,16 @@
 package audittrail
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"io"
-	"log"
+	"net"
+	"net/http"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -31,10 +34,17 @@ func GinMiddleware(opts ...GinMiddlewareOption) gin.HandlerFunc {
 			return
 		}
 
+		start := time.Now().UTC()
+		ctx, audit := withRequestAudit(c.Request.Context(), c.Request.Method, c.Request.URL.Path)
+		c.Request = c.Request.WithContext(ctx)
+
 		// 1. Capture request body (for POST/PUT/PATCH)
 		var requestBody any
+		var upload *uploadCapture
 		if shouldCaptureBody(c.Request.Method) && cfg.captureRequestBody {
-			requestBody = captureRequestPayload(c, cfg.maxBodySize)
+			if upload, c.Request.Body = captureUpload(c.Request, cfg.uploadPolicy); upload == nil {
+				requestBody = captureRequestPayload(c, cfg.maxBodySize)
+			}
 		}
 
 		// 2. Extract user ID dari context (set oleh auth middleware)
@@ -51,16 +61,26 @@ func GinMiddleware(opts ...GinMiddlewareOption) gin.HandlerFunc {
 		// 4. Wrap ResponseWriter jika capture response body diaktifkan
 		var responseWriter *responseBodyWriter
 		if cfg.captureResponseBody {
-			responseWriter = &responseBodyWriter{
-				ResponseWriter: c.Writer,
-				body:           &bytes.Buffer{},
-				maxSize:        cfg.maxBodySize,
-			}
+			responseWriter = responseWriterPool.Get().(*responseBodyWriter)
+			responseWriter.ResponseWriter = c.Writer
+			responseWriter.body = getBodyBuffer()
+			responseWriter.maxSize = cfg.maxBodySize
+			responseWriter.written = 0
+			responseWriter.captureOn = cfg.captureResponseOn
 			c.Writer = responseWriter
 		}
 
-		// 5. Process request
-		c.Next()
+		// 5. Process request; a panic is recorded, then re-raised for the recovery middleware
+		recovered, report := callRecovering(c.Next)
+		end := time.Now().UTC()
+		audit.setRoute(c.FullPath())
+		status := c.Writer.Status()
+		if report != nil {
+			status = http.StatusInternalServerError
+		}
+		if upload != nil {
+			requestBody = upload.finish()
+		}
 
 		// 6. Get custom action name (optional)
 		action := ""
@@ -68,10 +88,37 @@ func GinMiddleware(opts ...GinMiddlewareOption) gin.HandlerFunc {
 			action = a.(string)
 		}
 
-		// 7. Capture response body jika diaktifkan
+		// Severity: per-request override, then per-route override, then default from method/status
+		var severity Severity
+		if s, exists := c.Get("audit_severity"); exists {
+			switch v := s.(type) {
+			case Severity:
+				severity = v
+			case string:
+				severity = Severity(v)
+			}
+		}
+		if severity == "" {
+			severity, _ = matchRouteSeverity(cfg.routeSeverity, c.Request.Method, c.FullPath())
+		}
+
+		// 7. Capture response body jika diaktifkan. The entry outlives the request, so the
+		// bytes are copied out before the pooled buffer and writer are reused.
 		var responseBody any
 		if cfg.captureResponseBody && responseWriter != nil {
-			responseBody = parseResponseBody(responseWriter.body.Bytes())
+			if responseWriter.stream.streaming(c.Writer.Header()) {
+				// Server-sent events and websockets are summarized, not captured.
+				responseBody = responseWriter.stream.summary(c.Request, c.Writer.Header())
+			} else if cfg.captureResponseOn == nil || cfg.captureResponseOn(status) {
+				responseBody = rawPayload(bytes.Clone(responseWriter.body.Bytes()))
+			}
+			c.Writer = responseWriter.ResponseWriter
+			putBodyBuffer(responseWriter.body)
+			*responseWriter = responseBodyWriter{}
+			responseWriterPool.Put(responseWriter)
+		}
+		if report != nil {
+			responseBody = *report
 		}
 
 		// 8. Build entry using framework-agnostic helper
@@ -82,7 +129,7 @@ func GinMiddleware(opts ...GinMiddlewareOption) gin.HandlerFunc {
 				Body:   requestBody,
 			},
 			HTTPResponse{
-				StatusCode: c.Writer.Status(),
+				StatusCode: status,
 				Body:       responseBody,
 			},
 			RequestContext{
@@ -90,17 +137,24 @@ func GinMiddleware(opts ...GinMiddlewareOption) gin.HandlerFunc {
 				RequestID:   requestID,
 				Action:      action,
 				ServiceName: cfg.serviceName,
+				IPAddress:   c.ClientIP(),
+				Severity:    severity,
+				SessionID:   ginSessionID(c, cfg),
+				StartedAt:   start,
+				EndedAt:     end,
+
+				ImpersonatedBy: cfg.extractImpersonator(c),
 			},
 		)
 
-		// 9. Record async (non-blocking)
-		go func() {
-			if err := Record(c.Request.Context(), entry); err != nil {
-				if cfg.onError != nil {
-					cfg.onError(err)
-				}
-			}
-		}()
+		redactionFor(cfg.redaction, cfg.routeRedaction, c.Request.Method, c.FullPath()).apply(&entry)
+
+		// 9. Record async (non-blocking) on the shared worker pool, together with the entries
+		// the handler added through FromContext
+		recordAsync(c.Request.Context(), cfg.recorder, audit.flush(c.Request.Context(), entry), cfg.recordTimeout, cfg.onError)
+		if recovered != nil {
+			panic(recovered)
+		}
 	}
 }
 
@@ -113,11 +167,11 @@ func AutoGinMiddleware(opts ...GinMiddlewareOption) gin.HandlerFunc {
 
 		ctx := context.Background()
 		if err := InitFromEnv(ctx); err != nil {
-			log.Printf("audittrail: auto-init failed: %v", err)
+			logger().Error("audittrail: auto-init failed", "error", err)
 			return
 		}
 
-		log.Println("audittrail: auto-initialized for Gin")
+		logger().Info("audittrail: auto-initialized for Gin")
 	})
 
 	return GinMiddleware(opts...)
@@ -129,11 +183,21 @@ type GinMiddlewareOption func(*ginMiddlewareConfig)
 type ginMiddlewareConfig struct {
 	captureRequestBody  bool
 	captureResponseBody bool
+	captureResponseOn   func(status int) bool
 	maxBodySize         int64
 	extractUser         func(*gin.Context) string
+	extractImpersonator func(*gin.Context) string
 	serviceName         string
 	shouldSkip          func(*gin.Context) bool
 	onError             func(error)
+	routeSeverity       []routeSeverity
+	sessionKey          string
+	sessionCookie       string
+	redaction           payloadRedaction
+	routeRedaction      []routeRedaction
+	uploadPolicy        UploadPolicy
+	recorder            Recorder // nil for the global Record
+	recordTimeout       time.Duration
 }
 
 func defaultGinConfig() ginMiddlewareConfig {
@@ -151,13 +215,21 @@ func defaultGinConfig() ginMiddlewareConfig {
 			// Priority 2: dari header
 			return c.GetHeader("X-User-Id")
 		},
-		serviceName: "unknown",
+		extractImpersonator: func(c *gin.Context) string {
+			if id, ok := c.Value("impersonated_by").(string); ok && id != "" {
+				return id
+			}
+			return c.GetHeader("X-Impersonated-By")
+		},
+		serviceName:   "",
+		recordTimeout: defaultAsyncTimeout,
+		sessionKey:    "session_id",
 		shouldSkip: func(c *gin.Context) bool {
 			// Default: skip health check
 			return c.Request.URL.Path == "/health"
 		},
 		onError: func(err error) {
-			log.Printf("audittrail: %v", err)
+			logger().Error("audittrail: record failed", "error", err)
 		},
 	}
 }
@@ -176,6 +248,22 @@ func WithCaptureResponseBody(capture bool) GinMiddlewareOption {
 	}
 }
 
+// WithCaptureResponseOn captures response bodies, but records them only when fn returns
+// true for the status code, e.g. to keep failed and denied actions for debugging while
+// leaving success responses out of the database:
+//
+//	audittrail.WithCaptureResponseOn(func(status int) bool { return status >= 400 })
+//
+// Bodies of other responses are not buffered at all.
+func WithCaptureResponseOn(fn func(status int) bool) GinMiddlewareOption {
+	return func(c *ginMiddlewareConfig) {
+		if fn != nil {
+			c.captureResponseBody = true
+			c.captureResponseOn = fn
+		}
+	}
+}
+
 // WithMaxBodySize sets max request body size to capture (in bytes)
 func WithMaxBodySize(size int64) GinMiddlewareOption {
 	return func(c *ginMiddlewareConfig) {
@@ -192,13 +280,27 @@ func WithUserExtractor(fn func(*gin.Context) string) GinMiddlewareOption {
 	}
 }
 
-// WithServiceName sets service name for CreatedBy field
+// WithServiceName sets the service recorded in ServiceName; the user goes to Actor.
 func WithServiceName(name string) GinMiddlewareOption {
 	return func(c *ginMiddlewareConfig) {
 		c.serviceName = name
 	}
 }
 
+// WithGinSessionKey sets the Gin context key holding the session ID. Default: session_id
+func WithGinSessionKey(key string) GinMiddlewareOption {
+	return func(c *ginMiddlewareConfig) {
+		c.sessionKey = key
+	}
+}
+
+// WithGinSessionCookie reads the session ID from the named cookie when the context key is not set
+func WithGinSessionCookie(name string) GinMiddlewareOption {
+	return func(c *ginMiddlewareConfig) {
+		c.sessionCookie = name
+	}
+}
+
 // WithSkipPaths sets paths to skip from audit
 func WithSkipPaths(paths ...string) GinMiddlewareOption {
 	pathMap := make(map[string]bool)
@@ -230,47 +332,98 @@ func WithGinErrorHandler(fn func(error)) GinMiddlewareOption {
 	}
 }
 
+// WithRecordTimeout bounds recording an entry (default 10s; 0 disables the deadline).
+// Entries are recorded after the handler returns, detached from the request's context,
+// whose cancellation would otherwise drop them; its values (trace, tenant) are kept.
+func WithRecordTimeout(d time.Duration) GinMiddlewareOption {
+	return func(c *ginMiddlewareConfig) {
+		c.recordTimeout = max(d, 0)
+	}
+}
+
 // Helper functions
 
+func ginSessionID(c *gin.Context, cfg ginMiddlewareConfig) string {
+	if cfg.sessionKey != "" {
+		if v, exists := c.Get(cfg.sessionKey); exists {
+			if id, ok := v.(string); ok {
+				return id
+			}
+		}
+	}
+	if cfg.sessionCookie != "" {
+		if id, err := c.Cookie(cfg.sessionCookie); err == nil {
+			return id
+		}
+	}
+	return ""
+}
+
 func shouldCaptureBody(method string) bool {
 	return method == "POST" || method == "PUT" || method == "PATCH"
 }
 
+// maxPooledBuffer is the largest body buffer returned to bodyBufferPool, so one huge
+// request does not pin memory.
+const maxPooledBuffer = 64 * 1024
+
+var (
+	bodyBufferPool     = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+	responseWriterPool = sync.Pool{New: func() any { return new(responseBodyWriter) }}
+)
+
+func getBodyBuffer() *bytes.Buffer {
+	buf := bodyBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBodyBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledBuffer {
+		bodyBufferPool.Put(buf)
+	}
+}
+
 func captureRequestPayload(c *gin.Context, maxSize int64) any {
-	if c.Request.Body == nil {
-		return nil
+	payload, body := captureBody(c.Request.Body, maxSize)
+	c.Request.Body = body
+	return payload
+}
+
+// captureBody reads up to maxSize bytes of body as a payload (see rawPayload) and returns
+// a replacement body so the handler can still read it.
+func captureBody(body io.ReadCloser, maxSize int64) (any, io.ReadCloser) {
+	if body == nil {
+		return nil, nil
 	}
 
-	bodyBytes, err := io.ReadAll(io.LimitReader(c.Request.Body, maxSize))
+	buf := getBodyBuffer()
+	_, err := buf.ReadFrom(io.LimitReader(body, maxSize))
+	bodyBytes := bytes.Clone(buf.Bytes()) // exact-size copy shared by the handler and the entry
+	putBodyBuffer(buf)
 	if err != nil {
-		return nil
+		return nil, io.NopCloser(bytes.NewReader(bodyBytes))
 	}
 
 	// Restore body so handler can read it
-	c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-
-	// Try parse as JSON
-	var payload any
-	if err := json.Unmarshal(bodyBytes, &payload); err != nil {
-		// If not JSON, return as string
-		return string(bodyBytes)
-	}
-
-	return payload
+	return rawPayload(bodyBytes), io.NopCloser(bytes.NewReader(bodyBytes))
 }
 
 // responseBodyWriter wraps gin.ResponseWriter to capture response body
 type responseBodyWriter struct {
 	gin.ResponseWriter
-	body    *bytes.Buffer
-	maxSize int64
-	written int64
+	body      *bytes.Buffer
+	maxSize   int64
+	written   int64
+	captureOn func(status int) bool // nil captures every response
+	stream    streamState
 }
 
 // Write captures the response body while writing to the original writer
 func (w *responseBodyWriter) Write(b []byte) (int, error) {
-	// Capture body up to maxSize
-	if w.written < w.maxSize {
+	// Capture body up to maxSize; the status is final once the body is being written.
+	// Streamed responses are never buffered.
+	if w.written < w.maxSize && !w.stream.streaming(w.Header()) && (w.captureOn == nil || w.captureOn(w.Status())) {
 		remaining := w.maxSize - w.written
 		toWrite := int64(len(b))
 		if toWrite > remaining {
@@ -281,21 +434,45 @@ func (w *responseBodyWriter) Write(b []byte) (int, error) {
 	}
 
 	// Always write to the original writer
-	return w.ResponseWriter.Write(b)
+	n, err := w.ResponseWriter.Write(b)
+	w.stream.written += int64(n)
+	return n, err
+}
+
+// WriteString captures like Write; the embedded writer's WriteString would bypass it.
+func (w *responseBodyWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// ReadFrom copies src through Write so the body is captured.
+func (w *responseBodyWriter) ReadFrom(src io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{w}, src)
+}
+
+// Flush passes through and marks the response as streamed, which stops capture.
+func (w *responseBodyWriter) Flush() {
+	w.stream.flushes++
+	w.ResponseWriter.Flush()
 }
 
-// parseResponseBody attempts to parse response bytes as JSON, falls back to string
-func parseResponseBody(data []byte) any {
+// Hijack passes through and stops capture; the connection no longer belongs to HTTP.
+func (w *responseBodyWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.ResponseWriter.Hijack()
+	if err == nil {
+		w.stream.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// rawPayload keeps a captured body as raw JSON (json.RawMessage) without decoding
+// it; it is only parsed if something downstream needs the structure. Non-JSON bodies are
+// kept as a string.
+func rawPayload(data []byte) any {
 	if len(data) == 0 {
 		return nil
 	}
-
-	// Try parse as JSON
-	var payload any
-	if err := json.Unmarshal(data, &payload); err != nil {
-		// If not JSON, return as string
-		return string(data)
+	if jsonValid(data) {
+		return json.RawMessage(data)
 	}
-
-	return payload
+	return string(data)
 }
@@ -0,0 +1,172 @@
+package audittrail
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// useGlobalRecorder installs rec as the recorder behind the global Record for a test.
+func useGlobalRecorder(t testing.TB, rec Recorder) {
+	runtime.mu.Lock()
+	prev := runtime.recorder
+	runtime.recorder = rec
+	runtime.mu.Unlock()
+	t.Cleanup(func() {
+		runtime.mu.Lock()
+		runtime.recorder = prev
+		runtime.mu.Unlock()
+	})
+}
+
+func TestGinMiddlewareCapturesRawPayloads(t *testing.T) {
+	entries := make(chan Entry, 1)
+	useGlobalRecorder(t, RecorderFunc(func(ctx context.Context, e Entry) error {
+		if ctx.Err() != nil {
+			t.Errorf("record context canceled: %v", ctx.Err())
+		}
+		entries <- e
+		return nil
+	}))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinMiddleware(WithCaptureResponseBody(true)))
+	r.POST("/orders", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.Data(http.StatusCreated, "application/json", append([]byte(`{"echo":`), append(body, '}')...))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"id":"order-789"}`))
+	ctx, cancel := context.WithCancel(req.Context())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req.WithContext(ctx))
+	cancel()
+
+	e := <-entries
+	if w.Code != http.StatusCreated || w.Body.String() != `{"echo":{"id":"order-789"}}` {
+		t.Fatalf("handler saw wrong body: %d %s", w.Code, w.Body.String())
+	}
+	reqRaw, ok := e.Request.(json.RawMessage)
+	if !ok || string(reqRaw) != `{"id":"order-789"}` {
+		t.Fatalf("unexpected request payload: %#v", e.Request)
+	}
+	if resp, ok := e.Response.(json.RawMessage); !ok || string(resp) != `{"echo":{"id":"order-789"}}` {
+		t.Fatalf("unexpected response payload: %#v", e.Response)
+	}
+	if rawPayload([]byte("plain text")) != "plain text" || rawPayload(nil) != nil {
+		t.Fatal("non-JSON bodies should be kept as text")
+	}
+}
+
+func BenchmarkGinMiddleware(b *testing.B) {
+	useGlobalRecorder(b, RecorderFunc(func(context.Context, Entry) error { return nil }))
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(GinMiddleware(WithCaptureResponseBody(true)))
+	r.POST("/orders", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", []byte(`{"ok":true}`))
+	})
+	body := `{"id":"order-789","items":[{"sku":"A","qty":2}]}`
+
+	b.ReportAllocs()
+	for b.Loop() {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
+func TestGinMiddlewareCaptureResponseOn(t *testing.T) {
+	entries := make(chan Entry, 1)
+	useGlobalRecorder(t, RecorderFunc(func(_ context.Context, e Entry) error {
+		entries <- e
+		return nil
+	}))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinMiddleware(WithCaptureResponseOn(func(status int) bool { return status >= 400 })))
+	r.DELETE("/orders/:id", func(c *gin.Context) {
+		if c.Param("id") == "locked" {
+			c.Data(http.StatusForbidden, "application/json", []byte(`{"error":"order is locked"}`))
+			return
+		}
+		c.Data(http.StatusOK, "application/json", []byte(`{"deleted":true}`))
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/orders/1", nil))
+	if e := <-entries; e.Response != nil {
+		t.Fatalf("success response should not be stored: %s", e.Response)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/orders/locked", nil))
+	e := <-entries
+	if resp, ok := e.Response.(json.RawMessage); !ok || string(resp) != `{"error":"order is locked"}` || w.Body.String() != string(resp) {
+		t.Fatalf("unexpected error response payload: %#v", e.Response)
+	}
+}
+
+func TestGinMiddlewareRecordTimeout(t *testing.T) {
+	deadlines := make(chan time.Duration, 1)
+	useGlobalRecorder(t, RecorderFunc(func(ctx context.Context, e Entry) error {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			deadlines <- 0
+			return nil
+		}
+		deadlines <- time.Until(deadline)
+		return nil
+	}))
+	gin.SetMode(gin.TestMode)
+
+	serve := func(opts ...GinMiddlewareOption) time.Duration {
+		r := gin.New()
+		r.Use(GinMiddleware(opts...))
+		r.GET("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		ctx, cancel := context.WithTimeout(req.Context(), time.Millisecond)
+		defer cancel()
+		r.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+		return <-deadlines
+	}
+	if d := serve(); d <= time.Second || d > defaultAsyncTimeout {
+		t.Fatalf("expected the default record timeout instead of the request deadline, got %v", d)
+	}
+	if d := serve(WithRecordTimeout(time.Minute)); d <= defaultAsyncTimeout {
+		t.Fatalf("expected a 1m record timeout, got %v", d)
+	}
+	if d := serve(WithRecordTimeout(0)); d != 0 {
+		t.Fatalf("expected no deadline, got %v", d)
+	}
+}
+
+func TestGinMiddlewareRecordsPanics(t *testing.T) {
+	entries := make(chan Entry, 1)
+	useGlobalRecorder(t, RecorderFunc(func(_ context.Context, e Entry) error {
+		entries <- e
+		return nil
+	}))
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, _ any) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}), GinMiddleware())
+	r.GET("/orders", func(*gin.Context) { panic("boom") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected gin.Recovery to handle the re-raised panic, got %d", w.Code)
+	}
+	e := <-entries
+	if report, ok := e.Response.(PanicReport); !ok || report.Outcome != OutcomePanic || report.Panic != "boom" {
+		t.Fatalf("expected a panic report, got %#v", e.Response)
+	}
+}
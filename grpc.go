@@ -0,0 +1,249 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// collectorRecordMethod is the full gRPC method name of AuditCollector.Record.
+const collectorRecordMethod = "/audittrail.v1.AuditCollector/Record"
+
+// Message descriptors for collector.proto, built at init so no generated code is needed.
+// The messages travel through gRPC's standard proto codec, so clients generated by protoc
+// in other languages interoperate with CollectorServer.
+var (
+	pbEntryDesc          protoreflect.MessageDescriptor
+	pbRecordRequestDesc  protoreflect.MessageDescriptor
+	pbRecordResponseDesc protoreflect.MessageDescriptor
+)
+
+func init() {
+	field := func(name string, num int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(num),
+			Type:     typ.Enum(),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	entries := field("entries", 1, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".audittrail.v1.Entry")
+	entries.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("audittrail/v1/collector.proto"),
+		Package:    proto.String("audittrail.v1"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/timestamp.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Entry"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("schema_version", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+					field("id", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("request_id", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("action", 4, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("endpoint", 5, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("request_json", 6, descriptorpb.FieldDescriptorProto_TYPE_BYTES, ""),
+					field("response_json", 7, descriptorpb.FieldDescriptorProto_TYPE_BYTES, ""),
+					field("created_date", 8, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Timestamp"),
+					field("created_by", 9, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("expires_at", 10, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Timestamp"),
+					field("severity", 11, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("parent_id", 12, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("correlation_id", 13, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("session_id", 14, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("app_version", 15, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("hostname", 16, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("instance_id", 17, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("impersonated_by", 18, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("actor", 19, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("ip_address", 20, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("service_name", 21, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("started_at", 22, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Timestamp"),
+					field("ended_at", 23, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Timestamp"),
+					field("duration_ms", 24, descriptorpb.FieldDescriptorProto_TYPE_INT64, ""),
+					field("entity_type", 25, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("entity_id", 26, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("meta_json", 27, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+				},
+			},
+			{Name: proto.String("RecordRequest"), Field: []*descriptorpb.FieldDescriptorProto{entries}},
+			{
+				Name:  proto.String("RecordResponse"),
+				Field: []*descriptorpb.FieldDescriptorProto{field("accepted", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32, "")},
+			},
+		},
+	}
+
+	var deps protoregistry.Files
+	if err := deps.RegisterFile(timestamppb.File_google_protobuf_timestamp_proto); err != nil {
+		panic(err)
+	}
+	fd, err := protodesc.NewFile(file, &deps)
+	if err != nil {
+		panic(fmt.Sprintf("audittrail: invalid collector descriptor: %v", err))
+	}
+	pbEntryDesc = fd.Messages().ByName("Entry")
+	pbRecordRequestDesc = fd.Messages().ByName("RecordRequest")
+	pbRecordResponseDesc = fd.Messages().ByName("RecordResponse")
+}
+
+// CollectorServer is a gRPC "audit collector": producer services send entries with a
+// GRPCRecorder and only the collector holds database credentials.
+type CollectorServer struct {
+	recorder Recorder
+}
+
+// NewCollectorServer creates a collector that stores entries in rec, typically an *AuditTrail
+// or a PubSubRecorder.
+func NewCollectorServer(rec Recorder) (*CollectorServer, error) {
+	if rec == nil {
+		return nil, errors.New("audittrail: collector recorder must not be nil")
+	}
+	return &CollectorServer{recorder: rec}, nil
+}
+
+// Register adds the AuditCollector service to s. Authentication and TLS are configured on
+// the grpc.Server (credentials, interceptors) as for any other service.
+func (c *CollectorServer) Register(s grpc.ServiceRegistrar) {
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "audittrail.v1.AuditCollector",
+		HandlerType: (*collectorService)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Record",
+			Handler:    collectorRecordHandler,
+		}},
+		Metadata: "collector.proto",
+	}, c)
+}
+
+type collectorService interface {
+	record(ctx context.Context, req *dynamicpb.Message) (*dynamicpb.Message, error)
+}
+
+func collectorRecordHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	in := dynamicpb.NewMessage(pbRecordRequestDesc)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(collectorService).record(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: collectorRecordMethod}
+	return interceptor(ctx, in, info, func(ctx context.Context, req any) (any, error) {
+		return srv.(collectorService).record(ctx, req.(*dynamicpb.Message))
+	})
+}
+
+func (c *CollectorServer) record(ctx context.Context, req *dynamicpb.Message) (*dynamicpb.Message, error) {
+	list := req.Get(pbRecordRequestDesc.Fields().ByName("entries")).List()
+	entries := make([]Entry, list.Len())
+	for i := range entries {
+		data, err := proto.Marshal(list.Get(i).Message().Interface())
+		if err == nil {
+			entries[i], err = ProtobufCodec.Unmarshal(data)
+		}
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "entry %d: %v", i, err)
+		}
+	}
+	if err := validateBatch(entries); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	accepted, err := c.store(ctx, entries)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	resp := dynamicpb.NewMessage(pbRecordResponseDesc)
+	resp.Set(pbRecordResponseDesc.Fields().ByName("accepted"), protoreflect.ValueOfInt32(int32(accepted)))
+	return resp, nil
+}
+
+// validateBatch rejects a batch containing an entry without an action before anything is stored.
+func validateBatch(entries []Entry) error {
+	for i, entry := range entries {
+		if strings.TrimSpace(entry.Action) == "" {
+			return fmt.Errorf("entry %d: field Action is required", i)
+		}
+	}
+	return nil
+}
+
+// store records entries in order, stopping at the first failure. An *AuditTrail skips IDs it
+// already has, so clients can safely retry a partially stored batch.
+func (c *CollectorServer) store(ctx context.Context, entries []Entry) (int, error) {
+	for i, entry := range entries {
+		var err error
+		if audit, ok := c.recorder.(*AuditTrail); ok {
+			err = audit.recordOnce(ctx, entry)
+		} else {
+			err = c.recorder.Record(ctx, entry)
+		}
+		if err != nil {
+			return i, fmt.Errorf("record entry %s: %w", entry.ID, err)
+		}
+	}
+	return len(entries), nil
+}
+
+// GRPCRecorder forwards entries to a CollectorServer. Entries are normalized (ID and
+// CreatedDate assigned) on the client, so retrying a failed call does not duplicate rows.
+type GRPCRecorder struct {
+	conn grpc.ClientConnInterface
+	now  func() time.Time
+}
+
+// NewGRPCRecorder creates a recorder that calls the collector over conn.
+func NewGRPCRecorder(conn grpc.ClientConnInterface) (*GRPCRecorder, error) {
+	if conn == nil {
+		return nil, errors.New("audittrail: gRPC connection must not be nil")
+	}
+	return &GRPCRecorder{conn: conn, now: time.Now}, nil
+}
+
+// Record sends a single entry to the collector.
+func (g *GRPCRecorder) Record(ctx context.Context, entry Entry) error {
+	return g.send(ctx, []Entry{entry})
+}
+
+func (g *GRPCRecorder) send(ctx context.Context, entries []Entry) error {
+	req := dynamicpb.NewMessage(pbRecordRequestDesc)
+	list := req.Mutable(pbRecordRequestDesc.Fields().ByName("entries")).List()
+	for _, entry := range entries {
+		normalized, err := normalizeEntry(entry, g.now)
+		if err != nil {
+			return err
+		}
+		data, err := ProtobufCodec.Marshal(normalized)
+		if err != nil {
+			return err
+		}
+		msg := dynamicpb.NewMessage(pbEntryDesc)
+		if err := proto.Unmarshal(data, msg); err != nil {
+			return err
+		}
+		list.Append(protoreflect.ValueOfMessage(msg))
+	}
+	resp := dynamicpb.NewMessage(pbRecordResponseDesc)
+	return g.conn.Invoke(ctx, collectorRecordMethod, req, resp)
+}
@@ -0,0 +1,156 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ActionHeartbeat is recorded periodically by RunHeartbeat.
+const ActionHeartbeat = "AUDIT_HEARTBEAT"
+
+// defaultHeartbeatInterval is the time between heartbeats when none is configured.
+const defaultHeartbeatInterval = time.Minute
+
+// HeartbeatConfig configures RunHeartbeat.
+type HeartbeatConfig struct {
+	Interval    time.Duration // time between heartbeats; default 1m
+	ServiceName string        // stored as ServiceName; the recorder set up by Init fills it otherwise
+}
+
+// RunHeartbeat records an ActionHeartbeat entry through rec every Interval until ctx is
+// canceled, so HeartbeatGaps can tell a service that had nothing to audit from one whose
+// audit pipeline was down. The interval is stored in Meta as interval_ms. Failures are
+// logged and retried on the next tick.
+func RunHeartbeat(ctx context.Context, rec Recorder, cfg HeartbeatConfig) error {
+	if rec == nil {
+		return errors.New("audittrail: heartbeat recorder must not be nil")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultHeartbeatInterval
+	}
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	for {
+		entry := Entry{
+			Action:      ActionHeartbeat,
+			Severity:    SeverityInfo,
+			ServiceName: cfg.ServiceName,
+			Meta:        map[string]any{"interval_ms": cfg.Interval.Milliseconds()},
+		}
+		if err := rec.Record(ctx, entry); err != nil && ctx.Err() == nil {
+			logger().Warn("audittrail: heartbeat not recorded", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// startHeartbeat runs RunHeartbeat through the global Record when InitOptions asks for it,
+// until Shutdown. The caller holds runtime.mu.
+func startHeartbeat(ctx context.Context, opts *InitOptions) {
+	if opts.HeartbeatInterval <= 0 {
+		return
+	}
+	hbCtx, stop := context.WithCancel(context.WithoutCancel(ctx))
+	prev := runtime.cancel
+	runtime.cancel = func() {
+		stop()
+		if prev != nil {
+			prev()
+		}
+	}
+	runtime.wg.Add(1)
+	go func() {
+		defer runtime.wg.Done()
+		_ = RunHeartbeat(hbCtx, RecorderFunc(Record), HeartbeatConfig{Interval: opts.HeartbeatInterval})
+	}()
+}
+
+// GapQuery configures HeartbeatGaps.
+type GapQuery struct {
+	ServiceName string    // only this service; empty checks every service separately
+	From        time.Time // inclusive lower bound on log_created_date
+	To          time.Time // exclusive upper bound; default now
+	// MaxGap is the longest silence that is not a gap. Default: twice the interval stored
+	// in the service's heartbeats.
+	MaxGap time.Duration
+}
+
+// HeartbeatGap is a period in which a service recorded no heartbeat.
+type HeartbeatGap struct {
+	ServiceName string
+	From        time.Time // last heartbeat or lifecycle entry before the gap
+	To          time.Time // first one after it, or GapQuery.To when the service is still silent
+	// Planned is set when the gap starts with ActionPipelineStopped, i.e. the service was
+	// shut down rather than its audit pipeline failing.
+	Planned bool
+}
+
+// HeartbeatGaps returns the periods, oldest first, in which a service recorded neither a
+// heartbeat nor a pipeline lifecycle entry for longer than MaxGap. Entries are missing
+// from such a period even if the service was handling requests, unless it was Planned.
+func (r *AuditTrail) HeartbeatGaps(ctx context.Context, q GapQuery) ([]HeartbeatGap, error) {
+	if q.To.IsZero() {
+		q.To = r.now().UTC()
+	}
+	services := make(map[string]*heartbeatState)
+	var gaps []HeartbeatGap
+	f := Filter{
+		Actions:     []string{ActionHeartbeat, ActionPipelineStarted, ActionPipelineStopped},
+		ServiceName: q.ServiceName,
+		From:        q.From,
+		To:          q.To,
+	}
+	ctx = r.auditRead(ctx, "heartbeat_gaps", f, nil)
+	err := r.Iterate(ctx, f, func(e Entry) error {
+		s := services[e.ServiceName]
+		if s == nil {
+			s = &heartbeatState{maxGap: q.MaxGap}
+			services[e.ServiceName] = s
+		}
+		if s.maxGap <= 0 && e.Action == ActionHeartbeat {
+			if ms, ok := e.Meta["interval_ms"].(float64); ok && ms > 0 {
+				s.maxGap = 2 * time.Duration(ms) * time.Millisecond
+			}
+		}
+		if !s.last.IsZero() && e.CreatedDate.Sub(s.last) > s.gapLimit() {
+			gaps = append(gaps, HeartbeatGap{ServiceName: e.ServiceName, From: s.last, To: e.CreatedDate, Planned: s.stopped})
+		}
+		s.last, s.stopped = e.CreatedDate, e.Action == ActionPipelineStopped
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for name, s := range services {
+		if q.To.Sub(s.last) > s.gapLimit() {
+			gaps = append(gaps, HeartbeatGap{ServiceName: name, From: s.last, To: q.To, Planned: s.stopped})
+		}
+	}
+	sort.Slice(gaps, func(i, j int) bool {
+		if !gaps[i].From.Equal(gaps[j].From) {
+			return gaps[i].From.Before(gaps[j].From)
+		}
+		return gaps[i].ServiceName < gaps[j].ServiceName
+	})
+	return gaps, nil
+}
+
+// heartbeatState tracks the last sign of life of one service in HeartbeatGaps.
+type heartbeatState struct {
+	last    time.Time
+	stopped bool          // the last entry was ActionPipelineStopped
+	maxGap  time.Duration // zero until known from GapQuery or a heartbeat
+}
+
+func (s *heartbeatState) gapLimit() time.Duration {
+	if s.maxGap > 0 {
+		return s.maxGap
+	}
+	return 2 * defaultHeartbeatInterval
+}
@@ -0,0 +1,98 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunHeartbeat(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var got []Entry
+	rec := RecorderFunc(func(_ context.Context, e Entry) error {
+		got = append(got, e)
+		if len(got) == 2 {
+			cancel()
+		}
+		return nil
+	})
+	if err := RunHeartbeat(ctx, rec, HeartbeatConfig{Interval: time.Millisecond, ServiceName: "orders"}); err != nil {
+		t.Fatalf("RunHeartbeat: %v", err)
+	}
+	if len(got) != 2 || got[0].Action != ActionHeartbeat || got[0].ServiceName != "orders" || got[0].Meta["interval_ms"] != int64(1) {
+		t.Fatalf("unexpected heartbeats: %+v", got)
+	}
+}
+
+func TestHeartbeatGaps(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	columns := strings.Split(EntryColumns, ", ")
+	row := func(id, service, action string, at time.Duration) []driver.Value {
+		r := make([]driver.Value, len(columns))
+		r[0], r[2], r[6], r[19] = id, action, t0.Add(at), service
+		if action == ActionHeartbeat {
+			r[len(r)-1] = `{"interval_ms":60000}`
+		}
+		return r
+	}
+	var query string
+	db := openStubDB(t, &stubDriver{queryFn: func(q string, _ []driver.NamedValue) (driver.Rows, error) {
+		query = q
+		return &stubRows{columns: columns, values: [][]driver.Value{
+			row("b1", "billing", ActionHeartbeat, 0),
+			row("o1", "orders", ActionHeartbeat, 0),
+			row("o2", "orders", ActionHeartbeat, time.Minute),
+			row("o3", "orders", ActionHeartbeat, 10*time.Minute),
+			row("o4", "orders", ActionPipelineStopped, 11*time.Minute),
+			row("o5", "orders", ActionPipelineStarted, 20*time.Minute),
+			row("o6", "orders", ActionHeartbeat, 21*time.Minute),
+		}}, nil
+	}})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	gaps, err := audit.HeartbeatGaps(context.Background(), GapQuery{From: t0, To: t0.Add(22 * time.Minute)})
+	if err != nil {
+		t.Fatalf("HeartbeatGaps: %v", err)
+	}
+	want := []HeartbeatGap{
+		{ServiceName: "billing", From: t0, To: t0.Add(22 * time.Minute)},
+		{ServiceName: "orders", From: t0.Add(time.Minute), To: t0.Add(10 * time.Minute)},
+		{ServiceName: "orders", From: t0.Add(11 * time.Minute), To: t0.Add(20 * time.Minute), Planned: true},
+	}
+	if len(gaps) != len(want) {
+		t.Fatalf("expected %d gaps, got %+v", len(want), gaps)
+	}
+	for i := range want {
+		if gaps[i].ServiceName != want[i].ServiceName || !gaps[i].From.Equal(want[i].From) || !gaps[i].To.Equal(want[i].To) || gaps[i].Planned != want[i].Planned {
+			t.Fatalf("gap %d: got %+v, want %+v", i, gaps[i], want[i])
+		}
+	}
+	if !strings.Contains(query, "log_action IN ($1, $2, $3)") {
+		t.Fatalf("unexpected query: %s", query)
+	}
+}
+
+func TestRunHeartbeatStrictActions(t *testing.T) {
+	useStrictActions(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	var got []Entry
+	rec, err := NewPubSubRecorder(PublisherFunc(func(_ context.Context, e Entry) error {
+		got = append(got, e)
+		cancel()
+		return nil
+	}), nil)
+	if err != nil {
+		t.Fatalf("NewPubSubRecorder: %v", err)
+	}
+	if err := RunHeartbeat(ctx, rec, HeartbeatConfig{Interval: time.Hour}); err != nil {
+		t.Fatalf("RunHeartbeat: %v", err)
+	}
+	if len(got) != 1 || got[0].Action != ActionHeartbeat {
+		t.Fatalf("heartbeat not recorded in strict mode: %+v", got)
+	}
+}
@@ -1,6 +1,7 @@
 package audittrail
 
 import (
+	"context"
 	"time"
 )
 
@@ -21,10 +22,17 @@ type HTTPResponse struct {
 
 // RequestContext holds context data for audit entry
 type RequestContext struct {
-	UserID      string // User ID yang melakukan request (untuk CreatedBy)
-	RequestID   string // Request ID
-	Action      string // Custom action name (optional)
-	ServiceName string // Service name
+	UserID      string    // User ID yang melakukan request (untuk Actor)
+	RequestID   string    // Request ID
+	Action      string    // Custom action name (optional)
+	ServiceName string    // Service that records the entry
+	IPAddress   string    // Client IP
+	Severity    Severity  // Override severity; default DefaultSeverity(method, status)
+	SessionID   string    // Session the request belongs to
+	StartedAt   time.Time // When the request arrived; also used as CreatedDate
+	EndedAt     time.Time // When the handler returned
+	// ImpersonatedBy is the real actor when UserID is being impersonated
+	ImpersonatedBy string
 }
 
 // BuildEntry creates audit entry from HTTP context (framework agnostic)
@@ -34,6 +42,14 @@ func BuildEntry(req HTTPRequest, resp HTTPResponse, ctx RequestContext) Entry {
 	if action == "" {
 		action = req.Method + " " + req.Path
 	}
+	severity := ctx.Severity
+	if severity == "" {
+		severity = DefaultSeverity(req.Method, resp.StatusCode)
+	}
+	created := ctx.StartedAt
+	if created.IsZero() {
+		created = time.Now().UTC()
+	}
 
 	return Entry{
 		RequestID:   ctx.RequestID,
@@ -41,16 +57,21 @@ func BuildEntry(req HTTPRequest, resp HTTPResponse, ctx RequestContext) Entry {
 		Endpoint:    req.Path,
 		Request:     req.Body,
 		Response:    resp.Body,
-		CreatedDate: time.Now().UTC(),
-		CreatedBy:   ctx.UserID,
+		CreatedDate: created,
+		ServiceName: ctx.ServiceName,
+		Severity:    severity,
+		SessionID:   ctx.SessionID,
+		StartedAt:   ctx.StartedAt,
+		EndedAt:     ctx.EndedAt,
+		DurationMS:  durationMS(ctx.StartedAt, ctx.EndedAt),
+
+		ImpersonatedBy: ctx.ImpersonatedBy,
+		Actor:          ctx.UserID,
+		IPAddress:      ctx.IPAddress,
 	}
 }
 
 // RecordAsync records audit entry asynchronously (non-blocking)
 func RecordAsync(entry Entry) {
-	go func() {
-		if err := Record(nil, entry); err != nil {
-			// Error already logged by Record()
-		}
-	}()
+	recordAsync(context.Background(), nil, []Entry{entry}, defaultAsyncTimeout, nil)
 }
@@ -0,0 +1,53 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HistoryOptions narrows and pages a History call. Cursor and Limit work as in PageRequest.
+type HistoryOptions struct {
+	Cursor  string
+	Limit   int
+	Actions []string  // only these actions, e.g. the writes of the entity
+	From    time.Time // inclusive lower bound on log_created_date
+	To      time.Time // exclusive upper bound on log_created_date
+}
+
+// History returns one page of the entries recorded about an entity, oldest first, e.g. to
+// show the change history of an order. Run EnsureHistoryIndex once so the lookup does not
+// scan the table.
+func (r *AuditTrail) History(ctx context.Context, entityType, entityID string, opts HistoryOptions) (Page, error) {
+	if strings.TrimSpace(entityType) == "" || strings.TrimSpace(entityID) == "" {
+		return Page{}, errors.New("audittrail: entity type and ID must not be empty")
+	}
+	f := Filter{EntityType: entityType, EntityID: entityID, Actions: opts.Actions, From: opts.From, To: opts.To}
+	ctx = r.auditRead(ctx, "history", f, nil)
+	return r.Query(ctx, f, PageRequest{Cursor: opts.Cursor, Limit: opts.Limit})
+}
+
+// EnsureHistoryIndex creates the index History reads through, on (log_entity_type,
+// log_entity_id, log_created_date, log_audit_trail_id).
+func (r *AuditTrail) EnsureHistoryIndex(ctx context.Context) error {
+	if r == nil || r.db == nil {
+		return errors.New("audittrail: instance is not initialized")
+	}
+	columns := r.quoteList("log_entity_type, log_entity_id, log_created_date, log_audit_trail_id")
+	name := r.quote(r.table + "_entity_idx")
+	// MySQL has no IF NOT EXISTS for indexes; an existing one fails with "Duplicate key name".
+	ifNotExists := " IF NOT EXISTS"
+	if r.dialect == dialectMySQL {
+		ifNotExists = ""
+	}
+	query := fmt.Sprintf("CREATE INDEX%s %s ON %s (%s)", ifNotExists, name, r.tableRef, columns)
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		if r.dialect == dialectMySQL && strings.Contains(err.Error(), "Duplicate key name") {
+			return nil
+		}
+		return fmt.Errorf("audittrail: create history index failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	created := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	var queries []string
+	var lastArgs []driver.NamedValue
+	db := openStubDB(t, &stubDriver{queryFn: func(query string, args []driver.NamedValue) (driver.Rows, error) {
+		queries = append(queries, query)
+		lastArgs = args
+		row := make([]driver.Value, len(strings.Split(EntryColumns, ", ")))
+		row[0], row[2], row[6] = "e1", "ORDER_CREATED", created
+		row[len(row)-3], row[len(row)-2] = "order", "42"
+		return &stubRows{columns: strings.Split(EntryColumns, ", "), values: [][]driver.Value{row}}, nil
+	}})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	ctx := context.Background()
+	page, err := audit.History(ctx, "order", "42", HistoryOptions{Actions: []string{"ORDER_CREATED"}, Limit: 10})
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(page.Entries) != 1 || page.Entries[0].EntityType != "order" || page.Entries[0].EntityID != "42" || page.NextCursor != "" {
+		t.Fatalf("unexpected page: %+v", page)
+	}
+	if !strings.Contains(queries[0], "WHERE log_action IN ($1) AND log_entity_type = $2 AND log_entity_id = $3") ||
+		!strings.HasSuffix(queries[0], "ORDER BY log_created_date, log_audit_trail_id LIMIT 11") {
+		t.Fatalf("unexpected query: %s", queries[0])
+	}
+	if lastArgs[1].Value != "order" || lastArgs[2].Value != "42" {
+		t.Fatalf("unexpected args: %v", lastArgs)
+	}
+
+	if _, err := audit.History(ctx, "order", " ", HistoryOptions{}); err == nil {
+		t.Fatal("expected error without an entity ID")
+	}
+	if _, err := audit.History(ctx, "order", "42", HistoryOptions{Cursor: "!"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestEnsureHistoryIndex(t *testing.T) {
+	var calls []string
+	db := openStubDB(t, &stubDriver{execFn: func(query string, _ []driver.NamedValue) (driver.Result, error) {
+		calls = append(calls, query)
+		return stubResult{}, nil
+	}})
+	audit, _ := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err := audit.EnsureHistoryIndex(context.Background()); err != nil {
+		t.Fatalf("EnsureHistoryIndex: %v", err)
+	}
+	want := "CREATE INDEX IF NOT EXISTS audit_trail_entity_idx ON audit_trail (log_entity_type, log_entity_id, log_created_date, log_audit_trail_id)"
+	if len(calls) != 1 || calls[0] != want {
+		t.Fatalf("unexpected statements: %q", calls)
+	}
+
+	calls = nil
+	mysql := openDriverDB(t, &mysqlStubDriver{stubDriver{execFn: func(query string, _ []driver.NamedValue) (driver.Result, error) {
+		calls = append(calls, query)
+		return nil, errors.New("Error 1061: Duplicate key name 'audit_trail_entity_idx'")
+	}}})
+	audit, _ = NewAuditTrail(Config{DB: mysql})
+	if err := audit.EnsureHistoryIndex(context.Background()); err != nil {
+		t.Fatalf("expected an existing MySQL index to be accepted, got %v", err)
+	}
+	if !strings.HasPrefix(calls[0], "CREATE INDEX `audit_trail_entity_idx` ON `audit_trail` (`log_entity_type`, ") {
+		t.Fatalf("unexpected MySQL statement: %s", calls[0])
+	}
+}
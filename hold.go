@@ -0,0 +1,79 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Hold places matching entries under legal hold. Held entries are skipped by Purge and
+// AnonymizeActor until Release is called with a filter that matches them. A hold only
+// restricts deletion, so it is allowed on append-only tables too; the trigger installed by
+// InstallAppendOnlyTrigger lets through updates that change nothing but log_on_hold.
+// It returns the number of entries affected.
+func (r *AuditTrail) Hold(ctx context.Context, f Filter) (int64, error) {
+	return r.setHold(ctx, f, true)
+}
+
+// Release lifts the legal hold from matching entries.
+func (r *AuditTrail) Release(ctx context.Context, f Filter) (int64, error) {
+	return r.setHold(ctx, f, false)
+}
+
+func (r *AuditTrail) setHold(ctx context.Context, f Filter, hold bool) (int64, error) {
+	if r == nil || r.db == nil {
+		return 0, errors.New("audittrail: instance is not initialized")
+	}
+	b := &queryBuilder{placeholder: r.placeholder, dialect: r.dialect}
+	set := b.arg(hold)
+	query := fmt.Sprintf("UPDATE %s SET log_on_hold = %s%s", r.tableRef, set, b.where(f))
+	res, err := r.db.ExecContext(ctx, query, b.args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Purge deletes expired entries, except entries under legal hold. Entries with an expiry
+// (see RetentionRule) are deleted once it has passed, regardless of before; entries without
+// one are deleted when created before the given time.
+// It returns the number of entries deleted.
+func (r *AuditTrail) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if r == nil || r.db == nil {
+		return 0, errors.New("audittrail: instance is not initialized")
+	}
+	if before.IsZero() {
+		return 0, errors.New("audittrail: purge cutoff must be set")
+	}
+	b := &queryBuilder{placeholder: r.placeholder, dialect: r.dialect}
+	hold := b.arg(false)
+	cutoff := b.arg(before.UTC())
+	now := b.arg(r.now().UTC())
+	query := fmt.Sprintf(
+		"DELETE FROM %s WHERE log_on_hold = %s AND ((log_expires_at IS NULL AND log_created_date < %s) OR log_expires_at <= %s)",
+		r.tableRef, hold, cutoff, now,
+	)
+	res, err := r.execDelete(ctx, query, b.args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// PurgeExpired deletes entries whose expiry has passed, except entries under legal hold.
+// Entries without an expiry are kept.
+func (r *AuditTrail) PurgeExpired(ctx context.Context) (int64, error) {
+	if r == nil || r.db == nil {
+		return 0, errors.New("audittrail: instance is not initialized")
+	}
+	b := &queryBuilder{placeholder: r.placeholder, dialect: r.dialect}
+	hold := b.arg(false)
+	now := b.arg(r.now().UTC())
+	query := fmt.Sprintf("DELETE FROM %s WHERE log_on_hold = %s AND log_expires_at <= %s", r.tableRef, hold, now)
+	res, err := r.execDelete(ctx, query, b.args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
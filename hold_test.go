@@ -0,0 +1,75 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHoldAndPurgeSkipHeldEntries(t *testing.T) {
+	var calls []execCall
+	db := openStubDB(t, &stubDriver{
+		execFn: func(query string, args []driver.NamedValue) (driver.Result, error) {
+			calls = append(calls, execCall{query: query, args: args})
+			return stubResult{}, nil
+		},
+	})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderQuestion})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	if _, err := audit.Hold(context.Background(), Filter{Actor: "u1"}); err != nil {
+		t.Fatalf("Hold: %v", err)
+	}
+	cutoff := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := audit.Purge(context.Background(), cutoff); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(calls))
+	}
+	if calls[0].query != "UPDATE audit_trail SET log_on_hold = ? WHERE (log_actor = ? OR (log_actor IS NULL AND log_created_by = ?))" ||
+		calls[0].args[0].Value != true || calls[0].args[1].Value != "u1" {
+		t.Fatalf("unexpected hold query: %s %v", calls[0].query, calls[0].args)
+	}
+	if !strings.HasSuffix(calls[1].query, "WHERE log_on_hold = ? AND ((log_expires_at IS NULL AND log_created_date < ?) OR log_expires_at <= ?)") ||
+		calls[1].args[0].Value != false || calls[1].args[1].Value != cutoff {
+		t.Fatalf("unexpected purge query: %s %v", calls[1].query, calls[1].args)
+	}
+	if _, err := audit.Purge(context.Background(), time.Time{}); err == nil {
+		t.Fatal("expected error for zero purge cutoff")
+	}
+}
+
+func TestEnsureTableAddsMissingColumns(t *testing.T) {
+	var calls []string
+	db := openStubDB(t, &stubDriver{
+		execFn: func(query string, _ []driver.NamedValue) (driver.Result, error) {
+			calls = append(calls, query)
+			return stubResult{}, nil
+		},
+		queryFn: func(string, []driver.NamedValue) (driver.Rows, error) {
+			// A table created by an older release without log_on_hold.
+			return &stubRows{columns: []string{
+				"log_audit_trail_id", "log_req_id", "log_action", "log_endpoint",
+				"log_request", "log_response", "log_created_date", "log_created_by",
+			}}, nil
+		},
+	})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	if err := audit.EnsureTable(context.Background()); err != nil {
+		t.Fatalf("EnsureTable: %v", err)
+	}
+	if len(calls) != 21 || calls[1] != "ALTER TABLE audit_trail ADD COLUMN log_on_hold BOOLEAN NOT NULL DEFAULT FALSE" ||
+		calls[2] != "ALTER TABLE audit_trail ADD COLUMN log_expires_at TIMESTAMPTZ NULL" ||
+		calls[3] != "ALTER TABLE audit_trail ADD COLUMN log_severity VARCHAR(16) NULL" {
+		t.Fatalf("unexpected statements: %q", calls)
+	}
+}
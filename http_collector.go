@@ -0,0 +1,324 @@
+package audittrail
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// collectorEntriesPath is the HTTP route served by CollectorServer.HTTPHandler.
+const collectorEntriesPath = "/v1/entries"
+
+// maxCollectorBody bounds a decoded HTTP batch.
+const maxCollectorBody = 32 << 20
+
+// HTTPHandler serves the collector over plain HTTP at POST /v1/entries for producers that
+// use HTTPRecorder. The body is a JSON array of entries, optionally gzip-encoded. Requests
+// must present one of apiKeys as "Authorization: Bearer <key>" or "X-API-Key"; with no keys
+// configured every request is rejected.
+func (c *CollectorServer) HTTPHandler(apiKeys ...string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(collectorEntriesPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !validAPIKey(r, apiKeys) {
+			http.Error(w, "invalid API key", http.StatusUnauthorized)
+			return
+		}
+
+		body := io.Reader(r.Body)
+		if strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, "invalid gzip body", http.StatusBadRequest)
+				return
+			}
+			defer zr.Close()
+			body = zr
+		}
+		var raw []json.RawMessage
+		if err := json.NewDecoder(io.LimitReader(body, maxCollectorBody)).Decode(&raw); err != nil {
+			http.Error(w, "invalid JSON body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		entries := make([]Entry, len(raw))
+		for i, data := range raw {
+			entry, err := JSONCodec.Unmarshal(data)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("entry %d: %v", i, err), http.StatusBadRequest)
+				return
+			}
+			entries[i] = entry
+		}
+		if err := validateBatch(entries); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		accepted, err := c.store(r.Context(), entries)
+		if err != nil {
+			logger().Error("audittrail: collector failed to store batch", "accepted", accepted, "error", err)
+			http.Error(w, "failed to store entries", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]int{"accepted": accepted})
+	})
+	return mux
+}
+
+func validAPIKey(r *http.Request, keys []string) bool {
+	got := r.Header.Get("X-API-Key")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		got = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if got == "" {
+		return false
+	}
+	for _, key := range keys {
+		if key != "" && subtle.ConstantTimeCompare([]byte(got), []byte(key)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// HTTPRecorderConfig configures an HTTPRecorder.
+type HTTPRecorderConfig struct {
+	BaseURL       string        // collector base URL, e.g. https://audit.internal
+	APIKey        string        // sent as "Authorization: Bearer <key>"
+	BatchSize     int           // entries per request; default 100
+	FlushInterval time.Duration // used by Run; default 2s
+	MaxRetries    int           // retries per batch for network errors and 5xx; default 3
+	MaxPending    int           // entries kept while the collector is unreachable; default 10 * BatchSize
+	DisableGzip   bool
+	Client        *http.Client
+}
+
+// HTTPRecorder sends entries to a collector's HTTP endpoint, so producer services need no
+// broker or database dependencies. Entries are batched, gzip-compressed and retried with
+// exponential backoff; IDs are assigned client-side so retries are not stored twice.
+type HTTPRecorder struct {
+	cfg    HTTPRecorderConfig
+	url    string
+	client *http.Client
+
+	mu      sync.Mutex
+	pending []Entry
+	sending sync.Mutex // serializes batches so entries arrive in order
+}
+
+// NewHTTPRecorder creates a collector client. Call Run (or Flush periodically) so partial
+// batches are delivered, and Close on shutdown.
+func NewHTTPRecorder(cfg HTTPRecorderConfig) (*HTTPRecorder, error) {
+	if cfg.BaseURL == "" {
+		return nil, errors.New("audittrail: collector base URL must not be empty")
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 2 * time.Second
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.MaxPending <= 0 {
+		cfg.MaxPending = 10 * cfg.BatchSize
+	}
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &HTTPRecorder{
+		cfg:    cfg,
+		url:    strings.TrimRight(cfg.BaseURL, "/") + collectorEntriesPath,
+		client: client,
+	}, nil
+}
+
+// Record queues the entry and sends a batch once BatchSize entries are waiting.
+func (h *HTTPRecorder) Record(ctx context.Context, entry Entry) error {
+	normalized, err := normalizeEntry(entry, nil)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	h.pending = append(h.pending, normalized)
+	full := len(h.pending) >= h.cfg.BatchSize
+	h.mu.Unlock()
+	if full {
+		return h.Flush(ctx)
+	}
+	return nil
+}
+
+// Flush sends all queued entries in BatchSize chunks. Entries that could not be delivered
+// after retries stay queued, up to MaxPending; older entries beyond that are reported to
+// OnDrop hooks with ErrBufferFull. Batches the collector rejects (4xx) are reported to OnDrop
+// hooks and discarded.
+func (h *HTTPRecorder) Flush(ctx context.Context) error {
+	h.sending.Lock()
+	defer h.sending.Unlock()
+
+	for {
+		h.mu.Lock()
+		n := len(h.pending)
+		if n > h.cfg.BatchSize {
+			n = h.cfg.BatchSize
+		}
+		batch := append([]Entry(nil), h.pending[:n]...)
+		h.mu.Unlock()
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := h.sendWithRetry(ctx, batch); err != nil {
+			var permanent errPermanent
+			if errors.As(err, &permanent) {
+				// The collector rejected the batch; resending it would block the queue forever.
+				h.mu.Lock()
+				h.pending = h.pending[len(batch):]
+				h.mu.Unlock()
+				for _, entry := range batch {
+					reportDrop(entry, err)
+				}
+				return err
+			}
+			h.mu.Lock()
+			var dropped []Entry
+			if over := len(h.pending) - h.cfg.MaxPending; over > 0 {
+				dropped = append(dropped, h.pending[:over]...)
+				h.pending = h.pending[over:]
+			}
+			h.mu.Unlock()
+			for _, entry := range dropped {
+				reportDrop(entry, ErrBufferFull)
+			}
+			return err
+		}
+
+		h.mu.Lock()
+		h.pending = h.pending[len(batch):]
+		h.mu.Unlock()
+	}
+}
+
+// Pending reports how many entries are waiting to be sent.
+func (h *HTTPRecorder) Pending() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.pending)
+}
+
+// Run flushes every FlushInterval until ctx is canceled, then makes a final flush.
+func (h *HTTPRecorder) Run(ctx context.Context) error {
+	ticker := time.NewTicker(h.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			return h.Flush(flushCtx)
+		case <-ticker.C:
+			if err := h.Flush(ctx); err != nil {
+				logger().Warn("audittrail: collector flush failed", "error", err)
+			}
+		}
+	}
+}
+
+// Close sends any remaining entries.
+func (h *HTTPRecorder) Close(ctx context.Context) error {
+	return h.Flush(ctx)
+}
+
+// errPermanent marks collector responses that retrying cannot fix (4xx).
+type errPermanent struct{ error }
+
+func (h *HTTPRecorder) sendWithRetry(ctx context.Context, batch []Entry) error {
+	body, err := h.encode(batch)
+	if err != nil {
+		return err
+	}
+	backoff := 200 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		err = h.send(ctx, body)
+		var permanent errPermanent
+		if err == nil || errors.As(err, &permanent) || attempt >= h.cfg.MaxRetries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (h *HTTPRecorder) encode(batch []Entry) ([]byte, error) {
+	raw := make([]json.RawMessage, len(batch))
+	for i, entry := range batch {
+		data, err := JSONCodec.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		raw[i] = data
+	}
+	data, err := json.Marshal(raw)
+	if err != nil || h.cfg.DisableGzip {
+		return data, err
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write(data)
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (h *HTTPRecorder) send(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if !h.cfg.DisableGzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if h.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+h.cfg.APIKey)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("audittrail: collector request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("audittrail: collector: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	if resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusTooManyRequests {
+		return errPermanent{err}
+	}
+	return err
+}
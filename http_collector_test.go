@@ -0,0 +1,54 @@
+package audittrail
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHTTPRecorderSendsBatchesToCollector(t *testing.T) {
+	var got []Entry
+	collector, err := NewCollectorServer(RecorderFunc(func(_ context.Context, e Entry) error {
+		got = append(got, e)
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("NewCollectorServer: %v", err)
+	}
+	var failures atomic.Int32
+	failures.Store(1)
+	handler := collector.HTTPHandler("secret")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures.Add(-1) >= 0 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	rec, err := NewHTTPRecorder(HTTPRecorderConfig{BaseURL: srv.URL + "/", APIKey: "secret", BatchSize: 2})
+	if err != nil {
+		t.Fatalf("NewHTTPRecorder: %v", err)
+	}
+	ctx := context.Background()
+	for _, action := range []string{"A", "B", "C"} {
+		if err := rec.Record(ctx, Entry{Action: action, Request: map[string]any{"n": 1}}); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	if len(got) != 2 || rec.Pending() != 1 {
+		t.Fatalf("expected first batch delivered after retry, got %d (pending %d)", len(got), rec.Pending())
+	}
+	if err := rec.Close(ctx); err != nil || len(got) != 3 || got[2].Action != "C" {
+		t.Fatalf("Close: err=%v got=%d", err, len(got))
+	}
+
+	bad, _ := NewHTTPRecorder(HTTPRecorderConfig{BaseURL: srv.URL, APIKey: "wrong"})
+	_ = bad.Record(ctx, Entry{Action: "D"})
+	if err := bad.Flush(ctx); err == nil || bad.Pending() != 0 {
+		t.Fatalf("expected rejected batch to be dropped, err=%v pending=%d", err, bad.Pending())
+	}
+}
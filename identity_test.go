@@ -0,0 +1,59 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMigrateIdentity(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var updates []execCall
+	db := openStubDB(t, &stubDriver{
+		queryFn: func(query string, _ []driver.NamedValue) (driver.Rows, error) {
+			if !strings.Contains(query, "WHERE log_actor IS NULL AND log_service_name IS NULL AND log_created_by IS NOT NULL") ||
+				!strings.HasSuffix(query, "LIMIT 10") {
+				t.Fatalf("unexpected select: %s", query)
+			}
+			return &stubRows{
+				columns: strings.Split(EntryColumns, ", "),
+				values: [][]driver.Value{
+					{"e1", nil, "LOGIN", nil, nil, nil, created, "u1", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil},
+					{"e2", nil, "NIGHTLY_SYNC", nil, nil, nil, created, "billing", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				},
+			}, nil
+		},
+		execFn: func(query string, args []driver.NamedValue) (driver.Result, error) {
+			updates = append(updates, execCall{query: query, args: args})
+			return stubResult{}, nil
+		},
+	})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	n, err := audit.MigrateIdentity(context.Background(), IdentityMigration{ServiceNames: []string{"billing"}, BatchSize: 10})
+	if err != nil {
+		t.Fatalf("MigrateIdentity: %v", err)
+	}
+	if n != 2 || len(updates) != 2 {
+		t.Fatalf("expected 2 updates, got n=%d calls=%d", n, len(updates))
+	}
+	if !strings.Contains(updates[0].query, "log_row_hash = CASE WHEN log_row_hash = $3 THEN $4 ELSE log_row_hash END") {
+		t.Fatalf("unexpected update: %s", updates[0].query)
+	}
+	user, service := updates[0].args, updates[1].args
+	if stringArg(user, 0) != "u1" || user[1].Value != nil || service[0].Value != nil || stringArg(service, 1) != "billing" {
+		t.Fatalf("unexpected identities: %v / %v", user, service)
+	}
+
+	legacy := Entry{ID: "e1", Action: "LOGIN", CreatedDate: created, CreatedBy: "u1"}
+	migrated := legacy
+	migrated.Actor = "u1"
+	if stringArg(user, 2) != rowHash(legacy) || stringArg(user, 3) != rowHash(migrated) || stringArg(user, 4) != "e1" {
+		t.Fatalf("unexpected hash arguments: %v", user)
+	}
+}
@@ -0,0 +1,193 @@
+package audittrail
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Clock supplies the time stamped on entries. Tests can substitute a fixed clock.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to Clock.
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time { return f() }
+
+// systemClock is the wall clock.
+var systemClock = ClockFunc(time.Now)
+
+// IDGenerator supplies IDs for entries recorded without one. IDs must fit the
+// VARCHAR(64) log_audit_trail_id column.
+type IDGenerator interface {
+	NewID() string
+}
+
+// IDGeneratorFunc adapts a function to IDGenerator.
+type IDGeneratorFunc func() string
+
+func (f IDGeneratorFunc) NewID() string { return f() }
+
+// DefaultIDGenerator assigns IDs when no generator is configured. Its UUIDv7 IDs sort by
+// creation time, so the primary key index stays append-mostly and recent entries are
+// clustered together.
+var DefaultIDGenerator = NewUUIDv7Generator(nil)
+
+// RandomIDGenerator returns 32 hex characters of crypto/rand data, the ID format of
+// earlier releases. The IDs carry no ordering.
+var RandomIDGenerator IDGenerator = IDGeneratorFunc(func() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err == nil {
+		return hex.EncodeToString(b[:])
+	}
+	return fmt.Sprintf("%d", time.Now().UnixNano())
+})
+
+// WithIDGenerator assigns IDs from gen to entries recorded without one, before enrichers
+// run. Config.IDGenerator sets it for an AuditTrail.
+func WithIDGenerator(gen IDGenerator) RecorderOption {
+	return func(h *recorderHooks) { h.ids = gen }
+}
+
+// NewUUIDv7Generator returns RFC 9562 version 7 UUIDs: a 48-bit millisecond timestamp,
+// a 12-bit sub-millisecond fraction and 62 random bits, so IDs sort by creation time.
+// A nil clock uses the wall clock.
+func NewUUIDv7Generator(clock Clock) IDGenerator {
+	if clock == nil {
+		clock = systemClock
+	}
+	return IDGeneratorFunc(func() string {
+		now := clock.Now()
+		ms := uint64(now.UnixMilli())
+		frac := uint64(now.Nanosecond()%int(time.Millisecond)) * 4096 / uint64(time.Millisecond)
+
+		var b [16]byte
+		_, _ = rand.Read(b[8:])
+		binary.BigEndian.PutUint64(b[:8], ms<<16|0x7000|frac)
+		b[8] = b[8]&0x3f | 0x80 // RFC 9562 variant
+
+		var out [36]byte
+		hex.Encode(out[0:8], b[0:4])
+		out[8] = '-'
+		hex.Encode(out[9:13], b[4:6])
+		out[13] = '-'
+		hex.Encode(out[14:18], b[6:8])
+		out[18] = '-'
+		hex.Encode(out[19:23], b[8:10])
+		out[23] = '-'
+		hex.Encode(out[24:], b[10:])
+		return string(out[:])
+	})
+}
+
+// crockford is the ULID base32 alphabet.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULIDGenerator returns 26-character ULIDs: a 48-bit millisecond timestamp and 80
+// random bits. IDs generated within the same millisecond increment the random part, so
+// they are strictly increasing per generator.
+type ULIDGenerator struct {
+	clock Clock
+
+	mu      sync.Mutex
+	lastMS  uint64
+	lastRnd [10]byte
+}
+
+// NewULIDGenerator creates a monotonic ULID generator. A nil clock uses the wall clock.
+func NewULIDGenerator(clock Clock) *ULIDGenerator {
+	if clock == nil {
+		clock = systemClock
+	}
+	return &ULIDGenerator{clock: clock}
+}
+
+func (g *ULIDGenerator) NewID() string {
+	ms := uint64(g.clock.Now().UnixMilli())
+
+	g.mu.Lock()
+	if ms <= g.lastMS {
+		ms = g.lastMS
+		incrementBytes(g.lastRnd[:])
+	} else {
+		g.lastMS = ms
+		_, _ = rand.Read(g.lastRnd[:])
+	}
+	var b [16]byte
+	b[0], b[1], b[2], b[3], b[4], b[5] = byte(ms>>40), byte(ms>>32), byte(ms>>24), byte(ms>>16), byte(ms>>8), byte(ms)
+	copy(b[6:], g.lastRnd[:])
+	g.mu.Unlock()
+
+	// 128 bits as 26 base32 digits; the first digit holds the top 3 bits.
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+func incrementBytes(b []byte) {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return
+		}
+	}
+}
+
+// snowflakeEpoch is the custom epoch of Snowflake IDs (2020-01-01 UTC).
+var snowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// SnowflakeGenerator returns decimal 64-bit Snowflake IDs: 41 bits of milliseconds since
+// 2020-01-01, a 10-bit node number and a 12-bit per-millisecond sequence. Every instance
+// recording entries must use a distinct node.
+type SnowflakeGenerator struct {
+	clock Clock
+	node  int64
+
+	mu     sync.Mutex
+	lastMS int64
+	seq    int64
+}
+
+// NewSnowflakeGenerator creates a generator for node (0-1023). A nil clock uses the wall
+// clock.
+func NewSnowflakeGenerator(node int64, clock Clock) (*SnowflakeGenerator, error) {
+	if node < 0 || node > 1023 {
+		return nil, fmt.Errorf("audittrail: snowflake node must be between 0 and 1023, got %d", node)
+	}
+	if clock == nil {
+		clock = systemClock
+	}
+	return &SnowflakeGenerator{clock: clock, node: node}, nil
+}
+
+func (g *SnowflakeGenerator) NewID() string {
+	ms := g.clock.Now().Sub(snowflakeEpoch).Milliseconds()
+
+	g.mu.Lock()
+	if ms <= g.lastMS {
+		// Same millisecond or clock moved back: continue the sequence, borrowing the next
+		// millisecond when it is exhausted.
+		ms = g.lastMS
+		g.seq = (g.seq + 1) & 0xfff
+		if g.seq == 0 {
+			ms++
+		}
+	} else {
+		g.seq = 0
+	}
+	g.lastMS = ms
+	id := ms<<22 | g.node<<12 | g.seq
+	g.mu.Unlock()
+	return strconv.FormatInt(id, 10)
+}
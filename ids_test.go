@@ -0,0 +1,110 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUUIDv7Generator(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	gen := NewUUIDv7Generator(ClockFunc(func() time.Time { return now }))
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	first := gen.NewID()
+	if !pattern.MatchString(first) {
+		t.Fatalf("not a UUIDv7: %s", first)
+	}
+	ms, _ := strconv.ParseUint(first[0:8]+first[9:13], 16, 64)
+	if int64(ms) != now.UnixMilli() {
+		t.Fatalf("timestamp %d, want %d", ms, now.UnixMilli())
+	}
+	now = now.Add(time.Millisecond)
+	if second := gen.NewID(); second <= first {
+		t.Fatalf("IDs not time ordered: %s <= %s", second, first)
+	}
+}
+
+func TestULIDGeneratorIsMonotonic(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	gen := NewULIDGenerator(ClockFunc(func() time.Time { return now }))
+	ids := make([]string, 100)
+	for i := range ids {
+		if i == 50 {
+			now = now.Add(time.Second)
+		}
+		ids[i] = gen.NewID()
+	}
+	if !regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`).MatchString(ids[0]) {
+		t.Fatalf("unexpected ULID %s", ids[0])
+	}
+	var ms int64
+	for _, c := range ids[0][:10] {
+		ms = ms<<5 | int64(strings.IndexRune(crockford, c))
+	}
+	if ms != now.Add(-time.Second).UnixMilli() {
+		t.Fatalf("ULID timestamp %d, want %d", ms, now.Add(-time.Second).UnixMilli())
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Fatalf("ULIDs not increasing: %v", ids)
+	}
+}
+
+func TestSnowflakeGenerator(t *testing.T) {
+	if _, err := NewSnowflakeGenerator(1024, nil); err == nil {
+		t.Fatal("expected node range error")
+	}
+	now := snowflakeEpoch.Add(time.Hour)
+	gen, err := NewSnowflakeGenerator(7, ClockFunc(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("NewSnowflakeGenerator: %v", err)
+	}
+	var last int64
+	for i := 0; i < 5000; i++ {
+		id, err := strconv.ParseInt(gen.NewID(), 10, 64)
+		if err != nil || id <= last {
+			t.Fatalf("id %d not increasing after %d (%v)", id, last, err)
+		}
+		if node := id >> 12 & 1023; node != 7 {
+			t.Fatalf("node = %d", node)
+		}
+		last = id
+	}
+}
+
+func TestConfigClockAndIDGenerator(t *testing.T) {
+	var args []driver.NamedValue
+	db := openStubDB(t, &stubDriver{execFn: func(_ string, a []driver.NamedValue) (driver.Result, error) {
+		args = a
+		return stubResult{}, nil
+	}})
+	fixed := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	audit, err := NewAuditTrail(Config{
+		DB:          db,
+		Clock:       ClockFunc(func() time.Time { return fixed }),
+		IDGenerator: IDGeneratorFunc(func() string { return "id-1" }),
+	})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	if err := audit.Record(context.Background(), Entry{Action: "A"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if args[0].Value != "id-1" || !args[6].Value.(time.Time).Equal(fixed) {
+		t.Fatalf("unexpected args: %v", args)
+	}
+}
+
+func TestDefaultIDsAreTimeOrdered(t *testing.T) {
+	first := newID()
+	time.Sleep(2 * time.Millisecond)
+	second := newID()
+	if len(first) != 36 || first[14] != '7' || second <= first {
+		t.Fatalf("default IDs not time ordered UUIDv7: %s, %s", first, second)
+	}
+}
@@ -0,0 +1,37 @@
+package audittrail
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WithImpersonatorHeader sets which header names the real actor of impersonated requests,
+// e.g. the support admin acting as the user in the actor header. It is recorded as
+// ImpersonatedBy. Default: X-Impersonated-By.
+func WithImpersonatorHeader(name string) HTTPMiddlewareOption {
+	return func(c *httpMiddlewareConfig) {
+		c.impersonator = func(r *http.Request) string { return headerValue(r, name) }
+	}
+}
+
+// WithImpersonator sets how the real actor of impersonated requests is extracted, e.g. from
+// the session or token claims stored in the request context.
+func WithImpersonator(fn func(*http.Request) string) HTTPMiddlewareOption {
+	return func(c *httpMiddlewareConfig) {
+		if fn != nil {
+			c.impersonator = fn
+		}
+	}
+}
+
+// WithImpersonatorExtractor sets how the real actor of impersonated requests is extracted.
+// Default: the "impersonated_by" context key set by an auth middleware, then the
+// X-Impersonated-By header.
+func WithImpersonatorExtractor(fn func(*gin.Context) string) GinMiddlewareOption {
+	return func(c *ginMiddlewareConfig) {
+		if fn != nil {
+			c.extractImpersonator = fn
+		}
+	}
+}
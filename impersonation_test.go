@@ -0,0 +1,55 @@
+package audittrail
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHTTPMiddlewareImpersonation(t *testing.T) {
+	var got Entry
+	rec := RecorderFunc(func(_ context.Context, e Entry) error {
+		got = e
+		return nil
+	})
+	noop := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodPost, "/orders/7/refund", nil)
+	req.Header.Set("X-User-Id", "u1")
+	req.Header.Set("X-Impersonated-By", "admin-7")
+	HTTPMiddleware(rec)(noop).ServeHTTP(httptest.NewRecorder(), req)
+	if got.Actor != "u1" || got.ImpersonatedBy != "admin-7" {
+		t.Fatalf("unexpected actors: %q / %q", got.Actor, got.ImpersonatedBy)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/orders/7/refund", nil)
+	req.Header.Set("X-Support-Agent", "agent-3")
+	HTTPMiddleware(rec, WithImpersonatorHeader("X-Support-Agent"))(noop).ServeHTTP(httptest.NewRecorder(), req)
+	if got.ImpersonatedBy != "agent-3" {
+		t.Fatalf("unexpected impersonator: %q", got.ImpersonatedBy)
+	}
+}
+
+func TestGinMiddlewareImpersonation(t *testing.T) {
+	entries := make(chan Entry, 1)
+	useGlobalRecorder(t, RecorderFunc(func(_ context.Context, e Entry) error {
+		entries <- e
+		return nil
+	}))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", "u1")
+		c.Set("impersonated_by", "admin-7")
+	}, GinMiddleware())
+	r.POST("/orders", func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders", nil))
+	if e := <-entries; e.Actor != "u1" || e.ImpersonatedBy != "admin-7" {
+		t.Fatalf("unexpected actors: %q / %q", e.Actor, e.ImpersonatedBy)
+	}
+}
@@ -0,0 +1,103 @@
+package audittrail
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// importBatchSize is the number of entries Import stores per transaction.
+const importBatchSize = 500
+
+// Import loads entries exported by ObjectRecorder, Archiver or ParquetWriter back into the
+// table, e.g. to bring an archived month online for an investigation. JSONL input may be
+// gzipped. Entries are stored as exported: the recorder hooks (enrichers, transformers,
+// validators) are not run, and entries whose ID already exists are skipped, so importing
+// the same file twice is safe.
+// It returns the number of entries read; on error, earlier batches stay imported.
+func (r *AuditTrail) Import(ctx context.Context, rd io.Reader, format ObjectFormat) (int64, error) {
+	if r == nil || r.db == nil {
+		return 0, errors.New("audittrail: instance is not initialized")
+	}
+	if rd == nil {
+		return 0, errors.New("audittrail: import reader must not be nil")
+	}
+
+	var read int64
+	args := make([]any, 0, importBatchSize*insertColumnCount)
+	add := func(entry Entry) error {
+		row, err := r.rowArgs(entry)
+		if err != nil {
+			return fmt.Errorf("audittrail: entry %d: %w", read, err)
+		}
+		read++
+		args = append(args, row...)
+		if len(args) < importBatchSize*insertColumnCount {
+			return nil
+		}
+		err = r.insertRows(ctx, args, true, nil)
+		args = args[:0]
+		return err
+	}
+
+	var err error
+	switch format {
+	case ObjectJSONL:
+		err = readJSONL(rd, add)
+	case ObjectParquet:
+		var data []byte
+		if data, err = io.ReadAll(rd); err != nil {
+			return 0, err
+		}
+		var entries []Entry
+		if entries, err = readParquet(data); err != nil {
+			return 0, err
+		}
+		for _, entry := range entries {
+			if err = add(entry); err != nil {
+				break
+			}
+		}
+	default:
+		return 0, fmt.Errorf("audittrail: unsupported import format %d", format)
+	}
+	if err == nil {
+		err = r.insertRows(ctx, args, true, nil)
+	}
+	return read, err
+}
+
+// readJSONL calls fn for each entry of a JSONL stream, gunzipping it first when it starts
+// with the gzip magic bytes.
+func readJSONL(rd io.Reader, fn func(Entry) error) error {
+	br := bufio.NewReader(rd)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		rd = zr
+	} else {
+		rd = br
+	}
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 0, 64<<10), 64<<20)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		entry, err := JSONCodec.Unmarshal(scanner.Bytes())
+		if err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,84 @@
+package audittrail
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportJSONLSkipsDuplicatesAndHooks(t *testing.T) {
+	var calls []execCall
+	db := openStubDB(t, &stubDriver{execFn: func(query string, args []driver.NamedValue) (driver.Result, error) {
+		calls = append(calls, execCall{query: query, args: args})
+		return stubResult{}, nil
+	}})
+	enrich := WithEnrichers(func(_ context.Context, e *Entry) error {
+		e.CreatedBy = "importer"
+		return nil
+	})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar}, enrich)
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	created := time.Date(2024, 5, 3, 10, 0, 0, 0, time.UTC)
+	for _, id := range []string{"a", "b"} {
+		line, _ := JSONCodec.Marshal(Entry{ID: id, Action: "EXPORT", CreatedBy: "u1", CreatedDate: created, Request: map[string]any{"x": 1}})
+		zw.Write(append(line, '\n'))
+	}
+	zw.Close()
+
+	n, err := audit.Import(context.Background(), &buf, ObjectJSONL)
+	if err != nil || n != 2 {
+		t.Fatalf("Import = %d, %v", n, err)
+	}
+	if len(calls) != 1 || !strings.HasSuffix(calls[0].query, "ON CONFLICT (log_audit_trail_id) DO NOTHING") ||
+		len(calls[0].args) != 2*insertColumnCount {
+		t.Fatalf("unexpected inserts %+v", calls)
+	}
+	if calls[0].args[0].Value != "a" || calls[0].args[7].Value != "u1" || calls[0].args[4].Value != `{"x":1}` {
+		t.Fatalf("entry not stored as exported: %v", calls[0].args[:8])
+	}
+}
+
+func TestImportParquet(t *testing.T) {
+	var calls []execCall
+	db := openStubDB(t, &stubDriver{execFn: func(query string, args []driver.NamedValue) (driver.Result, error) {
+		calls = append(calls, execCall{query: query, args: args})
+		return stubResult{}, nil
+	}})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	var buf bytes.Buffer
+	w := NewParquetWriter(&buf)
+	for i := 0; i < importBatchSize+1; i++ {
+		if err := w.Write(Entry{ID: fmt.Sprintf("e%04d", i), Action: "EXPORT", CreatedDate: time.Unix(int64(i), 0)}); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	n, err := audit.Import(context.Background(), &buf, ObjectParquet)
+	if err != nil || n != importBatchSize+1 {
+		t.Fatalf("Import = %d, %v", n, err)
+	}
+	var rows int
+	for _, call := range calls {
+		rows += len(call.args) / insertColumnCount
+	}
+	if rows != importBatchSize+1 {
+		t.Fatalf("inserted %d rows", rows)
+	}
+}
@@ -0,0 +1,116 @@
+package audittrail
+
+import (
+	"context"
+	"os"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// Instance identification is read from these environment variables, in order, before
+// falling back to build info and os.Hostname.
+var (
+	appVersionEnv  = []string{"AUDIT_APP_VERSION", "APP_VERSION", "K_REVISION"}
+	instanceIDEnv  = []string{"AUDIT_INSTANCE_ID", "POD_NAME", "CLOUD_RUN_INSTANCE_ID", "HOSTNAME"}
+	serviceNameEnv = []string{"AUDIT_SERVICE_NAME", "OTEL_SERVICE_NAME", "K_SERVICE"}
+)
+
+// InstanceInfo identifies the process that records entries.
+type InstanceInfo struct {
+	ServiceName string
+	AppVersion  string
+	Hostname    string
+	InstanceID  string
+}
+
+var (
+	instanceOnce sync.Once
+	instanceInfo InstanceInfo
+)
+
+// CurrentInstance returns the service name, version, hostname and pod/instance ID of this
+// process. It is detected once: the service name from AUDIT_SERVICE_NAME, OTEL_SERVICE_NAME
+// or K_SERVICE; the version from AUDIT_APP_VERSION, APP_VERSION or K_REVISION,
+// else the main module version or VCS revision from the build info; the instance ID from
+// AUDIT_INSTANCE_ID, POD_NAME, CLOUD_RUN_INSTANCE_ID or HOSTNAME, else the hostname.
+func CurrentInstance() InstanceInfo {
+	instanceOnce.Do(func() {
+		instanceInfo = detectInstance(os.Getenv, debug.ReadBuildInfo, os.Hostname)
+	})
+	return instanceInfo
+}
+
+func detectInstance(getenv func(string) string, buildInfo func() (*debug.BuildInfo, bool), hostname func() (string, error)) InstanceInfo {
+	var info InstanceInfo
+	info.ServiceName = firstEnv(getenv, serviceNameEnv)
+	info.AppVersion = firstEnv(getenv, appVersionEnv)
+	if info.AppVersion == "" {
+		if bi, ok := buildInfo(); ok && bi != nil {
+			info.AppVersion = buildVersion(bi)
+		}
+	}
+	if name, err := hostname(); err == nil {
+		info.Hostname = name
+	}
+	info.InstanceID = firstEnv(getenv, instanceIDEnv)
+	if info.InstanceID == "" {
+		info.InstanceID = info.Hostname
+	}
+	return info
+}
+
+// buildVersion prefers a tagged module version and falls back to the VCS revision
+// ("(devel)" builds from a checkout).
+func buildVersion(bi *debug.BuildInfo) string {
+	if v := bi.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+	var revision, modified string
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value
+		}
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if revision != "" && modified == "true" {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+func firstEnv(getenv func(string) string, keys []string) string {
+	for _, key := range keys {
+		if val := strings.TrimSpace(getenv(key)); val != "" {
+			return val
+		}
+	}
+	return ""
+}
+
+// InstanceEnricher fills ServiceName, AppVersion, Hostname and InstanceID from
+// CurrentInstance when they are empty, so every entry records which service and instance
+// performed the write. Init adds it automatically.
+func InstanceEnricher() Enricher {
+	return func(_ context.Context, entry *Entry) error {
+		info := CurrentInstance()
+		if entry.ServiceName == "" {
+			entry.ServiceName = info.ServiceName
+		}
+		if entry.AppVersion == "" {
+			entry.AppVersion = info.AppVersion
+		}
+		if entry.Hostname == "" {
+			entry.Hostname = info.Hostname
+		}
+		if entry.InstanceID == "" {
+			entry.InstanceID = info.InstanceID
+		}
+		return nil
+	}
+}
@@ -0,0 +1,49 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"testing"
+)
+
+func TestDetectInstance(t *testing.T) {
+	env := map[string]string{"POD_NAME": "api-7c9f-x2", "OTEL_SERVICE_NAME": "orders"}
+	build := func() (*debug.BuildInfo, bool) {
+		return &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}, Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123456789abcdef0123"},
+			{Key: "vcs.modified", Value: "true"},
+		}}, true
+	}
+	host := func() (string, error) { return "node-1", nil }
+
+	info := detectInstance(func(k string) string { return env[k] }, build, host)
+	if info.AppVersion != "0123456789ab-dirty" || info.Hostname != "node-1" || info.InstanceID != "api-7c9f-x2" || info.ServiceName != "orders" {
+		t.Fatalf("unexpected instance: %+v", info)
+	}
+
+	env = map[string]string{"AUDIT_APP_VERSION": "v1.4.2"}
+	info = detectInstance(func(k string) string { return env[k] }, build, func() (string, error) { return "", errors.New("no host") })
+	if info.AppVersion != "v1.4.2" || info.Hostname != "" || info.InstanceID != "" || info.ServiceName != "" {
+		t.Fatalf("unexpected instance: %+v", info)
+	}
+
+	env = nil
+	info = detectInstance(func(k string) string { return env[k] }, func() (*debug.BuildInfo, bool) {
+		return &debug.BuildInfo{Main: debug.Module{Version: "v2.0.0"}}, true
+	}, host)
+	if info.AppVersion != "v2.0.0" || info.InstanceID != "node-1" {
+		t.Fatalf("unexpected instance: %+v", info)
+	}
+}
+
+func TestInstanceEnricherKeepsExplicitValues(t *testing.T) {
+	entry := Entry{Action: "A", Hostname: "custom"}
+	if err := InstanceEnricher()(context.Background(), &entry); err != nil {
+		t.Fatalf("enrich: %v", err)
+	}
+	info := CurrentInstance()
+	if entry.Hostname != "custom" || entry.InstanceID != info.InstanceID || entry.AppVersion != info.AppVersion {
+		t.Fatalf("unexpected entry: %+v (instance %+v)", entry, info)
+	}
+}
@@ -0,0 +1,116 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntegrityScanDetectsModifiedRows(t *testing.T) {
+	var stored [][]driver.Value
+	var gotQuery string
+	db := openStubDB(t, &stubDriver{
+		execFn: func(_ string, args []driver.NamedValue) (driver.Result, error) {
+			row := make([]driver.Value, len(args))
+			for i, arg := range args {
+				row[i] = arg.Value
+			}
+			stored = append(stored, row)
+			return stubResult{}, nil
+		},
+		queryFn: func(query string, _ []driver.NamedValue) (driver.Rows, error) {
+			gotQuery = query
+			return &stubRows{columns: strings.Split(insertColumns, ", "), values: stored}, nil
+		},
+	})
+	audit, err := NewAuditTrail(Config{
+		DB:          db,
+		Placeholder: PlaceholderDollar,
+		Retention:   []RetentionRule{{Action: "LOGIN", TTL: 24 * time.Hour}},
+	})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	ctx := context.Background()
+	created := time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC)
+	entries := []Entry{
+		{ID: "e1", Action: "LOGIN", CreatedBy: "u1", CreatedDate: created, Request: map[string]any{"b": 1, "a": "x"}},
+		{ID: "e2", Action: "DELETE_USER", CreatedBy: "u1", CreatedDate: created, Severity: SeverityCritical, Response: "ok"},
+		{ID: "e3", Action: "EXPORT", CreatedBy: "u2", CreatedDate: created},
+	}
+	for _, e := range entries {
+		if err := audit.Record(ctx, e); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	// Simulate storage: microsecond timestamps, reordered JSON, an edited row and a
+	// row written before the hash column existed.
+	for _, row := range stored {
+		row[6] = row[6].(time.Time).Truncate(time.Microsecond)
+	}
+	stored[0][4] = []byte(`{"a": "x", "b": 1}`)
+	stored[1][7] = "someone-else"
+	stored[2][insertColumnCount-1] = nil
+
+	report, err := audit.IntegrityScan(ctx, 10)
+	if err != nil {
+		t.Fatalf("IntegrityScan: %v", err)
+	}
+	if gotQuery != "SELECT "+EntryColumns+", log_row_hash FROM audit_trail ORDER BY RANDOM() LIMIT 10" {
+		t.Fatalf("unexpected query: %s", gotQuery)
+	}
+	if report.Checked != 3 || report.Unhashed != 1 || len(report.Mismatches) != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if m := report.Mismatches[0]; m.Entry.ID != "e2" || m.Stored == m.Computed {
+		t.Fatalf("unexpected mismatch: %+v", m)
+	}
+
+	var alerted []string
+	runCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		_ = audit.RunIntegrityScan(runCtx, IntegrityConfig{
+			OnMismatch: func(_ context.Context, m IntegrityMismatch) {
+				alerted = append(alerted, m.Entry.ID)
+				cancel()
+			},
+		})
+	}()
+	<-runCtx.Done()
+	if len(alerted) != 1 || alerted[0] != "e2" {
+		t.Fatalf("expected alert for e2, got %v", alerted)
+	}
+}
+
+func TestIntegrityScanSampleOrderPerDialect(t *testing.T) {
+	var queries []string
+	stub := stubDriver{queryFn: func(query string, _ []driver.NamedValue) (driver.Rows, error) {
+		queries = append(queries, query)
+		return &stubRows{columns: strings.Split(insertColumns, ", ")}, nil
+	}}
+	scan := func(d driver.Driver) string {
+		audit, err := NewAuditTrail(Config{DB: openDriverDB(t, d)})
+		if err != nil {
+			t.Fatalf("NewAuditTrail: %v", err)
+		}
+		if _, err := audit.IntegrityScan(context.Background(), 5); err != nil {
+			t.Fatalf("IntegrityScan: %v", err)
+		}
+		return queries[len(queries)-1]
+	}
+
+	if q := scan(&mysqlStubDriver{stub}); !strings.HasSuffix(q, " ORDER BY RAND() LIMIT 5") {
+		t.Fatalf("unexpected MySQL sample query: %s", q)
+	}
+	// Spanner has no RAND(): rows are ordered by a fingerprint salted afresh on every scan.
+	first := scan(&spannerStubDriver{stub})
+	if !strings.Contains(first, " ORDER BY FARM_FINGERPRINT(CONCAT(log_audit_trail_id, '") || !strings.HasSuffix(first, "')) LIMIT 5") {
+		t.Fatalf("unexpected Spanner sample query: %s", first)
+	}
+	if second := scan(&spannerStubDriver{stub}); second == first {
+		t.Fatalf("Spanner sample not salted per scan: %s", second)
+	}
+}
@@ -0,0 +1,123 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// iteratePageSize is the number of rows Iterate fetches per query.
+const iteratePageSize = 500
+
+// keysetPosition is the (created date, ID) pair that orders entries for keyset pagination.
+// The zero value starts at the beginning.
+type keysetPosition struct {
+	createdDate time.Time
+	id          string
+}
+
+// keysetPage selects up to limit entries matching f that sort after pos, ordered by
+// log_created_date then log_audit_trail_id. Unlike OFFSET, the cost does not grow with the
+// page number.
+func (r *AuditTrail) keysetPage(ctx context.Context, f Filter, pos keysetPosition, limit int) ([]Entry, error) {
+	clause, args := r.keysetClause(f, pos, limit)
+	return r.queryEntries(ctx, clause, args...)
+}
+
+func (r *AuditTrail) keysetClause(f Filter, pos keysetPosition, limit int) (string, []any) {
+	b := &queryBuilder{placeholder: r.placeholder, dialect: r.dialect}
+	clause := b.where(f)
+	if pos.id != "" {
+		created := pos.createdDate.UTC()
+		clause = andWhere(clause, fmt.Sprintf("(log_created_date > %s OR (log_created_date = %s AND log_audit_trail_id > %s))",
+			b.arg(created), b.arg(created), b.arg(pos.id)))
+	}
+	clause += fmt.Sprintf(" ORDER BY log_created_date, log_audit_trail_id LIMIT %d", limit)
+	return clause, b.args
+}
+
+// hashedEntry is an entry with the log_row_hash stored for it.
+type hashedEntry struct {
+	entry Entry
+	hash  sql.NullString
+}
+
+// keysetHashedPage is keysetPage that also reads each row's stored hash.
+func (r *AuditTrail) keysetHashedPage(ctx context.Context, f Filter, pos keysetPosition, limit int) ([]hashedEntry, error) {
+	clause, args := r.keysetClause(f, pos, limit)
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf("SELECT %s, log_row_hash FROM %s%s", entryColumns, r.tableRef, clause), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var page []hashedEntry
+	for rows.Next() {
+		var h hashedEntry
+		if h.entry, err = scanEntry(rowHashScanner{rows: rows, hash: &h.hash}); err != nil {
+			return nil, err
+		}
+		page = append(page, h)
+	}
+	return page, rows.Err()
+}
+
+// iterateHashed is Iterate for callers that verify rows against their stored hash, e.g. the
+// Archiver. It does not record a read.
+func (r *AuditTrail) iterateHashed(ctx context.Context, f Filter, fn func(Entry, sql.NullString) error) error {
+	var pos keysetPosition
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		page, err := r.keysetHashedPage(ctx, f, pos, iteratePageSize)
+		if err != nil {
+			return err
+		}
+		for _, h := range page {
+			if err := fn(h.entry, h.hash); err != nil {
+				return err
+			}
+		}
+		if len(page) < iteratePageSize {
+			return nil
+		}
+		last := page[len(page)-1].entry
+		pos = keysetPosition{createdDate: last.CreatedDate, id: last.ID}
+	}
+}
+
+// Iterate calls fn for every entry matching f, oldest first. Rows are streamed in pages
+// using keyset pagination, so exports and verifications over millions of rows run in
+// constant memory. An error from fn stops the iteration and is returned.
+func (r *AuditTrail) Iterate(ctx context.Context, f Filter, fn func(Entry) error) error {
+	if r == nil || r.db == nil {
+		return errors.New("audittrail: instance is not initialized")
+	}
+	if fn == nil {
+		return errors.New("audittrail: iterate callback must not be nil")
+	}
+	ctx = r.auditRead(ctx, "iterate", f, nil)
+	var pos keysetPosition
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		page, err := r.keysetPage(ctx, f, pos, iteratePageSize)
+		if err != nil {
+			return err
+		}
+		for _, entry := range page {
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+		if len(page) < iteratePageSize {
+			return nil
+		}
+		last := page[len(page)-1]
+		pos = keysetPosition{createdDate: last.CreatedDate, id: last.ID}
+	}
+}
@@ -0,0 +1,63 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIterateUsesKeysetPages(t *testing.T) {
+	base := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	total := iteratePageSize + 3
+	var queries []string
+	var queryArgs [][]driver.NamedValue
+	db := openStubDB(t, &stubDriver{queryFn: func(query string, args []driver.NamedValue) (driver.Rows, error) {
+		queries = append(queries, query)
+		queryArgs = append(queryArgs, args)
+		start, n := 0, iteratePageSize
+		if len(queries) > 1 {
+			start, n = iteratePageSize, total-iteratePageSize
+		}
+		values := make([][]driver.Value, n)
+		for i := range values {
+			id := fmt.Sprintf("e%04d", start+i)
+			values[i] = []driver.Value{id, nil, "EXPORT", nil, nil, nil, base.Add(time.Duration(start+i) * time.Second),
+				"u1", nil, nil, nil, nil, nil, nil, nil, nil}
+		}
+		return &stubRows{columns: strings.Split(EntryColumns, ", "), values: values}, nil
+	}})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	var seen int
+	err = audit.Iterate(context.Background(), Filter{Actor: "u1"}, func(Entry) error {
+		seen++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Iterate: %v", err)
+	}
+	if seen != total || len(queries) != 2 {
+		t.Fatalf("seen %d entries in %d queries", seen, len(queries))
+	}
+	if strings.Contains(queries[0], "log_created_date >") ||
+		!strings.Contains(queries[1], "WHERE (log_actor = $1 OR (log_actor IS NULL AND log_created_by = $2)) AND (log_created_date > $3 OR (log_created_date = $4 AND log_audit_trail_id > $5))") ||
+		!strings.HasSuffix(queries[1], fmt.Sprintf("ORDER BY log_created_date, log_audit_trail_id LIMIT %d", iteratePageSize)) {
+		t.Fatalf("unexpected queries: %q", queries)
+	}
+	if got := queryArgs[1][4].Value; got != fmt.Sprintf("e%04d", iteratePageSize-1) {
+		t.Fatalf("unexpected keyset id %v", got)
+	}
+
+	stop := errors.New("stop")
+	queries = nil
+	if err := audit.Iterate(context.Background(), Filter{}, func(Entry) error { return stop }); !errors.Is(err, stop) || len(queries) != 1 {
+		t.Fatalf("expected callback error to stop iteration, err=%v queries=%d", err, len(queries))
+	}
+}
@@ -0,0 +1,50 @@
+package audittrail
+
+import (
+	"encoding/json"
+	"sync/atomic"
+)
+
+// JSONEngine encodes and decodes JSON on the hot paths: JSONCodec (publishers and
+// consumers), payload columns written by AuditTrail and body capture in the middlewares.
+// It must behave like encoding/json (struct tags, json.Marshaler, json.RawMessage), as
+// jsoniter.ConfigCompatibleWithStandardLibrary does:
+//
+//	audittrail.SetJSONEngine(jsoniter.ConfigCompatibleWithStandardLibrary)
+//
+// goccy/go-json exposes package functions; wrap them in a small type.
+type JSONEngine interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+	Valid(data []byte) bool
+}
+
+// stdJSON is the default engine, encoding/json.
+type stdJSON struct{}
+
+func (stdJSON) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (stdJSON) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (stdJSON) Valid(data []byte) bool             { return json.Valid(data) }
+
+var jsonEngine atomic.Pointer[JSONEngine]
+
+// SetJSONEngine replaces encoding/json on the hot paths; nil restores it. Row hashes
+// (log_row_hash) are always computed with encoding/json so they stay stable.
+func SetJSONEngine(e JSONEngine) {
+	if e == nil {
+		jsonEngine.Store(nil)
+		return
+	}
+	jsonEngine.Store(&e)
+}
+
+func currentJSONEngine() JSONEngine {
+	if e := jsonEngine.Load(); e != nil {
+		return *e
+	}
+	return stdJSON{}
+}
+
+func jsonMarshal(v any) ([]byte, error)      { return currentJSONEngine().Marshal(v) }
+func jsonUnmarshal(data []byte, v any) error { return currentJSONEngine().Unmarshal(data, v) }
+func jsonValid(data []byte) bool             { return currentJSONEngine().Valid(data) }
@@ -0,0 +1,63 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+)
+
+// countingJSON wraps encoding/json and counts calls.
+type countingJSON struct{ marshal, unmarshal, valid int }
+
+func (c *countingJSON) Marshal(v any) ([]byte, error) {
+	c.marshal++
+	return json.Marshal(v)
+}
+
+func (c *countingJSON) Unmarshal(data []byte, v any) error {
+	c.unmarshal++
+	return json.Unmarshal(data, v)
+}
+
+func (c *countingJSON) Valid(data []byte) bool {
+	c.valid++
+	return json.Valid(data)
+}
+
+func TestSetJSONEngine(t *testing.T) {
+	engine := &countingJSON{}
+	SetJSONEngine(engine)
+	t.Cleanup(func() { SetJSONEngine(nil) })
+
+	data, err := JSONCodec.Marshal(Entry{ID: "e1", Action: "A", Request: map[string]int{"a": 1}})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	entry, err := JSONCodec.Unmarshal(data)
+	if err != nil || entry.ID != "e1" || string(entry.Request.(json.RawMessage)) != `{"a":1}` {
+		t.Fatalf("Unmarshal: %+v %v", entry, err)
+	}
+	if engine.marshal != 1 || engine.unmarshal != 2 {
+		t.Fatalf("codec did not use the engine: %+v", engine)
+	}
+
+	db := openStubDB(t, &stubDriver{
+		execFn: func(string, []driver.NamedValue) (driver.Result, error) { return stubResult{}, nil },
+	})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar}, WithSensitiveDetection(SensitiveOff))
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	if err := audit.Record(context.Background(), Entry{Action: "A", Request: map[string]int{"a": 1}}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if engine.marshal == 1 {
+		t.Fatalf("payload was not marshaled with the engine: %+v", engine)
+	}
+
+	SetJSONEngine(nil)
+	if _, ok := currentJSONEngine().(stdJSON); !ok {
+		t.Fatal("nil should restore encoding/json")
+	}
+}
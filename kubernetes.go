@@ -0,0 +1,173 @@
+package audittrail
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// KubernetesAuditEvent is an audit.k8s.io/v1 Event as sent by the Kubernetes API server's
+// audit webhook backend. Only the fields mapped to entries are decoded.
+type KubernetesAuditEvent struct {
+	AuditID    string `json:"auditID"`
+	Stage      string `json:"stage"`
+	RequestURI string `json:"requestURI"`
+	Verb       string `json:"verb"`
+	User       struct {
+		Username string   `json:"username"`
+		Groups   []string `json:"groups,omitempty"`
+	} `json:"user"`
+	ImpersonatedUser *struct {
+		Username string `json:"username"`
+	} `json:"impersonatedUser,omitempty"`
+	SourceIPs []string `json:"sourceIPs,omitempty"`
+	UserAgent string   `json:"userAgent,omitempty"`
+	ObjectRef *struct {
+		Resource    string `json:"resource,omitempty"`
+		Namespace   string `json:"namespace,omitempty"`
+		Name        string `json:"name,omitempty"`
+		APIGroup    string `json:"apiGroup,omitempty"`
+		Subresource string `json:"subresource,omitempty"`
+	} `json:"objectRef,omitempty"`
+	ResponseStatus *struct {
+		Code    int    `json:"code,omitempty"`
+		Reason  string `json:"reason,omitempty"`
+		Message string `json:"message,omitempty"`
+	} `json:"responseStatus,omitempty"`
+	RequestObject            json.RawMessage `json:"requestObject,omitempty"`
+	ResponseObject           json.RawMessage `json:"responseObject,omitempty"`
+	RequestReceivedTimestamp time.Time       `json:"requestReceivedTimestamp"`
+}
+
+// KubernetesRequest is recorded as the request payload of a Kubernetes audit event.
+type KubernetesRequest struct {
+	URI          string          `json:"uri"`
+	Groups       []string        `json:"groups,omitempty"`
+	Impersonated string          `json:"impersonated_user,omitempty"`
+	SourceIPs    []string        `json:"source_ips,omitempty"`
+	UserAgent    string          `json:"user_agent,omitempty"`
+	Object       json.RawMessage `json:"object,omitempty"` // logged at level RequestResponse
+}
+
+// KubernetesResponse is recorded as the response payload of a Kubernetes audit event.
+type KubernetesResponse struct {
+	Code    int             `json:"code,omitempty"`
+	Reason  string          `json:"reason,omitempty"`
+	Message string          `json:"message,omitempty"`
+	Object  json.RawMessage `json:"object,omitempty"` // logged at level RequestResponse
+}
+
+// k8sVerbMethods maps Kubernetes verbs to HTTP methods for DefaultSeverity.
+var k8sVerbMethods = map[string]string{
+	"create": http.MethodPost, "update": http.MethodPut, "patch": http.MethodPatch,
+	"delete": http.MethodDelete, "deletecollection": http.MethodDelete,
+}
+
+// Entry maps the event to an entry: the verb becomes Action, the user Actor and the
+// object reference Endpoint, written "<resource>[.<group>]/<namespace>/<name>[/<subresource>]"
+// (the request URI for non-resource requests). The ID is the audit ID, suffixed with the
+// stage for stages other than ResponseComplete.
+func (ev KubernetesAuditEvent) Entry() Entry {
+	entry := Entry{
+		ID:          ev.AuditID,
+		RequestID:   ev.AuditID,
+		Action:      ev.Verb,
+		Endpoint:    ev.RequestURI,
+		CreatedDate: ev.RequestReceivedTimestamp,
+		Actor:       ev.User.Username,
+	}
+	if ev.Stage != "" && ev.Stage != "ResponseComplete" {
+		entry.ID += "-" + strings.ToLower(ev.Stage)
+	}
+	if ref := ev.ObjectRef; ref != nil && ref.Resource != "" {
+		parts := []string{ref.Resource}
+		if ref.APIGroup != "" {
+			parts[0] += "." + ref.APIGroup
+		}
+		for _, p := range []string{ref.Namespace, ref.Name, ref.Subresource} {
+			if p != "" {
+				parts = append(parts, p)
+			}
+		}
+		entry.Endpoint = strings.Join(parts, "/")
+	}
+
+	req := KubernetesRequest{URI: ev.RequestURI, Groups: ev.User.Groups, SourceIPs: ev.SourceIPs, UserAgent: ev.UserAgent, Object: ev.RequestObject}
+	if ev.ImpersonatedUser != nil {
+		req.Impersonated = ev.ImpersonatedUser.Username
+	}
+	entry.Request = req
+	resp := KubernetesResponse{Object: ev.ResponseObject}
+	if s := ev.ResponseStatus; s != nil {
+		resp.Code, resp.Reason, resp.Message = s.Code, s.Reason, s.Message
+	}
+	entry.Response = resp
+
+	method, ok := k8sVerbMethods[ev.Verb]
+	if !ok {
+		method = http.MethodGet
+	}
+	entry.Severity = DefaultSeverity(method, resp.Code)
+	return entry
+}
+
+// KubernetesAuditConfig configures KubernetesAuditHandler.
+type KubernetesAuditConfig struct {
+	// Tokens are accepted as "Authorization: Bearer <token>", set with the token field of
+	// the webhook kubeconfig. With no tokens every request is rejected.
+	Tokens []string
+	// Stages lists the event stages recorded; default ResponseComplete and Panic.
+	Stages []string
+}
+
+// KubernetesAuditHandler accepts the EventList batches of the Kubernetes audit webhook
+// backend (--audit-webhook-config-file) and records each event in rec, so cluster audit
+// events land in the same store as application entries. Batches may be retried by the API
+// server; an *AuditTrail stores each event once.
+func KubernetesAuditHandler(rec Recorder, cfg KubernetesAuditConfig) (http.Handler, error) {
+	if rec == nil {
+		return nil, errors.New("audittrail: kubernetes audit recorder must not be nil")
+	}
+	stages := map[string]bool{"ResponseComplete": true, "Panic": true}
+	if len(cfg.Stages) > 0 {
+		stages = make(map[string]bool, len(cfg.Stages))
+		for _, s := range cfg.Stages {
+			stages[s] = true
+		}
+	}
+	collector := &CollectorServer{recorder: rec}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !validAPIKey(r, cfg.Tokens) {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		var list struct {
+			Items []KubernetesAuditEvent `json:"items"`
+		}
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxCollectorBody)).Decode(&list); err != nil {
+			http.Error(w, "invalid EventList: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		entries := make([]Entry, 0, len(list.Items))
+		for _, ev := range list.Items {
+			if stages[ev.Stage] && ev.AuditID != "" {
+				entries = append(entries, ev.Entry())
+			}
+		}
+		if accepted, err := collector.store(r.Context(), entries); err != nil {
+			logger().Error("audittrail: failed to store kubernetes audit events", "accepted", accepted, "error", err)
+			http.Error(w, "failed to store events", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}), nil
+}
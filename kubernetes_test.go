@@ -0,0 +1,65 @@
+package audittrail
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const k8sEventList = `{"kind":"EventList","apiVersion":"audit.k8s.io/v1","items":[
+{"level":"Metadata","auditID":"a1","stage":"RequestReceived","verb":"delete","user":{"username":"alice"}},
+{"level":"RequestResponse","auditID":"a1","stage":"ResponseComplete","requestURI":"/apis/apps/v1/namespaces/shop/deployments/web","verb":"delete",
+ "user":{"username":"alice","groups":["system:authenticated"]},"sourceIPs":["10.0.0.7"],"userAgent":"kubectl/v1.30",
+ "objectRef":{"resource":"deployments","namespace":"shop","name":"web","apiGroup":"apps","apiVersion":"v1"},
+ "responseStatus":{"metadata":{},"code":200},"requestReceivedTimestamp":"2024-05-01T09:30:00.123456Z"},
+{"level":"Metadata","auditID":"a2","stage":"ResponseComplete","requestURI":"/api/v1/namespaces/kube-system/secrets","verb":"list",
+ "user":{"username":"system:serviceaccount:shop:ci"},"impersonatedUser":{"username":"bob"},
+ "objectRef":{"resource":"secrets","namespace":"kube-system","apiVersion":"v1"},
+ "responseStatus":{"code":403,"reason":"Forbidden"},"requestReceivedTimestamp":"2024-05-01T09:31:00Z"}
+]}`
+
+func TestKubernetesAuditHandler(t *testing.T) {
+	var got []Entry
+	rec := RecorderFunc(func(_ context.Context, e Entry) error {
+		got = append(got, e)
+		return nil
+	})
+	h, err := KubernetesAuditHandler(rec, KubernetesAuditConfig{Tokens: []string{"s3cret"}})
+	if err != nil {
+		t.Fatalf("KubernetesAuditHandler: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/k8s-audit", strings.NewReader(k8sEventList))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized || len(got) != 0 {
+		t.Fatalf("expected unauthenticated batch to be rejected: %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/k8s-audit", strings.NewReader(k8sEventList))
+	req.Header.Set("Authorization", "Bearer s3cret")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || len(got) != 2 {
+		t.Fatalf("unexpected result: %d, %d entries", w.Code, len(got))
+	}
+
+	del := got[0]
+	if del.ID != "a1" || del.Action != "delete" || del.Actor != "alice" || del.Endpoint != "deployments.apps/shop/web" || del.Severity != SeverityCritical {
+		t.Fatalf("unexpected delete entry: %+v", del)
+	}
+	if !del.CreatedDate.Equal(time.Date(2024, 5, 1, 9, 30, 0, 123456000, time.UTC)) {
+		t.Fatalf("unexpected timestamp: %v", del.CreatedDate)
+	}
+	if r := del.Request.(KubernetesRequest); r.URI != "/apis/apps/v1/namespaces/shop/deployments/web" || r.SourceIPs[0] != "10.0.0.7" || r.UserAgent != "kubectl/v1.30" {
+		t.Fatalf("unexpected request payload: %+v", r)
+	}
+
+	list := got[1]
+	if list.Endpoint != "secrets/kube-system" || list.Severity != SeverityWarn || list.Request.(KubernetesRequest).Impersonated != "bob" || list.Response.(KubernetesResponse).Reason != "Forbidden" {
+		t.Fatalf("unexpected list entry: %+v", list)
+	}
+}
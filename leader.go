@@ -0,0 +1,305 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// Elector grants leadership to one replica at a time.
+type Elector interface {
+	// Campaign blocks until this replica is the leader or ctx is done. The returned context
+	// is canceled when leadership is lost; release gives leadership up and must be called.
+	Campaign(ctx context.Context) (leaderCtx context.Context, release func(), err error)
+}
+
+// RunAsLeader runs fn only while this replica holds leadership, so for transports without
+// consumer groups a single replica consumes while the others stand by. When leadership is
+// lost, fn's context is canceled and the replica campaigns again. It returns when ctx is
+// done, or with fn's result when fn returns while still leading.
+func RunAsLeader(ctx context.Context, e Elector, fn func(context.Context) error) error {
+	if e == nil {
+		return errors.New("audittrail: elector must not be nil")
+	}
+	for {
+		leaderCtx, release, err := e.Campaign(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		logger().Info("audittrail: acquired leadership")
+		err = fn(leaderCtx)
+		lost := leaderCtx.Err() != nil && ctx.Err() == nil
+		release()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if !lost {
+			return err
+		}
+		logger().Warn("audittrail: lost leadership, standing by")
+	}
+}
+
+// RunAsLeader runs the consumer only while this replica holds leadership (see RunAsLeader).
+// A deposed leader may still be finishing an entry when the next one starts; entries are
+// stored once, so this is harmless.
+func (c *Consumer) RunAsLeader(ctx context.Context, e Elector) error {
+	return RunAsLeader(ctx, e, c.Run)
+}
+
+// AdvisoryLockConfig configures NewAdvisoryLockElector.
+type AdvisoryLockConfig struct {
+	DB            *sql.DB       // Postgres database shared by the replicas
+	Name          string        // lock name; replicas using the same name compete
+	RetryInterval time.Duration // how often standbys try to take the lock; default 5s
+	CheckInterval time.Duration // how often the leader checks its session; default 5s
+}
+
+type advisoryLockElector struct {
+	db    *sql.DB
+	key   int64
+	retry time.Duration
+	check time.Duration
+}
+
+// NewAdvisoryLockElector elects the replica holding a Postgres session advisory lock. The
+// lock is held by a dedicated connection and released by Postgres when it drops, so a
+// crashed leader is replaced after RetryInterval; a leader that loses its connection
+// notices within CheckInterval.
+func NewAdvisoryLockElector(cfg AdvisoryLockConfig) (Elector, error) {
+	if cfg.DB == nil {
+		return nil, errors.New("audittrail: advisory lock DB must not be nil")
+	}
+	if cfg.Name == "" {
+		return nil, errors.New("audittrail: advisory lock name must not be empty")
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = 5 * time.Second
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = 5 * time.Second
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(cfg.Name))
+	return &advisoryLockElector{db: cfg.DB, key: int64(h.Sum64()), retry: cfg.RetryInterval, check: cfg.CheckInterval}, nil
+}
+
+func (e *advisoryLockElector) Campaign(ctx context.Context) (context.Context, func(), error) {
+	for {
+		conn, err := e.db.Conn(ctx)
+		if err == nil {
+			var acquired bool
+			err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", e.key).Scan(&acquired)
+			if err == nil && acquired {
+				return e.hold(ctx, conn)
+			}
+			_ = conn.Close()
+		}
+		if err != nil && ctx.Err() == nil {
+			logger().Warn("audittrail: advisory lock campaign failed", "error", err)
+		}
+		select {
+		case <-time.After(e.retry):
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+// hold checks the locked session until leadership is released or lost.
+func (e *advisoryLockElector) hold(ctx context.Context, conn *sql.Conn) (context.Context, func(), error) {
+	leaderCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(e.check)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := conn.ExecContext(leaderCtx, "SELECT 1"); err != nil {
+					if leaderCtx.Err() == nil {
+						logger().Error("audittrail: advisory lock session lost", "error", err)
+					}
+					cancel()
+					return
+				}
+			case <-leaderCtx.Done():
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			cancel()
+			<-done
+			unlockCtx, stop := context.WithTimeout(context.WithoutCancel(ctx), e.check)
+			defer stop()
+			if _, err := conn.ExecContext(unlockCtx, "SELECT pg_advisory_unlock($1)", e.key); err != nil {
+				// Discard the session instead of returning it to the pool still holding the lock.
+				_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+			}
+			_ = conn.Close()
+		})
+	}
+	return leaderCtx, release, nil
+}
+
+// ErrLeaseConflict is returned by LeaseStore.Update when the lease changed since it was read.
+var ErrLeaseConflict = errors.New("audittrail: lease was updated concurrently")
+
+// Lease is a leadership record kept in a LeaseStore.
+type Lease struct {
+	Holder   string
+	Renewed  time.Time
+	Duration time.Duration
+	Version  string // opaque, changed by every update, e.g. a Kubernetes resourceVersion
+}
+
+// LeaseStore reads and conditionally writes one lease. For Kubernetes, back it with a
+// coordination.k8s.io/v1 Lease through client-go: Get maps spec.holderIdentity,
+// spec.renewTime, spec.leaseDurationSeconds and metadata.resourceVersion (a zero Lease when
+// it does not exist yet); Update creates the Lease when prev.Version is empty and otherwise
+// updates it with prev.Version as resourceVersion, mapping a 409 Conflict to
+// ErrLeaseConflict. The service account needs get, create and update on leases.
+type LeaseStore interface {
+	Get(ctx context.Context) (Lease, error)
+	// Update replaces prev with next if the stored lease still has prev.Version, returning
+	// the stored lease with its new Version, or fails with ErrLeaseConflict.
+	Update(ctx context.Context, prev, next Lease) (Lease, error)
+}
+
+// LeaseConfig configures NewLeaseElector.
+type LeaseConfig struct {
+	Store         LeaseStore
+	Identity      string        // this replica; default CurrentInstance().InstanceID
+	Duration      time.Duration // how long a lease is valid without renewal; default 15s
+	RenewInterval time.Duration // how often the leader renews and standbys retry; default Duration/3
+	Now           func() time.Time
+}
+
+type leaseElector struct {
+	cfg LeaseConfig
+}
+
+// NewLeaseElector elects the replica holding a lease, like Kubernetes leader election: the
+// leader renews it every RenewInterval, and standbys take it over once it has not been
+// renewed for Duration. A leader that cannot renew steps down before its lease expires.
+func NewLeaseElector(cfg LeaseConfig) (Elector, error) {
+	if cfg.Store == nil {
+		return nil, errors.New("audittrail: lease store must not be nil")
+	}
+	if cfg.Identity == "" {
+		cfg.Identity = CurrentInstance().InstanceID
+	}
+	if cfg.Identity == "" {
+		return nil, errors.New("audittrail: lease identity must not be empty")
+	}
+	if cfg.Duration <= 0 {
+		cfg.Duration = 15 * time.Second
+	}
+	if cfg.RenewInterval <= 0 || cfg.RenewInterval >= cfg.Duration {
+		cfg.RenewInterval = cfg.Duration / 3
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &leaseElector{cfg: cfg}, nil
+}
+
+func (e *leaseElector) Campaign(ctx context.Context) (context.Context, func(), error) {
+	for {
+		lease, err := e.acquire(ctx)
+		if err == nil {
+			return e.hold(ctx, lease)
+		}
+		if !errors.Is(err, ErrLeaseConflict) && !errors.Is(err, errLeaseHeld) && ctx.Err() == nil {
+			logger().Warn("audittrail: lease campaign failed", "error", err)
+		}
+		select {
+		case <-time.After(e.cfg.RenewInterval):
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+var errLeaseHeld = errors.New("audittrail: lease is held by another replica")
+
+// acquire takes the lease if it is free, expired or already ours.
+func (e *leaseElector) acquire(ctx context.Context) (Lease, error) {
+	cur, err := e.cfg.Store.Get(ctx)
+	if err != nil {
+		return Lease{}, err
+	}
+	now := e.cfg.Now()
+	if cur.Holder != "" && cur.Holder != e.cfg.Identity && now.Before(cur.Renewed.Add(cur.Duration)) {
+		return Lease{}, errLeaseHeld
+	}
+	return e.cfg.Store.Update(ctx, cur, Lease{Holder: e.cfg.Identity, Renewed: now, Duration: e.cfg.Duration})
+}
+
+// hold renews the lease until leadership is released or lost.
+func (e *leaseElector) hold(ctx context.Context, lease Lease) (context.Context, func(), error) {
+	leaderCtx, cancel := context.WithCancel(ctx)
+	var mu sync.Mutex // guards lease
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(e.cfg.RenewInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				mu.Lock()
+				next := lease
+				next.Renewed = e.cfg.Now()
+				renewed, err := e.cfg.Store.Update(leaderCtx, lease, next)
+				if err == nil {
+					lease = renewed
+				}
+				// Step down on conflict, or before the lease expires for others.
+				deadline := lease.Renewed.Add(e.cfg.Duration - e.cfg.RenewInterval)
+				mu.Unlock()
+				if err != nil && (errors.Is(err, ErrLeaseConflict) || !e.cfg.Now().Before(deadline)) {
+					if leaderCtx.Err() == nil {
+						logger().Error("audittrail: lease lost", "error", err)
+					}
+					cancel()
+					return
+				}
+			case <-leaderCtx.Done():
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			cancel()
+			<-done
+			mu.Lock()
+			defer mu.Unlock()
+			if lease.Holder != e.cfg.Identity {
+				return
+			}
+			releaseCtx, stop := context.WithTimeout(context.WithoutCancel(ctx), e.cfg.RenewInterval)
+			defer stop()
+			// Expire the lease so a standby takes over at once.
+			if _, err := e.cfg.Store.Update(releaseCtx, lease, Lease{Duration: e.cfg.Duration}); err != nil && !errors.Is(err, ErrLeaseConflict) {
+				logger().Warn("audittrail: lease release failed", "error", err)
+			}
+		})
+	}
+	return leaderCtx, release, nil
+}
@@ -0,0 +1,174 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryLeaseStore is a LeaseStore with compare-and-swap on Version.
+type memoryLeaseStore struct {
+	mu      sync.Mutex
+	lease   Lease
+	version int
+}
+
+func (s *memoryLeaseStore) Get(context.Context) (Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lease, nil
+}
+
+func (s *memoryLeaseStore) Update(_ context.Context, prev, next Lease) (Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prev.Version != s.lease.Version {
+		return Lease{}, ErrLeaseConflict
+	}
+	s.version++
+	next.Version = strconv.Itoa(s.version)
+	s.lease = next
+	return next, nil
+}
+
+func (s *memoryLeaseStore) steal(holder string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version++
+	s.lease = Lease{Holder: holder, Renewed: time.Now(), Duration: time.Hour, Version: strconv.Itoa(s.version)}
+}
+
+func TestLeaseElectorSingleLeader(t *testing.T) {
+	store := &memoryLeaseStore{}
+	a, _ := NewLeaseElector(LeaseConfig{Store: store, Identity: "a", Duration: 300 * time.Millisecond, RenewInterval: 10 * time.Millisecond})
+	b, _ := NewLeaseElector(LeaseConfig{Store: store, Identity: "b", Duration: 300 * time.Millisecond, RenewInterval: 10 * time.Millisecond})
+	ctx := context.Background()
+
+	leaderCtx, release, err := a.Campaign(ctx)
+	if err != nil {
+		t.Fatalf("Campaign: %v", err)
+	}
+	standby, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := b.Campaign(standby); err == nil {
+		t.Fatal("expected b to stand by while a renews the lease")
+	}
+	if leaderCtx.Err() != nil {
+		t.Fatal("a lost leadership while renewing")
+	}
+
+	release()
+	_, releaseB, err := b.Campaign(ctx)
+	if err != nil {
+		t.Fatalf("b Campaign after release: %v", err)
+	}
+	defer releaseB()
+	if got, _ := store.Get(ctx); got.Holder != "b" {
+		t.Fatalf("expected b to hold the lease, got %+v", got)
+	}
+}
+
+func TestLeaseElectorStepsDownOnConflict(t *testing.T) {
+	store := &memoryLeaseStore{}
+	e, _ := NewLeaseElector(LeaseConfig{Store: store, Identity: "a", Duration: time.Second, RenewInterval: 5 * time.Millisecond})
+	leaderCtx, release, err := e.Campaign(context.Background())
+	if err != nil {
+		t.Fatalf("Campaign: %v", err)
+	}
+	defer release()
+
+	store.steal("b")
+	select {
+	case <-leaderCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected leadership to be lost after the lease was taken")
+	}
+	release()
+	if got, _ := store.Get(context.Background()); got.Holder != "b" {
+		t.Fatalf("release must not clear another holder's lease, got %+v", got)
+	}
+}
+
+func TestRunAsLeaderRecampaignsAfterLoss(t *testing.T) {
+	store := &memoryLeaseStore{}
+	e, _ := NewLeaseElector(LeaseConfig{Store: store, Identity: "a", Duration: 40 * time.Millisecond, RenewInterval: 5 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runs := 0
+	err := RunAsLeader(ctx, e, func(leaderCtx context.Context) error {
+		runs++
+		if runs == 1 {
+			store.steal("b")
+			// b's lease runs for an hour; let it lapse so a can win again.
+			go func() {
+				<-leaderCtx.Done()
+				store.mu.Lock()
+				store.lease.Renewed = time.Now().Add(-2 * time.Hour)
+				store.mu.Unlock()
+			}()
+		} else {
+			cancel()
+		}
+		<-leaderCtx.Done()
+		return nil
+	})
+	if err != nil || runs != 2 {
+		t.Fatalf("RunAsLeader: runs=%d err=%v", runs, err)
+	}
+
+	stop := errors.New("subscriber closed")
+	if err := RunAsLeader(context.Background(), e, func(context.Context) error { return stop }); err != stop {
+		t.Fatalf("expected fn's error when it returns while leading, got %v", err)
+	}
+}
+
+func TestAdvisoryLockElector(t *testing.T) {
+	var mu sync.Mutex
+	var execs []string
+	attempts := 0
+	db := openStubDB(t, &stubDriver{
+		queryFn: func(query string, _ []driver.NamedValue) (driver.Rows, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts++
+			return &stubRows{columns: []string{"pg_try_advisory_lock"}, values: [][]driver.Value{{attempts > 1}}}, nil
+		},
+		execFn: func(query string, _ []driver.NamedValue) (driver.Result, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			execs = append(execs, query)
+			return stubResult{}, nil
+		},
+	})
+	e, err := NewAdvisoryLockElector(AdvisoryLockConfig{DB: db, Name: "audit-consumer", RetryInterval: time.Millisecond, CheckInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewAdvisoryLockElector: %v", err)
+	}
+	leaderCtx, release, err := e.Campaign(context.Background())
+	if err != nil {
+		t.Fatalf("Campaign: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if leaderCtx.Err() != nil {
+		t.Fatal("leadership lost with a healthy session")
+	}
+	release()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 2 {
+		t.Fatalf("expected a retry before acquiring, got %d attempts", attempts)
+	}
+	if len(execs) < 2 || execs[0] != "SELECT 1" || !strings.Contains(execs[len(execs)-1], "pg_advisory_unlock") {
+		t.Fatalf("unexpected statements: %v", execs)
+	}
+	if _, err := NewAdvisoryLockElector(AdvisoryLockConfig{DB: db}); err == nil {
+		t.Fatal("expected error without a lock name")
+	}
+}
@@ -0,0 +1,53 @@
+package audittrail
+
+import (
+	"context"
+	"time"
+)
+
+// Actions of the entries the package records about its own pipeline, so gaps in the trail
+// can be explained during audits (see InitOptions.LifecycleEvents and
+// BreakerConfig.LifecycleRecorder).
+const (
+	ActionPipelineStarted  = "AUDIT_PIPELINE_STARTED"
+	ActionPipelineStopped  = "AUDIT_PIPELINE_STOPPED"
+	ActionPipelineDegraded = "AUDIT_PIPELINE_DEGRADED"
+)
+
+// lifecycleTimeout bounds recording a lifecycle entry, so a failing backend cannot hold
+// up Init or Shutdown.
+const lifecycleTimeout = 5 * time.Second
+
+// WithLifecycleEvents makes InitFromEnv record pipeline lifecycle entries (see
+// InitOptions.LifecycleEvents).
+func WithLifecycleEvents() InitOption {
+	return func(o *InitOptions) {
+		o.LifecycleEvents = true
+	}
+}
+
+// lifecycleEntry builds a lifecycle entry about the window from start to end; a zero start
+// describes a single point in time.
+func lifecycleEntry(action string, severity Severity, start, end time.Time, meta map[string]any) Entry {
+	e := Entry{Action: action, Severity: severity, CreatedDate: end, Meta: meta}
+	if !start.IsZero() {
+		e.StartedAt, e.EndedAt = start, end
+	}
+	return e
+}
+
+// recordLifecycle records a lifecycle entry through rec. Failures are logged rather than
+// returned: the pipeline being unhealthy is when these entries are written.
+func recordLifecycle(ctx context.Context, rec Recorder, entry Entry) {
+	if rec == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), lifecycleTimeout)
+	defer cancel()
+	if err := rec.Record(ctx, entry); err != nil {
+		logger().Warn("audittrail: lifecycle entry not recorded", "action", entry.Action, "error", err)
+	}
+}
@@ -0,0 +1,107 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestInitRecordsLifecycleEntries(t *testing.T) {
+	actions := make(chan string, 4)
+	driverName := fmt.Sprintf("audittrail_stub_%s_%d", t.Name(), time.Now().UnixNano())
+	sql.Register(driverName, &stubDriver{execFn: func(_ string, args []driver.NamedValue) (driver.Result, error) {
+		actions <- stringArg(args, 2)
+		return stubResult{}, nil
+	}})
+	t.Setenv(envTransport, TransportMemory)
+	t.Setenv(envDBDriver, driverName)
+
+	ctx := context.Background()
+	if err := InitFromEnv(ctx, WithLifecycleEvents(), func(o *InitOptions) { o.DisableInstanceFields = true }); err != nil {
+		t.Fatalf("InitFromEnv: %v", err)
+	}
+	select {
+	case action := <-actions:
+		if action != ActionPipelineStarted {
+			t.Fatalf("expected %s, got %s", ActionPipelineStarted, action)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("start was not recorded")
+	}
+
+	// Capture what Shutdown publishes, as the consumer stops right after.
+	var stopped Entry
+	runtime.mu.Lock()
+	published := runtime.recorder
+	runtime.recorder = RecorderFunc(func(ctx context.Context, e Entry) error {
+		stopped = e
+		return published.Record(ctx, e)
+	})
+	runtime.mu.Unlock()
+	if err := Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if stopped.Action != ActionPipelineStopped || stopped.StartedAt.IsZero() || stopped.EndedAt.Before(stopped.StartedAt) {
+		t.Fatalf("unexpected stop entry: %+v", stopped)
+	}
+}
+
+func TestCircuitBreakerRecordsTripAndRecovery(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	now := start
+	entries := make(chan Entry, 2)
+	b := NewCircuitBreaker(BreakerConfig{
+		FailureThreshold: 1,
+		OpenTimeout:      time.Minute,
+		Now:              func() time.Time { return now },
+		LifecycleRecorder: RecorderFunc(func(_ context.Context, e Entry) error {
+			entries <- e
+			return nil
+		}),
+	})
+	next := func() Entry {
+		t.Helper()
+		select {
+		case e := <-entries:
+			return e
+		case <-time.After(time.Second):
+			t.Fatal("lifecycle entry was not recorded")
+			return Entry{}
+		}
+	}
+	ctx := context.Background()
+
+	_ = b.Do(ctx, func(context.Context) error { return fmt.Errorf("db down") })
+	tripped := next()
+	if tripped.Action != ActionPipelineDegraded || tripped.Meta["phase"] != "tripped" ||
+		!tripped.CreatedDate.Equal(start) || !tripped.StartedAt.IsZero() || tripped.Meta["failures"] != 1 {
+		t.Fatalf("unexpected trip entry: %+v", tripped)
+	}
+
+	_ = b.Do(ctx, func(context.Context) error { return nil }) // rejected while open
+	now = now.Add(2 * time.Minute)
+	if err := b.Do(ctx, func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("trial call: %v", err)
+	}
+	recovered := next()
+	if recovered.Action != ActionPipelineDegraded || recovered.Severity != SeverityWarn || recovered.Meta["phase"] != "recovered" ||
+		!recovered.StartedAt.Equal(start) || !recovered.EndedAt.Equal(start.Add(2*time.Minute)) || recovered.Meta["rejected"] != 1 {
+		t.Fatalf("unexpected recovery entry: %+v", recovered)
+	}
+}
+
+func TestLifecycleEntriesStrictActions(t *testing.T) {
+	useStrictActions(t)
+	rec, err := NewPubSubRecorder(PublisherFunc(func(context.Context, Entry) error { return nil }), nil)
+	if err != nil {
+		t.Fatalf("NewPubSubRecorder: %v", err)
+	}
+	for _, action := range []string{ActionPipelineStarted, ActionPipelineStopped, ActionPipelineDegraded} {
+		if err := rec.Record(context.Background(), lifecycleEntry(action, SeverityInfo, time.Time{}, time.Now(), nil)); err != nil {
+			t.Fatalf("%s rejected in strict mode: %v", action, err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoSQLiteDriver is returned by NewLocal when the application has not imported a SQLite
+// driver.
+var ErrNoSQLiteDriver = errors.New("audittrail: no SQLite driver registered; import modernc.org/sqlite (pure Go) or github.com/mattn/go-sqlite3 (cgo)")
+
+// sqliteDrivers lists the database/sql driver names registered by the common SQLite packages,
+// in order of preference: modernc.org/sqlite (pure Go) and github.com/mattn/go-sqlite3 (cgo).
+var sqliteDrivers = []string{"sqlite", "sqlite3"}
+
+// NewLocal opens (or creates) a SQLite database file at path, ensures the audit table exists,
+// and returns a ready-to-use AuditTrail. It is meant for quickstarts and integration tests
+// that should not depend on Postgres or Pub/Sub. Use ":memory:" for a throwaway database.
+//
+// NewLocal needs no server, environment or configuration, but it is not dependency-free:
+// the module does not bundle a SQLite driver, so services on Postgres or MySQL do not pay
+// for one. The application must blank-import a driver, or NewLocal returns
+// ErrNoSQLiteDriver:
+//
+//	import _ "modernc.org/sqlite"           // pure Go
+//	import _ "github.com/mattn/go-sqlite3"  // cgo
+//
+// Call Close on the returned AuditTrail to release the database.
+func NewLocal(path string) (*AuditTrail, error) {
+	if strings.TrimSpace(path) == "" {
+		return nil, errors.New("audittrail: local database path must not be empty")
+	}
+
+	driver := registeredSQLiteDriver()
+	if driver == "" {
+		return nil, ErrNoSQLiteDriver
+	}
+
+	db, err := sql.Open(driver, path)
+	if err != nil {
+		return nil, fmt.Errorf("audittrail: open local database failed: %w", err)
+	}
+	// SQLite allows a single writer; a single connection also keeps ":memory:" databases shared.
+	db.SetMaxOpenConns(1)
+
+	audit, err := NewAuditTrail(Config{
+		DB:          db,
+		Placeholder: PlaceholderQuestion,
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	audit.ownsDB = true
+
+	if err := audit.EnsureTable(context.Background()); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("audittrail: create local table failed: %w", err)
+	}
+	return audit, nil
+}
+
+func registeredSQLiteDriver() string {
+	registered := make(map[string]bool)
+	for _, name := range sql.Drivers() {
+		registered[name] = true
+	}
+	for _, name := range sqliteDrivers {
+		if registered[name] {
+			return name
+		}
+	}
+	return ""
+}
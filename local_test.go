@@ -0,0 +1,44 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewLocalEnsuresTable(t *testing.T) {
+	if registeredSQLiteDriver() == "" {
+		if _, err := NewLocal("audit.db"); !errors.Is(err, ErrNoSQLiteDriver) {
+			t.Fatalf("expected ErrNoSQLiteDriver when no SQLite driver is registered, got %v", err)
+		}
+
+		var calls []execCall
+		sql.Register("sqlite3", &stubDriver{
+			execFn: func(query string, args []driver.NamedValue) (driver.Result, error) {
+				calls = append(calls, execCall{query: query, args: args})
+				return stubResult{}, nil
+			},
+			queryFn: func(string, []driver.NamedValue) (driver.Rows, error) {
+				return &stubRows{columns: auditColumnNames()}, nil
+			},
+		})
+		defer func() {
+			if len(calls) == 0 || !strings.Contains(calls[0].query, "CREATE TABLE IF NOT EXISTS audit_trail") {
+				t.Fatalf("expected table creation, got %+v", calls)
+			}
+		}()
+	}
+
+	audit, err := NewLocal(":memory:")
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	defer audit.Close()
+
+	if err := audit.Record(context.Background(), Entry{Action: "local-test"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package audittrail
+
+import "context"
+
+// SugaredLogger is the subset of *zap.SugaredLogger used by ZapRecorder.
+type SugaredLogger interface {
+	Infow(msg string, keysAndValues ...any)
+}
+
+// ZapRecorder writes entries as structured fields to a zap logger, for teams whose audit
+// store is their centralized logging system. Pass logger.Sugar() from go.uber.org/zap.
+type ZapRecorder struct {
+	logger SugaredLogger
+}
+
+// NewZapRecorder creates a recorder backed by a zap sugared logger.
+func NewZapRecorder(l SugaredLogger) *ZapRecorder {
+	return &ZapRecorder{logger: l}
+}
+
+// Record validates the entry and logs it with message "audit".
+func (z *ZapRecorder) Record(_ context.Context, entry Entry) error {
+	normalized, err := normalizeEntry(entry, nil)
+	if err != nil {
+		return err
+	}
+	fields := entryFields(normalized)
+	kv := make([]any, 0, len(fields)*2)
+	for _, key := range entryFieldOrder {
+		if v, ok := fields[key]; ok {
+			kv = append(kv, key, v)
+		}
+	}
+	z.logger.Infow("audit", kv...)
+	return nil
+}
+
+// FieldLogFunc logs a message with structured fields. For logrus:
+//
+//	func(fields map[string]any, msg string) { logrus.WithFields(fields).Info(msg) }
+type FieldLogFunc func(fields map[string]any, msg string)
+
+// LogrusRecorder writes entries as structured fields through a FieldLogFunc, which keeps
+// this package free of a logrus dependency.
+type LogrusRecorder struct {
+	log FieldLogFunc
+}
+
+// NewLogrusRecorder creates a recorder that logs each entry through fn.
+func NewLogrusRecorder(fn FieldLogFunc) *LogrusRecorder {
+	return &LogrusRecorder{log: fn}
+}
+
+// Record validates the entry and logs it with message "audit".
+func (l *LogrusRecorder) Record(_ context.Context, entry Entry) error {
+	normalized, err := normalizeEntry(entry, nil)
+	if err != nil {
+		return err
+	}
+	l.log(entryFields(normalized), "audit")
+	return nil
+}
+
+// entryFieldOrder fixes the field order for key/value loggers.
+var entryFieldOrder = []string{
+	"log_audit_trail_id", "log_action", "log_created_date", "log_req_id",
+	"log_endpoint", "log_created_by", "log_request", "log_response", "log_expires_at",
+	"log_severity", "log_parent_id", "log_correlation_id",
+	"log_session_id", "log_app_version", "log_hostname", "log_instance_id",
+	"log_impersonated_by", "log_actor", "log_ip_address", "log_service_name",
+	"log_started_at", "log_ended_at", "log_duration_ms", "log_entity_type", "log_entity_id",
+	"log_meta",
+}
+
+// entryFields flattens an entry into log fields, omitting empty optional values.
+func entryFields(e Entry) map[string]any {
+	attrs := entryAttrs(e)
+	fields := make(map[string]any, len(attrs))
+	for _, attr := range attrs {
+		fields[attr.Key] = attr.Value.Any()
+	}
+	return fields
+}
@@ -0,0 +1,41 @@
+package audittrail
+
+import (
+	"context"
+	"testing"
+)
+
+type fakeSugared struct {
+	msg string
+	kv  []any
+}
+
+func (f *fakeSugared) Infow(msg string, kv ...any) { f.msg, f.kv = msg, kv }
+
+func TestZapAndLogrusRecorders(t *testing.T) {
+	ctx := context.Background()
+	entry := Entry{Action: "DELETE_USER", CreatedBy: "admin-1", Endpoint: "/users/9"}
+
+	zl := &fakeSugared{}
+	if err := NewZapRecorder(zl).Record(ctx, entry); err != nil {
+		t.Fatalf("zap Record: %v", err)
+	}
+	if zl.msg != "audit" || len(zl.kv)%2 != 0 || zl.kv[0] != "log_audit_trail_id" || zl.kv[2] != "log_action" || zl.kv[3] != "DELETE_USER" {
+		t.Fatalf("unexpected zap fields: %v", zl.kv)
+	}
+
+	var fields map[string]any
+	rec := NewLogrusRecorder(func(f map[string]any, msg string) { fields = f })
+	if err := rec.Record(ctx, entry); err != nil {
+		t.Fatalf("logrus Record: %v", err)
+	}
+	if fields["log_created_by"] != "admin-1" || fields["log_endpoint"] != "/users/9" {
+		t.Fatalf("unexpected logrus fields: %v", fields)
+	}
+	if _, ok := fields["log_request"]; ok {
+		t.Fatalf("expected empty request to be omitted: %v", fields)
+	}
+	if err := rec.Record(ctx, Entry{}); err == nil {
+		t.Fatal("expected validation error")
+	}
+}
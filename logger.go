@@ -0,0 +1,166 @@
+package audittrail
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// Logger receives the package's own diagnostics (consumer errors, failed publishes, ...).
+// *slog.Logger satisfies it; adapt zerolog/zap loggers with a small wrapper.
+type Logger interface {
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+var logging struct {
+	mu     sync.RWMutex
+	logger Logger
+}
+
+// SetLogger replaces the package logger. Passing nil restores the default, slog.Default().
+func SetLogger(l Logger) {
+	logging.mu.Lock()
+	logging.logger = l
+	logging.mu.Unlock()
+}
+
+// logger returns the configured Logger. The default is resolved on every call so a later
+// slog.SetDefault in the application is honored.
+func logger() Logger {
+	logging.mu.RLock()
+	l := logging.logger
+	logging.mu.RUnlock()
+	if l == nil {
+		return slog.Default()
+	}
+	return l
+}
+
+// InitOption configures InitFromEnv.
+type InitOption func(*InitOptions)
+
+// WithLogger routes the package's diagnostics to l (see SetLogger).
+func WithLogger(l Logger) InitOption {
+	return func(o *InitOptions) {
+		o.Logger = l
+	}
+}
+
+// SlogRecorder emits audit entries as structured log records, so they can flow into an
+// existing logging pipeline in addition to (see MultiRecorder) or instead of a database.
+type SlogRecorder struct {
+	logger *slog.Logger
+	level  slog.Level
+}
+
+// NewSlogRecorder creates a recorder that writes each entry to h at the given level.
+func NewSlogRecorder(h slog.Handler, level slog.Level) *SlogRecorder {
+	return &SlogRecorder{logger: slog.New(h), level: level}
+}
+
+// Record validates the entry and logs it with message "audit" and one attribute per field.
+func (s *SlogRecorder) Record(ctx context.Context, entry Entry) error {
+	normalized, err := normalizeEntry(entry, nil)
+	if err != nil {
+		return err
+	}
+	s.logger.LogAttrs(ctx, s.level, "audit", entryAttrs(normalized)...)
+	return nil
+}
+
+func entryAttrs(e Entry) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("log_audit_trail_id", e.ID),
+		slog.String("log_action", e.Action),
+		slog.Time("log_created_date", e.CreatedDate),
+	}
+	if e.RequestID != "" {
+		attrs = append(attrs, slog.String("log_req_id", e.RequestID))
+	}
+	if e.Endpoint != "" {
+		attrs = append(attrs, slog.String("log_endpoint", e.Endpoint))
+	}
+	if e.CreatedBy != "" {
+		attrs = append(attrs, slog.String("log_created_by", e.CreatedBy))
+	}
+	if e.Request != nil {
+		attrs = append(attrs, slog.Any("log_request", e.Request))
+	}
+	if e.Response != nil {
+		attrs = append(attrs, slog.Any("log_response", e.Response))
+	}
+	if !e.ExpiresAt.IsZero() {
+		attrs = append(attrs, slog.Time("log_expires_at", e.ExpiresAt))
+	}
+	if e.Severity != "" {
+		attrs = append(attrs, slog.String("log_severity", string(e.Severity)))
+	}
+	if e.ParentID != "" {
+		attrs = append(attrs, slog.String("log_parent_id", e.ParentID))
+	}
+	if e.CorrelationID != "" {
+		attrs = append(attrs, slog.String("log_correlation_id", e.CorrelationID))
+	}
+	if e.SessionID != "" {
+		attrs = append(attrs, slog.String("log_session_id", e.SessionID))
+	}
+	if e.AppVersion != "" {
+		attrs = append(attrs, slog.String("log_app_version", e.AppVersion))
+	}
+	if e.Hostname != "" {
+		attrs = append(attrs, slog.String("log_hostname", e.Hostname))
+	}
+	if e.InstanceID != "" {
+		attrs = append(attrs, slog.String("log_instance_id", e.InstanceID))
+	}
+	if e.ImpersonatedBy != "" {
+		attrs = append(attrs, slog.String("log_impersonated_by", e.ImpersonatedBy))
+	}
+	if e.Actor != "" {
+		attrs = append(attrs, slog.String("log_actor", e.Actor))
+	}
+	if e.IPAddress != "" {
+		attrs = append(attrs, slog.String("log_ip_address", e.IPAddress))
+	}
+	if e.ServiceName != "" {
+		attrs = append(attrs, slog.String("log_service_name", e.ServiceName))
+	}
+	if !e.StartedAt.IsZero() {
+		attrs = append(attrs, slog.Time("log_started_at", e.StartedAt))
+	}
+	if !e.EndedAt.IsZero() {
+		attrs = append(attrs, slog.Time("log_ended_at", e.EndedAt))
+	}
+	if e.DurationMS != 0 {
+		attrs = append(attrs, slog.Int64("log_duration_ms", e.DurationMS))
+	}
+	if e.EntityType != "" {
+		attrs = append(attrs, slog.String("log_entity_type", e.EntityType))
+	}
+	if e.EntityID != "" {
+		attrs = append(attrs, slog.String("log_entity_id", e.EntityID))
+	}
+	if len(e.Meta) > 0 {
+		attrs = append(attrs, slog.Any("log_meta", e.Meta))
+	}
+	return attrs
+}
+
+// MultiRecorder records every entry to all recorders, returning the first error after
+// trying each of them.
+func MultiRecorder(recorders ...Recorder) Recorder {
+	return RecorderFunc(func(ctx context.Context, entry Entry) error {
+		var first error
+		for _, rec := range recorders {
+			if rec == nil {
+				continue
+			}
+			if err := rec.Record(ctx, entry); err != nil && first == nil {
+				first = err
+			}
+		}
+		return first
+	})
+}
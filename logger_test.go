@@ -0,0 +1,48 @@
+package audittrail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSlogRecorderEmitsStructuredEntry(t *testing.T) {
+	var buf bytes.Buffer
+	rec := NewSlogRecorder(slog.NewJSONHandler(&buf, nil), slog.LevelInfo)
+
+	err := MultiRecorder(rec, RecorderFunc(func(context.Context, Entry) error { return nil })).
+		Record(context.Background(), Entry{Action: "CREATE_ORDER", CreatedBy: "u1", Request: map[string]any{"qty": 2}})
+	if err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("decode log line %q: %v", buf.String(), err)
+	}
+	if line["msg"] != "audit" || line["log_action"] != "CREATE_ORDER" || line["log_created_by"] != "u1" || line["log_audit_trail_id"] == "" {
+		t.Fatalf("unexpected log line: %v", line)
+	}
+	if req, ok := line["log_request"].(map[string]any); !ok || req["qty"] != float64(2) {
+		t.Fatalf("unexpected request attribute: %v", line["log_request"])
+	}
+}
+
+func TestSetLoggerReceivesDiagnostics(t *testing.T) {
+	var buf bytes.Buffer
+	SetLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer SetLogger(nil)
+
+	failing := RecorderFunc(func(context.Context, Entry) error { return errors.New("boom") })
+	handler := HTTPMiddleware(failing)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/x", nil))
+
+	if !bytes.Contains(buf.Bytes(), []byte("middleware record failed")) || !bytes.Contains(buf.Bytes(), []byte("boom")) {
+		t.Fatalf("expected diagnostic in custom logger, got %q", buf.String())
+	}
+}
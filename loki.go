@@ -0,0 +1,242 @@
+package audittrail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrBufferFull is reported to OnDrop hooks when a batching recorder has to discard
+// entries because its sink has been unreachable for too long.
+var ErrBufferFull = errors.New("audittrail: buffer full")
+
+// LokiConfig configures a LokiRecorder.
+type LokiConfig struct {
+	URL           string            // push endpoint, e.g. http://loki:3100/loki/api/v1/push
+	Service       string            // "service" label
+	Tenant        string            // "tenant" label and X-Scope-OrgID header for multi-tenant Loki
+	Labels        map[string]string // extra static labels
+	BatchSize     int               // entries per push; default 100
+	MaxPending    int               // entries kept while Loki is unreachable; default 10 * BatchSize
+	FlushInterval time.Duration     // used by Run; default 5s
+	Client        *http.Client
+}
+
+// LokiRecorder pushes entries to Grafana Loki so audit events can be explored alongside
+// application logs. Entries are batched and grouped into streams labeled by service,
+// action and tenant; each log line is the entry's JSON encoding.
+type LokiRecorder struct {
+	cfg    LokiConfig
+	client *http.Client
+
+	mu      sync.Mutex
+	pending []Entry
+}
+
+// NewLokiRecorder creates a Loki recorder. Call Run (or Flush periodically) so partial
+// batches are delivered, and Close on shutdown.
+func NewLokiRecorder(cfg LokiConfig) (*LokiRecorder, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("audittrail: Loki URL must not be empty")
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.MaxPending <= 0 {
+		cfg.MaxPending = 10 * cfg.BatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &LokiRecorder{cfg: cfg, client: client}, nil
+}
+
+// Record queues the entry and pushes a batch once BatchSize entries are waiting.
+func (l *LokiRecorder) Record(ctx context.Context, entry Entry) error {
+	normalized, err := normalizeEntry(entry, nil)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.pending = append(l.pending, normalized)
+	full := len(l.pending) >= l.cfg.BatchSize
+	l.mu.Unlock()
+	if full {
+		return l.Flush(ctx)
+	}
+	return nil
+}
+
+// Flush pushes all queued entries. On failure they are kept for the next attempt, up to
+// MaxPending; older entries beyond that are reported to OnDrop hooks with ErrBufferFull.
+func (l *LokiRecorder) Flush(ctx context.Context) error {
+	l.mu.Lock()
+	batch := l.pending
+	l.pending = nil
+	l.mu.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	err := l.push(ctx, batch)
+	if err == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	l.pending = append(batch, l.pending...)
+	var dropped []Entry
+	if over := len(l.pending) - l.cfg.MaxPending; over > 0 {
+		dropped = l.pending[:over]
+		l.pending = l.pending[over:]
+	}
+	l.mu.Unlock()
+	for _, entry := range dropped {
+		reportDrop(entry, ErrBufferFull)
+	}
+	return err
+}
+
+// Pending reports how many entries are waiting to be pushed.
+func (l *LokiRecorder) Pending() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.pending)
+}
+
+// Run flushes every FlushInterval until ctx is canceled, then makes a final flush.
+func (l *LokiRecorder) Run(ctx context.Context) error {
+	ticker := time.NewTicker(l.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), l.cfg.FlushInterval)
+			defer cancel()
+			return l.Flush(flushCtx)
+		case <-ticker.C:
+			if err := l.Flush(ctx); err != nil {
+				logger().Warn("audittrail: Loki push failed", "error", err)
+			}
+		}
+	}
+}
+
+// Close pushes any remaining entries.
+func (l *LokiRecorder) Close(ctx context.Context) error {
+	return l.Flush(ctx)
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func (l *LokiRecorder) push(ctx context.Context, batch []Entry) error {
+	streams := map[string]*lokiStream{}
+	var keys []string
+	for _, entry := range batch {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			reportDrop(entry, marshalFailed("entry", err))
+			continue
+		}
+		labels := l.labels(entry)
+		key := labelKey(labels)
+		s, ok := streams[key]
+		if !ok {
+			s = &lokiStream{Stream: labels}
+			streams[key] = s
+			keys = append(keys, key)
+		}
+		s.Values = append(s.Values, [2]string{strconv.FormatInt(entry.CreatedDate.UnixNano(), 10), string(line)})
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	payload := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{}
+	for _, key := range keys {
+		s := streams[key]
+		sort.SliceStable(s.Values, func(i, j int) bool {
+			a, _ := strconv.ParseInt(s.Values[i][0], 10, 64)
+			b, _ := strconv.ParseInt(s.Values[j][0], 10, 64)
+			return a < b
+		})
+		payload.Streams = append(payload.Streams, s)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("audittrail: encode Loki push: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if l.cfg.Tenant != "" {
+		req.Header.Set("X-Scope-OrgID", l.cfg.Tenant)
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("audittrail: Loki push: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("audittrail: Loki push: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// labels builds the stream labels for an entry. Only low-cardinality values are used;
+// IDs and actors stay in the log line.
+func (l *LokiRecorder) labels(entry Entry) map[string]string {
+	labels := make(map[string]string, len(l.cfg.Labels)+3)
+	for k, v := range l.cfg.Labels {
+		labels[k] = v
+	}
+	labels["source"] = "audittrail"
+	labels["action"] = entry.Action
+	if entry.Severity != "" {
+		labels["severity"] = string(entry.Severity)
+	}
+	if l.cfg.Service != "" {
+		labels["service"] = l.cfg.Service
+	}
+	if l.cfg.Tenant != "" {
+		labels["tenant"] = l.cfg.Tenant
+	}
+	return labels
+}
+
+func labelKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	for _, k := range names {
+		buf.WriteString(k)
+		buf.WriteByte('=')
+		buf.WriteString(strconv.Quote(labels[k]))
+		buf.WriteByte(',')
+	}
+	return buf.String()
+}
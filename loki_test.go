@@ -0,0 +1,60 @@
+package audittrail
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLokiRecorderBatchesStreams(t *testing.T) {
+	var pushes []map[string][]lokiStream
+	var tenant string
+	fail := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		tenant = r.Header.Get("X-Scope-OrgID")
+		var body map[string][]lokiStream
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode push: %v", err)
+		}
+		pushes = append(pushes, body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	rec, err := NewLokiRecorder(LokiConfig{URL: srv.URL, Service: "orders", Tenant: "acme", BatchSize: 3})
+	if err != nil {
+		t.Fatalf("NewLokiRecorder: %v", err)
+	}
+	ctx := context.Background()
+	for _, action := range []string{"CREATE", "DELETE", "CREATE"} {
+		if err := rec.Record(ctx, Entry{Action: action}); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	if len(pushes) != 1 || rec.Pending() != 0 {
+		t.Fatalf("expected one push at batch size, got %d (pending %d)", len(pushes), rec.Pending())
+	}
+	streams := pushes[0]["streams"]
+	if len(streams) != 2 || tenant != "acme" {
+		t.Fatalf("expected 2 streams for tenant acme, got %d (%q)", len(streams), tenant)
+	}
+	if s := streams[0]; s.Stream["action"] != "CREATE" || s.Stream["service"] != "orders" || len(s.Values) != 2 {
+		t.Fatalf("unexpected stream: %+v", s)
+	}
+
+	fail = true
+	_ = rec.Record(ctx, Entry{Action: "UPDATE"})
+	if err := rec.Flush(ctx); err == nil || rec.Pending() != 1 {
+		t.Fatalf("expected failed flush to keep entry, err=%v pending=%d", err, rec.Pending())
+	}
+	fail = false
+	if err := rec.Close(ctx); err != nil || rec.Pending() != 0 || len(pushes) != 2 {
+		t.Fatalf("expected retry to deliver, err=%v pending=%d pushes=%d", err, rec.Pending(), len(pushes))
+	}
+}
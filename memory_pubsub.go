@@ -0,0 +1,80 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrPubSubClosed is returned when publishing to a closed MemoryPubSub.
+var ErrPubSubClosed = errors.New("audittrail: pubsub is closed")
+
+// MemoryPubSub is an in-process Publisher and Subscriber backed by a buffered channel.
+// It is intended for tests and local development where a real broker is not available.
+type MemoryPubSub struct {
+	ch        chan Entry
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewMemoryPubSub creates an in-memory queue that holds up to buffer entries.
+// Publish blocks when the buffer is full until a receiver catches up or ctx is done.
+func NewMemoryPubSub(buffer int) *MemoryPubSub {
+	if buffer < 0 {
+		buffer = 0
+	}
+	return &MemoryPubSub{
+		ch:   make(chan Entry, buffer),
+		done: make(chan struct{}),
+	}
+}
+
+// Publish enqueues an entry for receivers.
+func (m *MemoryPubSub) Publish(ctx context.Context, entry Entry) error {
+	select {
+	case <-m.done:
+		return ErrPubSubClosed
+	default:
+	}
+	select {
+	case m.ch <- entry:
+		return nil
+	case <-m.done:
+		return ErrPubSubClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Receive delivers entries to handler until ctx is canceled or Close is called.
+// Handler errors are logged and the entry is not redelivered.
+func (m *MemoryPubSub) Receive(ctx context.Context, handler func(context.Context, Entry) error) error {
+	for {
+		select {
+		case entry := <-m.ch:
+			if err := handler(ctx, entry); err != nil {
+				logger().Error("audittrail: handler failed", "entry_id", entry.ID, "error", err)
+			}
+		case <-m.done:
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Len reports how many entries are waiting to be received.
+func (m *MemoryPubSub) Len() int {
+	return len(m.ch)
+}
+
+// Backlog implements BacklogReporter for Consumer.Stats.
+func (m *MemoryPubSub) Backlog() (int64, error) {
+	return int64(len(m.ch)), nil
+}
+
+// Close stops all receivers and rejects further publishes.
+func (m *MemoryPubSub) Close() error {
+	m.closeOnce.Do(func() { close(m.done) })
+	return nil
+}
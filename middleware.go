@@ -1,9 +1,11 @@
 package audittrail
 
 import (
-	"log"
+	"bufio"
+	"io"
 	"net"
 	"net/http"
+	"net/netip"
 	"strings"
 	"time"
 )
@@ -14,19 +16,31 @@ type HTTPMiddlewareOption func(*httpMiddlewareConfig)
 type httpMiddlewareConfig struct {
 	requestIDHeader string
 	actorHeader     string
+	impersonator    func(*http.Request) string
 	ipHeader        string
+	trustedProxies  []netip.Prefix // ipHeader is only read from these peers
+	serviceName     string
 	action          func(*http.Request) string
 	requestPayload  func(*http.Request) any
 	responsePayload func(int) any
 	onError         func(error)
 	now             func() time.Time
+	routeSeverity   []routeSeverity
+	session         func(*http.Request) string
+	maxBodySize     int64 // capture request bodies up to this size; 0 disables
+	redaction       payloadRedaction
+	routeRedaction  []routeRedaction
+	uploadPolicy    UploadPolicy
 }
 
 func defaultHTTPConfig() httpMiddlewareConfig {
 	return httpMiddlewareConfig{
 		requestIDHeader: "X-Request-Id",
 		actorHeader:     "X-User-Id",
-		ipHeader:        "X-Forwarded-For",
+		impersonator: func(r *http.Request) string {
+			return headerValue(r, "X-Impersonated-By")
+		},
+		ipHeader: "X-Forwarded-For",
 		action: func(r *http.Request) string {
 			return strings.TrimSpace(r.Method + " " + r.URL.Path)
 		},
@@ -35,7 +49,7 @@ func defaultHTTPConfig() httpMiddlewareConfig {
 		},
 		responsePayload: nil,
 		onError: func(err error) {
-			log.Printf("audittrail: middleware record failed: %v", err)
+			logger().Error("audittrail: middleware record failed", "error", err)
 		},
 		now: time.Now,
 	}
@@ -59,24 +73,72 @@ func HTTPMiddleware(recorder Recorder, opts ...HTTPMiddlewareOption) func(http.H
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
 			start := cfg.now().UTC()
+			ctx, audit := withRequestAudit(r.Context(), r.Method, r.URL.Path)
+			r = r.WithContext(ctx)
+
+			var body any
+			var upload *uploadCapture
+			if cfg.maxBodySize > 0 && shouldCaptureBody(r.Method) {
+				if upload, r.Body = captureUpload(r, cfg.uploadPolicy); upload == nil {
+					body, r.Body = captureBody(r.Body, cfg.maxBodySize)
+				}
+			}
 
-			next.ServeHTTP(rec, r)
+			recovered, report := callRecovering(func() { next.ServeHTTP(rec, r) })
+			// ServeMux sets the pattern that matched on the request it was given.
+			audit.setRoute(parseCoverageRoute(r.Pattern).path)
+			end := cfg.now().UTC()
+			if report != nil {
+				rec.status = http.StatusInternalServerError
+			}
+			if upload != nil {
+				body = upload.finish()
+			}
 
 			entry := Entry{
 				RequestID:   headerValue(r, cfg.requestIDHeader),
 				Action:      cfg.action(r),
 				Endpoint:    r.URL.Path,
-				Request:     cfg.requestPayload(r),
+				Request:     body,
 				Response:    nil,
 				CreatedDate: start,
-				CreatedBy:   headerValue(r, cfg.actorHeader),
+				StartedAt:   start,
+				EndedAt:     end,
+				DurationMS:  end.Sub(start).Milliseconds(),
+				ServiceName: cfg.serviceName,
+
+				ImpersonatedBy: cfg.impersonator(r),
+				Actor:          headerValue(r, cfg.actorHeader),
+				IPAddress:      cfg.clientIP(r),
+			}
+			if entry.Request == nil {
+				entry.Request = cfg.requestPayload(r)
+			}
+			if cfg.session != nil {
+				entry.SessionID = cfg.session(r)
 			}
-			if cfg.responsePayload != nil {
+			if report != nil {
+				entry.Response = *report
+			} else if rec.stream.streaming(w.Header()) {
+				entry.Response = rec.stream.summary(r, w.Header())
+			} else if cfg.responsePayload != nil {
 				entry.Response = cfg.responsePayload(rec.status)
 			}
+			redactionFor(cfg.redaction, cfg.routeRedaction, r.Method, r.URL.Path).apply(&entry)
+			if sev, ok := matchRouteSeverity(cfg.routeSeverity, r.Method, r.URL.Path); ok {
+				entry.Severity = sev
+			} else {
+				entry.Severity = DefaultSeverity(r.Method, rec.status)
+			}
 
-			if err := recorder.Record(r.Context(), entry); err != nil && cfg.onError != nil {
-				cfg.onError(err)
+			entries := audit.flush(r.Context(), entry)
+			if err := recordEntries(r.Context(), recorder, entries); err != nil {
+				if cfg.onError != nil {
+					cfg.onError(err)
+				}
+			}
+			if recovered != nil {
+				panic(recovered)
 			}
 		})
 	}
@@ -89,20 +151,70 @@ func WithRequestIDHeader(name string) HTTPMiddlewareOption {
 	}
 }
 
-// WithActorHeader sets which header contains the actor/user ID. Default: X-User-Id.
+// WithActorHeader sets which header contains the actor/user ID stored in Actor. Default: X-User-Id.
 func WithActorHeader(name string) HTTPMiddlewareOption {
 	return func(c *httpMiddlewareConfig) {
 		c.actorHeader = name
 	}
 }
 
-// WithIPHeader sets which header contains the client IP. Default: X-Forwarded-For.
+// WithIPHeader sets which header contains the client IP stored in IPAddress when the peer is
+// a trusted proxy (see WithTrustedProxies). Default: X-Forwarded-For.
 func WithIPHeader(name string) HTTPMiddlewareOption {
 	return func(c *httpMiddlewareConfig) {
 		c.ipHeader = name
 	}
 }
 
+// WithTrustedProxies sets the proxies allowed to report the client IP: the IP header is only
+// read when the peer is one of these addresses or CIDR ranges (e.g. "10.0.0.0/8" for a load
+// balancer), and the client is the right-most valid address in it that is not a trusted
+// proxy. Invalid entries are logged and ignored. Without trusted proxies the header is
+// ignored and the peer address is used, as any client can set it.
+func WithTrustedProxies(proxies ...string) HTTPMiddlewareOption {
+	return func(c *httpMiddlewareConfig) {
+		for _, p := range proxies {
+			prefix, err := parseProxy(p)
+			if err != nil {
+				logger().Warn("audittrail: ignoring invalid trusted proxy", "proxy", p, "error", err)
+				continue
+			}
+			c.trustedProxies = append(c.trustedProxies, prefix)
+		}
+	}
+}
+
+// WithHTTPServiceName sets the service recorded in ServiceName (Gin: WithServiceName).
+// Default: CurrentInstance().ServiceName, filled in by InstanceEnricher.
+func WithHTTPServiceName(name string) HTTPMiddlewareOption {
+	return func(c *httpMiddlewareConfig) {
+		c.serviceName = name
+	}
+}
+
+// WithSessionCookie reads the session ID from the named cookie.
+func WithSessionCookie(name string) HTTPMiddlewareOption {
+	return func(c *httpMiddlewareConfig) {
+		c.session = func(r *http.Request) string {
+			if cookie, err := r.Cookie(name); err == nil {
+				return cookie.Value
+			}
+			return ""
+		}
+	}
+}
+
+// WithSessionContextKey reads the session ID from the request context value stored under
+// key by an outer middleware.
+func WithSessionContextKey(key any) HTTPMiddlewareOption {
+	return func(c *httpMiddlewareConfig) {
+		c.session = func(r *http.Request) string {
+			id, _ := r.Context().Value(key).(string)
+			return id
+		}
+	}
+}
+
 // WithAction customizes how the Action field is generated.
 func WithAction(fn func(*http.Request) string) HTTPMiddlewareOption {
 	return func(c *httpMiddlewareConfig) {
@@ -121,6 +233,15 @@ func WithRequestPayload(fn func(*http.Request) any) HTTPMiddlewareOption {
 	}
 }
 
+// WithRequestBody captures POST, PUT and PATCH bodies up to maxBytes as the request
+// payload. JSON is validated and stored as json.RawMessage without being decoded;
+// other bodies are stored as text. It takes precedence over WithRequestPayload.
+func WithRequestBody(maxBytes int64) HTTPMiddlewareOption {
+	return func(c *httpMiddlewareConfig) {
+		c.maxBodySize = maxBytes
+	}
+}
+
 // WithResponsePayload sets how the response payload is derived (default captures status code).
 func WithResponsePayload(fn func(status int) any) HTTPMiddlewareOption {
 	return func(c *httpMiddlewareConfig) {
@@ -146,9 +267,12 @@ func WithErrorHandler(fn func(error)) HTTPMiddlewareOption {
 	}
 }
 
+// statusRecorder records the status of a response. It passes Flush, Hijack and ReadFrom
+// through, so server-sent events, websockets and sendfile keep working behind it.
 type statusRecorder struct {
 	http.ResponseWriter
 	status int
+	stream streamState
 }
 
 func (r *statusRecorder) WriteHeader(code int) {
@@ -156,6 +280,37 @@ func (r *statusRecorder) WriteHeader(code int) {
 	r.ResponseWriter.WriteHeader(code)
 }
 
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.stream.written += int64(n)
+	return n, err
+}
+
+func (r *statusRecorder) ReadFrom(src io.Reader) (int64, error) {
+	n, err := readFrom(r.ResponseWriter, src)
+	r.stream.written += n
+	return n, err
+}
+
+func (r *statusRecorder) Flush() {
+	r.stream.flushes++
+	flush(r.ResponseWriter)
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := hijack(r.ResponseWriter)
+	if err == nil {
+		r.stream.hijacked = true
+		r.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 func headerValue(r *http.Request, name string) string {
 	if name == "" {
 		return ""
@@ -163,19 +318,59 @@ func headerValue(r *http.Request, name string) string {
 	return strings.TrimSpace(r.Header.Get(name))
 }
 
-func clientIP(r *http.Request, header string) string {
-	if header != "" {
-		raw := r.Header.Get(header)
-		if raw != "" {
-			parts := strings.Split(raw, ",")
-			if len(parts) > 0 {
-				return strings.TrimSpace(parts[0])
+// clientIP returns the peer address, or the client address from the IP header when the peer
+// is a trusted proxy (see WithTrustedProxies). Header hops that are not valid IP addresses
+// are skipped.
+func (c httpMiddlewareConfig) clientIP(r *http.Request) string {
+	peer := strings.TrimSpace(r.RemoteAddr)
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+	if c.ipHeader == "" || len(c.trustedProxies) == 0 {
+		return peer
+	}
+	if addr, err := netip.ParseAddr(peer); err != nil || !c.trusted(addr) {
+		return peer
+	}
+	var hops []netip.Addr
+	for _, v := range r.Header.Values(c.ipHeader) {
+		for _, hop := range strings.Split(v, ",") {
+			if addr, err := netip.ParseAddr(strings.TrimSpace(hop)); err == nil {
+				hops = append(hops, addr.Unmap())
 			}
 		}
 	}
+	if len(hops) == 0 {
+		return peer
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		if !c.trusted(hops[i]) {
+			return hops[i].String()
+		}
+	}
+	return hops[0].String()
+}
 
-	if host, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr)); err == nil {
-		return host
+func (c httpMiddlewareConfig) trusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range c.trustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseProxy accepts an address or a CIDR range.
+func parseProxy(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		return netip.ParsePrefix(s)
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
 	}
-	return strings.TrimSpace(r.RemoteAddr)
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
 }
@@ -1,12 +1,15 @@
 package audittrail
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -71,8 +74,8 @@ func TestHTTPMiddlewareRecordsEntry(t *testing.T) {
 	if got := args[6].Value.(time.Time); !got.Equal(fixedTime) {
 		t.Fatalf("created_date mismatch: %v", got)
 	}
-	if got := stringArg(args, 7); got != "user-9" {
-		t.Fatalf("created_by mismatch: %q", got)
+	if got := stringArg(args, 17); got != "user-9" {
+		t.Fatalf("actor mismatch: %q", got)
 	}
 
 	resp := stringArg(args, 5)
@@ -99,3 +102,127 @@ func stringArg(args []driver.NamedValue, idx int) string {
 		return fmt.Sprintf("%v", v)
 	}
 }
+
+func TestHTTPMiddlewareRequestBody(t *testing.T) {
+	var got Entry
+	rec := RecorderFunc(func(_ context.Context, e Entry) error {
+		got = e
+		return nil
+	})
+	var seen string
+	handler := HTTPMiddleware(rec, WithRequestBody(1024))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		seen = string(b)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"sku":"A","qty":2}`))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if seen != `{"sku":"A","qty":2}` {
+		t.Fatalf("handler saw %q", seen)
+	}
+	if raw, ok := got.Request.(json.RawMessage); !ok || string(raw) != `{"sku":"A","qty":2}` {
+		t.Fatalf("unexpected request payload: %#v", got.Request)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/orders", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if got.Request != nil {
+		t.Fatalf("GET bodies should not be captured: %#v", got.Request)
+	}
+}
+
+func TestHTTPMiddlewareRecordsPanics(t *testing.T) {
+	var got Entry
+	rec := RecorderFunc(func(_ context.Context, e Entry) error {
+		got = e
+		return nil
+	})
+	h := HTTPMiddleware(rec)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("nil map write")
+	}))
+
+	func() {
+		defer func() {
+			if v := recover(); v != "nil map write" {
+				t.Fatalf("expected the panic to be re-raised, got %v", v)
+			}
+		}()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders", nil))
+	}()
+
+	report, ok := got.Response.(PanicReport)
+	if !ok || report.Outcome != OutcomePanic || report.Panic != "nil map write" {
+		t.Fatalf("expected a panic report, got %#v", got.Response)
+	}
+	if len(report.Stack) > panicStackLimit || !strings.Contains(report.Stack, "goroutine") || len(report.StackSHA256) != 64 {
+		t.Fatalf("unexpected stack reference: %+v", report)
+	}
+	if got.Severity != SeverityWarn {
+		t.Fatalf("expected the severity of a 500, got %s", got.Severity)
+	}
+}
+
+func TestHTTPMiddlewareClientIPAndService(t *testing.T) {
+	var got Entry
+	rec := RecorderFunc(func(_ context.Context, e Entry) error {
+		got = e
+		return nil
+	})
+	noop := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	serve := func(remote, forwarded string, opts ...HTTPMiddlewareOption) Entry {
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req.RemoteAddr = remote
+		req.Header.Set("X-User-Id", "u1")
+		if forwarded != "" {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		HTTPMiddleware(rec, opts...)(noop).ServeHTTP(httptest.NewRecorder(), req)
+		return got
+	}
+
+	if e := serve("10.0.0.5:4711", "198.51.100.1, 10.0.0.9", WithHTTPServiceName("orders")); e.IPAddress != "10.0.0.5" ||
+		e.Actor != "u1" || e.ServiceName != "orders" || e.CreatedBy != "" {
+		t.Fatalf("unexpected identity: actor=%q ip=%q service=%q created_by=%q", e.Actor, e.IPAddress, e.ServiceName, e.CreatedBy)
+	}
+
+	trusted := WithTrustedProxies("10.0.0.0/8", "not-an-ip")
+	if e := serve("10.0.0.5:4711", "6.6.6.6, 203.0.113.7, 10.0.0.9", trusted); e.IPAddress != "203.0.113.7" {
+		t.Fatalf("expected the right-most untrusted hop, got %q", e.IPAddress)
+	}
+	if e := serve("192.0.2.10:4711", "6.6.6.6", trusted); e.IPAddress != "192.0.2.10" {
+		t.Fatalf("expected the header to be ignored from an untrusted peer, got %q", e.IPAddress)
+	}
+	if e := serve("[::1]:4711", "", trusted); e.IPAddress != "::1" {
+		t.Fatalf("expected the peer address without a header, got %q", e.IPAddress)
+	}
+	if e := serve("10.0.0.5:4711", "203.0.113.7, <script>, unknown, 10.0.0.9", trusted); e.IPAddress != "203.0.113.7" {
+		t.Fatalf("expected invalid hops to be skipped, got %q", e.IPAddress)
+	}
+	if e := serve("10.0.0.5:4711", "'; DROP TABLE audit_trail; --", trusted); e.IPAddress != "10.0.0.5" {
+		t.Fatalf("expected the peer address when no hop is valid, got %q", e.IPAddress)
+	}
+	if e := serve("10.0.0.5:4711", "::ffff:203.0.113.7", trusted); e.IPAddress != "203.0.113.7" {
+		t.Fatalf("expected an IPv4-mapped hop to be unmapped, got %q", e.IPAddress)
+	}
+}
+
+func TestHTTPMiddlewareRecordsDuration(t *testing.T) {
+	var got Entry
+	rec := RecorderFunc(func(_ context.Context, e Entry) error {
+		got = e
+		return nil
+	})
+	clock := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tick := func() time.Time {
+		now := clock
+		clock = clock.Add(250 * time.Millisecond)
+		return now
+	}
+	h := HTTPMiddleware(rec, WithNow(tick))(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders", nil))
+
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if !got.StartedAt.Equal(start) || !got.CreatedDate.Equal(start) || !got.EndedAt.Equal(start.Add(250*time.Millisecond)) || got.DurationMS != 250 {
+		t.Fatalf("unexpected timing: started=%v created=%v ended=%v duration=%d", got.StartedAt, got.CreatedDate, got.EndedAt, got.DurationMS)
+	}
+}
@@ -0,0 +1,246 @@
+package audittrail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxReportIDs caps the entry IDs kept in migration reports.
+const maxReportIDs = 100
+
+// DualWriteConfig configures a DualWriter.
+type DualWriteConfig struct {
+	Old Recorder // current store; its result is what Record returns
+	New Recorder // store being migrated to
+	// Strict also fails Record when the new store fails. By default new-store failures are
+	// only logged and counted, so the migration cannot break audit logging.
+	Strict bool
+}
+
+// DualWriteReport summarizes what a DualWriter has written so far.
+type DualWriteReport struct {
+	Written   int64    // entries stored in both stores
+	OldFailed int64    // entries the old store rejected
+	NewFailed int64    // entries the new store rejected
+	FailedIDs []string // first IDs missing from the new store, to backfill with Import or a replay
+}
+
+// DualWriter records every entry to an old and a new store while moving between backends,
+// e.g. from Postgres to ClickHouse or Elasticsearch. IDs and timestamps are assigned before
+// the entry is fanned out, so both stores hold identical entries that VerifyMigration can
+// compare. Typical rollout: dual-write, backfill history into the new store, verify, then
+// switch reads and finally drop the old store.
+type DualWriter struct {
+	cfg DualWriteConfig
+
+	mu     sync.Mutex
+	report DualWriteReport
+}
+
+// NewDualWriter creates a DualWriter.
+func NewDualWriter(cfg DualWriteConfig) (*DualWriter, error) {
+	if cfg.Old == nil || cfg.New == nil {
+		return nil, errors.New("audittrail: dual write needs an old and a new recorder")
+	}
+	return &DualWriter{cfg: cfg}, nil
+}
+
+// Record writes the entry to both stores concurrently. It returns the old store's error,
+// and with Strict the new store's error too.
+func (d *DualWriter) Record(ctx context.Context, entry Entry) error {
+	normalized, err := normalizeEntry(entry, nil)
+	if err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	var newErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		newErr = d.cfg.New.Record(ctx, normalized)
+	}()
+	oldErr := d.cfg.Old.Record(ctx, normalized)
+	wg.Wait()
+
+	d.mu.Lock()
+	switch {
+	case oldErr == nil && newErr == nil:
+		d.report.Written++
+	case oldErr != nil:
+		d.report.OldFailed++
+	}
+	if newErr != nil {
+		d.report.NewFailed++
+		if len(d.report.FailedIDs) < maxReportIDs {
+			d.report.FailedIDs = append(d.report.FailedIDs, normalized.ID)
+		}
+	}
+	d.mu.Unlock()
+
+	if newErr != nil {
+		logger().Warn("audittrail: dual write to new store failed", "entry_id", normalized.ID, "error", newErr)
+		if d.cfg.Strict && oldErr == nil {
+			return fmt.Errorf("audittrail: new store: %w", newErr)
+		}
+	}
+	return oldErr
+}
+
+// Report returns a snapshot of the write counters.
+func (d *DualWriter) Report() DualWriteReport {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	report := d.report
+	report.FailedIDs = append([]string(nil), d.report.FailedIDs...)
+	return report
+}
+
+// EntrySource streams entries matching a filter in (created date, ID) order. AuditTrail
+// implements it; adapt other backends by querying them in the same order.
+type EntrySource interface {
+	Iterate(ctx context.Context, f Filter, fn func(Entry) error) error
+}
+
+// MigrationReport is the result of VerifyMigration.
+type MigrationReport struct {
+	OldCount     int64
+	NewCount     int64
+	Matched      int64
+	MissingInNew []string // IDs only in the old store (first 100)
+	MissingInOld []string // IDs only in the new store (first 100)
+	Mismatched   []string // IDs whose content differs (first 100)
+	MissingCount int64    // total entries only in the old store
+	ExtraCount   int64    // total entries only in the new store
+	DiffCount    int64    // total entries whose content differs
+}
+
+// OK reports whether both stores hold exactly the same entries.
+func (m MigrationReport) OK() bool {
+	return m.MissingCount == 0 && m.ExtraCount == 0 && m.DiffCount == 0
+}
+
+// VerifyMigration compares the entries matching f in both stores with a merge join over
+// their (created date, ID) order, so it runs in constant memory. Timestamps are compared at
+// microsecond precision and payloads as JSON values, since stores differ in both.
+func VerifyMigration(ctx context.Context, oldSrc, newSrc EntrySource, f Filter) (MigrationReport, error) {
+	var report MigrationReport
+	if oldSrc == nil || newSrc == nil {
+		return report, errors.New("audittrail: verify migration needs an old and a new source")
+	}
+
+	// The new store is streamed through a channel so both sides advance in lockstep.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	next := make(chan Entry, iteratePageSize)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(next)
+		errc <- newSrc.Iterate(ctx, f, func(e Entry) error {
+			select {
+			case next <- e:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	pending, ok := <-next
+	missing := func(e Entry) {
+		report.MissingCount++
+		if len(report.MissingInNew) < maxReportIDs {
+			report.MissingInNew = append(report.MissingInNew, e.ID)
+		}
+	}
+	extra := func(e Entry) {
+		report.NewCount++
+		report.ExtraCount++
+		if len(report.MissingInOld) < maxReportIDs {
+			report.MissingInOld = append(report.MissingInOld, e.ID)
+		}
+	}
+
+	err := oldSrc.Iterate(ctx, f, func(old Entry) error {
+		report.OldCount++
+		for ok && entryBefore(pending, old) {
+			extra(pending)
+			pending, ok = <-next
+		}
+		if !ok || pending.ID != old.ID {
+			missing(old)
+			return nil
+		}
+		report.NewCount++
+		if sameEntry(old, pending) {
+			report.Matched++
+		} else {
+			report.DiffCount++
+			if len(report.Mismatched) < maxReportIDs {
+				report.Mismatched = append(report.Mismatched, old.ID)
+			}
+		}
+		pending, ok = <-next
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("audittrail: read old store: %w", err)
+	}
+	for ; ok; pending, ok = <-next {
+		extra(pending)
+	}
+	if err := <-errc; err != nil {
+		return report, fmt.Errorf("audittrail: read new store: %w", err)
+	}
+	return report, nil
+}
+
+// entryBefore reports whether a sorts before b in (created date, ID) order.
+func entryBefore(a, b Entry) bool {
+	ta, tb := a.CreatedDate.Truncate(time.Microsecond), b.CreatedDate.Truncate(time.Microsecond)
+	if !ta.Equal(tb) {
+		return ta.Before(tb)
+	}
+	return a.ID < b.ID
+}
+
+// sameEntry compares two copies of an entry read from different stores.
+func sameEntry(a, b Entry) bool {
+	a, b = comparableEntry(a), comparableEntry(b)
+	x, errA := json.Marshal(a)
+	y, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(x) == string(y)
+}
+
+// comparableEntry drops the differences stores introduce: timestamp precision and zone,
+// the wire schema version and the encoding of payloads.
+func comparableEntry(e Entry) Entry {
+	e.SchemaVersion = 0
+	e.CreatedDate = e.CreatedDate.UTC().Truncate(time.Microsecond)
+	for _, t := range []*time.Time{&e.ExpiresAt, &e.StartedAt, &e.EndedAt} {
+		if !t.IsZero() {
+			*t = t.UTC().Truncate(time.Microsecond)
+		}
+	}
+	e.Request, e.Response = jsonValue(e.Request), jsonValue(e.Response)
+	meta, _ := marshalMeta(e.Meta)
+	e.Meta = unmarshalMeta([]byte(meta.String))
+	return e
+}
+
+// jsonValue decodes a payload into plain JSON values, so map key order and raw versus
+// decoded payloads compare equal.
+func jsonValue(v any) any {
+	s, err := marshalJSONValue(v)
+	if err != nil || !s.Valid {
+		return nil
+	}
+	var out any
+	if err := json.Unmarshal([]byte(s.String), &out); err != nil {
+		return s.String
+	}
+	return out
+}
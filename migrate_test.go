@@ -0,0 +1,96 @@
+package audittrail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type sliceSource []Entry
+
+func (s sliceSource) Iterate(_ context.Context, _ Filter, fn func(Entry) error) error {
+	for _, e := range s {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestDualWriterWritesBothStores(t *testing.T) {
+	var mu sync.Mutex
+	var oldIDs, newIDs []string
+	failNew := errors.New("cluster down")
+	newErr := error(nil)
+	old := RecorderFunc(func(_ context.Context, e Entry) error {
+		mu.Lock()
+		defer mu.Unlock()
+		oldIDs = append(oldIDs, e.ID)
+		return nil
+	})
+	next := RecorderFunc(func(_ context.Context, e Entry) error {
+		mu.Lock()
+		defer mu.Unlock()
+		newIDs = append(newIDs, e.ID)
+		return newErr
+	})
+	dw, err := NewDualWriter(DualWriteConfig{Old: old, New: next})
+	if err != nil {
+		t.Fatalf("NewDualWriter: %v", err)
+	}
+	ctx := context.Background()
+	if err := dw.Record(ctx, Entry{Action: "EXPORT"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if len(oldIDs) != 1 || oldIDs[0] == "" || newIDs[0] != oldIDs[0] {
+		t.Fatalf("stores got different IDs: %v %v", oldIDs, newIDs)
+	}
+
+	newErr = failNew
+	if err := dw.Record(ctx, Entry{ID: "e2", Action: "EXPORT"}); err != nil {
+		t.Fatalf("new store failures must not fail Record: %v", err)
+	}
+	report := dw.Report()
+	if report.Written != 1 || report.NewFailed != 1 || len(report.FailedIDs) != 1 || report.FailedIDs[0] != "e2" {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	dw, _ = NewDualWriter(DualWriteConfig{Old: old, New: next, Strict: true})
+	if err := dw.Record(ctx, Entry{Action: "EXPORT"}); !errors.Is(err, failNew) {
+		t.Fatalf("expected strict mode to return the new store error, got %v", err)
+	}
+}
+
+func TestVerifyMigrationReportsDifferences(t *testing.T) {
+	base := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	at := func(i int) time.Time { return base.Add(time.Duration(i) * time.Minute) }
+	old := sliceSource{
+		{ID: "a", Action: "X", CreatedDate: at(1), Request: map[string]any{"k": 1, "j": "v"}},
+		{ID: "b", Action: "X", CreatedDate: at(2)},
+		{ID: "c", Action: "X", CreatedDate: at(3)},
+	}
+	next := sliceSource{
+		{ID: "a", Action: "X", CreatedDate: at(1).Add(300 * time.Nanosecond).In(time.FixedZone("x", 3600)),
+			Request: json.RawMessage(`{"j": "v", "k": 1}`), SchemaVersion: 2},
+		{ID: "c", Action: "Y", CreatedDate: at(3)},
+		{ID: "d", Action: "X", CreatedDate: at(4)},
+	}
+	report, err := VerifyMigration(context.Background(), old, next, Filter{})
+	if err != nil {
+		t.Fatalf("VerifyMigration: %v", err)
+	}
+	if report.OK() || report.OldCount != 3 || report.NewCount != 3 || report.Matched != 1 ||
+		len(report.MissingInNew) != 1 || report.MissingInNew[0] != "b" ||
+		len(report.Mismatched) != 1 || report.Mismatched[0] != "c" ||
+		len(report.MissingInOld) != 1 || report.MissingInOld[0] != "d" {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	report, err = VerifyMigration(context.Background(), old, old, Filter{})
+	if err != nil || !report.OK() || report.Matched != 3 {
+		t.Fatalf("identical stores: %+v, %v", report, err)
+	}
+}
@@ -0,0 +1,134 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// PipelineConfig configures a named pipeline (see Register).
+type PipelineConfig struct {
+	// Topic, Subscription and Table default to AUDIT_PUBSUB_TOPIC, AUDIT_PUBSUB_SUBSCRIPTION
+	// and AUDIT_TABLE with the pipeline name appended, e.g. "audit-trail-security" and
+	// "audit_trail_security". The database and project are shared with InitFromEnv.
+	Topic        string
+	Subscription string
+	Table        string
+
+	// Options configures the pipeline like InitWithOptions; Resilient is not supported.
+	Options *InitOptions
+
+	// Recorder, when set, is used instead of opening a database and Pub/Sub, e.g. to send a
+	// pipeline to Loki or to an in-memory recorder in tests.
+	Recorder Recorder
+}
+
+// namedPipeline is a pipeline started by Register.
+type namedPipeline struct {
+	recorder Recorder
+	pipeline *pipeline // nil when PipelineConfig.Recorder is used
+	options  *InitOptions
+}
+
+// Register starts a named pipeline next to the one of InitFromEnv, so one service can send
+// security events to one topic and table and data changes to another:
+//
+//	_ = audittrail.Register("security", audittrail.PipelineConfig{Table: "security_audit"})
+//	_ = audittrail.For("security").Record(ctx, entry)
+//
+// Its consumer runs until Shutdown. Registering a name twice fails.
+func Register(name string, cfg PipelineConfig) error {
+	if name == "" {
+		return errors.New("audittrail: pipeline name must not be empty")
+	}
+	opts := cfg.Options
+	if opts == nil {
+		opts = &InitOptions{}
+	}
+	if opts.Resilient {
+		return errors.New("audittrail: resilient mode is not supported for named pipelines")
+	}
+	if lookupPipeline(name) != nil {
+		return fmt.Errorf("audittrail: pipeline %q is already registered", name)
+	}
+
+	named := &namedPipeline{recorder: cfg.Recorder, options: opts}
+	if named.recorder == nil {
+		p, err := startPipeline(context.Background(), opts, pipelineResources{
+			name:         name,
+			topic:        cfg.Topic,
+			subscription: cfg.Subscription,
+			table:        cfg.Table,
+		}, &runtime.wg)
+		if err != nil {
+			return fmt.Errorf("audittrail: start pipeline %q: %w", name, err)
+		}
+		named.recorder = p.recorder
+		named.pipeline = p
+	}
+
+	runtime.mu.Lock()
+	if _, ok := runtime.pipelines[name]; ok {
+		runtime.mu.Unlock()
+		named.close()
+		return fmt.Errorf("audittrail: pipeline %q is already registered", name)
+	}
+	if runtime.pipelines == nil {
+		runtime.pipelines = make(map[string]*namedPipeline)
+	}
+	runtime.pipelines[name] = named
+	runtime.mu.Unlock()
+	OnDrop(opts.OnDrop)
+	return nil
+}
+
+// For returns the recorder of a pipeline added with Register; "" is the pipeline of
+// InitFromEnv. The name is resolved on every Record, so For may be called before Register,
+// e.g. in a package-level variable. Recording to an unknown name fails.
+func For(name string) Recorder {
+	return namedRecorder(name)
+}
+
+type namedRecorder string
+
+func (n namedRecorder) Record(ctx context.Context, entry Entry) error {
+	if n == "" {
+		return Record(ctx, entry)
+	}
+	named := lookupPipeline(string(n))
+	if named == nil {
+		return fmt.Errorf("audittrail: pipeline %q is not registered", string(n))
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	err := named.recorder.Record(ctx, entry)
+	if err != nil && named.options.OnPublishError != nil {
+		named.options.OnPublishError(err)
+	}
+	return err
+}
+
+func lookupPipeline(name string) *namedPipeline {
+	runtime.mu.Lock()
+	defer runtime.mu.Unlock()
+	return runtime.pipelines[name]
+}
+
+// stop cancels the consumer; close releases the connections once it has returned.
+func (n *namedPipeline) stop() {
+	if n.pipeline != nil {
+		n.pipeline.cancel()
+	}
+}
+
+func (n *namedPipeline) close() {
+	if n.pipeline == nil {
+		return
+	}
+	n.pipeline.cancel()
+	if n.pipeline.client != nil {
+		_ = n.pipeline.client.Close()
+	}
+	_ = n.pipeline.db.Close()
+}
@@ -0,0 +1,80 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegisterNamedPipelines(t *testing.T) {
+	inserts := make(chan execCall, 4)
+	driverName := fmt.Sprintf("audittrail_stub_%s_%d", t.Name(), time.Now().UnixNano())
+	sql.Register(driverName, &stubDriver{execFn: func(query string, args []driver.NamedValue) (driver.Result, error) {
+		inserts <- execCall{query: query, args: args}
+		return stubResult{}, nil
+	}})
+	t.Setenv(envTransport, TransportMemory)
+	t.Setenv(envDBDriver, driverName)
+	ctx := context.Background()
+	defer func() { _ = Shutdown(ctx) }()
+
+	opts := &InitOptions{DisableInstanceFields: true}
+	if err := Register("security", PipelineConfig{Options: opts}); err != nil {
+		t.Fatalf("Register security: %v", err)
+	}
+	if err := Register("changes", PipelineConfig{Table: "data_changes", Options: opts}); err != nil {
+		t.Fatalf("Register changes: %v", err)
+	}
+	if err := Register("security", PipelineConfig{}); err == nil {
+		t.Fatal("expected error registering a name twice")
+	}
+
+	if err := For("security").Record(ctx, Entry{ID: "s1", Action: "LOGIN"}); err != nil {
+		t.Fatalf("Record security: %v", err)
+	}
+	if call := <-inserts; !strings.Contains(call.query, "INSERT INTO audit_trail_security") || stringArg(call.args, 0) != "s1" {
+		t.Fatalf("unexpected security insert: %s", call.query)
+	}
+	if err := For("changes").Record(ctx, Entry{ID: "c1", Action: "UPDATE"}); err != nil {
+		t.Fatalf("Record changes: %v", err)
+	}
+	if call := <-inserts; !strings.Contains(call.query, "INSERT INTO data_changes") || stringArg(call.args, 0) != "c1" {
+		t.Fatalf("unexpected changes insert: %s", call.query)
+	}
+
+	if err := For("billing").Record(ctx, Entry{Action: "PAY"}); err == nil {
+		t.Fatal("expected error for an unregistered pipeline")
+	}
+}
+
+func TestRegisterWithRecorder(t *testing.T) {
+	ctx := context.Background()
+	defer func() { _ = Shutdown(ctx) }()
+
+	var got []Entry
+	rec := RecorderFunc(func(_ context.Context, entry Entry) error {
+		got = append(got, entry)
+		return nil
+	})
+	security := For("security") // resolved at Record time
+	if err := Register("security", PipelineConfig{Recorder: rec}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := security.Record(ctx, Entry{ID: "s1", Action: "LOGIN"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "s1" {
+		t.Fatalf("unexpected entries: %+v", got)
+	}
+
+	if err := Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if err := security.Record(ctx, Entry{Action: "LOGIN"}); err == nil {
+		t.Fatal("expected Shutdown to unregister the pipeline")
+	}
+}
@@ -0,0 +1,398 @@
+package audittrail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Notification is a message about the audit trail meant for people, e.g. a tamper alert.
+type Notification struct {
+	Title    string            `json:"title"`
+	Text     string            `json:"text"`
+	Severity Severity          `json:"severity"`
+	Fields   map[string]string `json:"fields,omitempty"` // details such as the entry ID
+	Time     time.Time         `json:"time"`
+	// DedupKey groups notifications about the same problem, e.g. into one PagerDuty incident.
+	DedupKey string `json:"dedup_key,omitempty"`
+}
+
+// Notifier delivers notifications to a channel such as a webhook, Slack, Teams, email or
+// PagerDuty.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NotifierFunc adapts a function to Notifier.
+type NotifierFunc func(ctx context.Context, n Notification) error
+
+func (f NotifierFunc) Notify(ctx context.Context, n Notification) error { return f(ctx, n) }
+
+// MultiNotifier sends every notification to all notifiers, returning their errors joined.
+func MultiNotifier(notifiers ...Notifier) Notifier {
+	return NotifierFunc(func(ctx context.Context, n Notification) error {
+		var errs []error
+		for _, notifier := range notifiers {
+			if notifier == nil {
+				continue
+			}
+			if err := notifier.Notify(ctx, n); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// WebhookNotifier POSTs each notification as JSON to url. A nil client uses one with a 10s
+// timeout.
+func WebhookNotifier(url string, client *http.Client) Notifier {
+	return NotifierFunc(func(ctx context.Context, n Notification) error {
+		return postJSON(ctx, client, url, n, "webhook")
+	})
+}
+
+// SlackNotifier posts each notification to a Slack incoming webhook.
+func SlackNotifier(webhookURL string, client *http.Client) Notifier {
+	return NotifierFunc(func(ctx context.Context, n Notification) error {
+		text := "*" + n.Title + "*"
+		if n.Text != "" {
+			text += "\n" + n.Text
+		}
+		for _, k := range sortedKeys(n.Fields) {
+			text += fmt.Sprintf("\n• %s: `%s`", k, n.Fields[k])
+		}
+		return postJSON(ctx, client, webhookURL, map[string]string{"text": text}, "Slack")
+	})
+}
+
+// TeamsNotifier posts each notification as an Adaptive Card to a Microsoft Teams incoming
+// webhook (a Workflows "post to a channel when a webhook request is received" URL).
+func TeamsNotifier(webhookURL string, client *http.Client) Notifier {
+	return NotifierFunc(func(ctx context.Context, n Notification) error {
+		body := []map[string]any{
+			{"type": "TextBlock", "text": n.Title, "weight": "Bolder", "size": "Medium", "wrap": true},
+		}
+		if n.Text != "" {
+			body = append(body, map[string]any{"type": "TextBlock", "text": n.Text, "wrap": true})
+		}
+		if len(n.Fields) > 0 {
+			facts := make([]map[string]string, 0, len(n.Fields))
+			for _, k := range sortedKeys(n.Fields) {
+				facts = append(facts, map[string]string{"title": k, "value": n.Fields[k]})
+			}
+			body = append(body, map[string]any{"type": "FactSet", "facts": facts})
+		}
+		msg := map[string]any{
+			"type": "message",
+			"attachments": []map[string]any{{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": map[string]any{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"body":    body,
+				},
+			}},
+		}
+		return postJSON(ctx, client, webhookURL, msg, "Teams")
+	})
+}
+
+// pagerDutyURL is the PagerDuty Events API v2 endpoint.
+var pagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDutyNotifier triggers a PagerDuty incident through the Events API v2 for each
+// notification, using the routing key of an Events API integration. Notifications with
+// the same DedupKey update one incident.
+func PagerDutyNotifier(routingKey string, client *http.Client) Notifier {
+	return NotifierFunc(func(ctx context.Context, n Notification) error {
+		severity := "info"
+		switch Severity(strings.ToUpper(string(n.Severity))) {
+		case SeverityCritical:
+			severity = "critical"
+		case SeverityWarn:
+			severity = "warning"
+		}
+		source := CurrentInstance().Hostname
+		if source == "" {
+			source = "audittrail"
+		}
+		event := map[string]any{
+			"routing_key":  routingKey,
+			"event_action": "trigger",
+			"payload": map[string]any{
+				"summary":        n.Title,
+				"source":         source,
+				"severity":       severity,
+				"timestamp":      n.Time.UTC().Format(time.RFC3339),
+				"custom_details": n.Fields,
+			},
+		}
+		if n.DedupKey != "" {
+			event["dedup_key"] = n.DedupKey
+		}
+		return postJSON(ctx, client, pagerDutyURL, event, "PagerDuty")
+	})
+}
+
+// SMTPConfig configures EmailNotifier.
+type SMTPConfig struct {
+	Addr string    // host:port of the SMTP server
+	Auth smtp.Auth // optional, e.g. smtp.PlainAuth("", user, password, host)
+	From string
+	To   []string
+}
+
+// smtpSendMail sends mail; replaced in tests.
+var smtpSendMail = smtp.SendMail
+
+// EmailNotifier sends each notification as a plain text email through an SMTP server.
+func EmailNotifier(cfg SMTPConfig) Notifier {
+	return NotifierFunc(func(_ context.Context, n Notification) error {
+		if cfg.Addr == "" || cfg.From == "" || len(cfg.To) == 0 {
+			return errors.New("audittrail: SMTP address, sender and recipients must not be empty")
+		}
+		var body strings.Builder
+		body.WriteString(n.Text)
+		for _, k := range sortedKeys(n.Fields) {
+			fmt.Fprintf(&body, "\n%s: %s", k, n.Fields[k])
+		}
+		if err := smtpSendMail(cfg.Addr, cfg.Auth, cfg.From, cfg.To, mailMessage(cfg, n.Title, body.String())); err != nil {
+			return fmt.Errorf("audittrail: send email: %w", err)
+		}
+		return nil
+	})
+}
+
+// mailMessage renders a plain text message with its headers.
+func mailMessage(cfg SMTPConfig, subject, text string) []byte {
+	var b bytes.Buffer
+	writeMailHeader(&b, cfg, subject, "text/plain; charset=utf-8")
+	b.WriteString(strings.ReplaceAll(text, "\n", "\r\n"))
+	return b.Bytes()
+}
+
+// writeMailHeader writes the message headers and the blank line ending them. Header values
+// are stripped of line breaks so a title cannot inject headers.
+func writeMailHeader(b *bytes.Buffer, cfg SMTPConfig, subject, contentType string) {
+	clean := strings.NewReplacer("\r", " ", "\n", " ")
+	fmt.Fprintf(b, "From: %s\r\n", clean.Replace(cfg.From))
+	fmt.Fprintf(b, "To: %s\r\n", clean.Replace(strings.Join(cfg.To, ", ")))
+	fmt.Fprintf(b, "Subject: %s\r\n", clean.Replace(subject))
+	fmt.Fprintf(b, "MIME-Version: 1.0\r\nContent-Type: %s\r\n\r\n", contentType)
+}
+
+// TamperNotification describes a row that failed IntegrityScan.
+func TamperNotification(m IntegrityMismatch) Notification {
+	return Notification{
+		Title:    "Audit trail tampering detected",
+		Text:     fmt.Sprintf("Entry %s no longer matches the hash it was written with.", m.Entry.ID),
+		Severity: SeverityCritical,
+		Fields: map[string]string{
+			"entry_id":      m.Entry.ID,
+			"action":        m.Entry.Action,
+			"created_date":  m.Entry.CreatedDate.UTC().Format(time.RFC3339),
+			"stored_hash":   m.Stored,
+			"computed_hash": m.Computed,
+		},
+		Time:     time.Now().UTC(),
+		DedupKey: "audittrail-tamper-" + m.Entry.ID,
+	}
+}
+
+// NotifyTamper returns an IntegrityConfig.OnMismatch that sends every mismatch to n:
+//
+//	go audit.RunIntegrityScan(ctx, audittrail.IntegrityConfig{
+//		OnMismatch: audittrail.NotifyTamper(audittrail.MultiNotifier(slack, pagerDuty)),
+//	})
+//
+// Delivery failures are logged.
+func NotifyTamper(n Notifier) func(context.Context, IntegrityMismatch) {
+	return func(ctx context.Context, m IntegrityMismatch) {
+		if err := n.Notify(ctx, TamperNotification(m)); err != nil {
+			logger().Error("audittrail: tamper notification failed", "entry_id", m.Entry.ID, "error", err)
+		}
+	}
+}
+
+// notifyTimeout bounds a notification sent in the background by NotifyAlert.
+const notifyTimeout = 30 * time.Second
+
+// NotifyAlert returns an AlertFunc that sends triggered AlertRules to n. As AlertFuncs run
+// synchronously, the notification is sent in the background; failures are logged.
+func NotifyAlert(n Notifier) AlertFunc {
+	return func(ctx context.Context, a Alert) {
+		name := a.Rule.Name
+		if name == "" {
+			name = a.Rule.Action
+		}
+		note := Notification{
+			Title:    "Audit alert: " + name,
+			Text:     fmt.Sprintf("%s performed %d matching actions within %s.", a.Actor, a.Count, a.Rule.Window),
+			Severity: SeverityWarn,
+			Fields: map[string]string{
+				"actor":        a.Actor,
+				"count":        fmt.Sprint(a.Count),
+				"window_start": a.WindowStart.UTC().Format(time.RFC3339),
+				"window_end":   a.WindowEnd.UTC().Format(time.RFC3339),
+				"last_entry":   a.Entry.ID,
+			},
+			Time:     a.WindowEnd,
+			DedupKey: "audittrail-alert-" + name + "-" + a.Actor,
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+			defer cancel()
+			if err := n.Notify(ctx, note); err != nil {
+				logger().Error("audittrail: alert notification failed", "rule", name, "error", err)
+			}
+		}()
+	}
+}
+
+// NotifyFilter selects the entries NotifierRecorder sends.
+type NotifyFilter struct {
+	Actions     []string // glob patterns matched against Entry.Action (e.g. "ROLE_*"); empty matches all
+	MinSeverity Severity // only entries at least this severe; empty sends all
+	// Format turns an entry into a notification; default EntryNotification.
+	Format func(Entry) Notification
+}
+
+func (f NotifyFilter) match(e Entry) bool {
+	if !e.Severity.AtLeast(f.MinSeverity) {
+		return false
+	}
+	if len(f.Actions) == 0 {
+		return true
+	}
+	for _, pattern := range f.Actions {
+		if matchActionPattern(pattern, e.Action) {
+			return true
+		}
+	}
+	return false
+}
+
+// NotifierRecorder returns a Recorder that sends the entries matching f to n, so critical
+// administrative actions show up in a Slack or Teams channel as they happen. Add it next to
+// the store rather than in place of it:
+//
+//	slack := audittrail.NotifierRecorder(audittrail.SlackNotifier(url, nil), audittrail.NotifyFilter{
+//		Actions: []string{"ROLE_GRANTED", "USER_DELETED"},
+//	})
+//	rec := audittrail.MultiRecorder(audit, slack)
+//
+// Notifications are sent in the background, so a slow or failing channel never delays or
+// fails the request being audited; failures are logged.
+func NotifierRecorder(n Notifier, f NotifyFilter) Recorder {
+	format := f.Format
+	if format == nil {
+		format = EntryNotification
+	}
+	return RecorderFunc(func(ctx context.Context, entry Entry) error {
+		if n == nil || !f.match(entry) {
+			return nil
+		}
+		note := format(entry)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+			defer cancel()
+			if err := n.Notify(ctx, note); err != nil {
+				logger().Error("audittrail: entry notification failed", "action", entry.Action, "error", err)
+			}
+		}()
+		return nil
+	})
+}
+
+// EntryNotification describes an entry for people: who did what to which entity, with the
+// identifiers needed to look the entry up.
+func EntryNotification(e Entry) Notification {
+	actor := e.ActorOrCreator()
+	if actor == "" {
+		actor = "unknown actor"
+	}
+	text := actor + " performed " + e.Action
+	if entity := strings.TrimSpace(e.EntityType + " " + e.EntityID); entity != "" {
+		text += " on " + entity
+	}
+	if e.ImpersonatedBy != "" {
+		text += " (impersonated by " + e.ImpersonatedBy + ")"
+	}
+	fields := make(map[string]string)
+	for k, v := range map[string]string{
+		"entry_id":     e.ID,
+		"request_id":   e.RequestID,
+		"endpoint":     e.Endpoint,
+		"service_name": e.ServiceName,
+		"ip_address":   e.IPAddress,
+	} {
+		if v != "" {
+			fields[k] = v
+		}
+	}
+	at := e.CreatedDate
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+	severity := e.Severity
+	if severity == "" {
+		severity = SeverityInfo
+	}
+	return Notification{
+		Title:    "Audit: " + e.Action,
+		Text:     text,
+		Severity: severity,
+		Fields:   fields,
+		Time:     at,
+		DedupKey: e.ID,
+	}
+}
+
+// postJSON POSTs v as JSON to url and fails on a non-2xx response; channel names the
+// service in errors.
+func postJSON(ctx context.Context, client *http.Client, url string, v any, channel string) error {
+	if url == "" {
+		return fmt.Errorf("audittrail: %s URL must not be empty", channel)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("audittrail: encode %s notification: %w", channel, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("audittrail: %s notification: %w", channel, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("audittrail: %s notification: %s: %s", channel, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,179 @@
+package audittrail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotifyTamperSendsToChannels(t *testing.T) {
+	var webhook Notification
+	var slack map[string]string
+	var pd map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		switch r.URL.Path {
+		case "/hook":
+			err = json.NewDecoder(r.Body).Decode(&webhook)
+		case "/slack":
+			err = json.NewDecoder(r.Body).Decode(&slack)
+		case "/pd":
+			err = json.NewDecoder(r.Body).Decode(&pd)
+		}
+		if err != nil {
+			t.Errorf("decode %s: %v", r.URL.Path, err)
+		}
+	}))
+	defer srv.Close()
+	prev := pagerDutyURL
+	pagerDutyURL = srv.URL + "/pd"
+	defer func() { pagerDutyURL = prev }()
+
+	var mail string
+	prevSend := smtpSendMail
+	smtpSendMail = func(addr string, _ smtp.Auth, from string, to []string, msg []byte) error {
+		mail = string(msg)
+		return nil
+	}
+	defer func() { smtpSendMail = prevSend }()
+
+	n := MultiNotifier(
+		WebhookNotifier(srv.URL+"/hook", nil),
+		SlackNotifier(srv.URL+"/slack", nil),
+		PagerDutyNotifier("routing-key", nil),
+		EmailNotifier(SMTPConfig{Addr: "smtp.example.com:25", From: "audit@example.com", To: []string{"sec@example.com"}}),
+	)
+	NotifyTamper(n)(context.Background(), IntegrityMismatch{
+		Entry:    Entry{ID: "e-1", Action: "USER_DELETED"},
+		Stored:   "aaa",
+		Computed: "bbb",
+	})
+
+	if webhook.Severity != SeverityCritical || webhook.Fields["entry_id"] != "e-1" {
+		t.Fatalf("webhook payload: %+v", webhook)
+	}
+	if !strings.Contains(slack["text"], "*Audit trail tampering detected*") || !strings.Contains(slack["text"], "stored_hash: `aaa`") {
+		t.Fatalf("slack text: %q", slack["text"])
+	}
+	payload, _ := pd["payload"].(map[string]any)
+	if pd["routing_key"] != "routing-key" || pd["dedup_key"] != "audittrail-tamper-e-1" || payload["severity"] != "critical" {
+		t.Fatalf("pagerduty event: %+v", pd)
+	}
+	if !strings.Contains(mail, "Subject: Audit trail tampering detected\r\n") || !strings.Contains(mail, "computed_hash: bbb") {
+		t.Fatalf("email: %q", mail)
+	}
+}
+
+func TestNotifierErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	err := MultiNotifier(
+		SlackNotifier(srv.URL, nil),
+		NotifierFunc(func(context.Context, Notification) error { return errors.New("boom") }),
+	).Notify(context.Background(), Notification{Title: "t"})
+	if err == nil || !strings.Contains(err.Error(), "403 Forbidden: invalid_token") || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected both errors, got %v", err)
+	}
+	if err := EmailNotifier(SMTPConfig{}).Notify(context.Background(), Notification{}); err == nil {
+		t.Fatal("expected error for empty SMTP config")
+	}
+}
+
+func TestMailMessageStripsHeaderBreaks(t *testing.T) {
+	msg := string(mailMessage(SMTPConfig{From: "a@example.com", To: []string{"b@example.com"}}, "hi\r\nBcc: x@example.com", "body"))
+	if strings.Contains(msg, "\r\nBcc:") {
+		t.Fatalf("subject injected a header: %q", msg)
+	}
+}
+
+func TestNotifyAlertSendsInBackground(t *testing.T) {
+	got := make(chan Notification, 1)
+	alert := NotifyAlert(NotifierFunc(func(_ context.Context, n Notification) error {
+		got <- n
+		return nil
+	}))
+	alert(context.Background(), Alert{
+		Rule:  AlertRule{Name: "mass-delete", Window: time.Minute},
+		Actor: "user-9",
+		Count: 5,
+	})
+	select {
+	case n := <-got:
+		if n.Title != "Audit alert: mass-delete" || n.Fields["actor"] != "user-9" || n.Fields["count"] != "5" {
+			t.Fatalf("notification: %+v", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for notification")
+	}
+}
+
+func TestNotifierRecorderFiltersEntries(t *testing.T) {
+	got := make(chan Notification, 3)
+	rec := NotifierRecorder(NotifierFunc(func(_ context.Context, n Notification) error {
+		got <- n
+		return nil
+	}), NotifyFilter{Actions: []string{"ROLE_*", "USER_DELETED"}, MinSeverity: SeverityWarn})
+
+	for _, e := range []Entry{
+		{Action: "ROLE_GRANTED", Severity: SeverityWarn, Actor: "admin", EntityType: "user", EntityID: "42"},
+		{Action: "ROLE_GRANTED", Severity: SeverityInfo},
+		{Action: "ORDER_CREATED", Severity: SeverityCritical},
+	} {
+		if err := rec.Record(context.Background(), e); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	select {
+	case n := <-got:
+		if n.Title != "Audit: ROLE_GRANTED" || n.Text != "admin performed ROLE_GRANTED on user 42" || n.Severity != SeverityWarn {
+			t.Fatalf("notification: %+v", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for notification")
+	}
+	select {
+	case n := <-got:
+		t.Fatalf("unexpected notification: %+v", n)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestTeamsNotifierPostsAdaptiveCard(t *testing.T) {
+	var msg struct {
+		Type        string `json:"type"`
+		Attachments []struct {
+			ContentType string `json:"contentType"`
+			Content     struct {
+				Body []map[string]any `json:"body"`
+			} `json:"content"`
+		} `json:"attachments"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	err := TeamsNotifier(srv.URL, nil).Notify(context.Background(), EntryNotification(Entry{ID: "e-1", Action: "USER_DELETED", Actor: "admin"}))
+	if err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if msg.Type != "message" || len(msg.Attachments) != 1 || msg.Attachments[0].ContentType != "application/vnd.microsoft.card.adaptive" {
+		t.Fatalf("message: %+v", msg)
+	}
+	body := msg.Attachments[0].Content.Body
+	if len(body) != 3 || body[0]["text"] != "Audit: USER_DELETED" || body[2]["type"] != "FactSet" {
+		t.Fatalf("card body: %+v", body)
+	}
+}
@@ -0,0 +1,264 @@
+package audittrail
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ObjectStore uploads a finished object. Adapt cloud SDK clients with ObjectStoreFunc:
+//
+//	// AWS S3 (aws-sdk-go-v2)
+//	audittrail.ObjectStoreFunc(func(ctx context.Context, key string, body []byte, contentType string) error {
+//		_, err := s3Client.PutObject(ctx, &s3.PutObjectInput{
+//			Bucket: aws.String(bucket), Key: aws.String(key),
+//			Body: bytes.NewReader(body), ContentType: aws.String(contentType),
+//		})
+//		return err
+//	})
+//
+//	// Azure Blob Storage (azblob)
+//	audittrail.ObjectStoreFunc(func(ctx context.Context, key string, body []byte, _ string) error {
+//		_, err := blobClient.UploadBuffer(ctx, container, key, body, nil)
+//		return err
+//	})
+type ObjectStore interface {
+	Put(ctx context.Context, key string, body []byte, contentType string) error
+}
+
+// ObjectStoreFunc adapts a function to ObjectStore.
+type ObjectStoreFunc func(ctx context.Context, key string, body []byte, contentType string) error
+
+func (f ObjectStoreFunc) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	return f(ctx, key, body, contentType)
+}
+
+// ObjectConfig configures an ObjectRecorder.
+type ObjectConfig struct {
+	Store         ObjectStore
+	Prefix        string        // key prefix; default "audit"
+	MaxEntries    int           // flush once this many entries are buffered; default 10000
+	MaxBytes      int           // flush once the buffered (uncompressed) size reaches this; default 64 MiB
+	FlushInterval time.Duration // used by Run; default 5m
+	Uncompressed  bool          // write plain .jsonl instead of .jsonl.gz (or uncompressed Parquet pages)
+	Format        ObjectFormat  // default ObjectJSONL
+}
+
+// ObjectFormat selects the file format written by ObjectRecorder.
+type ObjectFormat int
+
+const (
+	ObjectJSONL   ObjectFormat = iota // newline-delimited JSON, gzipped unless Uncompressed
+	ObjectParquet                     // Parquet with the ParquetWriter schema
+)
+
+// objectPart buffers one partition until it is written.
+type objectPart struct {
+	lines   bytes.Buffer // JSONL
+	entries []Entry      // Parquet
+	count   int
+	size    int
+}
+
+// ObjectRecorder buffers entries and writes them as hour-partitioned JSONL or Parquet objects,
+// e.g. audit/dt=2024-06-01/hour=13/part-<writer>-0001.jsonl.gz, a layout Athena and BigQuery
+// external tables can read directly. Partitions follow each entry's CreatedDate in UTC.
+type ObjectRecorder struct {
+	cfg    ObjectConfig
+	writer string
+
+	mu      sync.Mutex
+	parts   map[time.Time]*objectPart
+	seq     map[time.Time]int
+	entries int
+	size    int
+}
+
+// NewObjectRecorder creates an object-storage recorder. Call Run (or Flush periodically)
+// so partially filled objects are written, and Close on shutdown.
+func NewObjectRecorder(cfg ObjectConfig) (*ObjectRecorder, error) {
+	if cfg.Store == nil {
+		return nil, errors.New("audittrail: object store must not be nil")
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "audit"
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 10000
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 64 << 20
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Minute
+	}
+	return &ObjectRecorder{
+		cfg:    cfg,
+		writer: RandomIDGenerator.NewID()[:8], // keeps part names unique across instances and restarts
+		parts:  map[time.Time]*objectPart{},
+		seq:    map[time.Time]int{},
+	}, nil
+}
+
+// Record buffers the entry and flushes once MaxEntries or MaxBytes is reached.
+func (o *ObjectRecorder) Record(ctx context.Context, entry Entry) error {
+	normalized, err := normalizeEntry(entry, nil)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(normalized)
+	if err != nil {
+		return marshalFailed("entry", err)
+	}
+
+	partition := normalized.CreatedDate.UTC().Truncate(time.Hour)
+	o.mu.Lock()
+	part, ok := o.parts[partition]
+	if !ok {
+		part = &objectPart{}
+		o.parts[partition] = part
+	}
+	if o.cfg.Format == ObjectParquet {
+		part.entries = append(part.entries, normalized)
+	} else {
+		part.lines.Write(line)
+		part.lines.WriteByte('\n')
+	}
+	part.count++
+	part.size += len(line) + 1
+	o.entries++
+	o.size += len(line) + 1
+	full := o.entries >= o.cfg.MaxEntries || o.size >= o.cfg.MaxBytes
+	o.mu.Unlock()
+
+	if full {
+		return o.Flush(ctx)
+	}
+	return nil
+}
+
+// Flush writes one object per buffered partition. Partitions that fail to upload stay
+// buffered for the next attempt; the first error is returned.
+func (o *ObjectRecorder) Flush(ctx context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	partitions := make([]time.Time, 0, len(o.parts))
+	for p := range o.parts {
+		partitions = append(partitions, p)
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i].Before(partitions[j]) })
+
+	var first error
+	for _, p := range partitions {
+		part := o.parts[p]
+		body, contentType, err := o.encode(part)
+		if err == nil {
+			err = o.cfg.Store.Put(ctx, o.key(p, o.seq[p]+1), body, contentType)
+		}
+		if err != nil {
+			if first == nil {
+				first = fmt.Errorf("audittrail: object upload failed: %w", err)
+			}
+			continue
+		}
+		o.seq[p]++
+		o.entries -= part.count
+		o.size -= part.size
+		delete(o.parts, p)
+	}
+	return first
+}
+
+// Pending reports how many entries are buffered.
+func (o *ObjectRecorder) Pending() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.entries
+}
+
+// Run flushes every FlushInterval until ctx is canceled, then makes a final flush.
+func (o *ObjectRecorder) Run(ctx context.Context) error {
+	ticker := time.NewTicker(o.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			return o.Flush(flushCtx)
+		case <-ticker.C:
+			if err := o.Flush(ctx); err != nil {
+				logger().Warn("audittrail: object flush failed", "error", err)
+			}
+		}
+	}
+}
+
+// Close writes any buffered entries.
+func (o *ObjectRecorder) Close(ctx context.Context) error {
+	return o.Flush(ctx)
+}
+
+func (o *ObjectRecorder) key(partition time.Time, seq int) string {
+	return path.Join(
+		o.cfg.Prefix,
+		"dt="+partition.Format("2006-01-02"),
+		fmt.Sprintf("hour=%02d", partition.Hour()),
+		fmt.Sprintf("part-%s-%04d%s", o.writer, seq, objectExt(o.cfg.Format, o.cfg.Uncompressed)),
+	)
+}
+
+// objectExt returns the file extension for objects written in format.
+func objectExt(format ObjectFormat, uncompressed bool) string {
+	switch {
+	case format == ObjectParquet:
+		return ".parquet"
+	case uncompressed:
+		return ".jsonl"
+	}
+	return ".jsonl.gz"
+}
+
+func (o *ObjectRecorder) encode(part *objectPart) ([]byte, string, error) {
+	return encodeObject(part, o.cfg.Format, o.cfg.Uncompressed)
+}
+
+// encodeObject renders a buffered part in the given format and returns the body and its
+// content type.
+func encodeObject(part *objectPart, format ObjectFormat, uncompressed bool) ([]byte, string, error) {
+	if format == ObjectParquet {
+		var buf bytes.Buffer
+		w := NewParquetWriter(&buf)
+		w.Uncompressed = uncompressed
+		for _, entry := range part.entries {
+			if err := w.Write(entry); err != nil {
+				return nil, "", err
+			}
+		}
+		if err := w.Close(); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "application/vnd.apache.parquet", nil
+	}
+	lines := part.lines.Bytes()
+	if uncompressed {
+		return append([]byte(nil), lines...), "application/x-ndjson", nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(lines); err != nil {
+		return nil, "", err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "application/gzip", nil
+}
@@ -0,0 +1,66 @@
+package audittrail
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestObjectRecorderWritesPartitionedJSONL(t *testing.T) {
+	objects := map[string][]byte{}
+	fail := false
+	store := ObjectStoreFunc(func(_ context.Context, key string, body []byte, contentType string) error {
+		if fail {
+			return errors.New("unavailable")
+		}
+		if contentType != "application/gzip" {
+			t.Errorf("unexpected content type %q", contentType)
+		}
+		objects[key] = body
+		return nil
+	})
+	rec, err := NewObjectRecorder(ObjectConfig{Store: store, MaxEntries: 100})
+	if err != nil {
+		t.Fatalf("NewObjectRecorder: %v", err)
+	}
+
+	ctx := context.Background()
+	h13 := time.Date(2024, 6, 1, 13, 5, 0, 0, time.UTC)
+	for _, at := range []time.Time{h13, h13.Add(10 * time.Minute), h13.Add(time.Hour)} {
+		if err := rec.Record(ctx, Entry{Action: "EXPORT", CreatedDate: at}); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	fail = true
+	if err := rec.Flush(ctx); err == nil || rec.Pending() != 3 {
+		t.Fatalf("expected failed flush to keep entries, err=%v pending=%d", err, rec.Pending())
+	}
+	fail = false
+	if err := rec.Close(ctx); err != nil || rec.Pending() != 0 {
+		t.Fatalf("Close: err=%v pending=%d", err, rec.Pending())
+	}
+	if len(objects) != 2 {
+		t.Fatalf("expected 2 objects, got %d", len(objects))
+	}
+
+	for key, body := range objects {
+		if !strings.HasPrefix(key, "audit/dt=2024-06-01/hour=1") || !strings.HasSuffix(key, "-0001.jsonl.gz") {
+			t.Fatalf("unexpected key %q", key)
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("gzip: %v", err)
+		}
+		data, _ := io.ReadAll(zr)
+		lines := strings.Count(string(data), "\n")
+		if strings.Contains(key, "hour=13") && lines != 2 || strings.Contains(key, "hour=14") && lines != 1 {
+			t.Fatalf("unexpected line count %d in %s", lines, key)
+		}
+	}
+}
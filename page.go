@@ -0,0 +1,88 @@
+package audittrail
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// ErrInvalidCursor is returned by Query for cursors it did not produce.
+var ErrInvalidCursor = errors.New("audittrail: invalid cursor")
+
+// PageRequest selects one page of a Query. Cursor is empty for the first page and
+// Page.NextCursor afterwards; Limit defaults to 100 and is capped at 1000.
+type PageRequest struct {
+	Cursor string
+	Limit  int
+}
+
+// Page is one page of Query results, oldest first. NextCursor is empty on the last page.
+type Page struct {
+	Entries    []Entry
+	NextCursor string
+}
+
+// Query returns one page of entries matching f. Pages are addressed by an opaque cursor
+// built from the last entry's created date and ID (keyset pagination), so deep pages are
+// as fast as the first and entries inserted meanwhile do not shift pages.
+func (r *AuditTrail) Query(ctx context.Context, f Filter, req PageRequest) (Page, error) {
+	if r == nil || r.db == nil {
+		return Page{}, errors.New("audittrail: instance is not initialized")
+	}
+	pos, err := decodeCursor(req.Cursor)
+	if err != nil {
+		return Page{}, err
+	}
+	ctx = r.auditRead(ctx, "query", f, nil)
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+	limit = min(limit, maxPageLimit)
+
+	entries, err := r.keysetPage(ctx, f, pos, limit+1)
+	if err != nil {
+		return Page{}, err
+	}
+	page := Page{Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		last := page.Entries[limit-1]
+		page.NextCursor = encodeCursor(keysetPosition{createdDate: last.CreatedDate, id: last.ID})
+	}
+	return page, nil
+}
+
+// encodeCursor renders a position as base64url("<unix nanos>:<id>").
+func encodeCursor(pos keysetPosition) string {
+	raw := fmt.Sprintf("%d:%s", pos.createdDate.UnixNano(), pos.id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (keysetPosition, error) {
+	if cursor == "" {
+		return keysetPosition{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return keysetPosition{}, ErrInvalidCursor
+	}
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok || id == "" {
+		return keysetPosition{}, ErrInvalidCursor
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return keysetPosition{}, ErrInvalidCursor
+	}
+	return keysetPosition{createdDate: time.Unix(0, n).UTC(), id: id}, nil
+}
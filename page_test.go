@@ -0,0 +1,76 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryPagesWithCursor(t *testing.T) {
+	base := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	all := []string{"a", "b", "c", "d", "e"}
+	var queries []string
+	var lastArgs []driver.NamedValue
+	db := openStubDB(t, &stubDriver{queryFn: func(query string, args []driver.NamedValue) (driver.Rows, error) {
+		queries = append(queries, query)
+		lastArgs = args
+		start := 0
+		if len(args) == 3 {
+			for i, id := range all {
+				if id == args[2].Value {
+					start = i + 1
+				}
+			}
+		}
+		var values [][]driver.Value
+		for i := start; i < len(all) && len(values) < 3; i++ {
+			values = append(values, []driver.Value{all[i], nil, "VIEW", nil, nil, nil, base.Add(time.Duration(i) * time.Second),
+				nil, nil, nil, nil, nil, nil, nil, nil, nil})
+		}
+		return &stubRows{columns: strings.Split(EntryColumns, ", "), values: values}, nil
+	}})
+	audit, err := NewAuditTrail(Config{DB: db})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	ctx := context.Background()
+	first, err := audit.Query(ctx, Filter{}, PageRequest{Limit: 2})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(first.Entries) != 2 || first.Entries[1].ID != "b" || first.NextCursor == "" {
+		t.Fatalf("unexpected first page: %+v", first)
+	}
+	if !strings.HasSuffix(queries[0], "ORDER BY log_created_date, log_audit_trail_id LIMIT 3") || strings.Contains(queries[0], "OFFSET") {
+		t.Fatalf("unexpected query: %s", queries[0])
+	}
+
+	second, err := audit.Query(ctx, Filter{}, PageRequest{Cursor: first.NextCursor, Limit: 2})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(second.Entries) != 2 || second.Entries[0].ID != "c" || !lastArgs[0].Value.(time.Time).Equal(base.Add(time.Second)) {
+		t.Fatalf("unexpected second page: %+v", second)
+	}
+
+	last, err := audit.Query(ctx, Filter{}, PageRequest{Cursor: second.NextCursor, Limit: 2})
+	if err != nil || len(last.Entries) != 1 || last.NextCursor != "" {
+		t.Fatalf("unexpected last page: %+v, %v", last, err)
+	}
+
+	if _, err := audit.Query(ctx, Filter{}, PageRequest{Cursor: "not a cursor!"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	pos := keysetPosition{createdDate: time.Date(2024, 6, 1, 12, 0, 0, 123456789, time.UTC), id: "id:with:colons"}
+	got, err := decodeCursor(encodeCursor(pos))
+	if err != nil || !got.createdDate.Equal(pos.createdDate) || got.id != pos.id {
+		t.Fatalf("round trip: %+v, %v", got, err)
+	}
+}
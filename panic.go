@@ -0,0 +1,52 @@
+package audittrail
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"runtime/debug"
+)
+
+// OutcomePanic is the PanicReport outcome of a request whose handler panicked.
+const OutcomePanic = "panic"
+
+// panicStackLimit bounds the stack kept in a PanicReport; the full stack belongs in logs.
+const panicStackLimit = 2048
+
+// PanicReport is recorded as the response payload of a request whose handler panicked. The
+// middlewares record it with status 500 and then re-panic, so the recovery middleware of the
+// application (gin.Recovery, net/http) still handles the panic.
+type PanicReport struct {
+	Outcome     string `json:"outcome"` // OutcomePanic
+	Panic       string `json:"panic"`   // the recovered value
+	Stack       string `json:"stack"`   // the first 2KB of the goroutine stack
+	StackSHA256 string `json:"stack_sha256"`
+	Truncated   bool   `json:"truncated,omitempty"`
+}
+
+// callRecovering runs fn and returns what it panicked with and a report for the entry.
+func callRecovering(fn func()) (recovered any, report *PanicReport) {
+	defer func() {
+		if v := recover(); v != nil {
+			recovered = v
+			report = newPanicReport(v, debug.Stack())
+		}
+	}()
+	fn()
+	return nil, nil
+}
+
+func newPanicReport(v any, stack []byte) *PanicReport {
+	sum := sha256.Sum256(stack)
+	report := &PanicReport{
+		Outcome:     OutcomePanic,
+		Panic:       fmt.Sprint(v),
+		Stack:       string(stack),
+		StackSHA256: hex.EncodeToString(sum[:]),
+	}
+	if len(stack) > panicStackLimit {
+		report.Stack = string(stack[:panicStackLimit])
+		report.Truncated = true
+	}
+	return report
+}
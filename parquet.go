@@ -0,0 +1,449 @@
+package audittrail
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// Parquet physical types, converted types and enums used by ParquetWriter (parquet.thrift).
+const (
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetUTF8            = 0
+	parquetTimestampMicros = 10
+	parquetSigned64        = 18 // INT_64
+	parquetJSON            = 19
+
+	parquetRequired = 0
+	parquetOptional = 1
+
+	parquetPlain = 0
+	parquetRLE   = 3
+
+	parquetUncompressed = 0
+	parquetGzip         = 2
+)
+
+var parquetMagic = []byte("PAR1")
+
+// parquetColumn maps one Entry field onto a flat Parquet column.
+type parquetColumn struct {
+	name      string
+	physical  int32
+	converted int32
+	optional  bool
+	bytes     func(Entry) ([]byte, bool, error) // BYTE_ARRAY columns
+	int64     func(Entry) (int64, bool)         // INT64 columns
+}
+
+func stringColumn(name string, optional bool, get func(Entry) string) parquetColumn {
+	return parquetColumn{name: name, physical: parquetByteArray, converted: parquetUTF8, optional: optional,
+		bytes: func(e Entry) ([]byte, bool, error) {
+			v := nullString(get(e))
+			return []byte(v.String), v.Valid, nil
+		}}
+}
+
+func timestampColumn(name string, optional bool, get func(Entry) time.Time) parquetColumn {
+	return parquetColumn{name: name, physical: parquetInt64, converted: parquetTimestampMicros, optional: optional,
+		int64: func(e Entry) (int64, bool) {
+			t := get(e)
+			return t.UTC().UnixMicro(), !t.IsZero() || !optional
+		}}
+}
+
+func int64Column(name string, get func(Entry) int64) parquetColumn {
+	return parquetColumn{name: name, physical: parquetInt64, converted: parquetSigned64, optional: true,
+		int64: func(e Entry) (int64, bool) {
+			v := get(e)
+			return v, v != 0
+		}}
+}
+
+func jsonColumn(name, what string, get func(Entry) any) parquetColumn {
+	return parquetColumn{name: name, physical: parquetByteArray, converted: parquetJSON, optional: true,
+		bytes: func(e Entry) ([]byte, bool, error) {
+			v, err := marshalJSONValue(get(e))
+			if err != nil {
+				return nil, false, marshalFailed(what, err)
+			}
+			return []byte(v.String), v.Valid, nil
+		}}
+}
+
+// parquetSchema mirrors the audit table: one column per Entry field, payloads as JSON
+// strings and created_date as a UTC microsecond timestamp.
+var parquetSchema = []parquetColumn{
+	stringColumn("log_audit_trail_id", false, func(e Entry) string { return e.ID }),
+	stringColumn("log_req_id", true, func(e Entry) string { return e.RequestID }),
+	stringColumn("log_action", false, func(e Entry) string { return e.Action }),
+	stringColumn("log_endpoint", true, func(e Entry) string { return e.Endpoint }),
+	jsonColumn("log_request", "request", func(e Entry) any { return e.Request }),
+	jsonColumn("log_response", "response", func(e Entry) any { return e.Response }),
+	timestampColumn("log_created_date", false, func(e Entry) time.Time { return e.CreatedDate }),
+	stringColumn("log_created_by", true, func(e Entry) string { return e.CreatedBy }),
+	timestampColumn("log_expires_at", true, func(e Entry) time.Time { return e.ExpiresAt }),
+	stringColumn("log_severity", true, func(e Entry) string { return string(e.Severity) }),
+	stringColumn("log_parent_id", true, func(e Entry) string { return e.ParentID }),
+	stringColumn("log_correlation_id", true, func(e Entry) string { return e.CorrelationID }),
+	stringColumn("log_session_id", true, func(e Entry) string { return e.SessionID }),
+	stringColumn("log_app_version", true, func(e Entry) string { return e.AppVersion }),
+	stringColumn("log_hostname", true, func(e Entry) string { return e.Hostname }),
+	stringColumn("log_instance_id", true, func(e Entry) string { return e.InstanceID }),
+	stringColumn("log_impersonated_by", true, func(e Entry) string { return e.ImpersonatedBy }),
+	stringColumn("log_actor", true, func(e Entry) string { return e.Actor }),
+	stringColumn("log_ip_address", true, func(e Entry) string { return e.IPAddress }),
+	stringColumn("log_service_name", true, func(e Entry) string { return e.ServiceName }),
+	timestampColumn("log_started_at", true, func(e Entry) time.Time { return e.StartedAt }),
+	timestampColumn("log_ended_at", true, func(e Entry) time.Time { return e.EndedAt }),
+	int64Column("log_duration_ms", func(e Entry) int64 { return e.DurationMS }),
+	stringColumn("log_entity_type", true, func(e Entry) string { return e.EntityType }),
+	stringColumn("log_entity_id", true, func(e Entry) string { return e.EntityID }),
+	jsonColumn("log_meta", "meta", func(e Entry) any {
+		if len(e.Meta) == 0 {
+			return nil
+		}
+		return e.Meta
+	}),
+}
+
+// ParquetWriter streams entries into a Parquet file with a columnar schema matching Entry,
+// for audit archives queried from Spark, Athena, BigQuery or DuckDB. Rows are buffered and
+// written as one row group per RowGroupSize entries; Close writes the footer.
+//
+// It also implements Recorder, so it can be used directly as a file sink.
+type ParquetWriter struct {
+	// RowGroupSize is the number of rows per row group; default 100000.
+	RowGroupSize int
+	// Uncompressed disables gzip compression of data pages.
+	Uncompressed bool
+
+	mu      sync.Mutex
+	w       io.Writer
+	offset  int64
+	rows    []Entry
+	groups  [][]byte // encoded RowGroup structs
+	numRows int64
+	closed  bool
+}
+
+// NewParquetWriter creates a writer that writes a Parquet file to w.
+func NewParquetWriter(w io.Writer) *ParquetWriter {
+	return &ParquetWriter{w: w}
+}
+
+// Record normalizes the entry and appends it to the file.
+func (p *ParquetWriter) Record(_ context.Context, entry Entry) error {
+	normalized, err := normalizeEntry(entry, nil)
+	if err != nil {
+		return err
+	}
+	return p.Write(normalized)
+}
+
+// Write appends an entry as written, flushing a row group when it is full.
+func (p *ParquetWriter) Write(entry Entry) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return errors.New("audittrail: parquet writer is closed")
+	}
+	size := p.RowGroupSize
+	if size <= 0 {
+		size = 100000
+	}
+	p.rows = append(p.rows, entry)
+	if len(p.rows) >= size {
+		return p.flushRowGroup()
+	}
+	return nil
+}
+
+// Close writes the buffered rows and the file footer. It does not close the underlying writer.
+func (p *ParquetWriter) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	if err := p.flushRowGroup(); err != nil {
+		return err
+	}
+	if err := p.writeMagic(); err != nil {
+		return err
+	}
+
+	var t thriftWriter
+	t.i32(1, 1)
+	t.listBegin(2, thriftStruct, len(parquetSchema)+1)
+	t.elemBegin()
+	t.binary(4, []byte("schema"))
+	t.i32(5, int32(len(parquetSchema)))
+	t.structEnd()
+	for _, col := range parquetSchema {
+		t.elemBegin()
+		t.i32(1, col.physical)
+		rep := int32(parquetRequired)
+		if col.optional {
+			rep = parquetOptional
+		}
+		t.i32(3, rep)
+		t.binary(4, []byte(col.name))
+		t.i32(6, col.converted)
+		t.structEnd()
+	}
+	t.i64(3, p.numRows)
+	t.listBegin(4, thriftStruct, len(p.groups))
+	for _, g := range p.groups {
+		t.buf.Write(g)
+	}
+	t.binary(6, []byte("audittrail"))
+	t.buf.WriteByte(0)
+
+	footer := t.buf.Bytes()
+	var tail [8]byte
+	binary.LittleEndian.PutUint32(tail[:4], uint32(len(footer)))
+	copy(tail[4:], parquetMagic)
+	if err := p.write(footer); err != nil {
+		return err
+	}
+	return p.write(tail[:])
+}
+
+func (p *ParquetWriter) write(b []byte) error {
+	n, err := p.w.Write(b)
+	p.offset += int64(n)
+	return err
+}
+
+func (p *ParquetWriter) writeMagic() error {
+	if p.offset > 0 {
+		return nil
+	}
+	return p.write(parquetMagic)
+}
+
+// flushRowGroup writes the buffered rows as one row group with a single data page per column.
+func (p *ParquetWriter) flushRowGroup() error {
+	if len(p.rows) == 0 {
+		return nil
+	}
+	if err := p.writeMagic(); err != nil {
+		return err
+	}
+
+	codec := int32(parquetGzip)
+	if p.Uncompressed {
+		codec = parquetUncompressed
+	}
+	var group thriftWriter
+	group.listBegin(1, thriftStruct, len(parquetSchema))
+	var totalSize int64
+	for _, col := range parquetSchema {
+		raw, err := encodeParquetPage(col, p.rows)
+		if err != nil {
+			return err
+		}
+		data := raw
+		if codec == parquetGzip {
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			_, _ = zw.Write(raw)
+			if err := zw.Close(); err != nil {
+				return err
+			}
+			data = buf.Bytes()
+		}
+
+		var header thriftWriter
+		header.i32(1, 0) // DATA_PAGE
+		header.i32(2, int32(len(raw)))
+		header.i32(3, int32(len(data)))
+		header.structBegin(5)
+		header.i32(1, int32(len(p.rows)))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.structEnd()
+		header.buf.WriteByte(0)
+
+		pageOffset := p.offset
+		if err := p.write(header.buf.Bytes()); err != nil {
+			return err
+		}
+		if err := p.write(data); err != nil {
+			return err
+		}
+		uncompressed := int64(header.buf.Len() + len(raw))
+		compressed := int64(header.buf.Len() + len(data))
+		totalSize += uncompressed
+
+		group.elemBegin()
+		group.i64(2, pageOffset)
+		group.structBegin(3)
+		group.i32(1, col.physical)
+		group.listBegin(2, thriftI32, 2)
+		group.varint(zigzag(parquetPlain))
+		group.varint(zigzag(parquetRLE))
+		group.listBegin(3, thriftBinary, 1)
+		group.varint(uint64(len(col.name)))
+		group.buf.WriteString(col.name)
+		group.i32(4, codec)
+		group.i64(5, int64(len(p.rows)))
+		group.i64(6, uncompressed)
+		group.i64(7, compressed)
+		group.i64(9, pageOffset)
+		group.structEnd()
+		group.structEnd()
+	}
+	group.i64(2, totalSize)
+	group.i64(3, int64(len(p.rows)))
+	group.buf.WriteByte(0)
+
+	p.groups = append(p.groups, group.buf.Bytes())
+	p.numRows += int64(len(p.rows))
+	p.rows = p.rows[:0]
+	return nil
+}
+
+// encodeParquetPage returns the uncompressed body of a v1 data page: definition levels
+// (optional columns only) followed by the PLAIN-encoded non-null values.
+func encodeParquetPage(col parquetColumn, rows []Entry) ([]byte, error) {
+	var values bytes.Buffer
+	levels := make([]bool, len(rows))
+	var scratch [8]byte
+	for i, e := range rows {
+		if col.int64 != nil {
+			v, ok := col.int64(e)
+			if !ok {
+				continue
+			}
+			binary.LittleEndian.PutUint64(scratch[:], uint64(v))
+			values.Write(scratch[:8])
+			levels[i] = true
+			continue
+		}
+		b, ok, err := col.bytes(e)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		levels[i] = true
+		binary.LittleEndian.PutUint32(scratch[:4], uint32(len(b)))
+		values.Write(scratch[:4])
+		values.Write(b)
+	}
+	if !col.optional {
+		return values.Bytes(), nil
+	}
+
+	rle := encodeLevels(levels)
+	page := make([]byte, 4, 4+len(rle)+values.Len())
+	binary.LittleEndian.PutUint32(page, uint32(len(rle)))
+	page = append(page, rle...)
+	return append(page, values.Bytes()...), nil
+}
+
+// encodeLevels writes definition levels (bit width 1) as RLE runs of the hybrid encoding.
+func encodeLevels(levels []bool) []byte {
+	var out []byte
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		out = binary.AppendUvarint(out, uint64(j-i)<<1)
+		if levels[i] {
+			out = append(out, 1)
+		} else {
+			out = append(out, 0)
+		}
+		i = j
+	}
+	return out
+}
+
+// Thrift compact protocol type ids.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter is a minimal Thrift compact protocol encoder for Parquet metadata.
+type thriftWriter struct {
+	buf   bytes.Buffer
+	last  int16
+	stack []int16
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(zigzag(int64(id)))
+	}
+	t.last = id
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	t.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func zigzag(v int64) uint64 { return uint64((v << 1) ^ (v >> 63)) }
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) binary(id int16, b []byte) {
+	t.field(id, thriftBinary)
+	t.varint(uint64(len(b)))
+	t.buf.Write(b)
+}
+
+func (t *thriftWriter) listBegin(id int16, elem byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf.WriteByte(byte(n)<<4 | elem)
+		return
+	}
+	t.buf.WriteByte(0xf0 | elem)
+	t.varint(uint64(n))
+}
+
+func (t *thriftWriter) structBegin(id int16) {
+	t.field(id, thriftStruct)
+	t.elemBegin()
+}
+
+// elemBegin starts a struct that is a list element (no field header).
+func (t *thriftWriter) elemBegin() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+func (t *thriftWriter) structEnd() {
+	t.buf.WriteByte(0)
+	t.last = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
@@ -0,0 +1,365 @@
+package audittrail
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// errParquetFormat reports a file readParquet cannot decode.
+var errParquetFormat = errors.New("audittrail: unsupported or corrupt parquet file")
+
+// parquetSetters fills Entry fields from decoded column values, keyed by column name. Columns
+// not listed (written by newer versions) are ignored.
+var parquetSetters = map[string]func(*Entry, []byte, int64){
+	"log_audit_trail_id":  func(e *Entry, b []byte, _ int64) { e.ID = string(b) },
+	"log_req_id":          func(e *Entry, b []byte, _ int64) { e.RequestID = string(b) },
+	"log_action":          func(e *Entry, b []byte, _ int64) { e.Action = string(b) },
+	"log_endpoint":        func(e *Entry, b []byte, _ int64) { e.Endpoint = string(b) },
+	"log_request":         func(e *Entry, b []byte, _ int64) { e.Request = decodePayload(b) },
+	"log_response":        func(e *Entry, b []byte, _ int64) { e.Response = decodePayload(b) },
+	"log_created_date":    func(e *Entry, _ []byte, v int64) { e.CreatedDate = time.UnixMicro(v).UTC() },
+	"log_created_by":      func(e *Entry, b []byte, _ int64) { e.CreatedBy = string(b) },
+	"log_expires_at":      func(e *Entry, _ []byte, v int64) { e.ExpiresAt = time.UnixMicro(v).UTC() },
+	"log_severity":        func(e *Entry, b []byte, _ int64) { e.Severity = Severity(b) },
+	"log_parent_id":       func(e *Entry, b []byte, _ int64) { e.ParentID = string(b) },
+	"log_correlation_id":  func(e *Entry, b []byte, _ int64) { e.CorrelationID = string(b) },
+	"log_session_id":      func(e *Entry, b []byte, _ int64) { e.SessionID = string(b) },
+	"log_app_version":     func(e *Entry, b []byte, _ int64) { e.AppVersion = string(b) },
+	"log_hostname":        func(e *Entry, b []byte, _ int64) { e.Hostname = string(b) },
+	"log_instance_id":     func(e *Entry, b []byte, _ int64) { e.InstanceID = string(b) },
+	"log_impersonated_by": func(e *Entry, b []byte, _ int64) { e.ImpersonatedBy = string(b) },
+	"log_actor":           func(e *Entry, b []byte, _ int64) { e.Actor = string(b) },
+	"log_ip_address":      func(e *Entry, b []byte, _ int64) { e.IPAddress = string(b) },
+	"log_service_name":    func(e *Entry, b []byte, _ int64) { e.ServiceName = string(b) },
+	"log_started_at":      func(e *Entry, _ []byte, v int64) { e.StartedAt = time.UnixMicro(v).UTC() },
+	"log_ended_at":        func(e *Entry, _ []byte, v int64) { e.EndedAt = time.UnixMicro(v).UTC() },
+	"log_duration_ms":     func(e *Entry, _ []byte, v int64) { e.DurationMS = v },
+	"log_entity_type":     func(e *Entry, b []byte, _ int64) { e.EntityType = string(b) },
+	"log_entity_id":       func(e *Entry, b []byte, _ int64) { e.EntityID = string(b) },
+	"log_meta":            func(e *Entry, b []byte, _ int64) { e.Meta = unmarshalMeta(b) },
+}
+
+// readParquet decodes a Parquet file into entries. It supports what ParquetWriter produces:
+// flat INT64 and BYTE_ARRAY columns in v1 data pages, PLAIN encoded, uncompressed or gzip.
+func readParquet(data []byte) ([]Entry, error) {
+	if len(data) < 12 || !bytes.Equal(data[:4], parquetMagic) || !bytes.Equal(data[len(data)-4:], parquetMagic) {
+		return nil, errParquetFormat
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if footerLen <= 0 || footerLen > len(data)-12 {
+		return nil, errParquetFormat
+	}
+	meta, err := (&thriftDecoder{data: data[len(data)-8-footerLen : len(data)-8]}).readStruct()
+	if err != nil {
+		return nil, err
+	}
+
+	// Schema element 0 is the root; the rest are the flat columns.
+	optional := map[string]bool{}
+	for _, el := range asList(meta[2]) {
+		s, _ := el.(thriftFields)
+		if name, ok := s[4].([]byte); ok && s[5] == nil {
+			optional[string(name)] = s[3] == int64(parquetOptional)
+		}
+	}
+
+	var entries []Entry
+	for _, g := range asList(meta[4]) {
+		group, _ := g.(thriftFields)
+		rows, _ := group[3].(int64)
+		start := len(entries)
+		entries = append(entries, make([]Entry, rows)...)
+		for _, c := range asList(group[1]) {
+			chunk, _ := c.(thriftFields)
+			cm, _ := chunk[3].(thriftFields)
+			path := asList(cm[3])
+			if len(path) != 1 {
+				return nil, errParquetFormat
+			}
+			name := string(path[0].([]byte))
+			set, ok := parquetSetters[name]
+			if !ok {
+				continue
+			}
+			physical, _ := cm[1].(int64)
+			codec, _ := cm[4].(int64)
+			offset, _ := cm[9].(int64)
+			if err := readParquetColumn(data, offset, codec, physical, optional[name], entries[start:], set); err != nil {
+				return nil, fmt.Errorf("audittrail: parquet column %s: %w", name, err)
+			}
+		}
+	}
+	return entries, nil
+}
+
+// readParquetColumn decodes the data pages of one column chunk starting at offset.
+func readParquetColumn(data []byte, offset, codec, physical int64, optional bool, rows []Entry,
+	set func(*Entry, []byte, int64)) error {
+	row := 0
+	for row < len(rows) {
+		if offset < 0 || offset >= int64(len(data)) {
+			return errParquetFormat
+		}
+		hr := &thriftDecoder{data: data[offset:]}
+		header, err := hr.readStruct()
+		if err != nil {
+			return err
+		}
+		if header[1] != int64(0) { // DATA_PAGE
+			return errParquetFormat
+		}
+		size, _ := header[3].(int64)
+		start := offset + int64(hr.pos)
+		if size < 0 || start+size > int64(len(data)) {
+			return errParquetFormat
+		}
+		page := data[start : start+size]
+		offset = start + size
+
+		switch codec {
+		case parquetUncompressed:
+		case parquetGzip:
+			zr, err := gzip.NewReader(bytes.NewReader(page))
+			if err != nil {
+				return err
+			}
+			if page, err = io.ReadAll(zr); err != nil {
+				return err
+			}
+		default:
+			return errParquetFormat
+		}
+		dph, _ := header[5].(thriftFields)
+		n, _ := dph[1].(int64)
+		if n <= 0 || row+int(n) > len(rows) || dph[2] != int64(parquetPlain) {
+			return errParquetFormat
+		}
+
+		defined := make([]bool, n)
+		for i := range defined {
+			defined[i] = true
+		}
+		if optional {
+			if len(page) < 4 {
+				return errParquetFormat
+			}
+			levelsLen := int(binary.LittleEndian.Uint32(page))
+			if levelsLen > len(page)-4 {
+				return errParquetFormat
+			}
+			if err := decodeLevels(page[4:4+levelsLen], defined); err != nil {
+				return err
+			}
+			page = page[4+levelsLen:]
+		}
+
+		for i := range defined {
+			if !defined[i] {
+				continue
+			}
+			switch physical {
+			case parquetInt64:
+				if len(page) < 8 {
+					return errParquetFormat
+				}
+				set(&rows[row+i], nil, int64(binary.LittleEndian.Uint64(page)))
+				page = page[8:]
+			case parquetByteArray:
+				if len(page) < 4 {
+					return errParquetFormat
+				}
+				l := int(binary.LittleEndian.Uint32(page))
+				if l > len(page)-4 {
+					return errParquetFormat
+				}
+				set(&rows[row+i], page[4:4+l], 0)
+				page = page[4+l:]
+			default:
+				return errParquetFormat
+			}
+		}
+		row += int(n)
+	}
+	return nil
+}
+
+// decodeLevels reads bit-width-1 definition levels in the RLE/bit-packed hybrid encoding.
+func decodeLevels(data []byte, levels []bool) error {
+	i := 0
+	for i < len(levels) {
+		header, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errParquetFormat
+		}
+		data = data[n:]
+		if header&1 == 0 { // RLE run
+			if len(data) < 1 {
+				return errParquetFormat
+			}
+			for run := int(header >> 1); run > 0 && i < len(levels); run-- {
+				levels[i] = data[0] == 1
+				i++
+			}
+			data = data[1:]
+			continue
+		}
+		groups := int(header >> 1) // bit-packed: groups of 8 values, one byte each at width 1
+		if len(data) < groups {
+			return errParquetFormat
+		}
+		for _, b := range data[:groups] {
+			for bit := 0; bit < 8 && i < len(levels); bit++ {
+				levels[i] = b&(1<<bit) != 0
+				i++
+			}
+		}
+		data = data[groups:]
+	}
+	return nil
+}
+
+// thriftFields is a decoded Thrift struct: field id to value. Integers decode as int64,
+// binaries as []byte, lists as []any and nested structs as thriftFields.
+type thriftFields map[int16]any
+
+func asList(v any) []any {
+	l, _ := v.([]any)
+	return l
+}
+
+// thriftDecoder is a minimal Thrift compact protocol decoder for Parquet metadata.
+type thriftDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (t *thriftDecoder) byte() (byte, error) {
+	if t.pos >= len(t.data) {
+		return 0, errParquetFormat
+	}
+	b := t.data[t.pos]
+	t.pos++
+	return b, nil
+}
+
+func (t *thriftDecoder) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(t.data[t.pos:])
+	if n <= 0 {
+		return 0, errParquetFormat
+	}
+	t.pos += n
+	return v, nil
+}
+
+func (t *thriftDecoder) readStruct() (thriftFields, error) {
+	fields := thriftFields{}
+	var last int16
+	for {
+		b, err := t.byte()
+		if err != nil {
+			return nil, err
+		}
+		if b == 0 {
+			return fields, nil
+		}
+		typ := b & 0x0f
+		id := last + int16(b>>4)
+		if b>>4 == 0 {
+			v, err := t.uvarint()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(int64(v>>1) ^ -int64(v&1))
+		}
+		last = id
+		v, err := t.readValue(typ)
+		if err != nil {
+			return nil, err
+		}
+		fields[id] = v
+	}
+}
+
+func (t *thriftDecoder) readValue(typ byte) (any, error) {
+	switch typ {
+	case 1, 2: // boolean true/false in a field header
+		return typ == 1, nil
+	case 3: // byte
+		b, err := t.byte()
+		return int64(int8(b)), err
+	case 4, thriftI32, thriftI64:
+		v, err := t.uvarint()
+		return int64(v>>1) ^ -int64(v&1), err
+	case 7: // double
+		if t.pos+8 > len(t.data) {
+			return nil, errParquetFormat
+		}
+		t.pos += 8
+		return nil, nil
+	case thriftBinary:
+		n, err := t.uvarint()
+		if err != nil || n > uint64(len(t.data)-t.pos) {
+			return nil, errParquetFormat
+		}
+		b := t.data[t.pos : t.pos+int(n)]
+		t.pos += int(n)
+		return b, nil
+	case thriftList, 10: // list, set
+		h, err := t.byte()
+		if err != nil {
+			return nil, err
+		}
+		n := uint64(h >> 4)
+		if n == 15 {
+			if n, err = t.uvarint(); err != nil {
+				return nil, err
+			}
+		}
+		if n > uint64(len(t.data)-t.pos) {
+			return nil, errParquetFormat
+		}
+		elem := h & 0x0f
+		list := make([]any, 0, n)
+		for i := uint64(0); i < n; i++ {
+			var v any
+			if elem == 1 || elem == 2 { // booleans in lists take a byte each
+				b, err := t.byte()
+				if err != nil {
+					return nil, err
+				}
+				v = b == 1
+			} else if v, err = t.readValue(elem); err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case 11: // map; decoded only to skip it
+		n, err := t.uvarint()
+		if err != nil || n == 0 {
+			return nil, err
+		}
+		kv, err := t.byte()
+		if err != nil {
+			return nil, err
+		}
+		for i := uint64(0); i < 2*n; i++ {
+			elem := kv >> 4
+			if i%2 == 1 {
+				elem = kv & 0x0f
+			}
+			if _, err := t.readValue(elem); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case thriftStruct:
+		return t.readStruct()
+	}
+	return nil, errParquetFormat
+}
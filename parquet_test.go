@@ -0,0 +1,188 @@
+package audittrail
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// thriftReader decodes Thrift compact structs into field id -> value maps for assertions.
+type thriftReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) value(typ byte) any {
+	switch typ {
+	case 1, 2:
+		return typ == 1
+	case 3:
+		r.pos++
+		return int64(r.b[r.pos-1])
+	case 4, 5, 6:
+		u := r.uvarint()
+		return int64(u>>1) ^ -int64(u&1)
+	case 8:
+		n := int(r.uvarint())
+		r.pos += n
+		return string(r.b[r.pos-n : r.pos])
+	case 9:
+		h := r.b[r.pos]
+		r.pos++
+		n, elem := int(h>>4), h&0x0f
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i] = r.value(elem)
+		}
+		return list
+	case 12:
+		return r.structValue()
+	}
+	panic("unsupported thrift type")
+}
+
+func (r *thriftReader) structValue() map[int16]any {
+	fields := map[int16]any{}
+	var last int16
+	for {
+		h := r.b[r.pos]
+		r.pos++
+		if h == 0 {
+			return fields
+		}
+		id := last + int16(h>>4)
+		if h>>4 == 0 {
+			u := r.uvarint()
+			id = int16(int64(u>>1) ^ -int64(u&1))
+		}
+		fields[id] = r.value(h & 0x0f)
+		last = id
+	}
+}
+
+func TestParquetWriterLayout(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewParquetWriter(&buf)
+	w.Uncompressed = true
+	created := time.Date(2024, 6, 1, 13, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{ID: "a", Action: "CREATE", CreatedBy: "u1", Request: map[string]any{"x": 1}, CreatedDate: created},
+		{ID: "b", Action: "DELETE", CreatedDate: created},
+	}
+	for _, e := range entries {
+		if err := w.Write(e); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	data := buf.Bytes()
+	if !bytes.HasPrefix(data, parquetMagic) || !bytes.HasSuffix(data, parquetMagic) {
+		t.Fatal("missing PAR1 magic")
+	}
+	n := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := (&thriftReader{b: data[len(data)-8-n : len(data)-8]}).structValue()
+	if footer[3] != int64(2) {
+		t.Fatalf("expected 2 rows, got %v", footer[3])
+	}
+	schema := footer[2].([]any)
+	if len(schema) != len(parquetSchema)+1 || schema[1].(map[int16]any)[4] != "log_audit_trail_id" {
+		t.Fatalf("unexpected schema: %v", schema)
+	}
+
+	// The first column chunk holds the IDs, PLAIN-encoded after the page header.
+	chunk := footer[4].([]any)[0].(map[int16]any)[1].([]any)[0].(map[int16]any)
+	offset := int(chunk[3].(map[int16]any)[9].(int64))
+	page := &thriftReader{b: data, pos: offset}
+	header := page.structValue()
+	body := data[page.pos : page.pos+int(header[3].(int64))]
+	if want := "\x01\x00\x00\x00a\x01\x00\x00\x00b"; string(body) != want {
+		t.Fatalf("unexpected id page %q", body)
+	}
+
+	// log_req_id is all null: a single RLE run of zero definition levels, no values.
+	chunk = footer[4].([]any)[0].(map[int16]any)[1].([]any)[1].(map[int16]any)
+	page = &thriftReader{b: data, pos: int(chunk[3].(map[int16]any)[9].(int64))}
+	header = page.structValue()
+	body = data[page.pos : page.pos+int(header[3].(int64))]
+	if want := "\x02\x00\x00\x00\x04\x00"; string(body) != want {
+		t.Fatalf("unexpected req_id page %q", body)
+	}
+}
+
+func TestObjectRecorderParquetFormat(t *testing.T) {
+	var keys []string
+	var body []byte
+	store := ObjectStoreFunc(func(_ context.Context, key string, b []byte, _ string) error {
+		keys, body = append(keys, key), b
+		return nil
+	})
+	rec, err := NewObjectRecorder(ObjectConfig{Store: store, Format: ObjectParquet})
+	if err != nil {
+		t.Fatalf("NewObjectRecorder: %v", err)
+	}
+	if err := rec.Record(context.Background(), Entry{Action: "EXPORT"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if err := rec.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if len(keys) != 1 || !strings.HasSuffix(keys[0], "-0001.parquet") || !bytes.HasPrefix(body, parquetMagic) {
+		t.Fatalf("unexpected object %v", keys)
+	}
+}
+
+func TestReadParquetRoundTrip(t *testing.T) {
+	created := time.Date(2024, 6, 1, 13, 0, 0, 123000, time.UTC)
+	entries := []Entry{
+		{ID: "a", Action: "CREATE", CreatedBy: "u1", Request: map[string]any{"x": 1},
+			CreatedDate: created, ExpiresAt: created.Add(time.Hour), Severity: SeverityCritical, InstanceID: "pod-1"},
+		{ID: "b", Action: "DELETE", CreatedDate: created},
+	}
+	for _, uncompressed := range []bool{false, true} {
+		var buf bytes.Buffer
+		w := NewParquetWriter(&buf)
+		w.Uncompressed = uncompressed
+		w.RowGroupSize = 1
+		for _, e := range entries {
+			if err := w.Write(e); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+
+		got, err := readParquet(buf.Bytes())
+		if err != nil || len(got) != 2 {
+			t.Fatalf("readParquet = %d entries, %v", len(got), err)
+		}
+		a := got[0]
+		if a.ID != "a" || a.CreatedBy != "u1" || !a.CreatedDate.Equal(created) || !a.ExpiresAt.Equal(created.Add(time.Hour)) ||
+			a.Severity != SeverityCritical || a.InstanceID != "pod-1" || string(a.Request.(json.RawMessage)) != `{"x":1}` {
+			t.Fatalf("unexpected entry %+v", a)
+		}
+		if b := got[1]; b.ID != "b" || b.Request != nil || b.CreatedBy != "" || !b.ExpiresAt.IsZero() {
+			t.Fatalf("unexpected entry %+v", b)
+		}
+	}
+
+	if _, err := readParquet([]byte("PAR1 not parquet PAR1")); err == nil {
+		t.Fatal("expected error for corrupt file")
+	}
+}
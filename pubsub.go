@@ -2,9 +2,8 @@ package audittrail
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
 	"time"
 
 	"cloud.google.com/go/pubsub"
@@ -36,10 +35,11 @@ func (f SubscriberFunc) Receive(ctx context.Context, handler func(context.Contex
 type PubSubRecorder struct {
 	publisher Publisher
 	now       func() time.Time
+	hooks     recorderHooks
 }
 
 // NewPubSubRecorder creates a recorder that publishes entries to a queue.
-func NewPubSubRecorder(publisher Publisher, now func() time.Time) (*PubSubRecorder, error) {
+func NewPubSubRecorder(publisher Publisher, now func() time.Time, opts ...RecorderOption) (*PubSubRecorder, error) {
 	if publisher == nil {
 		return nil, errors.New("audittrail: publisher must not be nil")
 	}
@@ -49,11 +49,19 @@ func NewPubSubRecorder(publisher Publisher, now func() time.Time) (*PubSubRecord
 	return &PubSubRecorder{
 		publisher: publisher,
 		now:       now,
+		hooks:     newRecorderHooks(opts),
 	}, nil
 }
 
 // Record validates and publishes an entry to the queue.
 func (p *PubSubRecorder) Record(ctx context.Context, entry Entry) error {
+	entry, err := p.hooks.apply(ctx, entry)
+	if errors.Is(err, errQuarantined) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
 	normalized, err := normalizeEntry(entry, p.now)
 	if err != nil {
 		return err
@@ -63,13 +71,37 @@ func (p *PubSubRecorder) Record(ctx context.Context, entry Entry) error {
 
 // Consumer receives audit entries and persists them to the database.
 type Consumer struct {
-	audit      *AuditTrail
-	subscriber Subscriber
-	onError    func(error)
+	audit        *AuditTrail
+	subscriber   Subscriber
+	onError      func(error)
+	analyzer     *Analyzer
+	checkpointer Checkpointer
+	poison       PoisonStore
+	txCheckpoint *SQLCheckpointer
+	stats        consumerCounters
+	gate         consumerGate
+	batchSize    int
+	batchWait    time.Duration
+
+	statsInterval time.Duration
+	statsReport   func(ConsumerStats)
+}
+
+// ConsumerOption configures optional Consumer behavior.
+type ConsumerOption func(*Consumer)
+
+// WithAlertRule evaluates rule against every persisted entry and calls fn when it triggers.
+func WithAlertRule(rule AlertRule, fn AlertFunc) ConsumerOption {
+	return func(c *Consumer) {
+		if c.analyzer == nil {
+			c.analyzer = NewAnalyzer()
+		}
+		c.analyzer.AddRule(rule, fn)
+	}
 }
 
 // NewConsumer wires a subscriber to a database-backed audit trail.
-func NewConsumer(audit *AuditTrail, subscriber Subscriber, onError func(error)) (*Consumer, error) {
+func NewConsumer(audit *AuditTrail, subscriber Subscriber, onError func(error), opts ...ConsumerOption) (*Consumer, error) {
 	if audit == nil {
 		return nil, errors.New("audittrail: audit must not be nil")
 	}
@@ -77,62 +109,220 @@ func NewConsumer(audit *AuditTrail, subscriber Subscriber, onError func(error))
 		return nil, errors.New("audittrail: subscriber must not be nil")
 	}
 	if onError == nil {
-		onError = func(err error) { log.Printf("audittrail consumer error: %v", err) }
+		onError = func(err error) { logger().Error("audittrail: consumer error", "error", err) }
 	}
-	return &Consumer{
+	c := &Consumer{
 		audit:      audit,
 		subscriber: subscriber,
 		onError:    onError,
-	}, nil
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(c)
+		}
+	}
+	if c.txCheckpoint != nil && c.txCheckpoint.audit.db != audit.db {
+		return nil, errors.New("audittrail: transactional checkpointer must use the consumer's database")
+	}
+	return c, nil
 }
 
 // Run starts consuming entries until the subscriber stops or context is canceled.
 func (c *Consumer) Run(ctx context.Context) error {
+	if c.poison != nil {
+		ctx = context.WithValue(ctx, poisonKey{}, countingPoisonStore{PoisonStore: c.poison, counters: &c.stats})
+	}
+	if c.statsReport != nil && c.statsInterval > 0 {
+		reportCtx, stop := context.WithCancel(ctx)
+		defer stop()
+		go c.reportStats(reportCtx)
+	}
+	if c.batchSize > 1 {
+		return c.runBatched(ctx)
+	}
 	return c.subscriber.Receive(ctx, func(ctx context.Context, entry Entry) error {
-		if err := c.audit.Record(ctx, entry); err != nil {
-			if c.onError != nil {
-				c.onError(err)
-			}
+		if err := c.gate.acquire(ctx); err != nil {
+			return err
+		}
+		defer c.gate.release()
+		if err := c.store(ctx, []Entry{entry}, MessagePosition(ctx)); err != nil {
+			c.failed(err)
 			return err
 		}
+		c.persisted(ctx, entry)
 		return nil
 	})
 }
 
+// persisted runs the post-persistence steps (stats, alerting, checkpointing) for an entry.
+func (c *Consumer) persisted(ctx context.Context, entry Entry) {
+	c.observed(ctx, entry)
+	c.checkpoint(ctx, MessagePosition(ctx))
+}
+
+// observed updates stats and alerting for a persisted entry.
+func (c *Consumer) observed(ctx context.Context, entry Entry) {
+	c.observe(entry)
+	if c.analyzer != nil {
+		c.analyzer.Observe(ctx, entry)
+	}
+}
+
+// checkpoint saves position with the WithCheckpointer checkpointer, if any.
+func (c *Consumer) checkpoint(ctx context.Context, position string) {
+	if c.checkpointer == nil || c.txCheckpoint != nil || position == "" {
+		return
+	}
+	if err := c.checkpointer.Save(ctx, position); err != nil && c.onError != nil {
+		c.onError(err)
+	}
+}
+
 // MarshalEntryJSON is a helper for external publishers that need JSON payloads.
+// The payload is stamped with CurrentSchemaVersion.
 func MarshalEntryJSON(entry Entry) ([]byte, error) {
-	return json.Marshal(entry)
+	return JSONCodec.Marshal(entry)
+}
+
+// UnmarshalEntryJSON decodes a payload produced by MarshalEntryJSON from any schema version.
+func UnmarshalEntryJSON(data []byte) (Entry, error) {
+	return JSONCodec.Unmarshal(data)
 }
 
 // ==================== GCP Pub/Sub Implementation ====================
 
+// codecAttribute is the Pub/Sub message attribute naming the Codec used for Data.
+const codecAttribute = "audittrail_codec"
+
+// GCPOption configures the GCP Pub/Sub publisher.
+type GCPOption func(*gcpPublisher)
+
+// WithCodec selects the wire encoding (default JSONCodec). Subscribers pick the matching
+// decoder from the message attributes, so producers can switch codecs independently.
+func WithCodec(c Codec) GCPOption {
+	return func(p *gcpPublisher) {
+		if c != nil {
+			p.codec = c
+		}
+	}
+}
+
+// WithOrderingKey enables Pub/Sub message ordering and derives each message's ordering key
+// from the entry (nil selects DefaultOrderingKey). Entries sharing a key are delivered in
+// publish order; the subscription must be created with message ordering enabled.
+func WithOrderingKey(fn func(Entry) string) GCPOption {
+	return func(p *gcpPublisher) {
+		if fn == nil {
+			fn = DefaultOrderingKey
+		}
+		p.orderingKey = fn
+	}
+}
+
+// WithSizePolicy sets how entries larger than Pub/Sub accepts are handled. By default they
+// are rejected with ErrEntryTooLarge before publishing; MaxBytes defaults to
+// PubSubMaxMessageBytes. Published sizes are counted in EntrySizes either way.
+func WithSizePolicy(policy SizePolicy) GCPOption {
+	return func(p *gcpPublisher) { p.sizePolicy = policy }
+}
+
+// DefaultOrderingKey orders entries per request, falling back to per actor.
+func DefaultOrderingKey(entry Entry) string {
+	if entry.RequestID != "" {
+		return entry.RequestID
+	}
+	return entry.ActorOrCreator()
+}
+
 // gcpPublisher implements Publisher interface using Google Cloud Pub/Sub.
 type gcpPublisher struct {
-	topic *pubsub.Topic
+	topic       *pubsub.Topic
+	codec       Codec
+	orderingKey func(Entry) string
+	sizePolicy  SizePolicy
 }
 
 // NewGCPPublisher creates a Publisher implementation using GCP Pub/Sub.
-func NewGCPPublisher(topic *pubsub.Topic) Publisher {
-	return &gcpPublisher{topic: topic}
+func NewGCPPublisher(topic *pubsub.Topic, opts ...GCPOption) Publisher {
+	p := &gcpPublisher{topic: topic, codec: JSONCodec}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(p)
+		}
+	}
+	if p.sizePolicy.MaxBytes <= 0 {
+		p.sizePolicy.MaxBytes = PubSubMaxMessageBytes
+	}
+	p.codec = LimitSize(p.codec, p.sizePolicy)
+	if p.orderingKey != nil && topic != nil {
+		topic.EnableMessageOrdering = true
+	}
+	return p
 }
 
 // Publish sends an audit entry to GCP Pub/Sub topic.
 func (p *gcpPublisher) Publish(ctx context.Context, entry Entry) error {
-	data, err := json.Marshal(entry)
+	data, err := p.codec.Marshal(entry)
 	if err != nil {
 		return err
 	}
 
-	result := p.topic.Publish(ctx, &pubsub.Message{Data: data})
+	msg := &pubsub.Message{
+		Data:       data,
+		Attributes: map[string]string{codecAttribute: p.codec.Name()},
+	}
+	if p.orderingKey != nil {
+		msg.OrderingKey = p.orderingKey(entry)
+	}
+
+	result := p.topic.Publish(ctx, msg)
 
 	// Wait for publish result synchronously to properly handle errors
 	if _, err := result.Get(ctx); err != nil {
+		if msg.OrderingKey != "" {
+			// A failed publish pauses its ordering key; resume so later entries are not rejected.
+			p.topic.ResumePublish(msg.OrderingKey)
+		}
 		return err
 	}
 
 	return nil
 }
 
+// PublishBatch publishes all entries before waiting for any result, letting the client
+// bundle them into few requests. The returned error joins every failed publish.
+func (p *gcpPublisher) PublishBatch(ctx context.Context, entries []Entry) error {
+	msgs := make([]*pubsub.Message, len(entries))
+	for i, entry := range entries {
+		data, err := p.codec.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		msgs[i] = &pubsub.Message{
+			Data:       data,
+			Attributes: map[string]string{codecAttribute: p.codec.Name()},
+		}
+		if p.orderingKey != nil {
+			msgs[i].OrderingKey = p.orderingKey(entry)
+		}
+	}
+
+	results := make([]*pubsub.PublishResult, len(msgs))
+	for i, msg := range msgs {
+		results[i] = p.topic.Publish(ctx, msg)
+	}
+	var errs []error
+	for i, result := range results {
+		if _, err := result.Get(ctx); err != nil {
+			if key := msgs[i].OrderingKey; key != "" {
+				p.topic.ResumePublish(key)
+			}
+			errs = append(errs, fmt.Errorf("entry %s: %w", entries[i].ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // gcpSubscriber implements Subscriber interface using Google Cloud Pub/Sub.
 type gcpSubscriber struct {
 	sub *pubsub.Subscription
@@ -146,17 +336,33 @@ func NewGCPSubscriber(sub *pubsub.Subscription) Subscriber {
 // Receive listens for messages from GCP Pub/Sub subscription.
 func (s *gcpSubscriber) Receive(ctx context.Context, handler func(context.Context, Entry) error) error {
 	return s.sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
-		var entry Entry
-		if err := json.Unmarshal(msg.Data, &entry); err != nil {
-			log.Printf("audittrail: failed to unmarshal pubsub message: %v, data: %s", err, string(msg.Data))
-			msg.Nack()
+		name := msg.Attributes[codecAttribute]
+		codec, ok := CodecByName(name)
+		if !ok {
+			logger().Error("audittrail: unknown codec for pubsub message", "codec", name, "message_id", msg.ID)
+			s.poison(ctx, msg, fmt.Errorf("audittrail: unknown codec %q", name))
+			return
+		}
+		entry, err := codec.Unmarshal(msg.Data)
+		if err != nil {
+			logger().Error("audittrail: failed to unmarshal pubsub message", "message_id", msg.ID, "error", err)
+			s.poison(ctx, msg, err)
 			return
 		}
 		if err := handler(ctx, entry); err != nil {
-			log.Printf("audittrail: handler failed for entry %s: %v", entry.ID, err)
+			logger().Error("audittrail: handler failed", "entry_id", entry.ID, "error", err)
 			msg.Nack()
 			return
 		}
 		msg.Ack()
 	})
 }
+
+// poison acks an undecodable message once it is quarantined (see WithPoisonQuarantine).
+func (s *gcpSubscriber) poison(ctx context.Context, msg *pubsub.Message, err error) {
+	if QuarantinePoison(ctx, PoisonMessage{Source: "pubsub", MessageID: msg.ID, Codec: msg.Attributes[codecAttribute], Data: msg.Data, Error: err.Error()}) {
+		msg.Ack()
+		return
+	}
+	msg.Nack()
+}
@@ -77,3 +77,41 @@ func TestConsumerPersistsEntry(t *testing.T) {
 		t.Fatalf("expected 1 DB call, got %d", len(calls))
 	}
 }
+
+func TestMemoryPubSubDeliversToReceiver(t *testing.T) {
+	ps := NewMemoryPubSub(4)
+	recorder, err := NewPubSubRecorder(ps, nil)
+	if err != nil {
+		t.Fatalf("NewPubSubRecorder: %v", err)
+	}
+	if err := recorder.Record(context.Background(), Entry{Action: "memory-test"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if ps.Len() != 1 {
+		t.Fatalf("expected 1 queued entry, got %d", ps.Len())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	got := make(chan Entry, 1)
+	go func() {
+		_ = ps.Receive(ctx, func(_ context.Context, entry Entry) error {
+			got <- entry
+			return nil
+		})
+	}()
+
+	select {
+	case entry := <-got:
+		if entry.Action != "memory-test" || entry.ID == "" {
+			t.Fatalf("unexpected entry: %+v", entry)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for entry")
+	}
+
+	_ = ps.Close()
+	if err := ps.Publish(context.Background(), Entry{Action: "late"}); err != ErrPubSubClosed {
+		t.Fatalf("expected ErrPubSubClosed, got %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ==================== Apache Pulsar Implementation ====================
+//
+// The Pulsar transport is written against small interfaces so this module does not depend
+// on github.com/apache/pulsar-client-go. Wire it up with a few lines of glue:
+//
+//	producer, _ := client.CreateProducer(pulsar.ProducerOptions{Topic: "audit"})
+//	pub := audittrail.NewPulsarPublisher(audittrail.PulsarSendFunc(
+//		func(ctx context.Context, key string, payload []byte, props map[string]string) error {
+//			_, err := producer.Send(ctx, &pulsar.ProducerMessage{Key: key, Payload: payload, Properties: props})
+//			return err
+//		}))
+//
+//	consumer, _ := client.Subscribe(pulsar.ConsumerOptions{
+//		Topic: "audit", SubscriptionName: "audit-writer", Type: pulsar.Shared,
+//	})
+//	sub := audittrail.NewPulsarSubscriber(pulsarConsumer{consumer})
+//
+//	type pulsarConsumer struct{ pulsar.Consumer }
+//
+//	func (c pulsarConsumer) Receive(ctx context.Context) (audittrail.PulsarMessage, error) {
+//		return c.Consumer.Receive(ctx)
+//	}
+//	func (c pulsarConsumer) Ack(m audittrail.PulsarMessage) error { return c.Consumer.Ack(m.(pulsar.Message)) }
+//	func (c pulsarConsumer) Nack(m audittrail.PulsarMessage)       { c.Consumer.Nack(m.(pulsar.Message)) }
+//
+// With a Shared (or KeyShared) subscription several consumer processes split the stream;
+// entries that fail to persist are negatively acknowledged and redelivered, possibly to
+// another consumer, after the subscription's NackRedeliveryDelay.
+
+// PulsarSendFunc sends one message to a Pulsar producer.
+type PulsarSendFunc func(ctx context.Context, key string, payload []byte, properties map[string]string) error
+
+// PulsarMessage is the subset of pulsar.Message used by the subscriber.
+type PulsarMessage interface {
+	Payload() []byte
+	Properties() map[string]string
+}
+
+// PulsarConsumer is the subset of pulsar.Consumer used by the subscriber.
+type PulsarConsumer interface {
+	Receive(ctx context.Context) (PulsarMessage, error)
+	Ack(PulsarMessage) error
+	Nack(PulsarMessage)
+}
+
+// PulsarOption configures the Pulsar publisher.
+type PulsarOption func(*pulsarPublisher)
+
+// WithPulsarCodec selects the wire encoding (default JSONCodec).
+func WithPulsarCodec(c Codec) PulsarOption {
+	return func(p *pulsarPublisher) {
+		if c != nil {
+			p.codec = c
+		}
+	}
+}
+
+// WithPulsarKey derives each message key from the entry (default DefaultOrderingKey), which
+// keeps entries of one request in order on KeyShared subscriptions.
+func WithPulsarKey(fn func(Entry) string) PulsarOption {
+	return func(p *pulsarPublisher) {
+		if fn != nil {
+			p.key = fn
+		}
+	}
+}
+
+type pulsarPublisher struct {
+	send  PulsarSendFunc
+	codec Codec
+	key   func(Entry) string
+}
+
+// NewPulsarPublisher creates a Publisher that sends entries through send.
+func NewPulsarPublisher(send PulsarSendFunc, opts ...PulsarOption) Publisher {
+	p := &pulsarPublisher{send: send, codec: JSONCodec, key: DefaultOrderingKey}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(p)
+		}
+	}
+	return p
+}
+
+// Publish sends an audit entry and waits for the broker acknowledgement.
+func (p *pulsarPublisher) Publish(ctx context.Context, entry Entry) error {
+	if p.send == nil {
+		return errors.New("audittrail: pulsar producer is not configured")
+	}
+	data, err := p.codec.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return p.send(ctx, p.key(entry), data, map[string]string{codecAttribute: p.codec.Name()})
+}
+
+type pulsarSubscriber struct {
+	consumer PulsarConsumer
+}
+
+// NewPulsarSubscriber creates a Subscriber reading from a Pulsar consumer.
+func NewPulsarSubscriber(consumer PulsarConsumer) Subscriber {
+	return &pulsarSubscriber{consumer: consumer}
+}
+
+// Receive processes messages until ctx is canceled. Messages are acked after the handler
+// succeeds and nacked otherwise so Pulsar redelivers them.
+func (s *pulsarSubscriber) Receive(ctx context.Context, handler func(context.Context, Entry) error) error {
+	if s.consumer == nil {
+		return errors.New("audittrail: pulsar consumer is not configured")
+	}
+	for {
+		msg, err := s.consumer.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		name := msg.Properties()[codecAttribute]
+		codec, ok := CodecByName(name)
+		if !ok {
+			logger().Error("audittrail: unknown codec for pulsar message", "codec", name)
+			s.poison(ctx, msg, fmt.Errorf("audittrail: unknown codec %q", name))
+			continue
+		}
+		entry, err := codec.Unmarshal(msg.Payload())
+		if err != nil {
+			logger().Error("audittrail: failed to unmarshal pulsar message", "error", err)
+			s.poison(ctx, msg, err)
+			continue
+		}
+		if err := handler(ctx, entry); err != nil {
+			logger().Error("audittrail: handler failed", "entry_id", entry.ID, "error", err)
+			s.consumer.Nack(msg)
+			continue
+		}
+		if err := s.consumer.Ack(msg); err != nil {
+			logger().Warn("audittrail: pulsar ack failed", "entry_id", entry.ID, "error", err)
+		}
+	}
+}
+
+// poison acks an undecodable message once it is quarantined (see WithPoisonQuarantine).
+func (s *pulsarSubscriber) poison(ctx context.Context, msg PulsarMessage, err error) {
+	if !QuarantinePoison(ctx, PoisonMessage{Source: "pulsar", Codec: msg.Properties()[codecAttribute], Data: msg.Payload(), Error: err.Error()}) {
+		s.consumer.Nack(msg)
+		return
+	}
+	if err := s.consumer.Ack(msg); err != nil {
+		logger().Warn("audittrail: pulsar ack failed", "error", err)
+	}
+}
@@ -0,0 +1,70 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type fakePulsarMessage struct {
+	payload []byte
+	props   map[string]string
+}
+
+func (m fakePulsarMessage) Payload() []byte               { return m.payload }
+func (m fakePulsarMessage) Properties() map[string]string { return m.props }
+
+// fakePulsarConsumer replays queued messages and records acks and nacks.
+type fakePulsarConsumer struct {
+	queue       chan PulsarMessage
+	acks, nacks int
+}
+
+func (c *fakePulsarConsumer) Receive(ctx context.Context) (PulsarMessage, error) {
+	select {
+	case m := <-c.queue:
+		return m, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *fakePulsarConsumer) Ack(PulsarMessage) error { c.acks++; return nil }
+func (c *fakePulsarConsumer) Nack(m PulsarMessage)    { c.nacks++; c.queue <- m }
+
+func TestPulsarRoundTripWithNack(t *testing.T) {
+	consumer := &fakePulsarConsumer{queue: make(chan PulsarMessage, 4)}
+	var keys []string
+	pub := NewPulsarPublisher(func(_ context.Context, key string, payload []byte, props map[string]string) error {
+		keys = append(keys, key)
+		consumer.queue <- fakePulsarMessage{payload: payload, props: props}
+		return nil
+	}, WithPulsarCodec(ProtobufCodec))
+
+	if err := pub.Publish(context.Background(), Entry{ID: "e1", Action: "PAY", RequestID: "req-1"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != "req-1" {
+		t.Fatalf("expected request ID as key, got %v", keys)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	err := NewPulsarSubscriber(consumer).Receive(ctx, func(_ context.Context, e Entry) error {
+		attempts++
+		if e.ID != "e1" || e.Action != "PAY" {
+			t.Errorf("unexpected entry %+v", e)
+		}
+		if attempts == 1 {
+			return errors.New("db down")
+		}
+		cancel()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if attempts != 2 || consumer.nacks != 1 || consumer.acks != 1 {
+		t.Fatalf("attempts=%d nacks=%d acks=%d", attempts, consumer.nacks, consumer.acks)
+	}
+}
@@ -0,0 +1,181 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PoisonMessage is a message a subscriber received but could not decode into an Entry.
+type PoisonMessage struct {
+	ID            string // quarantine row ID, set by the store
+	Source        string // transport, e.g. "pubsub" or "pulsar"
+	MessageID     string // transport message ID, if any
+	Codec         string // codec named by the message attributes
+	Data          []byte
+	Error         string
+	QuarantinedAt time.Time
+}
+
+// PoisonStore keeps undecodable messages out of the stream (see WithPoisonQuarantine).
+type PoisonStore interface {
+	Store(ctx context.Context, msg PoisonMessage) error
+}
+
+type poisonKey struct{}
+
+// WithPoisonQuarantine stores messages the subscriber cannot decode in store and
+// acknowledges them, instead of negatively acknowledging them forever. Fix the codec or
+// the producer, then replay them with SQLQuarantine.Reprocess.
+func WithPoisonQuarantine(store PoisonStore) ConsumerOption {
+	return func(c *Consumer) {
+		c.poison = store
+	}
+}
+
+// QuarantinePoison stores msg in the PoisonStore the Consumer attached to ctx and reports
+// whether it was stored. Subscriber implementations call it when a message cannot be
+// decoded: they acknowledge the message when it returns true and negatively acknowledge
+// it otherwise.
+func QuarantinePoison(ctx context.Context, msg PoisonMessage) bool {
+	store, _ := ctx.Value(poisonKey{}).(PoisonStore)
+	if store == nil {
+		return false
+	}
+	if err := store.Store(ctx, msg); err != nil {
+		logger().Error("audittrail: failed to quarantine message", "source", msg.Source, "message_id", msg.MessageID, "error", err)
+		return false
+	}
+	logger().Warn("audittrail: undecodable message quarantined", "source", msg.Source, "message_id", msg.MessageID, "error", msg.Error)
+	return true
+}
+
+// quarantineColumns is the schema of the SQLQuarantine table.
+var quarantineColumns = []column{
+	{name: "quarantine_id", ddl: "VARCHAR(64) PRIMARY KEY"},
+	{name: "source", ddl: "VARCHAR(64) NOT NULL"},
+	{name: "message_id", ddl: "VARCHAR(255) NULL"},
+	{name: "codec", ddl: "VARCHAR(64) NULL"},
+	{name: "payload", ddl: "TEXT NOT NULL"},
+	{name: "error_message", ddl: "TEXT NOT NULL"},
+	{name: "quarantined_date", ddl: "TIMESTAMP NOT NULL"},
+}
+
+// SQLQuarantine is a PoisonStore keeping messages in a table next to the audit table.
+// Payloads are stored base64-encoded.
+type SQLQuarantine struct {
+	audit *AuditTrail
+	table string
+}
+
+// NewSQLQuarantine creates a quarantine in "<audit table>_quarantine"; call EnsureTable to
+// create it.
+func NewSQLQuarantine(audit *AuditTrail) (*SQLQuarantine, error) {
+	if audit == nil || audit.db == nil {
+		return nil, errors.New("audittrail: audit must not be nil")
+	}
+	return &SQLQuarantine{audit: audit, table: audit.quote(audit.table + "_quarantine")}, nil
+}
+
+// EnsureTable creates the quarantine table if it does not exist.
+func (q *SQLQuarantine) EnsureTable(ctx context.Context) error {
+	query := q.audit.createTableQuery(q.table, quarantineColumns, "quarantine_id")
+	_, err := q.audit.db.ExecContext(ctx, query)
+	return err
+}
+
+// Store inserts msg, assigning its ID and time when unset.
+func (q *SQLQuarantine) Store(ctx context.Context, msg PoisonMessage) error {
+	if msg.ID == "" {
+		msg.ID = newID()
+	}
+	if msg.QuarantinedAt.IsZero() {
+		msg.QuarantinedAt = q.audit.now().UTC()
+	}
+	b := &queryBuilder{placeholder: q.audit.placeholder, dialect: q.audit.dialect}
+	query := fmt.Sprintf("INSERT INTO %s (quarantine_id, source, message_id, codec, payload, error_message, quarantined_date) VALUES (%s, %s, %s, %s, %s, %s, %s)",
+		q.table, b.arg(msg.ID), b.arg(msg.Source), b.arg(nullString(msg.MessageID)), b.arg(nullString(msg.Codec)),
+		b.arg(base64.StdEncoding.EncodeToString(msg.Data)), b.arg(msg.Error), b.arg(msg.QuarantinedAt))
+	_, err := q.audit.db.ExecContext(ctx, query, b.args...)
+	return err
+}
+
+// List returns up to limit quarantined messages, oldest first; limit <= 0 returns all.
+func (q *SQLQuarantine) List(ctx context.Context, limit int) ([]PoisonMessage, error) {
+	query := fmt.Sprintf("SELECT quarantine_id, source, message_id, codec, payload, error_message, quarantined_date FROM %s ORDER BY quarantined_date, quarantine_id", q.table)
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	rows, err := q.audit.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var msgs []PoisonMessage
+	for rows.Next() {
+		var msg PoisonMessage
+		var messageID, codec sql.NullString
+		var payload string
+		if err := rows.Scan(&msg.ID, &msg.Source, &messageID, &codec, &payload, &msg.Error, &msg.QuarantinedAt); err != nil {
+			return nil, err
+		}
+		msg.MessageID, msg.Codec = messageID.String, codec.String
+		if msg.Data, err = base64.StdEncoding.DecodeString(payload); err != nil {
+			return nil, fmt.Errorf("audittrail: quarantined message %s: %w", msg.ID, err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, rows.Err()
+}
+
+// Delete removes a quarantined message, e.g. one that will never be decodable.
+func (q *SQLQuarantine) Delete(ctx context.Context, id string) error {
+	b := &queryBuilder{placeholder: q.audit.placeholder, dialect: q.audit.dialect}
+	_, err := q.audit.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE quarantine_id = %s", q.table, b.arg(id)), b.args...)
+	return err
+}
+
+// Reprocess decodes every quarantined message again and stores the entries in the audit
+// table, removing the messages that succeed. decode defaults to the codec the message
+// named; pass your own to repair payloads a producer got wrong. Messages that still fail
+// stay quarantined with the new error. It returns how many messages were stored.
+func (q *SQLQuarantine) Reprocess(ctx context.Context, decode func(PoisonMessage) (Entry, error)) (int, error) {
+	if decode == nil {
+		decode = func(msg PoisonMessage) (Entry, error) {
+			codec, ok := CodecByName(msg.Codec)
+			if !ok {
+				return Entry{}, fmt.Errorf("audittrail: unknown codec %q", msg.Codec)
+			}
+			return codec.Unmarshal(msg.Data)
+		}
+	}
+	msgs, err := q.List(ctx, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	stored := 0
+	for _, msg := range msgs {
+		entry, err := decode(msg)
+		if err != nil {
+			b := &queryBuilder{placeholder: q.audit.placeholder, dialect: q.audit.dialect}
+			query := fmt.Sprintf("UPDATE %s SET error_message = %s WHERE quarantine_id = %s", q.table, b.arg(err.Error()), b.arg(msg.ID))
+			if _, err := q.audit.db.ExecContext(ctx, query, b.args...); err != nil {
+				return stored, err
+			}
+			continue
+		}
+		if err := q.audit.recordOnce(ctx, entry); err != nil {
+			return stored, fmt.Errorf("audittrail: reprocess message %s: %w", msg.ID, err)
+		}
+		if err := q.Delete(ctx, msg.ID); err != nil {
+			return stored, err
+		}
+		stored++
+	}
+	return stored, nil
+}
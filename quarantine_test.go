@@ -0,0 +1,132 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryPoisonStore keeps quarantined messages in a slice.
+type memoryPoisonStore struct {
+	mu   sync.Mutex
+	msgs []PoisonMessage
+}
+
+func (s *memoryPoisonStore) Store(_ context.Context, msg PoisonMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.msgs = append(s.msgs, msg)
+	return nil
+}
+
+func TestConsumerQuarantinesUndecodableMessages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	db := openStubDB(t, &stubDriver{execFn: func(string, []driver.NamedValue) (driver.Result, error) {
+		cancel() // the decodable entry is stored last
+		return stubResult{}, nil
+	}})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	consumer := &fakePulsarConsumer{queue: make(chan PulsarMessage, 4)}
+	consumer.queue <- fakePulsarMessage{payload: []byte("{not json"), props: map[string]string{codecAttribute: "json"}}
+	consumer.queue <- fakePulsarMessage{payload: []byte("x"), props: map[string]string{codecAttribute: "thrift"}}
+	good, _ := JSONCodec.Marshal(Entry{ID: "e1", Action: "PAY"})
+	consumer.queue <- fakePulsarMessage{payload: good, props: map[string]string{codecAttribute: "json"}}
+
+	store := &memoryPoisonStore{}
+	c, err := NewConsumer(audit, NewPulsarSubscriber(consumer), nil, WithPoisonQuarantine(store))
+	if err != nil {
+		t.Fatalf("NewConsumer: %v", err)
+	}
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if consumer.acks != 3 || consumer.nacks != 0 {
+		t.Fatalf("expected every message acked, got acks=%d nacks=%d", consumer.acks, consumer.nacks)
+	}
+	if len(store.msgs) != 2 || string(store.msgs[0].Data) != "{not json" || store.msgs[0].Source != "pulsar" {
+		t.Fatalf("unexpected quarantine: %+v", store.msgs)
+	}
+	if !strings.Contains(store.msgs[1].Error, `unknown codec "thrift"`) {
+		t.Fatalf("expected codec error, got %q", store.msgs[1].Error)
+	}
+}
+
+func TestQuarantinePoisonWithoutStore(t *testing.T) {
+	if QuarantinePoison(context.Background(), PoisonMessage{Source: "pulsar", Data: []byte("x")}) {
+		t.Fatal("expected no quarantine without a store, so the message is nacked")
+	}
+}
+
+func TestSQLQuarantineStoresAndReprocesses(t *testing.T) {
+	var mu sync.Mutex
+	var execs []execCall
+	rows := [][]driver.Value{
+		{"q1", "pubsub", "m1", "json", base64.StdEncoding.EncodeToString([]byte(`{"log_audit_trail_id":"e1","log_action":"PAY"}`)), "old", time.Now()},
+		{"q2", "pubsub", nil, nil, base64.StdEncoding.EncodeToString([]byte("garbage")), "old", time.Now()},
+	}
+	db := openStubDB(t, &stubDriver{
+		execFn: func(query string, args []driver.NamedValue) (driver.Result, error) {
+			mu.Lock()
+			execs = append(execs, execCall{query: query, args: args})
+			mu.Unlock()
+			return stubResult{}, nil
+		},
+		queryFn: func(query string, _ []driver.NamedValue) (driver.Rows, error) {
+			return &stubRows{columns: []string{"quarantine_id", "source", "message_id", "codec", "payload", "error_message", "quarantined_date"}, values: rows}, nil
+		},
+	})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	q, err := NewSQLQuarantine(audit)
+	if err != nil {
+		t.Fatalf("NewSQLQuarantine: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := q.EnsureTable(ctx); err != nil {
+		t.Fatalf("EnsureTable: %v", err)
+	}
+	if err := q.Store(ctx, PoisonMessage{Source: "pubsub", Data: []byte{0xff, 0x00}, Error: "bad"}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if !strings.Contains(execs[0].query, `CREATE TABLE IF NOT EXISTS audit_trail_quarantine (`) {
+		t.Fatalf("unexpected DDL: %s", execs[0].query)
+	}
+	if !strings.HasPrefix(execs[1].query, "INSERT INTO audit_trail_quarantine ") || stringArg(execs[1].args, 4) != "/wA=" {
+		t.Fatalf("unexpected insert: %s %v", execs[1].query, execs[1].args)
+	}
+	execs = nil
+
+	stored, err := q.Reprocess(ctx, nil)
+	if err != nil || stored != 1 {
+		t.Fatalf("Reprocess: %d %v", stored, err)
+	}
+	if len(execs) != 3 {
+		t.Fatalf("expected insert, delete and update, got %d statements", len(execs))
+	}
+	var inserted, deleted, updated bool
+	for _, e := range execs {
+		switch {
+		case strings.HasPrefix(e.query, "INSERT INTO audit_trail ") && stringArg(e.args, 0) == "e1":
+			inserted = true
+		case strings.HasPrefix(e.query, "DELETE") && stringArg(e.args, 0) == "q1":
+			deleted = true
+		case strings.HasPrefix(e.query, "UPDATE") && stringArg(e.args, 1) == "q2":
+			updated = true
+		}
+	}
+	if !inserted || !deleted || !updated {
+		t.Fatalf("unexpected statements: %+v", execs)
+	}
+}
@@ -0,0 +1,171 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Filter selects audit entries for read APIs. Zero-valued fields are ignored.
+type Filter struct {
+	Actions        []string  // match any of these actions
+	Actor          string    // match log_actor, or log_created_by for entries without one
+	Endpoint       string    // match log_endpoint
+	RequestID      string    // match log_req_id
+	CorrelationID  string    // match log_correlation_id
+	SessionID      string    // match log_session_id
+	Hostname       string    // match log_hostname
+	InstanceID     string    // match log_instance_id
+	ImpersonatedBy string    // match log_impersonated_by
+	IPAddress      string    // match log_ip_address
+	ServiceName    string    // match log_service_name
+	EntityType     string    // match log_entity_type
+	EntityID       string    // match log_entity_id
+	Contains       string    // text search over log_request and log_response (see EnsureSearchIndex)
+	From           time.Time // inclusive lower bound on log_created_date
+	To             time.Time // exclusive upper bound on log_created_date
+}
+
+// queryBuilder collects positional arguments and renders placeholders in the configured style.
+type queryBuilder struct {
+	placeholder PlaceholderStyle
+	dialect     sqlDialect
+	args        []any
+}
+
+func (b *queryBuilder) arg(v any) string {
+	b.args = append(b.args, v)
+	if b.placeholder == PlaceholderDollar {
+		return fmt.Sprintf("$%d", len(b.args))
+	}
+	return "?"
+}
+
+// where renders the filter as a WHERE clause (including the keyword), or "" when empty.
+func (b *queryBuilder) where(f Filter) string {
+	var conds []string
+	if len(f.Actions) > 0 {
+		parts := make([]string, len(f.Actions))
+		for i, action := range f.Actions {
+			parts[i] = b.arg(action)
+		}
+		conds = append(conds, fmt.Sprintf("log_action IN (%s)", strings.Join(parts, ", ")))
+	}
+	if f.Actor != "" {
+		conds = append(conds, fmt.Sprintf("(log_actor = %s OR (log_actor IS NULL AND log_created_by = %s))", b.arg(f.Actor), b.arg(f.Actor)))
+	}
+	if f.Endpoint != "" {
+		conds = append(conds, "log_endpoint = "+b.arg(f.Endpoint))
+	}
+	if f.RequestID != "" {
+		conds = append(conds, "log_req_id = "+b.arg(f.RequestID))
+	}
+	if f.CorrelationID != "" {
+		conds = append(conds, "log_correlation_id = "+b.arg(f.CorrelationID))
+	}
+	if f.SessionID != "" {
+		conds = append(conds, "log_session_id = "+b.arg(f.SessionID))
+	}
+	if f.Hostname != "" {
+		conds = append(conds, "log_hostname = "+b.arg(f.Hostname))
+	}
+	if f.InstanceID != "" {
+		conds = append(conds, "log_instance_id = "+b.arg(f.InstanceID))
+	}
+	if f.ImpersonatedBy != "" {
+		conds = append(conds, "log_impersonated_by = "+b.arg(f.ImpersonatedBy))
+	}
+	if f.IPAddress != "" {
+		conds = append(conds, "log_ip_address = "+b.arg(f.IPAddress))
+	}
+	if f.ServiceName != "" {
+		conds = append(conds, "log_service_name = "+b.arg(f.ServiceName))
+	}
+	if f.EntityType != "" {
+		conds = append(conds, "log_entity_type = "+b.arg(f.EntityType))
+	}
+	if f.EntityID != "" {
+		conds = append(conds, "log_entity_id = "+b.arg(f.EntityID))
+	}
+	if f.Contains != "" {
+		conds = append(conds, b.contains(f.Contains))
+	}
+	if !f.From.IsZero() {
+		conds = append(conds, "log_created_date >= "+b.arg(f.From.UTC()))
+	}
+	if !f.To.IsZero() {
+		conds = append(conds, "log_created_date < "+b.arg(f.To.UTC()))
+	}
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+// searchDocument is the tsvector over both payloads. EnsureSearchIndex indexes exactly this
+// expression, so it must not change without a new index.
+const searchDocument = "to_tsvector('simple', coalesce(log_request::text, '') || ' ' || coalesce(log_response::text, ''))"
+
+// contains renders a payload search condition: a full-text match on Postgres, and a
+// substring match on other databases. Spanner's LIKE has no ESCAPE clause, so STRPOS is
+// used there.
+func (b *queryBuilder) contains(term string) string {
+	if b.placeholder == PlaceholderDollar {
+		return fmt.Sprintf("%s @@ plainto_tsquery('simple', %s)", searchDocument, b.arg(term))
+	}
+	if b.dialect == dialectSpanner {
+		return fmt.Sprintf("(STRPOS(log_request, %s) > 0 OR STRPOS(log_response, %s) > 0)", b.arg(term), b.arg(term))
+	}
+	pattern := "%" + escapeLike(term) + "%"
+	return fmt.Sprintf("(log_request LIKE %s ESCAPE '!' OR log_response LIKE %s ESCAPE '!')", b.arg(pattern), b.arg(pattern))
+}
+
+// escapeLike escapes the LIKE wildcards in s with "!", which unlike a backslash needs no
+// quoting in any SQL dialect.
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+// andWhere appends a condition to a clause produced by where. Arguments referenced by cond
+// must be added after the clause was rendered so "?" placeholders stay in order.
+func andWhere(where, cond string) string {
+	if where == "" {
+		return " WHERE " + cond
+	}
+	return where + " AND " + cond
+}
+
+// Count returns the number of entries matching f.
+func (r *AuditTrail) Count(ctx context.Context, f Filter) (int64, error) {
+	if r == nil || r.db == nil {
+		return 0, errors.New("audittrail: instance is not initialized")
+	}
+	ctx = r.auditRead(ctx, "count", f, nil)
+	b := &queryBuilder{placeholder: r.placeholder, dialect: r.dialect}
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", r.tableRef, b.where(f))
+	var n int64
+	if err := r.db.QueryRowContext(ctx, query, b.args...).Scan(&n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Exists reports whether any entry matches f, e.g. "has this user ever changed this
+// setting". It stops at the first match instead of counting.
+func (r *AuditTrail) Exists(ctx context.Context, f Filter) (bool, error) {
+	if r == nil || r.db == nil {
+		return false, errors.New("audittrail: instance is not initialized")
+	}
+	ctx = r.auditRead(ctx, "exists", f, nil)
+	b := &queryBuilder{placeholder: r.placeholder, dialect: r.dialect}
+	query := fmt.Sprintf("SELECT 1 FROM %s%s LIMIT 1", r.tableRef, b.where(f))
+	var one int
+	err := r.db.QueryRowContext(ctx, query, b.args...).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,103 @@
+package audittrail
+
+import (
+	"context"
+	"time"
+)
+
+// ActionAuditRead is recorded for every query or export of the trail when read auditing is
+// enabled (see WithReadAuditing).
+const ActionAuditRead = "AUDIT_READ"
+
+// WithReadAuditing makes an AuditTrail record an ActionAuditRead entry for every read
+// through its APIs, so access to the trail is audited too: Query, Iterate, Count, Exists,
+// Stats, History, Chain, HeartbeatGaps, BuildDigest and Archiver exports. The entry's
+// Request holds the filter, Meta the operation, and Actor the user set with UserIDKey or
+// SetUserID. Entries go to rec, or to the AuditTrail itself when rec is nil. A failure to
+// record is logged and does not fail the read. Other recorders ignore the option.
+func WithReadAuditing(rec Recorder) RecorderOption {
+	return func(h *recorderHooks) {
+		h.readAudit = true
+		h.readRecorder = rec
+	}
+}
+
+type readAuditedKey struct{}
+
+// auditRead records a read of the trail when read auditing is enabled and returns ctx marked
+// so reads made on its behalf, e.g. History calling Query, are not recorded again.
+func (r *AuditTrail) auditRead(ctx context.Context, operation string, f Filter, details map[string]any) context.Context {
+	if r == nil || !r.hooks.readAudit || ctx.Value(readAuditedKey{}) != nil {
+		return ctx
+	}
+	ctx = context.WithValue(ctx, readAuditedKey{}, true)
+	meta := map[string]any{"operation": operation}
+	for k, v := range details {
+		meta[k] = v
+	}
+	entry := Entry{
+		Action:   ActionAuditRead,
+		Actor:    readActor(ctx),
+		Request:  filterPayload(f),
+		Severity: SeverityInfo,
+		Meta:     meta,
+	}
+	if _, _, p := FromContext(ctx).routeInfo(); p != "" {
+		entry.Endpoint = p
+	}
+	var rec Recorder = r
+	if r.hooks.readRecorder != nil {
+		rec = r.hooks.readRecorder
+	}
+	if err := rec.Record(ctx, entry); err != nil {
+		logger().Warn("audittrail: read access not recorded", "operation", operation, "error", err)
+	}
+	return ctx
+}
+
+// readActor returns the user reading the trail: UserIDKey, or the user set with SetUserID.
+func readActor(ctx context.Context) string {
+	if userID, _ := ctx.Value(UserIDKey).(string); userID != "" {
+		return userID
+	}
+	if ra := FromContext(ctx); ra != nil {
+		ra.mu.Lock()
+		defer ra.mu.Unlock()
+		return ra.userID
+	}
+	return ""
+}
+
+// filterPayload returns the set fields of f, keyed by the columns they match.
+func filterPayload(f Filter) map[string]any {
+	out := make(map[string]any)
+	if len(f.Actions) > 0 {
+		out["log_action"] = f.Actions
+	}
+	for k, v := range map[string]string{
+		"log_actor":           f.Actor,
+		"log_endpoint":        f.Endpoint,
+		"log_req_id":          f.RequestID,
+		"log_correlation_id":  f.CorrelationID,
+		"log_session_id":      f.SessionID,
+		"log_hostname":        f.Hostname,
+		"log_instance_id":     f.InstanceID,
+		"log_impersonated_by": f.ImpersonatedBy,
+		"log_ip_address":      f.IPAddress,
+		"log_service_name":    f.ServiceName,
+		"log_entity_type":     f.EntityType,
+		"log_entity_id":       f.EntityID,
+		"contains":            f.Contains,
+	} {
+		if v != "" {
+			out[k] = v
+		}
+	}
+	if !f.From.IsZero() {
+		out["from"] = f.From.UTC().Format(time.RFC3339Nano)
+	}
+	if !f.To.IsZero() {
+		out["to"] = f.To.UTC().Format(time.RFC3339Nano)
+	}
+	return out
+}
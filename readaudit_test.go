@@ -0,0 +1,107 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+)
+
+func TestReadAuditing(t *testing.T) {
+	columns := strings.Split(EntryColumns, ", ")
+	var execs []execCall
+	db := openStubDB(t, &stubDriver{
+		queryFn: func(q string, _ []driver.NamedValue) (driver.Rows, error) {
+			if strings.HasPrefix(q, "SELECT COUNT(*)") {
+				return &stubRows{columns: []string{"n"}, values: [][]driver.Value{{int64(3)}}}, nil
+			}
+			return &stubRows{columns: columns}, nil
+		},
+		execFn: func(query string, args []driver.NamedValue) (driver.Result, error) {
+			execs = append(execs, execCall{query: query, args: args})
+			return stubResult{}, nil
+		},
+	})
+	var reads []Entry
+	rec := RecorderFunc(func(_ context.Context, e Entry) error {
+		reads = append(reads, e)
+		return nil
+	})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar}, WithReadAuditing(rec))
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), UserIDKey, "auditor-1")
+	if _, err := audit.History(ctx, "order", "42", HistoryOptions{}); err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if _, err := audit.Count(ctx, Filter{Actor: "user-9"}); err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if len(reads) != 2 {
+		t.Fatalf("expected one entry per read, got %+v", reads)
+	}
+	history, count := reads[0], reads[1]
+	if history.Action != ActionAuditRead || history.Actor != "auditor-1" || history.Meta["operation"] != "history" {
+		t.Fatalf("unexpected history read entry: %+v", history)
+	}
+	if req := history.Request.(map[string]any); req["log_entity_type"] != "order" || req["log_entity_id"] != "42" {
+		t.Fatalf("filter not recorded: %+v", history.Request)
+	}
+	if count.Meta["operation"] != "count" || count.Request.(map[string]any)["log_actor"] != "user-9" {
+		t.Fatalf("unexpected count read entry: %+v", count)
+	}
+
+	// Without a recorder, reads are recorded to the trail itself; without the option, not at all.
+	self, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar}, WithReadAuditing(nil))
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	if _, err := self.Exists(ctx, Filter{}); err != nil {
+		t.Fatalf("Exists: %v", err)
+	}
+	if len(execs) != 1 || !strings.HasPrefix(execs[0].query, "INSERT") || execs[0].args[2].Value != ActionAuditRead {
+		t.Fatalf("expected the read to be inserted, got %+v", execs)
+	}
+	plain, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	if _, err := plain.Count(ctx, Filter{}); err != nil || len(execs) != 1 || len(reads) != 2 {
+		t.Fatalf("read recorded without WithReadAuditing: err=%v", err)
+	}
+}
+
+func TestReadAuditingStrictActions(t *testing.T) {
+	useStrictActions(t)
+	columns := strings.Split(EntryColumns, ", ")
+	db := openStubDB(t, &stubDriver{queryFn: func(q string, _ []driver.NamedValue) (driver.Rows, error) {
+		if strings.HasPrefix(q, "SELECT COUNT(*)") {
+			return &stubRows{columns: []string{"n"}, values: [][]driver.Value{{int64(0)}}}, nil
+		}
+		return &stubRows{columns: columns}, nil
+	}})
+	var reads []Entry
+	store, err := NewPubSubRecorder(PublisherFunc(func(_ context.Context, e Entry) error {
+		reads = append(reads, e)
+		return nil
+	}), nil)
+	if err != nil {
+		t.Fatalf("NewPubSubRecorder: %v", err)
+	}
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar}, WithReadAuditing(store))
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := audit.Count(ctx, Filter{}); err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if _, err := audit.Query(ctx, Filter{}, PageRequest{}); err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(reads) != 2 || reads[0].Meta["operation"] != "count" || reads[1].Meta["operation"] != "query" {
+		t.Fatalf("reads not recorded in strict mode: %+v", reads)
+	}
+}
@@ -0,0 +1,297 @@
+package audittrail
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+)
+
+// Redaction reduces a captured request or response payload before it is recorded.
+// Payloads are JSON (json.RawMessage), text or whatever a payload function returned.
+type Redaction func(payload any) any
+
+// RedactFields replaces the values of the given object keys, at any depth and compared
+// case-insensitively, with RedactedValue. Non-JSON payloads are kept as they are.
+func RedactFields(keys ...string) Redaction {
+	set := fieldSet(keys)
+	return func(payload any) any {
+		decoded, ok := decodeJSONPayload(payload)
+		if !ok {
+			return payload
+		}
+		var redact func(v any) any
+		redact = func(v any) any {
+			switch val := v.(type) {
+			case map[string]any:
+				for k, child := range val {
+					if set[strings.ToLower(k)] {
+						val[k] = RedactedValue
+					} else {
+						val[k] = redact(child)
+					}
+				}
+			case []any:
+				for i, child := range val {
+					val[i] = redact(child)
+				}
+			}
+			return v
+		}
+		return encodeJSONPayload(redact(decoded), payload)
+	}
+}
+
+// KeepFields keeps only the given fields of a JSON object payload, e.g. KeepFields("id",
+// "status") for responses. Fields are top-level keys or paths in a small JSONPath subset:
+// "customer.id" (or "$.customer.id") selects a nested key and "items[*].sku" a key of every
+// array element; the selected values keep their position in the structure. Payloads that
+// are not JSON objects are dropped.
+func KeepFields(fields ...string) Redaction {
+	paths := make([][]pathSegment, len(fields))
+	for i, f := range fields {
+		paths[i] = parseFieldPath(f)
+	}
+	return func(payload any) any {
+		decoded, ok := decodeJSONPayload(payload)
+		if _, isObject := decoded.(map[string]any); !ok || !isObject {
+			return nil
+		}
+		var kept any = map[string]any{}
+		for _, p := range paths {
+			if v, ok := projectPath(decoded, p); ok {
+				kept = mergeProjected(kept, v)
+			}
+		}
+		return encodeJSONPayload(kept, nil)
+	}
+}
+
+// pathSegment is one step of a field path: an object key, or every element of an array.
+type pathSegment struct {
+	key  string
+	each bool
+}
+
+// parseFieldPath splits "$.items[*].sku" into key and [*] segments.
+func parseFieldPath(field string) []pathSegment {
+	field = strings.TrimPrefix(strings.TrimPrefix(field, "$"), ".")
+	var segs []pathSegment
+	for _, part := range strings.Split(field, ".") {
+		key, each := part, 0
+		for strings.HasSuffix(key, "[*]") {
+			key, each = strings.TrimSuffix(key, "[*]"), each+1
+		}
+		if key != "" {
+			segs = append(segs, pathSegment{key: key})
+		}
+		for range each {
+			segs = append(segs, pathSegment{each: true})
+		}
+	}
+	return segs
+}
+
+// projectPath returns the parts of v selected by path, nested as in v.
+func projectPath(v any, path []pathSegment) (any, bool) {
+	if len(path) == 0 {
+		return v, true
+	}
+	seg := path[0]
+	if seg.each {
+		arr, ok := v.([]any)
+		if !ok {
+			return nil, false
+		}
+		// Elements without the field stay as null so positions line up when merging.
+		out := make([]any, len(arr))
+		for i, elem := range arr {
+			out[i], _ = projectPath(elem, path[1:])
+		}
+		return out, true
+	}
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	child, ok := obj[seg.key]
+	if !ok {
+		return nil, false
+	}
+	sub, ok := projectPath(child, path[1:])
+	if !ok {
+		return nil, false
+	}
+	return map[string]any{seg.key: sub}, true
+}
+
+// mergeProjected combines two projections of the same payload.
+func mergeProjected(a, b any) any {
+	switch av := a.(type) {
+	case nil:
+		return b
+	case map[string]any:
+		if bv, ok := b.(map[string]any); ok {
+			for k, v := range bv {
+				av[k] = mergeProjected(av[k], v)
+			}
+			return av
+		}
+	case []any:
+		if bv, ok := b.([]any); ok && len(av) == len(bv) {
+			for i := range av {
+				av[i] = mergeProjected(av[i], bv[i])
+			}
+			return av
+		}
+	}
+	if b == nil {
+		return a
+	}
+	return b
+}
+
+// DropPayload records no payload at all.
+func DropPayload(any) any { return nil }
+
+// decodeJSONPayload decodes a payload into plain JSON values, keeping numbers exact.
+func decodeJSONPayload(payload any) (any, bool) {
+	raw, err := marshalJSONValue(payload)
+	if err != nil || !raw.Valid {
+		return nil, false
+	}
+	dec := json.NewDecoder(strings.NewReader(raw.String))
+	dec.UseNumber()
+	var decoded any
+	if err := dec.Decode(&decoded); err != nil || dec.More() {
+		return nil, false
+	}
+	return decoded, true
+}
+
+// encodeJSONPayload re-encodes a decoded payload as json.RawMessage, falling back to fallback
+// if that fails.
+func encodeJSONPayload(v, fallback any) any {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return fallback
+	}
+	return json.RawMessage(buf)
+}
+
+// payloadRedaction holds the redactions for request and response payloads; nil keeps a
+// payload as captured.
+type payloadRedaction struct {
+	request, response Redaction
+}
+
+// routeRedaction is a per-route override, matched like routeSeverity.
+type routeRedaction struct {
+	pattern string
+	payloadRedaction
+}
+
+// redactionFor returns the redactions for a request: those of the first matching route,
+// falling back to the global ones for a side the route leaves nil.
+func redactionFor(global payloadRedaction, routes []routeRedaction, method, p string) payloadRedaction {
+	for _, r := range routes {
+		matched, _ := path.Match(r.pattern, p)
+		if !matched {
+			matched, _ = path.Match(r.pattern, method+" "+p)
+		}
+		if !matched {
+			continue
+		}
+		out := r.payloadRedaction
+		if out.request == nil {
+			out.request = global.request
+		}
+		if out.response == nil {
+			out.response = global.response
+		}
+		return out
+	}
+	return global
+}
+
+// apply runs the redactions on an entry's payloads.
+func (p payloadRedaction) apply(entry *Entry) {
+	if p.request != nil && entry.Request != nil {
+		entry.Request = p.request(entry.Request)
+	}
+	if p.response != nil && entry.Response != nil {
+		entry.Response = p.response(entry.Response)
+	}
+}
+
+// WithRedaction sets the redactions applied to every request and response payload; pass
+// nil to keep a side as captured. For example, store full requests but only the id and
+// status of responses:
+//
+//	audittrail.WithRedaction(audittrail.RedactFields("password"), audittrail.KeepFields("id", "status"))
+func WithRedaction(request, response Redaction) HTTPMiddlewareOption {
+	return func(c *httpMiddlewareConfig) {
+		c.redaction = payloadRedaction{request: request, response: response}
+	}
+}
+
+// WithRouteRedaction overrides the redactions for requests whose path (or "METHOD path")
+// matches pattern. A nil side falls back to WithRedaction; the first matching route wins.
+func WithRouteRedaction(pattern string, request, response Redaction) HTTPMiddlewareOption {
+	return func(c *httpMiddlewareConfig) {
+		c.routeRedaction = append(c.routeRedaction, routeRedaction{pattern, payloadRedaction{request, response}})
+	}
+}
+
+// WithGinRedaction sets the redactions applied to every captured request and response body.
+func WithGinRedaction(request, response Redaction) GinMiddlewareOption {
+	return func(c *ginMiddlewareConfig) {
+		c.redaction = payloadRedaction{request: request, response: response}
+	}
+}
+
+// WithGinRouteRedaction overrides the redactions for requests whose route template
+// (c.FullPath()) or "METHOD template" matches pattern. A nil side falls back to
+// WithGinRedaction.
+func WithGinRouteRedaction(pattern string, request, response Redaction) GinMiddlewareOption {
+	return func(c *ginMiddlewareConfig) {
+		c.routeRedaction = append(c.routeRedaction, routeRedaction{pattern, payloadRedaction{request, response}})
+	}
+}
+
+// WithRequestFields stores only the listed fields of request bodies (see KeepFields), a
+// lighter and safer alternative to full-body capture for regulated data:
+//
+//	audittrail.WithRequestFields("product_id", "quantity", "items[*].sku")
+//
+// It enables body capture up to 1MB unless WithRequestBody sets a limit.
+func WithRequestFields(fields ...string) HTTPMiddlewareOption {
+	return func(c *httpMiddlewareConfig) {
+		c.redaction.request = KeepFields(fields...)
+		if c.maxBodySize <= 0 {
+			c.maxBodySize = 1024 * 1024
+		}
+	}
+}
+
+// WithResponseFields stores only the listed fields of the response payload (see
+// WithResponsePayload and KeepFields).
+func WithResponseFields(fields ...string) HTTPMiddlewareOption {
+	return func(c *httpMiddlewareConfig) {
+		c.redaction.response = KeepFields(fields...)
+	}
+}
+
+// WithGinRequestFields stores only the listed fields of request bodies (see KeepFields).
+func WithGinRequestFields(fields ...string) GinMiddlewareOption {
+	return func(c *ginMiddlewareConfig) {
+		c.redaction.request = KeepFields(fields...)
+	}
+}
+
+// WithGinResponseFields captures response bodies but stores only the listed fields.
+func WithGinResponseFields(fields ...string) GinMiddlewareOption {
+	return func(c *ginMiddlewareConfig) {
+		c.captureResponseBody = true
+		c.redaction.response = KeepFields(fields...)
+	}
+}
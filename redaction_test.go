@@ -0,0 +1,100 @@
+package audittrail
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedactionHelpers(t *testing.T) {
+	payload := json.RawMessage(`{"id":7,"Password":"p","user":{"token":"t","name":"n"},"items":[{"token":"x"}]}`)
+	got := RedactFields("password", "token")(payload)
+	if string(got.(json.RawMessage)) != `{"Password":"[REDACTED]","id":7,"items":[{"token":"[REDACTED]"}],"user":{"name":"n","token":"[REDACTED]"}}` {
+		t.Fatalf("unexpected redacted payload: %s", got)
+	}
+	if got := RedactFields("password")("plain text"); got != "plain text" {
+		t.Fatalf("text payloads should be kept: %v", got)
+	}
+	if got := KeepFields("id", "status")(payload); string(got.(json.RawMessage)) != `{"id":7}` {
+		t.Fatalf("unexpected projected payload: %s", got)
+	}
+	order := json.RawMessage(`{"customer":{"id":"c1","email":"a@b.c"},"items":[{"sku":"A","qty":2,"note":"x"},{"qty":1}],"card":"4111"}`)
+	got = KeepFields("$.customer.id", "items[*].sku", "items[*].qty", "missing.key")(order)
+	if string(got.(json.RawMessage)) != `{"customer":{"id":"c1"},"items":[{"qty":2,"sku":"A"},{"qty":1}]}` {
+		t.Fatalf("unexpected path projection: %s", got)
+	}
+	if got := KeepFields("id")(json.RawMessage(`[1,2]`)); got != nil {
+		t.Fatalf("non-object payloads should be dropped: %v", got)
+	}
+}
+
+func TestHTTPMiddlewareRedaction(t *testing.T) {
+	var got Entry
+	rec := RecorderFunc(func(_ context.Context, e Entry) error {
+		got = e
+		return nil
+	})
+	handler := HTTPMiddleware(rec,
+		WithRequestBody(1024),
+		WithResponsePayload(func(status int) any { return map[string]any{"status": status, "debug": "trace"} }),
+		WithRedaction(nil, KeepFields("status")),
+		WithRouteRedaction("POST /login", RedactFields("password"), nil),
+		WithRouteRedaction("/files/*", DropPayload, DropPayload),
+	)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"sku":"A"}`)))
+	if string(got.Request.(json.RawMessage)) != `{"sku":"A"}` || string(got.Response.(json.RawMessage)) != `{"status":200}` {
+		t.Fatalf("unexpected global redaction: %s / %s", got.Request, got.Response)
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"user":"u1","password":"p"}`)))
+	if string(got.Request.(json.RawMessage)) != `{"password":"[REDACTED]","user":"u1"}` || string(got.Response.(json.RawMessage)) != `{"status":200}` {
+		t.Fatalf("unexpected route redaction: %s / %s", got.Request, got.Response)
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/files/a.pdf", strings.NewReader(`{"data":"..."}`)))
+	if got.Request != nil || got.Response != nil {
+		t.Fatalf("expected payloads to be dropped: %v / %v", got.Request, got.Response)
+	}
+}
+
+func TestGinMiddlewareRouteRedaction(t *testing.T) {
+	entries := make(chan Entry, 1)
+	useGlobalRecorder(t, RecorderFunc(func(_ context.Context, e Entry) error {
+		entries <- e
+		return nil
+	}))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinMiddleware(WithCaptureResponseBody(true), WithGinRouteRedaction("/users/:id", nil, KeepFields("id"))))
+	r.PUT("/users/:id", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", []byte(`{"id":"u1","email":"a@b.c"}`))
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/users/u1", strings.NewReader(`{"email":"a@b.c"}`)))
+	e := <-entries
+	if string(e.Request.(json.RawMessage)) != `{"email":"a@b.c"}` || string(e.Response.(json.RawMessage)) != `{"id":"u1"}` {
+		t.Fatalf("unexpected payloads: %s / %s", e.Request, e.Response)
+	}
+}
+
+func TestHTTPMiddlewareRequestFields(t *testing.T) {
+	var got Entry
+	rec := RecorderFunc(func(_ context.Context, e Entry) error {
+		got = e
+		return nil
+	})
+	handler := HTTPMiddleware(rec, WithRequestFields("product_id", "quantity"))(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	body := `{"product_id":"p1","quantity":3,"card_number":"4111111111111111"}`
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/cart", strings.NewReader(body)))
+	if string(got.Request.(json.RawMessage)) != `{"product_id":"p1","quantity":3}` {
+		t.Fatalf("unexpected projected request: %s", got.Request)
+	}
+}
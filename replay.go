@@ -0,0 +1,202 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Seeker is implemented by subscribers whose stream can be rewound to a point in time.
+type Seeker interface {
+	SeekToTime(ctx context.Context, t time.Time) error
+}
+
+// Checkpointer persists the position of the last persisted message for offset-based
+// transports (Kafka offsets, Redis stream IDs, ...), so a restarted subscriber can resume.
+type Checkpointer interface {
+	Save(ctx context.Context, position string) error
+	Load(ctx context.Context) (string, error)
+}
+
+type positionKey struct{}
+
+// WithMessagePosition attaches a transport position to the handler context. Subscriber
+// implementations call it so the Consumer can checkpoint after persisting the entry.
+func WithMessagePosition(ctx context.Context, position string) context.Context {
+	return context.WithValue(ctx, positionKey{}, position)
+}
+
+// MessagePosition returns the transport position attached by WithMessagePosition.
+func MessagePosition(ctx context.Context) string {
+	pos, _ := ctx.Value(positionKey{}).(string)
+	return pos
+}
+
+// positionLess orders transport positions of one stream. Runs of digits compare
+// numerically, so Kafka offsets ("9" < "10") and Redis stream IDs ("1700000000000-9" <
+// "1700000000000-10") sort in stream order; other bytes compare as is.
+func positionLess(a, b string) bool {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			na, nb := digitRun(a), digitRun(b)
+			x, y := strings.TrimLeft(a[:na], "0"), strings.TrimLeft(b[:nb], "0")
+			if len(x) != len(y) {
+				return len(x) < len(y)
+			}
+			if x != y {
+				return x < y
+			}
+			a, b = a[na:], b[nb:]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+// digitRun returns the length of the run of digits s starts with.
+func digitRun(s string) int {
+	n := 0
+	for n < len(s) && isDigit(s[n]) {
+		n++
+	}
+	return n
+}
+
+// WithCheckpointer saves the message position (see WithMessagePosition) after each entry
+// is persisted.
+func WithCheckpointer(cp Checkpointer) ConsumerOption {
+	return func(c *Consumer) {
+		c.checkpointer = cp
+	}
+}
+
+// WithTransactionalCheckpoint saves the message position (see WithMessagePosition) in the
+// same database transaction that inserts the entries, instead of after it as
+// WithCheckpointer does. A crash then either loses both or keeps both, so a subscriber that
+// resumes from cp.Load() neither skips nor re-reads messages: effectively-once persistence
+// for offset-based transports such as Kafka or Redis streams. With WithBatchInsert the
+// highest position of each batch is saved. cp must use the consumer's AuditTrail database, and
+// Config.CopyFrom is not used for batches, since COPY cannot join the transaction.
+func WithTransactionalCheckpoint(cp *SQLCheckpointer) ConsumerOption {
+	return func(c *Consumer) {
+		c.txCheckpoint = cp
+	}
+}
+
+// store persists entries received at position, saving the position in the same transaction
+// when the consumer has a transactional checkpointer.
+func (c *Consumer) store(ctx context.Context, entries []Entry, position string) error {
+	if c.txCheckpoint == nil || position == "" {
+		if len(entries) == 1 {
+			return c.audit.recordOnce(ctx, entries[0])
+		}
+		return c.audit.recordBatch(ctx, entries, true, nil)
+	}
+	return c.audit.recordBatch(ctx, entries, true, func(tx *sql.Tx) error {
+		return c.txCheckpoint.save(ctx, tx, position)
+	})
+}
+
+// Replay rewinds the subscriber to from and consumes until ctx is canceled, rebuilding
+// the table from the stream. Entries already present are skipped, so replaying over
+// existing data is safe. The subscriber must implement Seeker.
+func (c *Consumer) Replay(ctx context.Context, from time.Time) error {
+	seeker, ok := c.subscriber.(Seeker)
+	if !ok {
+		return errors.New("audittrail: subscriber does not support replay")
+	}
+	if err := seeker.SeekToTime(ctx, from); err != nil {
+		return fmt.Errorf("audittrail: seek subscriber failed: %w", err)
+	}
+	return c.Run(ctx)
+}
+
+// SeekToTime rewinds the GCP subscription. Messages acknowledged after t are redelivered
+// only if the subscription retains acknowledged messages.
+func (s *gcpSubscriber) SeekToTime(ctx context.Context, t time.Time) error {
+	return s.sub.SeekToTime(ctx, t)
+}
+
+// checkpointColumns is the schema of the SQLCheckpointer table.
+var checkpointColumns = []column{
+	{name: "consumer_name", ddl: "VARCHAR(255) PRIMARY KEY"},
+	{name: "position", ddl: "VARCHAR(255) NOT NULL"},
+	{name: "updated_date", ddl: "TIMESTAMP NOT NULL"},
+}
+
+// SQLCheckpointer stores consumer positions in a table next to the audit table.
+type SQLCheckpointer struct {
+	audit *AuditTrail
+	table string
+	name  string
+}
+
+// NewSQLCheckpointer creates a checkpointer for the named consumer. Positions are kept in
+// "<audit table>_checkpoints"; call EnsureTable to create it.
+func NewSQLCheckpointer(audit *AuditTrail, consumerName string) (*SQLCheckpointer, error) {
+	if audit == nil || audit.db == nil {
+		return nil, errors.New("audittrail: audit must not be nil")
+	}
+	if consumerName == "" {
+		return nil, errors.New("audittrail: consumer name must not be empty")
+	}
+	return &SQLCheckpointer{audit: audit, table: audit.quote(audit.table + "_checkpoints"), name: consumerName}, nil
+}
+
+// EnsureTable creates the checkpoint table if it does not exist.
+func (c *SQLCheckpointer) EnsureTable(ctx context.Context) error {
+	query := c.audit.createTableQuery(c.table, checkpointColumns, "consumer_name")
+	_, err := c.audit.db.ExecContext(ctx, query)
+	return err
+}
+
+// Save records the position, replacing the previous one.
+func (c *SQLCheckpointer) Save(ctx context.Context, position string) error {
+	return c.save(ctx, c.audit.db, position)
+}
+
+// execer is satisfied by *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func (c *SQLCheckpointer) save(ctx context.Context, db execer, position string) error {
+	now := c.audit.now().UTC()
+	b := &queryBuilder{placeholder: c.audit.placeholder, dialect: c.audit.dialect}
+	update := fmt.Sprintf("UPDATE %s SET position = %s, updated_date = %s WHERE consumer_name = %s",
+		c.table, b.arg(position), b.arg(now), b.arg(c.name))
+	res, err := db.ExecContext(ctx, update, b.args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		return nil
+	}
+
+	b = &queryBuilder{placeholder: c.audit.placeholder, dialect: c.audit.dialect}
+	insert := fmt.Sprintf("INSERT INTO %s (consumer_name, position, updated_date) VALUES (%s, %s, %s)",
+		c.table, b.arg(c.name), b.arg(position), b.arg(now))
+	_, err = db.ExecContext(ctx, insert, b.args...)
+	return err
+}
+
+// Load returns the last saved position, or "" if none was saved.
+func (c *SQLCheckpointer) Load(ctx context.Context) (string, error) {
+	b := &queryBuilder{placeholder: c.audit.placeholder, dialect: c.audit.dialect}
+	query := fmt.Sprintf("SELECT position FROM %s WHERE consumer_name = %s", c.table, b.arg(c.name))
+	var position string
+	err := c.audit.db.QueryRowContext(ctx, query, b.args...).Scan(&position)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return position, err
+}
@@ -0,0 +1,143 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type seekingSubscriber struct {
+	SubscriberFunc
+	seekedTo time.Time
+}
+
+func (s *seekingSubscriber) SeekToTime(_ context.Context, t time.Time) error {
+	s.seekedTo = t
+	return nil
+}
+
+type memoryCheckpointer struct{ positions []string }
+
+func (m *memoryCheckpointer) Save(_ context.Context, pos string) error {
+	m.positions = append(m.positions, pos)
+	return nil
+}
+
+func (m *memoryCheckpointer) Load(context.Context) (string, error) {
+	if len(m.positions) == 0 {
+		return "", nil
+	}
+	return m.positions[len(m.positions)-1], nil
+}
+
+func TestConsumerReplayIsIdempotentAndCheckpoints(t *testing.T) {
+	var calls []execCall
+	db := openStubDB(t, &stubDriver{
+		execFn: func(query string, args []driver.NamedValue) (driver.Result, error) {
+			calls = append(calls, execCall{query: query, args: args})
+			return stubResult{}, nil
+		},
+	})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	sub := &seekingSubscriber{SubscriberFunc: func(ctx context.Context, handler func(context.Context, Entry) error) error {
+		for i, id := range []string{"e1", "e2"} {
+			msgCtx := WithMessagePosition(ctx, strings.Repeat("x", i+1))
+			if err := handler(msgCtx, Entry{ID: id, Action: "replayed"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}}
+	cp := &memoryCheckpointer{}
+	consumer, err := NewConsumer(audit, sub, nil, WithCheckpointer(cp))
+	if err != nil {
+		t.Fatalf("NewConsumer: %v", err)
+	}
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := consumer.Replay(context.Background(), from); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if !sub.seekedTo.Equal(from) {
+		t.Fatalf("expected seek to %v, got %v", from, sub.seekedTo)
+	}
+	if len(calls) != 2 || !strings.HasSuffix(calls[0].query, "ON CONFLICT (log_audit_trail_id) DO NOTHING") {
+		t.Fatalf("expected idempotent inserts, got %+v", calls)
+	}
+	if last, _ := cp.Load(context.Background()); last != "xx" || len(cp.positions) != 2 {
+		t.Fatalf("unexpected checkpoints: %v", cp.positions)
+	}
+
+	plain, _ := NewConsumer(audit, SubscriberFunc(sub.SubscriberFunc), nil)
+	if err := plain.Replay(context.Background(), from); err == nil {
+		t.Fatal("expected error for subscriber without Seeker")
+	}
+}
+
+func TestConsumerTransactionalCheckpoint(t *testing.T) {
+	var calls []execCall
+	failCheckpoint := false
+	db := openStubDB(t, &stubDriver{
+		execFn: func(query string, args []driver.NamedValue) (driver.Result, error) {
+			calls = append(calls, execCall{query: query, args: args})
+			if failCheckpoint && strings.Contains(query, "_checkpoints") {
+				return nil, errors.New("checkpoint table locked")
+			}
+			return stubResult{}, nil
+		},
+	})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	cp, err := NewSQLCheckpointer(audit, "orders")
+	if err != nil {
+		t.Fatalf("NewSQLCheckpointer: %v", err)
+	}
+
+	var handlerErrs []error
+	sub := SubscriberFunc(func(ctx context.Context, handler func(context.Context, Entry) error) error {
+		for i, id := range []string{"e1", "e2"} {
+			handlerErrs = append(handlerErrs, handler(WithMessagePosition(ctx, fmt.Sprint("p0:", 41+i)), Entry{ID: id, Action: "CREATE"}))
+		}
+		return nil
+	})
+	consumer, err := NewConsumer(audit, sub, func(error) {}, WithTransactionalCheckpoint(cp))
+	if err != nil {
+		t.Fatalf("NewConsumer: %v", err)
+	}
+	if err := consumer.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(calls) != 4 {
+		t.Fatalf("expected insert and checkpoint per entry, got %+v", calls)
+	}
+	if !strings.HasPrefix(calls[0].query, "INSERT INTO audit_trail ") || !strings.HasPrefix(calls[1].query, "UPDATE audit_trail_checkpoints") ||
+		stringArg(calls[3].args, 0) != "p0:42" {
+		t.Fatalf("unexpected statements: %+v", calls)
+	}
+	if handlerErrs[0] != nil || handlerErrs[1] != nil {
+		t.Fatalf("unexpected handler errors: %v", handlerErrs)
+	}
+
+	failCheckpoint, handlerErrs = true, nil
+	if err := consumer.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if handlerErrs[0] == nil {
+		t.Fatal("expected the entry to fail when its checkpoint cannot be saved")
+	}
+
+	other, _ := NewAuditTrail(Config{DB: openStubDB(t, &stubDriver{}), Placeholder: PlaceholderDollar})
+	if _, err := NewConsumer(other, sub, nil, WithTransactionalCheckpoint(cp)); err == nil {
+		t.Fatal("expected error for a checkpointer on another database")
+	}
+}
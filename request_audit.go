@@ -0,0 +1,235 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+	"time"
+)
+
+// RequestAudit is the audit state of one request handled by HTTPMiddleware or
+// GinMiddleware. Handlers reach it through FromContext.
+type RequestAudit struct {
+	mu         sync.Mutex
+	entries    []Entry
+	entityType string
+	entityID   string
+	meta       map[string]any
+	action     string
+	userID     string
+
+	// method, path and route (template) of the request, set by the middleware.
+	method, path, route string
+}
+
+type requestAuditKey struct{}
+
+type contextKey string
+
+// Context keys read by HTTPMiddleware and GinMiddleware, for middleware that runs before
+// them, e.g. authentication:
+//
+//	next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), audittrail.UserIDKey, claims.Subject)))
+//
+// A string stored under UserIDKey becomes the Actor and one under ActionKey the Action of
+// the request's entry, overriding headers, Gin's "user_id" and "audit_action" keys and
+// the configured extractors. Handlers, which run inside the middleware, use SetUserID and
+// SetAction instead.
+const (
+	UserIDKey contextKey = "audittrail.user_id"
+	ActionKey contextKey = "audittrail.action"
+)
+
+// SetAction sets the Action of the request's entry from a handler, under net/http, chi,
+// echo or Gin alike. It does nothing outside an audited request.
+func SetAction(ctx context.Context, action string) {
+	if ra := FromContext(ctx); ra != nil {
+		ra.mu.Lock()
+		defer ra.mu.Unlock()
+		ra.action = action
+	}
+}
+
+// SetUserID sets the Actor of the request's entry from a handler, like SetAction.
+func SetUserID(ctx context.Context, userID string) {
+	if ra := FromContext(ctx); ra != nil {
+		ra.mu.Lock()
+		defer ra.mu.Unlock()
+		ra.userID = userID
+	}
+}
+
+// withRequestAudit returns ctx carrying a new RequestAudit for a request.
+func withRequestAudit(ctx context.Context, method, path string) (context.Context, *RequestAudit) {
+	ra := &RequestAudit{method: method, path: path}
+	return context.WithValue(ctx, requestAuditKey{}, ra), ra
+}
+
+// setRoute records the route template that served the request, once it is known.
+func (ra *RequestAudit) setRoute(route string) {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	ra.route = route
+}
+
+// routeInfo returns the request's method, route template (empty when unknown) and path.
+func (ra *RequestAudit) routeInfo() (method, route, path string) {
+	if ra == nil {
+		return "", "", ""
+	}
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	return ra.method, ra.route, ra.path
+}
+
+// FromContext returns the audit state of the request being handled, for handlers that
+// perform several auditable sub-actions:
+//
+//	audittrail.FromContext(r.Context()).Add(audittrail.Entry{Action: "STOCK_RESERVED", Request: item})
+//
+// In Gin, pass c.Request.Context(). Outside an audited request it returns nil, whose methods
+// do nothing; Add logs the dropped entry.
+func FromContext(ctx context.Context) *RequestAudit {
+	if ctx == nil {
+		return nil
+	}
+	ra, _ := ctx.Value(requestAuditKey{}).(*RequestAudit)
+	return ra
+}
+
+// Current returns the audit state of the request being handled, so handlers can enrich
+// the entry the middleware records once they return:
+//
+//	audittrail.Current(r.Context()).SetEntity("order", id).SetMeta("total", 99)
+//
+// It is the same value as FromContext and nil outside an audited request.
+func Current(ctx context.Context) *RequestAudit {
+	return FromContext(ctx)
+}
+
+// SetEntity sets the EntityType and EntityID of the request's entry (see History).
+func (ra *RequestAudit) SetEntity(entityType, id string) *RequestAudit {
+	if ra == nil {
+		return nil
+	}
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	ra.entityType, ra.entityID = entityType, id
+	return ra
+}
+
+// SetMeta sets key in the Meta of the request's entry. Values must marshal to JSON.
+func (ra *RequestAudit) SetMeta(key string, value any) *RequestAudit {
+	if ra == nil {
+		return nil
+	}
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	if ra.meta == nil {
+		ra.meta = make(map[string]any)
+	}
+	ra.meta[key] = value
+	return ra
+}
+
+// Add queues an entry that the middleware records together with the request's own entry.
+// Empty RequestID, Actor, ImpersonatedBy, IPAddress, SessionID, ServiceName and Endpoint are
+// taken from the request's entry, and the entry is linked to it with DerivedFrom.
+// CreatedDate defaults to the time of the call. Outside an audited request the entry is
+// dropped with a warning.
+func (ra *RequestAudit) Add(entry Entry) {
+	if ra == nil {
+		logger().Warn("audittrail: entry added outside an audited request was dropped", "action", entry.Action)
+		return
+	}
+	if entry.CreatedDate.IsZero() {
+		entry.CreatedDate = time.Now().UTC()
+	}
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	ra.entries = append(ra.entries, entry)
+}
+
+// flush applies the context keys and the handler's enrichment to the request's entry and
+// returns it followed by the added entries, which share its request context.
+func (ra *RequestAudit) flush(ctx context.Context, main Entry) []Entry {
+	if action, _ := ctx.Value(ActionKey).(string); action != "" {
+		main.Action = action
+	}
+	if userID, _ := ctx.Value(UserIDKey).(string); userID != "" {
+		main.Actor = userID
+	}
+
+	ra.mu.Lock()
+	added := ra.entries
+	ra.entries = nil
+	if ra.action != "" {
+		main.Action = ra.action
+	}
+	if ra.userID != "" {
+		main.Actor = ra.userID
+	}
+	if ra.entityType != "" || ra.entityID != "" {
+		main.EntityType, main.EntityID = ra.entityType, ra.entityID
+	}
+	if len(ra.meta) > 0 {
+		meta := make(map[string]any, len(main.Meta)+len(ra.meta))
+		maps.Copy(meta, main.Meta)
+		maps.Copy(meta, ra.meta)
+		main.Meta = meta
+	}
+	ra.mu.Unlock()
+	if len(added) == 0 {
+		return []Entry{main}
+	}
+
+	main.EnsureID()
+	out := make([]Entry, 0, len(added)+1)
+	out = append(out, main)
+	for _, e := range added {
+		for _, f := range []struct{ dst, src *string }{
+			{&e.RequestID, &main.RequestID},
+			{&e.Actor, &main.Actor},
+			{&e.ImpersonatedBy, &main.ImpersonatedBy},
+			{&e.IPAddress, &main.IPAddress},
+			{&e.SessionID, &main.SessionID},
+			{&e.ServiceName, &main.ServiceName},
+			{&e.Endpoint, &main.Endpoint},
+		} {
+			if *f.dst == "" {
+				*f.dst = *f.src
+			}
+		}
+		if e.ParentID == "" {
+			e = DerivedFrom(&main, e)
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// recordEntries records entries in one RecordBatch call when the recorder supports it, so
+// a request's entries are stored together, and one by one otherwise. Entries that were not
+// recorded are reported as dropped.
+func recordEntries(ctx context.Context, recorder Recorder, entries []Entry) error {
+	if len(entries) > 1 {
+		if batch, ok := recorder.(BatchRecorder); ok {
+			err := batch.RecordBatch(ctx, entries)
+			if err != nil {
+				for _, e := range entries {
+					reportDrop(e, err)
+				}
+			}
+			return err
+		}
+	}
+	var errs []error
+	for _, e := range entries {
+		if err := recorder.Record(ctx, e); err != nil {
+			reportDrop(e, err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,222 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHTTPMiddlewareRecordsAddedEntries(t *testing.T) {
+	var execs []execCall
+	db := openStubDB(t, &stubDriver{
+		execFn: func(query string, args []driver.NamedValue) (driver.Result, error) {
+			execs = append(execs, execCall{query: query, args: args})
+			return stubResult{}, nil
+		},
+	})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderQuestion})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	h := HTTPMiddleware(audit, WithErrorHandler(func(err error) { t.Fatalf("unexpected error: %v", err) }))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			FromContext(r.Context()).Add(Entry{Action: "STOCK_RESERVED", Request: map[string]any{"sku": "A1"}})
+			FromContext(r.Context()).Add(Entry{Action: "PAYMENT_CAPTURED", Actor: "billing"})
+		}))
+	req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	req.Header.Set("X-Request-Id", "req-1")
+	req.Header.Set("X-User-Id", "user-9")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	// One multi-row INSERT inside the batch transaction.
+	if len(execs) != 1 || strings.Count(execs[0].query, "(?") != 3 {
+		t.Fatalf("expected one INSERT of three rows, got %d statements", len(execs))
+	}
+	args := execs[0].args
+	n := len(args) / 3
+	mainID := args[0].Value
+	for i, want := range []struct{ action, actor string }{{"", "user-9"}, {"STOCK_RESERVED", "user-9"}, {"PAYMENT_CAPTURED", "billing"}} {
+		row := args[i*n : (i+1)*n]
+		if row[1].Value != "req-1" || row[17].Value != want.actor {
+			t.Fatalf("row %d: request_id=%v actor=%v", i, row[1].Value, row[17].Value)
+		}
+		if i > 0 && (row[2].Value != want.action || row[10].Value != mainID || row[3].Value != "/orders") {
+			t.Fatalf("row %d: action=%v parent=%v endpoint=%v", i, row[2].Value, row[10].Value, row[3].Value)
+		}
+	}
+}
+
+func TestGinMiddlewareRecordsAddedEntries(t *testing.T) {
+	entries := make(chan Entry, 2)
+	useGlobalRecorder(t, RecorderFunc(func(_ context.Context, e Entry) error {
+		entries <- e
+		return nil
+	}))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinMiddleware(WithUserExtractor(func(*gin.Context) string { return "user-9" })))
+	r.POST("/orders", func(c *gin.Context) {
+		FromContext(c.Request.Context()).Add(Entry{Action: "STOCK_RESERVED"})
+		c.Status(http.StatusCreated)
+	})
+	req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	req.Header.Set("X-Request-Id", "req-1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	byAction := map[string]Entry{}
+	for range 2 {
+		select {
+		case e := <-entries:
+			byAction[e.Action] = e
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for entries")
+		}
+	}
+	added, ok := byAction["STOCK_RESERVED"]
+	if !ok || added.RequestID != "req-1" || added.Actor != "user-9" || added.ParentID == "" {
+		t.Fatalf("added entry does not share the request: %+v", added)
+	}
+}
+
+// batchRecorder records batches on a channel and fails single-entry records.
+type batchRecorder struct {
+	batches chan []Entry
+}
+
+func (b batchRecorder) Record(context.Context, Entry) error {
+	return errors.New("unexpected single-entry record")
+}
+
+func (b batchRecorder) RecordBatch(_ context.Context, entries []Entry) error {
+	b.batches <- entries
+	return nil
+}
+
+func TestGinMiddlewareRecordsRequestInOneBatch(t *testing.T) {
+	rec := batchRecorder{batches: make(chan []Entry, 2)}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinMiddleware(func(cfg *ginMiddlewareConfig) { cfg.recorder = rec }, WithGinErrorHandler(func(err error) { t.Errorf("unexpected error: %v", err) })))
+	r.POST("/orders", func(c *gin.Context) {
+		FromContext(c.Request.Context()).Add(Entry{Action: "STOCK_RESERVED"})
+		FromContext(c.Request.Context()).Add(Entry{Action: "PAYMENT_CAPTURED"})
+		c.Status(http.StatusCreated)
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders", nil))
+
+	select {
+	case batch := <-rec.batches:
+		if len(batch) != 3 || batch[1].Action != "STOCK_RESERVED" || batch[2].Action != "PAYMENT_CAPTURED" {
+			t.Fatalf("unexpected batch: %+v", batch)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the batch")
+	}
+	select {
+	case batch := <-rec.batches:
+		t.Fatalf("expected a single RecordBatch call, got another of %d entries", len(batch))
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestMiddlewaresApplyCurrentEnrichment(t *testing.T) {
+	var got Entry
+	rec := RecorderFunc(func(_ context.Context, e Entry) error {
+		got = e
+		return nil
+	})
+	h := HTTPMiddleware(rec)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Current(r.Context()).SetEntity("order", "42").SetMeta("total", 99)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders", nil))
+	if got.EntityType != "order" || got.EntityID != "42" || got.Meta["total"] != 99 {
+		t.Fatalf("entry not enriched: %+v", got)
+	}
+
+	entries := make(chan Entry, 1)
+	useGlobalRecorder(t, RecorderFunc(func(_ context.Context, e Entry) error {
+		entries <- e
+		return nil
+	}))
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinMiddleware())
+	r.PUT("/orders/:id", func(c *gin.Context) {
+		Current(c.Request.Context()).SetEntity("order", c.Param("id")).SetMeta("status", "shipped")
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/orders/7", nil))
+	select {
+	case e := <-entries:
+		if e.EntityID != "7" || e.Meta["status"] != "shipped" {
+			t.Fatalf("gin entry not enriched: %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for entry")
+	}
+}
+
+func TestMiddlewaresHonorContextKeys(t *testing.T) {
+	var got Entry
+	rec := RecorderFunc(func(_ context.Context, e Entry) error {
+		got = e
+		return nil
+	})
+	auth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), UserIDKey, "user-9")))
+		})
+	}
+	h := auth(HTTPMiddleware(rec)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetAction(r.Context(), "ORDER_CREATED")
+	})))
+	req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	req.Header.Set("X-User-Id", "header-user")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if got.Action != "ORDER_CREATED" || got.Actor != "user-9" {
+		t.Fatalf("context keys not honored: action=%q actor=%q", got.Action, got.Actor)
+	}
+
+	entries := make(chan Entry, 1)
+	useGlobalRecorder(t, RecorderFunc(func(_ context.Context, e Entry) error {
+		entries <- e
+		return nil
+	}))
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinMiddleware())
+	r.POST("/orders", func(c *gin.Context) {
+		c.Set("audit_action", "GIN_ACTION")
+		SetAction(c.Request.Context(), "ORDER_CREATED")
+		SetUserID(c.Request.Context(), "user-9")
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders", nil))
+	select {
+	case e := <-entries:
+		if e.Action != "ORDER_CREATED" || e.Actor != "user-9" {
+			t.Fatalf("gin ignored SetAction/SetUserID: action=%q actor=%q", e.Action, e.Actor)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for entry")
+	}
+}
+
+func TestRequestAuditOutsideRequest(t *testing.T) {
+	ra := FromContext(context.Background())
+	if ra != nil {
+		t.Fatal("expected no request audit outside the middleware")
+	}
+	ra.Add(Entry{Action: "DROPPED"})           // must not panic
+	SetAction(context.Background(), "IGNORED") // must not panic
+	if Current(context.Background()).SetEntity("order", "1").SetMeta("k", 1) != nil {
+		t.Fatal("expected enrichment outside a request to be a no-op")
+	}
+}
@@ -0,0 +1,232 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultPendingLimit bounds the entries held in memory until a resilient Init completes.
+	defaultPendingLimit = 10000
+	// maxInitRetryInterval caps the backoff between resilient initialization attempts.
+	maxInitRetryInterval = time.Minute
+)
+
+var errShutdownBeforeInit = errors.New("audittrail: shut down before initialization completed")
+
+// WithResilientInit makes InitFromEnv succeed even when the database or broker is down,
+// buffering entries until the pipeline is up (see InitOptions.Resilient).
+func WithResilientInit() InitOption {
+	return func(o *InitOptions) {
+		o.Resilient = true
+	}
+}
+
+// pendingRecord is an entry recorded before the pipeline was up.
+type pendingRecord struct {
+	ctx   context.Context
+	entry Entry
+}
+
+// pendingRecorder holds entries until the pipeline is up, then forwards to it.
+type pendingRecorder struct {
+	mu      sync.Mutex
+	target  Recorder
+	entries []pendingRecord
+	limit   int
+	spool   *FileSpool
+	closed  bool
+}
+
+func (p *pendingRecorder) Record(ctx context.Context, entry Entry) error {
+	p.mu.Lock()
+	if target := p.target; target != nil {
+		p.mu.Unlock()
+		return target.Record(ctx, entry)
+	}
+	defer p.mu.Unlock()
+	if p.closed {
+		return errShutdownBeforeInit
+	}
+	if entry.CreatedDate.IsZero() {
+		// Keep the time of the event, not of the delayed publish.
+		entry.CreatedDate = time.Now().UTC()
+	}
+	if p.spool != nil {
+		if err := p.spool.Append(entry); err != nil {
+			reportDrop(entry, err)
+			return err
+		}
+		return nil
+	}
+	if len(p.entries) >= p.limit {
+		reportDrop(entry, ErrBufferFull)
+		return ErrBufferFull
+	}
+	p.entries = append(p.entries, pendingRecord{ctx: context.WithoutCancel(ctx), entry: entry})
+	return nil
+}
+
+// install replays the held entries, in order, through target and forwards later entries to
+// it. Entries the pipeline rejects are reported to OnDrop; when the spool cannot be drained,
+// nothing is forwarded yet so the order is kept for the next attempt.
+func (p *pendingRecorder) install(ctx context.Context, target Recorder) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.spool != nil {
+		if err := p.spool.Drain(ctx, target.Record); err != nil {
+			return err
+		}
+	}
+	for _, rec := range p.entries {
+		if err := target.Record(rec.ctx, rec.entry); err != nil {
+			reportDrop(rec.entry, err)
+		}
+	}
+	p.entries = nil
+	p.target = target
+	return nil
+}
+
+// pending reports how many entries are waiting for the pipeline.
+func (p *pendingRecorder) pending() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.spool != nil {
+		return p.spool.Len()
+	}
+	return len(p.entries)
+}
+
+// close drops the entries held in memory; spooled entries stay on disk for the next start.
+func (p *pendingRecorder) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.target != nil || p.closed {
+		return
+	}
+	p.closed = true
+	for _, rec := range p.entries {
+		reportDrop(rec.entry, errShutdownBeforeInit)
+	}
+	p.entries = nil
+}
+
+// resilientInit retries startPipeline with backoff until the pipeline is up.
+type resilientInit struct {
+	opts     *InitOptions
+	pending  *pendingRecorder
+	pipeline *pipeline
+	interval time.Duration
+
+	// degradedSince is when the first attempt failed and lastErr the latest failure, for
+	// ActionPipelineDegraded.
+	degradedSince time.Time
+	lastErr       error
+}
+
+// startResilient installs a buffering recorder and brings the pipeline up, in the background
+// if the first attempt fails.
+func startResilient(ctx context.Context, opts *InitOptions) {
+	attemptOpts := *opts
+	attemptOpts.StartupPing = true
+	limit := opts.PendingLimit
+	if limit <= 0 {
+		limit = defaultPendingLimit
+	}
+	r := &resilientInit{
+		opts:     &attemptOpts,
+		pending:  &pendingRecorder{limit: limit, spool: opts.Spool},
+		interval: opts.RetryInterval,
+	}
+	if r.interval <= 0 {
+		r.interval = time.Second
+	}
+
+	retryCtx, cancel := context.WithCancel(ctx)
+	runtime.mu.Lock()
+	runtime.initialized = true
+	runtime.initializing = false
+	runtime.recorder = r.pending
+	runtime.pending = r.pending
+	runtime.cancel = cancel
+	runtime.options = opts
+	startHeartbeat(ctx, opts)
+	runtime.mu.Unlock()
+	OnDrop(opts.OnDrop)
+
+	err := r.attempt(retryCtx)
+	if err == nil {
+		r.started(retryCtx, 1)
+		return
+	}
+	r.degradedSince, r.lastErr = time.Now().UTC(), err
+	logger().Warn("audittrail: initialization failed, recording to a buffer and retrying in the background", "retry_in", r.interval, "error", err)
+	runtime.wg.Add(1)
+	go func() {
+		defer runtime.wg.Done()
+		r.run(retryCtx)
+	}()
+}
+
+// attempt starts the pipeline, unless an earlier attempt did, and hands it the held entries.
+func (r *resilientInit) attempt(ctx context.Context) error {
+	if r.pipeline == nil {
+		p, err := startPipeline(ctx, r.opts, pipelineResources{}, &runtime.wg)
+		if err != nil {
+			return err
+		}
+		r.pipeline = p
+		runtime.mu.Lock()
+		stop := runtime.cancel
+		runtime.cancel = func() {
+			stop()
+			p.cancel()
+		}
+		runtime.db = p.db
+		runtime.pubsub = p.client
+		runtime.mu.Unlock()
+	}
+	return r.pending.install(ctx, r.pipeline.recorder)
+}
+
+func (r *resilientInit) run(ctx context.Context) {
+	for attempt := 2; ; attempt++ {
+		select {
+		case <-time.After(r.interval):
+		case <-ctx.Done():
+			return
+		}
+		err := r.attempt(ctx)
+		if err == nil {
+			logger().Info("audittrail: initialized after retrying", "attempts", attempt)
+			r.started(ctx, attempt)
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		r.lastErr = err
+		r.interval = min(r.interval*2, maxInitRetryInterval)
+		logger().Warn("audittrail: initialization failed", "attempt", attempt, "pending", r.pending.pending(), "retry_in", r.interval, "error", err)
+	}
+}
+
+// started records that the pipeline came up after attempts tries and, when Init had to
+// wait for it, the time it was degraded.
+func (r *resilientInit) started(ctx context.Context, attempts int) {
+	now := time.Now().UTC()
+	runtime.mu.Lock()
+	runtime.startedAt = now
+	runtime.mu.Unlock()
+	if !r.opts.LifecycleEvents {
+		return
+	}
+	if !r.degradedSince.IsZero() {
+		meta := map[string]any{"reason": "init_failed", "attempts": attempts, "error": r.lastErr.Error()}
+		recordLifecycle(ctx, r.pipeline.recorder, lifecycleEntry(ActionPipelineDegraded, SeverityWarn, r.degradedSince, now, meta))
+	}
+	recordLifecycle(ctx, r.pipeline.recorder, lifecycleEntry(ActionPipelineStarted, SeverityInfo, time.Time{}, now, r.pipeline.describe()))
+}
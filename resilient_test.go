@@ -0,0 +1,105 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyDriver refuses connections while down is set, like a database that is still booting.
+type flakyDriver struct {
+	stubDriver
+	down atomic.Bool
+}
+
+func (d *flakyDriver) Open(name string) (driver.Conn, error) {
+	if d.down.Load() {
+		return nil, errors.New("connection refused")
+	}
+	return d.stubDriver.Open(name)
+}
+
+func TestResilientInitBuffersUntilDatabaseIsUp(t *testing.T) {
+	inserted := make(chan string, 4)
+	d := &flakyDriver{stubDriver: stubDriver{execFn: func(_ string, args []driver.NamedValue) (driver.Result, error) {
+		inserted <- stringArg(args, 0)
+		return stubResult{}, nil
+	}}}
+	d.down.Store(true)
+	driverName := fmt.Sprintf("audittrail_flaky_%d", time.Now().UnixNano())
+	sql.Register(driverName, d)
+	t.Setenv(envTransport, TransportMemory)
+	t.Setenv(envDBDriver, driverName)
+
+	ctx := context.Background()
+	err := InitFromEnv(ctx, WithResilientInit(), func(o *InitOptions) {
+		o.RetryInterval = 5 * time.Millisecond
+		o.PendingLimit = 2
+		o.DisableInstanceFields = true
+	})
+	if err != nil {
+		t.Fatalf("expected resilient init to succeed while the database is down, got %v", err)
+	}
+	defer func() { _ = Shutdown(ctx) }()
+
+	for _, id := range []string{"e1", "e2"} {
+		if err := Record(ctx, Entry{ID: id, Action: "LOGIN"}); err != nil {
+			t.Fatalf("Record %s: %v", id, err)
+		}
+	}
+	if err := Record(ctx, Entry{ID: "e3", Action: "LOGIN"}); !errors.Is(err, ErrBufferFull) {
+		t.Fatalf("expected ErrBufferFull beyond PendingLimit, got %v", err)
+	}
+
+	d.down.Store(false)
+	for _, want := range []string{"e1", "e2"} {
+		select {
+		case id := <-inserted:
+			if id != want {
+				t.Fatalf("expected %s, got %s", want, id)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("entry %s was not stored after the database came up", want)
+		}
+	}
+	if err := Record(ctx, Entry{ID: "e4", Action: "LOGIN"}); err != nil {
+		t.Fatalf("Record after init: %v", err)
+	}
+	if id := <-inserted; id != "e4" {
+		t.Fatalf("expected e4, got %s", id)
+	}
+}
+
+func TestResilientInitSpoolsToDisk(t *testing.T) {
+	d := &flakyDriver{}
+	d.down.Store(true)
+	driverName := fmt.Sprintf("audittrail_flaky_%d", time.Now().UnixNano())
+	sql.Register(driverName, d)
+	t.Setenv(envTransport, TransportMemory)
+	t.Setenv(envDBDriver, driverName)
+
+	spool, err := NewFileSpool(filepath.Join(t.TempDir(), "pending.jsonl"), 0)
+	if err != nil {
+		t.Fatalf("NewFileSpool: %v", err)
+	}
+	defer spool.Close()
+	ctx := context.Background()
+	if err := InitFromEnv(ctx, WithResilientInit(), func(o *InitOptions) { o.Spool = spool }); err != nil {
+		t.Fatalf("InitFromEnv: %v", err)
+	}
+	if err := Record(ctx, Entry{ID: "e1", Action: "LOGIN"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if err := Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if spool.Len() != 1 {
+		t.Fatalf("expected the entry to stay spooled for the next start, got %d", spool.Len())
+	}
+}
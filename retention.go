@@ -0,0 +1,42 @@
+package audittrail
+
+import (
+	"database/sql"
+	"time"
+)
+
+// RetentionRule keeps entries whose action matches Action (a path.Match glob such as
+// "LOGIN_*"; empty or "*" matches everything) for TTL after they were created. Rules are set
+// in Config.Retention and checked in order; the first match wins.
+//
+//	Retention: []audittrail.RetentionRule{
+//		{Action: "LOGIN_*", TTL: 30 * 24 * time.Hour},
+//		{Action: "PAYMENT_*", TTL: 7 * 365 * 24 * time.Hour},
+//	}
+type RetentionRule struct {
+	Action string
+	TTL    time.Duration
+}
+
+// ExpiryFor returns the expiry the first matching rule assigns to entry, or the zero time.
+func ExpiryFor(rules []RetentionRule, entry Entry) time.Time {
+	for _, rule := range rules {
+		if rule.TTL > 0 && matchActionPattern(rule.Action, entry.Action) {
+			return entry.CreatedDate.Add(rule.TTL).UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// expiresAt returns the stored log_expires_at value: the entry's own ExpiresAt if set,
+// otherwise the one derived from the retention rules.
+func (r *AuditTrail) expiresAt(entry Entry) sql.NullTime {
+	expires := entry.ExpiresAt
+	if expires.IsZero() {
+		expires = ExpiryFor(r.retention, entry)
+	}
+	if expires.IsZero() {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: expires.UTC(), Valid: true}
+}
@@ -0,0 +1,64 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+func TestRetentionRulesSetExpiry(t *testing.T) {
+	var args []driver.NamedValue
+	db := openStubDB(t, &stubDriver{
+		execFn: func(_ string, a []driver.NamedValue) (driver.Result, error) {
+			args = a
+			return stubResult{}, nil
+		},
+	})
+	created := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	audit, err := NewAuditTrail(Config{DB: db, Retention: []RetentionRule{
+		{Action: "LOGIN_*", TTL: 30 * 24 * time.Hour},
+		{Action: "PAYMENT_*", TTL: 7 * 365 * 24 * time.Hour},
+	}})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	ctx := context.Background()
+	cases := []struct {
+		entry Entry
+		want  any
+	}{
+		{Entry{Action: "LOGIN_SUCCESS", CreatedDate: created}, created.Add(30 * 24 * time.Hour)},
+		{Entry{Action: "PAYMENT_REFUND", CreatedDate: created}, created.Add(7 * 365 * 24 * time.Hour)},
+		{Entry{Action: "UPDATE_PROFILE", CreatedDate: created}, nil},
+		{Entry{Action: "LOGIN_SUCCESS", CreatedDate: created, ExpiresAt: created.Add(time.Hour)}, created.Add(time.Hour)},
+	}
+	for _, tc := range cases {
+		if err := audit.Record(ctx, tc.entry); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+		got := args[8].Value
+		if want, ok := tc.want.(time.Time); ok {
+			if g, ok := got.(time.Time); !ok || !g.Equal(want) {
+				t.Fatalf("%s: expected expiry %v, got %v", tc.entry.Action, want, got)
+			}
+		} else if got != nil {
+			t.Fatalf("%s: expected no expiry, got %v", tc.entry.Action, got)
+		}
+	}
+}
+
+func TestExpiresAtSurvivesCodecs(t *testing.T) {
+	expires := time.Date(2031, 6, 1, 0, 0, 0, 0, time.UTC)
+	for _, codec := range []Codec{JSONCodec, ProtobufCodec, AvroCodec} {
+		data, err := codec.Marshal(Entry{ID: "e1", Action: "PAY", CreatedDate: expires.AddDate(-7, 0, 0), ExpiresAt: expires})
+		if err != nil {
+			t.Fatalf("%s Marshal: %v", codec.Name(), err)
+		}
+		got, err := codec.Unmarshal(data)
+		if err != nil || !got.ExpiresAt.Equal(expires) {
+			t.Fatalf("%s: expected ExpiresAt %v, got %v (%v)", codec.Name(), expires, got.ExpiresAt, err)
+		}
+	}
+}
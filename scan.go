@@ -0,0 +1,106 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// EntryColumns lists the audit table columns in the order ScanEntry expects them, for
+// custom queries: "SELECT " + EntryColumns + " FROM audit_trail WHERE ...".
+const EntryColumns = entryColumns
+
+// ScanEntry reads the current row of rows, which must select EntryColumns, into an Entry.
+// Request and Response come back as the stored JSON (json.RawMessage), timestamps in UTC
+// and NULL columns as zero values.
+func ScanEntry(rows *sql.Rows) (Entry, error) {
+	if rows == nil {
+		return Entry{}, errors.New("audittrail: rows must not be nil")
+	}
+	return scanEntry(rows)
+}
+
+// scanEntry reads one row selected with entryColumns. JSON payloads are returned as
+// json.RawMessage so they are passed through unchanged.
+func scanEntry(s rowScanner) (Entry, error) {
+	var (
+		e                                           Entry
+		requestID, endpoint, createdBy, severity    sql.NullString
+		request, response, parentID, correlationID  sql.NullString
+		sessionID, appVersion, hostname, instanceID sql.NullString
+		impersonatedBy, actor, ipAddress, service   sql.NullString
+		entityType, entityID, meta                  sql.NullString
+		expiresAt, startedAt, endedAt               sql.NullTime
+		durationMS                                  sql.NullInt64
+	)
+	err := s.Scan(&e.ID, &requestID, &e.Action, &endpoint, &request, &response, &e.CreatedDate,
+		&createdBy, &expiresAt, &severity, &parentID, &correlationID, &sessionID,
+		&appVersion, &hostname, &instanceID, &impersonatedBy, &actor, &ipAddress, &service,
+		&startedAt, &endedAt, &durationMS, &entityType, &entityID, &meta)
+	if err != nil {
+		return Entry{}, err
+	}
+	e.RequestID = requestID.String
+	e.Endpoint = endpoint.String
+	if request.Valid {
+		e.Request = json.RawMessage(request.String)
+	}
+	if response.Valid {
+		e.Response = json.RawMessage(response.String)
+	}
+	e.CreatedDate = e.CreatedDate.UTC()
+	e.CreatedBy = createdBy.String
+	if expiresAt.Valid {
+		e.ExpiresAt = expiresAt.Time.UTC()
+	}
+	e.Severity = Severity(severity.String)
+	e.ParentID = parentID.String
+	e.CorrelationID = correlationID.String
+	e.SessionID = sessionID.String
+	e.AppVersion = appVersion.String
+	e.Hostname = hostname.String
+	e.InstanceID = instanceID.String
+	e.ImpersonatedBy = impersonatedBy.String
+	e.Actor = actor.String
+	e.IPAddress = ipAddress.String
+	e.ServiceName = service.String
+	if startedAt.Valid {
+		e.StartedAt = startedAt.Time.UTC()
+	}
+	if endedAt.Valid {
+		e.EndedAt = endedAt.Time.UTC()
+	}
+	e.DurationMS = durationMS.Int64
+	e.EntityType = entityType.String
+	e.EntityID = entityID.String
+	e.Meta = unmarshalMeta([]byte(meta.String))
+	return e, nil
+}
+
+// queryEntries runs a SELECT of entryColumns with the given clause (WHERE, ORDER BY, ...)
+// and scans every row.
+func (r *AuditTrail) queryEntries(ctx context.Context, clause string, args ...any) ([]Entry, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s%s", entryColumns, r.tableRef, clause)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
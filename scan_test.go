@@ -0,0 +1,101 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanEntryRoundTrip(t *testing.T) {
+	created := time.Date(2024, 6, 1, 12, 30, 45, 123456000, time.UTC)
+	entries := []Entry{
+		{
+			ID: "full", RequestID: "req-1", Action: "UPDATE_ORDER", Endpoint: "PUT /orders/1",
+			Request:     json.RawMessage(`{"qty":2,"note":"ünïcode"}`),
+			Response:    json.RawMessage(`[1,2,3]`),
+			CreatedDate: created, CreatedBy: "u1", ExpiresAt: created.Add(24 * time.Hour),
+			Severity: SeverityWarn, ParentID: "p1", CorrelationID: "c1", SessionID: "s1",
+			AppVersion: "v1.2.3", Hostname: "host-a", InstanceID: "pod-1",
+		},
+		{ID: "minimal", Action: "LOGIN", CreatedDate: created},
+	}
+
+	var stored [][]driver.Value
+	db := openStubDB(t, &stubDriver{
+		execFn: func(_ string, args []driver.NamedValue) (driver.Result, error) {
+			row := make([]driver.Value, len(args))
+			for i, a := range args {
+				// Drivers commonly return JSON and text columns as bytes.
+				if s, ok := a.Value.(string); ok && (i == 4 || i == 5) {
+					row[i] = []byte(s)
+				} else {
+					row[i] = a.Value
+				}
+			}
+			stored = append(stored, row)
+			return stubResult{}, nil
+		},
+		queryFn: func(string, []driver.NamedValue) (driver.Rows, error) {
+			return &stubRows{columns: strings.Split(EntryColumns, ", "), values: stored}, nil
+		},
+	})
+	audit, err := NewAuditTrail(Config{DB: db})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	for _, e := range entries {
+		if err := audit.Record(context.Background(), e); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	rows, err := db.Query("SELECT " + EntryColumns + " FROM audit_trail")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	defer rows.Close()
+	var got []Entry
+	for rows.Next() {
+		e, err := ScanEntry(rows)
+		if err != nil {
+			t.Fatalf("ScanEntry: %v", err)
+		}
+		got = append(got, e)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows: %v", err)
+	}
+	if !reflect.DeepEqual(got, entries) {
+		t.Fatalf("round trip mismatch:\n got %+v\nwant %+v", got, entries)
+	}
+}
+
+func TestScanEntryNormalizesTimezone(t *testing.T) {
+	local := time.Date(2024, 6, 1, 14, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	db := openStubDB(t, &stubDriver{queryFn: func(string, []driver.NamedValue) (driver.Rows, error) {
+		return &stubRows{
+			columns: strings.Split(EntryColumns, ", "),
+			values:  [][]driver.Value{{"id", nil, "A", nil, nil, nil, local, nil, local, nil, nil, nil, nil, nil, nil, nil}},
+		}, nil
+	}})
+	rows, err := db.Query("SELECT " + EntryColumns + " FROM audit_trail")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		t.Fatal("expected a row")
+	}
+	e, err := ScanEntry(rows)
+	if err != nil {
+		t.Fatalf("ScanEntry: %v", err)
+	}
+	if e.CreatedDate.Location() != time.UTC || !e.CreatedDate.Equal(local) || !e.ExpiresAt.Equal(local) ||
+		e.Request != nil || e.CreatedBy != "" || e.Severity != "" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+}
@@ -0,0 +1,130 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// column describes one audit table column for EnsureTable and migrations.
+type column struct {
+	name string
+	ddl  string // type and constraints, e.g. "VARCHAR(255) NULL"
+}
+
+// auditColumns lists the table schema in creation order. Columns added after the
+// original release must be nullable or have a default so existing tables can be migrated.
+var auditColumns = []column{
+	{name: "log_audit_trail_id", ddl: "VARCHAR(64) PRIMARY KEY"},
+	{name: "log_req_id", ddl: "VARCHAR(128) NULL"},
+	{name: "log_action", ddl: "VARCHAR(255) NOT NULL"},
+	{name: "log_endpoint", ddl: "TEXT NULL"},
+	{name: "log_request", ddl: "JSON NULL"},
+	{name: "log_response", ddl: "JSON NULL"},
+	{name: "log_created_date", ddl: "TIMESTAMP NOT NULL"},
+	{name: "log_created_by", ddl: "VARCHAR(255) NULL"},
+	{name: "log_on_hold", ddl: "BOOLEAN NOT NULL DEFAULT FALSE"},
+	{name: "log_expires_at", ddl: "TIMESTAMP NULL"},
+	{name: "log_severity", ddl: "VARCHAR(16) NULL"},
+	{name: "log_parent_id", ddl: "VARCHAR(64) NULL"},
+	{name: "log_correlation_id", ddl: "VARCHAR(64) NULL"},
+	{name: "log_session_id", ddl: "VARCHAR(255) NULL"},
+	{name: "log_app_version", ddl: "VARCHAR(128) NULL"},
+	{name: "log_hostname", ddl: "VARCHAR(255) NULL"},
+	{name: "log_instance_id", ddl: "VARCHAR(255) NULL"},
+	{name: "log_impersonated_by", ddl: "VARCHAR(255) NULL"},
+	{name: "log_actor", ddl: "VARCHAR(255) NULL"},
+	{name: "log_ip_address", ddl: "VARCHAR(64) NULL"},
+	{name: "log_service_name", ddl: "VARCHAR(255) NULL"},
+	{name: "log_started_at", ddl: "TIMESTAMP NULL"},
+	{name: "log_ended_at", ddl: "TIMESTAMP NULL"},
+	{name: "log_duration_ms", ddl: "BIGINT NULL"},
+	{name: "log_entity_type", ddl: "VARCHAR(64) NULL"},
+	{name: "log_entity_id", ddl: "VARCHAR(255) NULL"},
+	{name: "log_meta", ddl: "JSON NULL"},
+	{name: "log_row_hash", ddl: "VARCHAR(64) NULL"},
+}
+
+// EnsureTable creates the audit table if it does not exist and adds any columns
+// introduced by newer versions of this package to an existing table. On MySQL the payload
+// columns use the native JSON type and timestamps use DATETIME(6); existing TIMESTAMP
+// columns are not converted. On Spanner the table gets GoogleSQL types and a log_commit_ts
+// commit-timestamp column. With Config.Encryption the "<table>_keys" data key table is
+// created as well.
+func (r *AuditTrail) EnsureTable(ctx context.Context) error {
+	if r == nil || r.db == nil {
+		return errors.New("audittrail: instance is not initialized")
+	}
+
+	query := r.createTableQuery(r.tableRef, r.tableColumns(), "log_audit_trail_id")
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		return err
+	}
+	if r.encryptor != nil {
+		if _, err := r.db.ExecContext(ctx, r.createTableQuery(r.encryptor.table, keyColumns, "key_id")); err != nil {
+			return err
+		}
+	}
+	return r.migrateColumns(ctx)
+}
+
+// createTableQuery renders CREATE TABLE IF NOT EXISTS for the dialect. The column named key
+// must be declared with PRIMARY KEY.
+func (r *AuditTrail) createTableQuery(table string, columns []column, key string) string {
+	defs := make([]string, len(columns))
+	for i, col := range columns {
+		defs[i] = "\t\t\t" + r.quote(col.name) + " " + r.columnDDL(col)
+	}
+	if r.dialect == dialectSpanner {
+		// Spanner declares the primary key after the columns and rejects the semicolon.
+		return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n%s\n) PRIMARY KEY (%s)",
+			table, strings.Join(defs, ",\n"), r.quote(key))
+	}
+	return fmt.Sprintf("\n\t\tCREATE TABLE IF NOT EXISTS %s (\n%s\n\t\t);", table, strings.Join(defs, ",\n"))
+}
+
+// migrateColumns adds columns missing from an existing table.
+func (r *AuditTrail) migrateColumns(ctx context.Context) error {
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s WHERE 1 = 0", r.tableRef))
+	if err != nil {
+		return fmt.Errorf("audittrail: inspect table columns failed: %w", err)
+	}
+	existing, err := rows.Columns()
+	rows.Close()
+	if err != nil {
+		return fmt.Errorf("audittrail: inspect table columns failed: %w", err)
+	}
+
+	have := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		have[strings.ToLower(name)] = true
+	}
+	for _, col := range r.tableColumns() {
+		if have[col.name] {
+			continue
+		}
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", r.tableRef, r.quote(col.name), r.columnDDL(col))
+		if _, err := r.db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("audittrail: add column %s failed: %w", col.name, err)
+		}
+	}
+	return nil
+}
+
+// EnsureSearchIndex creates the GIN index used by Filter.Contains on Postgres. Without it
+// searches scan the whole table. It does nothing on other databases, which fall back to
+// LIKE matching.
+func (r *AuditTrail) EnsureSearchIndex(ctx context.Context) error {
+	if r == nil || r.db == nil {
+		return errors.New("audittrail: instance is not initialized")
+	}
+	if r.placeholder != PlaceholderDollar {
+		return nil
+	}
+	query := fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_search_idx ON %s USING GIN (%s)", r.table, r.table, searchDocument)
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("audittrail: create search index failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mysqlStubDriver and spannerStubDriver are detected by their type names.
+type (
+	mysqlStubDriver   struct{ stubDriver }
+	spannerStubDriver struct{ stubDriver }
+)
+
+func openDriverDB(t *testing.T, d driver.Driver) *sql.DB {
+	t.Helper()
+	name := fmt.Sprintf("audittrail_%T_%d", d, time.Now().UnixNano())
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestMySQLDialect(t *testing.T) {
+	var calls []string
+	d := &mysqlStubDriver{stubDriver{
+		execFn: func(query string, _ []driver.NamedValue) (driver.Result, error) {
+			calls = append(calls, query)
+			return stubResult{}, nil
+		},
+		queryFn: func(string, []driver.NamedValue) (driver.Rows, error) {
+			return &stubRows{columns: auditColumnNames()[:10]}, nil
+		},
+	}}
+	audit, err := NewAuditTrail(Config{DB: openDriverDB(t, d), TableName: "order"})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	ctx := context.Background()
+	if err := audit.EnsureTable(ctx); err != nil {
+		t.Fatalf("EnsureTable: %v", err)
+	}
+	if !strings.Contains(calls[0], "CREATE TABLE IF NOT EXISTS `order` (") ||
+		!strings.Contains(calls[0], "`log_request` JSON NULL") ||
+		!strings.Contains(calls[0], "`log_created_date` DATETIME(6) NOT NULL") ||
+		!strings.Contains(calls[0], "`log_expires_at` DATETIME(6) NULL") {
+		t.Fatalf("unexpected CREATE TABLE: %s", calls[0])
+	}
+	if calls[1] != "ALTER TABLE `order` ADD COLUMN `log_severity` VARCHAR(16) NULL" {
+		t.Fatalf("unexpected migration: %s", calls[1])
+	}
+
+	calls = nil
+	if err := audit.Record(ctx, Entry{Action: "EXPORT"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if !strings.HasPrefix(calls[0], "INSERT INTO `order` (`log_audit_trail_id`, `log_req_id`, ") {
+		t.Fatalf("unexpected insert: %s", calls[0])
+	}
+	if err := audit.recordOnce(ctx, Entry{Action: "EXPORT"}); err != nil || !strings.HasPrefix(calls[1], "INSERT IGNORE INTO `order`") {
+		t.Fatalf("unexpected insert: %s, %v", calls[1], err)
+	}
+}
+
+func TestSpannerDialect(t *testing.T) {
+	var calls, queries []string
+	d := &spannerStubDriver{stubDriver{
+		execFn: func(query string, _ []driver.NamedValue) (driver.Result, error) {
+			calls = append(calls, query)
+			return stubResult{}, nil
+		},
+		queryFn: func(query string, _ []driver.NamedValue) (driver.Rows, error) {
+			queries = append(queries, query)
+			if strings.HasPrefix(query, "SELECT COUNT(*)") {
+				return &stubRows{columns: []string{"n"}, values: [][]driver.Value{{int64(0)}}}, nil
+			}
+			return &stubRows{columns: auditColumnNames()}, nil
+		},
+	}}
+	audit, err := NewAuditTrail(Config{DB: openDriverDB(t, d)})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	ctx := context.Background()
+	if err := audit.EnsureTable(ctx); err != nil {
+		t.Fatalf("EnsureTable: %v", err)
+	}
+	create := calls[0]
+	for _, want := range []string{
+		"CREATE TABLE IF NOT EXISTS `audit_trail` (",
+		"`log_audit_trail_id` STRING(64) NOT NULL,",
+		"`log_endpoint` STRING(MAX),",
+		"`log_request` STRING(MAX),",
+		"`log_created_date` TIMESTAMP NOT NULL,",
+		"`log_on_hold` BOOL NOT NULL DEFAULT (FALSE),",
+		"`log_commit_ts` TIMESTAMP OPTIONS (allow_commit_timestamp=true)\n) PRIMARY KEY (`log_audit_trail_id`)",
+	} {
+		if !strings.Contains(create, want) {
+			t.Fatalf("CREATE TABLE missing %q:\n%s", want, create)
+		}
+	}
+	if len(calls) != 2 || calls[1] != "ALTER TABLE `audit_trail` ADD COLUMN `log_commit_ts` TIMESTAMP OPTIONS (allow_commit_timestamp=true)" {
+		t.Fatalf("unexpected migration: %q", calls[1:])
+	}
+
+	calls = nil
+	if err := audit.RecordBatch(ctx, []Entry{{Action: "A"}, {Action: "B"}}); err != nil {
+		t.Fatalf("RecordBatch: %v", err)
+	}
+	if err := audit.recordOnce(ctx, Entry{Action: "A"}); err != nil {
+		t.Fatalf("recordOnce: %v", err)
+	}
+	if !strings.Contains(calls[0], ", `log_commit_ts`) VALUES (?, ") ||
+		strings.Count(calls[0], ", PENDING_COMMIT_TIMESTAMP())") != 2 ||
+		!strings.HasPrefix(calls[1], "INSERT OR IGNORE INTO `audit_trail`") {
+		t.Fatalf("unexpected inserts: %q", calls)
+	}
+
+	if _, err := audit.Count(ctx, Filter{Contains: "50%"}); err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if want := "(STRPOS(log_request, ?) > 0 OR STRPOS(log_response, ?) > 0)"; !strings.Contains(queries[len(queries)-1], want) {
+		t.Fatalf("unexpected search query: %s", queries[len(queries)-1])
+	}
+}
@@ -0,0 +1,44 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+)
+
+func TestFilterContains(t *testing.T) {
+	b := &queryBuilder{placeholder: PlaceholderDollar}
+	where := b.where(Filter{Actor: "u1", Contains: "order-789"})
+	if !strings.Contains(where, "log_created_by = $2)) AND "+searchDocument+" @@ plainto_tsquery('simple', $3)") ||
+		b.args[2] != "order-789" {
+		t.Fatalf("unexpected postgres clause: %s %v", where, b.args)
+	}
+
+	b = &queryBuilder{placeholder: PlaceholderQuestion}
+	where = b.where(Filter{Contains: "50%_off!"})
+	if where != " WHERE (log_request LIKE ? ESCAPE '!' OR log_response LIKE ? ESCAPE '!')" ||
+		len(b.args) != 2 || b.args[0] != "%50!%!_off!!%" {
+		t.Fatalf("unexpected fallback clause: %s %v", where, b.args)
+	}
+}
+
+func TestEnsureSearchIndex(t *testing.T) {
+	var calls []string
+	db := openStubDB(t, &stubDriver{execFn: func(query string, _ []driver.NamedValue) (driver.Result, error) {
+		calls = append(calls, query)
+		return stubResult{}, nil
+	}})
+	for _, style := range []PlaceholderStyle{PlaceholderDollar, PlaceholderQuestion} {
+		audit, err := NewAuditTrail(Config{DB: db, Placeholder: style})
+		if err != nil {
+			t.Fatalf("NewAuditTrail: %v", err)
+		}
+		if err := audit.EnsureSearchIndex(context.Background()); err != nil {
+			t.Fatalf("EnsureSearchIndex: %v", err)
+		}
+	}
+	if len(calls) != 1 || calls[0] != "CREATE INDEX IF NOT EXISTS audit_trail_search_idx ON audit_trail USING GIN ("+searchDocument+")" {
+		t.Fatalf("unexpected statements: %q", calls)
+	}
+}
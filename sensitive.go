@@ -0,0 +1,166 @@
+package audittrail
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SensitiveMode decides what the built-in recorders do with payload data that looks like
+// a secret or PII.
+type SensitiveMode int
+
+const (
+	SensitiveMask SensitiveMode = iota // replace it with RedactedValue (default)
+	SensitiveFlag                      // log a warning and store it unchanged
+	SensitiveOff                       // skip detection
+)
+
+// DefaultSensitiveFields are payload keys treated as sensitive. Keys are compared in lower
+// case without "_" and "-", so "api_key", "apiKey" and "API-KEY" all match "apikey". Names
+// of up to four letters must match the whole key; longer names also match inside a key
+// ("new_password", "refreshToken").
+var DefaultSensitiveFields = []string{
+	"password", "passwd", "pwd", "secret", "token", "apikey", "privatekey",
+	"credential", "authorization", "cookie", "ssn", "cvv", "cvc",
+	"cardnumber", "creditcard", "pan",
+}
+
+var (
+	ssnPattern  = regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)
+	cardPattern = regexp.MustCompile(`\b[2-6](?:[ -]?\d){12,18}\b`)
+	jwtPattern  = regexp.MustCompile(`\beyJ[\w-]+\.[\w-]+\.[\w-]+`)
+)
+
+// WithSensitiveDetection selects how payloads are screened for likely secrets and PII that
+// nobody configured redaction for: values under keys in DefaultSensitiveFields, and strings
+// containing US social security numbers, card numbers that pass the Luhn check or JWTs.
+// Detection runs after the transformers, so they can redact fields on purpose first.
+func WithSensitiveDetection(mode SensitiveMode) RecorderOption {
+	return func(h *recorderHooks) { h.sensitive = mode }
+}
+
+// screenSensitive applies the sensitive data mode to the payloads of entry.
+func (h *recorderHooks) screenSensitive(entry Entry) Entry {
+	if h.sensitive == SensitiveOff {
+		return entry
+	}
+	request, requestPaths := maskSensitive(entry.Request)
+	response, responsePaths := maskSensitive(entry.Response)
+	if len(requestPaths) == 0 && len(responsePaths) == 0 {
+		return entry
+	}
+	if h.sensitive == SensitiveFlag {
+		logger().Warn("audittrail: sensitive data in payload", "action", entry.Action, "id", entry.ID,
+			"request", requestPaths, "response", responsePaths)
+		return entry
+	}
+	entry.Request, entry.Response = request, response
+	return entry
+}
+
+// maskSensitive returns v with sensitive values redacted and the JSON paths ("$.user.ssn")
+// that were. v is returned unchanged when nothing was found.
+func maskSensitive(v any) (any, []string) {
+	raw, err := marshalJSONValue(v)
+	if err != nil || !raw.Valid {
+		return v, nil
+	}
+	var decoded any
+	dec := json.NewDecoder(strings.NewReader(raw.String))
+	dec.UseNumber()
+	if err := dec.Decode(&decoded); err != nil || dec.More() {
+		// Plain text payload.
+		if masked := maskText(raw.String); masked != raw.String {
+			return masked, []string{"$"}
+		}
+		return v, nil
+	}
+
+	var paths []string
+	masked := maskValue(decoded, "$", &paths)
+	if len(paths) == 0 {
+		return v, nil
+	}
+	buf, err := json.Marshal(masked)
+	if err != nil {
+		return v, nil
+	}
+	sort.Strings(paths)
+	return json.RawMessage(buf), paths
+}
+
+func maskValue(v any, path string, paths *[]string) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, child := range val {
+			p := path + "." + k
+			if isSensitiveField(k) {
+				if child != nil && child != "" {
+					val[k] = RedactedValue
+					*paths = append(*paths, p)
+				}
+				continue
+			}
+			val[k] = maskValue(child, p, paths)
+		}
+		return val
+	case []any:
+		for i, child := range val {
+			val[i] = maskValue(child, path+"["+strconv.Itoa(i)+"]", paths)
+		}
+		return val
+	case string:
+		masked := maskText(val)
+		if masked != val {
+			*paths = append(*paths, path)
+		}
+		return masked
+	}
+	return v
+}
+
+var (
+	fieldSeparators = strings.NewReplacer("_", "", "-", "")
+	cardSeparators  = strings.NewReplacer(" ", "", "-", "")
+)
+
+func isSensitiveField(key string) bool {
+	key = fieldSeparators.Replace(strings.ToLower(key))
+	for _, name := range DefaultSensitiveFields {
+		if key == name || (len(name) > 4 && strings.Contains(key, name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskText redacts SSNs, card numbers and JWTs inside free text.
+func maskText(s string) string {
+	s = ssnPattern.ReplaceAllString(s, RedactedValue)
+	s = jwtPattern.ReplaceAllString(s, RedactedValue)
+	return cardPattern.ReplaceAllStringFunc(s, func(m string) string {
+		if luhnValid(cardSeparators.Replace(m)) {
+			return RedactedValue
+		}
+		return m
+	})
+}
+
+// luhnValid reports whether digits passes the Luhn checksum used by card numbers.
+func luhnValid(digits string) bool {
+	sum, double := 0, false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
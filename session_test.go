@@ -0,0 +1,52 @@
+package audittrail
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type sessionKey struct{}
+
+func TestHTTPMiddlewareSessionExtraction(t *testing.T) {
+	var got Entry
+	rec := RecorderFunc(func(_ context.Context, e Entry) error {
+		got = e
+		return nil
+	})
+	noop := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.AddCookie(&http.Cookie{Name: "sid", Value: "sess-1"})
+	HTTPMiddleware(rec, WithSessionCookie("sid"))(noop).ServeHTTP(httptest.NewRecorder(), req)
+	if got.SessionID != "sess-1" {
+		t.Fatalf("expected session from cookie, got %q", got.SessionID)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req = req.WithContext(context.WithValue(req.Context(), sessionKey{}, "sess-2"))
+	HTTPMiddleware(rec, WithSessionContextKey(sessionKey{}))(noop).ServeHTTP(httptest.NewRecorder(), req)
+	if got.SessionID != "sess-2" {
+		t.Fatalf("expected session from context, got %q", got.SessionID)
+	}
+}
+
+func TestGinSessionID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.AddCookie(&http.Cookie{Name: "sid", Value: "cookie-sess"})
+
+	cfg := defaultGinConfig()
+	WithGinSessionCookie("sid")(&cfg)
+	if got := ginSessionID(c, cfg); got != "cookie-sess" {
+		t.Fatalf("expected cookie session, got %q", got)
+	}
+	c.Set("session_id", "ctx-sess")
+	if got := ginSessionID(c, cfg); got != "ctx-sess" {
+		t.Fatalf("expected context key to take precedence, got %q", got)
+	}
+}
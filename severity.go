@@ -0,0 +1,83 @@
+package audittrail
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Severity ranks how important an audit event is for downstream alerting.
+type Severity string
+
+const (
+	SeverityInfo     Severity = "INFO"
+	SeverityWarn     Severity = "WARN"
+	SeverityCritical Severity = "CRITICAL"
+)
+
+// rank orders severities; unknown or empty values rank as INFO.
+func (s Severity) rank() int {
+	switch Severity(strings.ToUpper(string(s))) {
+	case SeverityCritical:
+		return 2
+	case SeverityWarn:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// AtLeast reports whether s is as severe as min.
+func (s Severity) AtLeast(min Severity) bool {
+	return s.rank() >= min.rank()
+}
+
+// DefaultSeverity derives a severity from an HTTP exchange: successful deletions are
+// CRITICAL; denied requests (401/403) and server errors are WARN; everything else is INFO.
+func DefaultSeverity(method string, status int) Severity {
+	switch {
+	case status == http.StatusUnauthorized, status == http.StatusForbidden, status >= 500:
+		return SeverityWarn
+	case strings.EqualFold(method, http.MethodDelete) && status < 400:
+		return SeverityCritical
+	default:
+		return SeverityInfo
+	}
+}
+
+// routeSeverity is a per-route override; pattern is matched with path.Match against the
+// path and against "METHOD path".
+type routeSeverity struct {
+	pattern  string
+	severity Severity
+}
+
+func matchRouteSeverity(routes []routeSeverity, method, p string) (Severity, bool) {
+	for _, r := range routes {
+		if ok, _ := path.Match(r.pattern, p); ok {
+			return r.severity, true
+		}
+		if ok, _ := path.Match(r.pattern, method+" "+p); ok {
+			return r.severity, true
+		}
+	}
+	return "", false
+}
+
+// WithRouteSeverity overrides the severity of requests whose path (or "METHOD path")
+// matches pattern, e.g. WithRouteSeverity("POST /admin/*", SeverityCritical).
+// Earlier overrides take precedence.
+func WithRouteSeverity(pattern string, severity Severity) HTTPMiddlewareOption {
+	return func(c *httpMiddlewareConfig) {
+		c.routeSeverity = append(c.routeSeverity, routeSeverity{pattern: pattern, severity: severity})
+	}
+}
+
+// WithGinRouteSeverity overrides the severity of requests whose route template
+// (c.FullPath(), e.g. "/users/:id/roles") or "METHOD template" matches pattern.
+// A handler can also set the severity for one request with c.Set("audit_severity", ...).
+func WithGinRouteSeverity(pattern string, severity Severity) GinMiddlewareOption {
+	return func(c *ginMiddlewareConfig) {
+		c.routeSeverity = append(c.routeSeverity, routeSeverity{pattern: pattern, severity: severity})
+	}
+}
@@ -0,0 +1,52 @@
+package audittrail
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPMiddlewareSeverity(t *testing.T) {
+	var got []Entry
+	rec := RecorderFunc(func(_ context.Context, e Entry) error {
+		got = append(got, e)
+		return nil
+	})
+	handler := HTTPMiddleware(rec, WithRouteSeverity("POST /admin/*", SeverityCritical))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/secret" {
+				w.WriteHeader(http.StatusForbidden)
+			}
+		}))
+
+	cases := []struct {
+		method, path string
+		want         Severity
+	}{
+		{http.MethodGet, "/orders", SeverityInfo},
+		{http.MethodDelete, "/orders/1", SeverityCritical},
+		{http.MethodGet, "/secret", SeverityWarn},
+		{http.MethodPost, "/admin/roles", SeverityCritical},
+	}
+	for _, tc := range cases {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tc.method, tc.path, nil))
+	}
+	for i, tc := range cases {
+		if got[i].Severity != tc.want {
+			t.Fatalf("%s %s: expected %s, got %s", tc.method, tc.path, tc.want, got[i].Severity)
+		}
+	}
+}
+
+func TestAlertRuleMinSeverity(t *testing.T) {
+	fired := 0
+	a := NewAnalyzer()
+	a.AddRule(AlertRule{Threshold: 1, Window: time.Minute, MinSeverity: SeverityWarn}, func(context.Context, Alert) { fired++ })
+	a.Observe(context.Background(), Entry{Action: "READ", CreatedBy: "u1", Severity: SeverityInfo})
+	a.Observe(context.Background(), Entry{Action: "DELETE", CreatedBy: "u2", Severity: SeverityCritical})
+	if fired != 1 {
+		t.Fatalf("expected only the critical entry to alert, fired %d", fired)
+	}
+}
@@ -0,0 +1,221 @@
+package audittrail
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// PubSubMaxMessageBytes is the largest message data Google Cloud Pub/Sub accepts.
+const PubSubMaxMessageBytes = 10 * 1000 * 1000
+
+// ErrEntryTooLarge matches (via errors.Is) entries whose serialized size exceeds the
+// SizePolicy limit and could not be reduced to fit.
+var ErrEntryTooLarge = errors.New("audittrail: entry too large")
+
+// OversizeAction selects what happens to an entry larger than SizePolicy.MaxBytes.
+type OversizeAction int
+
+const (
+	// OversizeReject fails the publish with ErrEntryTooLarge.
+	OversizeReject OversizeAction = iota
+	// OversizeTruncate keeps a prefix of the request and response payloads, largest first,
+	// as an OversizedPayload.
+	OversizeTruncate
+	// OversizeSummarize replaces the request and response payloads with their size and
+	// SHA-256 digest, as an OversizedPayload.
+	OversizeSummarize
+)
+
+// SizePolicy bounds the serialized size of published entries.
+type SizePolicy struct {
+	// MaxBytes is the largest encoded entry sent as is. Zero or less means no limit.
+	MaxBytes int
+	// Action applies to larger entries. Default: OversizeReject.
+	Action OversizeAction
+	// OnReject is called with the entry and its encoded size when it is rejected, either by
+	// OversizeReject or because truncating or summarizing did not make it fit. It must be
+	// fast and must not block.
+	OnReject func(entry Entry, size int)
+}
+
+// OversizedPayload replaces a request or response payload cut down by a SizePolicy.
+type OversizedPayload struct {
+	Truncated bool   `json:"truncated"`
+	Bytes     int    `json:"bytes"`            // size of the original payload as JSON
+	SHA256    string `json:"sha256"`           // hex digest of the original payload as JSON
+	Prefix    string `json:"prefix,omitempty"` // start of the original payload (OversizeTruncate)
+}
+
+// oversizeSlack is room left for the OversizedPayload wrapper when truncating.
+const oversizeSlack = 256
+
+// LimitSize wraps c so every marshaled entry is counted in EntrySizes and entries larger
+// than policy.MaxBytes are handled by policy.Action. The wrapper keeps c's name, so
+// subscribers decode its messages with c:
+//
+//	pub := audittrail.NewPulsarPublisher(send, audittrail.WithPulsarCodec(audittrail.LimitSize(
+//		audittrail.JSONCodec, audittrail.SizePolicy{MaxBytes: 5 << 20, Action: audittrail.OversizeSummarize})))
+func LimitSize(c Codec, policy SizePolicy) Codec {
+	if c == nil {
+		c = JSONCodec
+	}
+	return sizeCodec{Codec: c, policy: policy}
+}
+
+type sizeCodec struct {
+	Codec
+	policy SizePolicy
+}
+
+func (c sizeCodec) Marshal(entry Entry) ([]byte, error) {
+	data, err := c.Codec.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	observeEntrySize(len(data))
+	limit := c.policy.MaxBytes
+	if limit <= 0 || len(data) <= limit {
+		return data, nil
+	}
+
+	size := len(data)
+	switch c.policy.Action {
+	case OversizeTruncate:
+		data, err = c.truncate(entry, data)
+	case OversizeSummarize:
+		summarized := entry
+		summarized.Request, err = summarizePayload(entry.Request, -1)
+		if err == nil {
+			summarized.Response, err = summarizePayload(entry.Response, -1)
+		}
+		if err == nil {
+			data, err = c.Codec.Marshal(summarized)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > limit {
+		if c.policy.OnReject != nil {
+			c.policy.OnReject(entry, size)
+		}
+		return nil, fmt.Errorf("%w: %d bytes, limit %d", ErrEntryTooLarge, size, limit)
+	}
+	return data, nil
+}
+
+// truncate shortens the payload prefixes until the entry fits or they are empty. Each round
+// cuts the remaining excess from the largest payload first; JSON escaping of the prefixes
+// can take a second round.
+func (c sizeCodec) truncate(entry Entry, data []byte) ([]byte, error) {
+	payloads := [2]any{entry.Request, entry.Response}
+	var full, keep [2]int // JSON size and prefix length per payload
+	for i, p := range payloads {
+		s, err := marshalJSONValue(p)
+		if err != nil {
+			return nil, marshalFailed("payload", err)
+		}
+		full[i], keep[i] = len(s.String), len(s.String)
+	}
+	for round := 0; round < 4 && len(data) > c.policy.MaxBytes && (keep[0] > 0 || keep[1] > 0); round++ {
+		cut := len(data) - c.policy.MaxBytes + oversizeSlack
+		for cut > 0 && (keep[0] > 0 || keep[1] > 0) {
+			i := 0
+			if keep[1] > keep[0] {
+				i = 1
+			}
+			d := min(cut, keep[i])
+			keep[i] -= d
+			cut -= d
+		}
+
+		shrunk := entry
+		var err error
+		if keep[0] < full[0] {
+			if shrunk.Request, err = summarizePayload(payloads[0], keep[0]); err != nil {
+				return nil, err
+			}
+		}
+		if keep[1] < full[1] {
+			if shrunk.Response, err = summarizePayload(payloads[1], keep[1]); err != nil {
+				return nil, err
+			}
+		}
+		if data, err = c.Codec.Marshal(shrunk); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// summarizePayload returns p as an OversizedPayload keeping the first prefix bytes of its
+// JSON form (none when prefix < 0). Absent payloads stay nil.
+func summarizePayload(p any, prefix int) (any, error) {
+	s, err := marshalJSONValue(p)
+	if err != nil {
+		return nil, marshalFailed("payload", err)
+	}
+	if !s.Valid {
+		return nil, nil
+	}
+	sum := sha256.Sum256([]byte(s.String))
+	out := OversizedPayload{Truncated: true, Bytes: len(s.String), SHA256: hex.EncodeToString(sum[:])}
+	if prefix > 0 {
+		for prefix < len(s.String) && !utf8.RuneStart(s.String[prefix]) {
+			prefix--
+		}
+		out.Prefix = s.String[:min(prefix, len(s.String))]
+	}
+	return out, nil
+}
+
+// entrySizeBounds are the upper bounds, in bytes, of the EntrySizes buckets.
+var entrySizeBounds = [...]int{1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, PubSubMaxMessageBytes}
+
+var entrySizes struct {
+	buckets [len(entrySizeBounds)]atomic.Uint64
+	count   atomic.Uint64
+	sum     atomic.Uint64
+}
+
+func observeEntrySize(n int) {
+	for i, bound := range entrySizeBounds {
+		if n <= bound {
+			entrySizes.buckets[i].Add(1)
+			break
+		}
+	}
+	entrySizes.sum.Add(uint64(n))
+	entrySizes.count.Add(1)
+}
+
+// SizeHistogram is a snapshot of the encoded sizes of published entries.
+type SizeHistogram struct {
+	// Buckets maps an upper bound in bytes to the number of entries of at most that size.
+	// Counts are cumulative, as Prometheus histograms expect; entries above the largest
+	// bound are only in Count.
+	Buckets map[float64]uint64
+	Count   uint64
+	Sum     uint64 // total bytes
+}
+
+// EntrySizes returns the histogram of entry sizes marshaled by size-limited codecs (see
+// LimitSize) since process start. Export it as a metric, e.g. from a Prometheus collector:
+//
+//	h := audittrail.EntrySizes()
+//	ch <- prometheus.MustNewConstHistogram(desc, h.Count, float64(h.Sum), h.Buckets)
+func EntrySizes() SizeHistogram {
+	h := SizeHistogram{Buckets: make(map[float64]uint64, len(entrySizeBounds))}
+	var cumulative uint64
+	for i, bound := range entrySizeBounds {
+		cumulative += entrySizes.buckets[i].Load()
+		h.Buckets[float64(bound)] = cumulative
+	}
+	h.Count = entrySizes.count.Load()
+	h.Sum = entrySizes.sum.Load()
+	return h
+}
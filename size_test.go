@@ -0,0 +1,136 @@
+package audittrail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func oversizedEntry() Entry {
+	return Entry{
+		ID:       "big",
+		Action:   "POST /api/upload",
+		Request:  json.RawMessage(`{"blob":"` + strings.Repeat("é", 4000) + `"}`),
+		Response: map[string]any{"status": "ok"},
+	}
+}
+
+func payloadMap(t *testing.T, p any) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("marshal payload: %v", err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("decode payload %s: %v", data, err)
+	}
+	return m
+}
+
+func TestLimitSizeRejectsWithCallback(t *testing.T) {
+	var rejected string
+	var rejectedSize int
+	codec := LimitSize(JSONCodec, SizePolicy{MaxBytes: 2000, OnReject: func(e Entry, size int) { rejected, rejectedSize = e.ID, size }})
+
+	_, err := codec.Marshal(oversizedEntry())
+	if !errors.Is(err, ErrEntryTooLarge) {
+		t.Fatalf("expected ErrEntryTooLarge, got %v", err)
+	}
+	if rejected != "big" || rejectedSize <= 2000 {
+		t.Fatalf("expected reject callback, got %q %d", rejected, rejectedSize)
+	}
+
+	if _, err := codec.Marshal(Entry{ID: "small", Action: "GET /"}); err != nil {
+		t.Fatalf("small entry: %v", err)
+	}
+	if codec.Name() != "json" {
+		t.Fatalf("expected wrapped codec name, got %q", codec.Name())
+	}
+}
+
+func TestLimitSizeTruncatesPayloads(t *testing.T) {
+	codec := LimitSize(JSONCodec, SizePolicy{MaxBytes: 2000, Action: OversizeTruncate})
+	data, err := codec.Marshal(oversizedEntry())
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if len(data) > 2000 {
+		t.Fatalf("expected at most 2000 bytes, got %d", len(data))
+	}
+	entry, err := codec.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	req := payloadMap(t, entry.Request)
+	prefix, _ := req["prefix"].(string)
+	if req["truncated"] != true || !strings.HasPrefix(prefix, `{"blob":"éé`) {
+		t.Fatalf("expected truncated request, got %v", entry.Request)
+	}
+	if !utf8.ValidString(prefix) {
+		t.Fatalf("prefix cut inside a rune: %q", prefix)
+	}
+	if resp := payloadMap(t, entry.Response); resp["status"] != "ok" {
+		t.Fatalf("expected small response kept, got %v", entry.Response)
+	}
+}
+
+func TestLimitSizeSummarizesPayloads(t *testing.T) {
+	codec := LimitSize(ProtobufCodec, SizePolicy{MaxBytes: 2000, Action: OversizeSummarize})
+	data, err := codec.Marshal(oversizedEntry())
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	entry, err := codec.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	req := payloadMap(t, entry.Request)
+	if req["truncated"] != true || req["prefix"] != nil || len(req["sha256"].(string)) != 64 {
+		t.Fatalf("expected summarized request, got %v", entry.Request)
+	}
+	if resp := payloadMap(t, entry.Response); resp["truncated"] != true {
+		t.Fatalf("expected summarized response, got %v", entry.Response)
+	}
+}
+
+func TestEntrySizesCountsMarshaledEntries(t *testing.T) {
+	before := EntrySizes()
+	codec := LimitSize(JSONCodec, SizePolicy{})
+	if _, err := codec.Marshal(Entry{ID: "e1", Action: "GET /"}); err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if _, err := codec.Marshal(oversizedEntry()); err != nil {
+		t.Fatalf("unlimited codec rejected entry: %v", err)
+	}
+
+	after := EntrySizes()
+	if after.Count != before.Count+2 || after.Sum <= before.Sum+8000 {
+		t.Fatalf("unexpected count/sum: %+v -> %+v", before, after)
+	}
+	if after.Buckets[1024] != before.Buckets[1024]+1 || after.Buckets[16384] != before.Buckets[16384]+2 {
+		t.Fatalf("unexpected buckets: %v -> %v", before.Buckets, after.Buckets)
+	}
+}
+
+func TestGCPPublisherAppliesSizePolicy(t *testing.T) {
+	ctx := context.Background()
+	srv, client := newFakePubSub(t)
+	topic, err := client.CreateTopic(ctx, "audit")
+	if err != nil {
+		t.Fatalf("CreateTopic: %v", err)
+	}
+	defer topic.Stop()
+
+	pub := NewGCPPublisher(topic, WithSizePolicy(SizePolicy{MaxBytes: 2000, Action: OversizeSummarize}), WithCodec(ProtobufCodec))
+	if err := pub.Publish(ctx, oversizedEntry()); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	msgs := srv.Messages()
+	if len(msgs) != 1 || len(msgs[0].Data) > 2000 || msgs[0].Attributes[codecAttribute] != "protobuf" {
+		t.Fatalf("unexpected messages: %v", msgs)
+	}
+}
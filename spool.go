@@ -0,0 +1,369 @@
+package audittrail
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrSpoolFull is returned when appending would grow the spool beyond its size limit.
+var ErrSpoolFull = errors.New("audittrail: spool is full")
+
+var errSpoolClosed = errors.New("audittrail: spool is closed")
+
+// DefaultSpoolMaxBytes is the spool size limit used when NewFileSpool is given none.
+const DefaultSpoolMaxBytes = 256 << 20
+
+const (
+	// minSpoolRetryInterval and maxSpoolRetryInterval bound the backoff between background
+	// drains of a SpoolingPublisher's spool while the broker is down.
+	minSpoolRetryInterval = time.Second
+	maxSpoolRetryInterval = time.Minute
+)
+
+// FileSpool is an append-only, fsync'd JSONL file holding entries that could not be published.
+// It survives process restarts: entries left in the file are replayed by the next Drain.
+type FileSpool struct {
+	drainMu sync.Mutex // serializes Drain; mu is only held to append and rewrite
+
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	size     int64
+	pending  int
+	maxBytes int64
+	closed   bool
+}
+
+// NewFileSpool opens (or creates) a spool file. maxBytes bounds the file size; 0 or less
+// means DefaultSpoolMaxBytes. Entries left by a previous run are counted, not loaded.
+func NewFileSpool(path string, maxBytes int64) (*FileSpool, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultSpoolMaxBytes
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("audittrail: create spool directory failed: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("audittrail: open spool failed: %w", err)
+	}
+	size, pending, err := countLines(path)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("audittrail: read spool failed: %w", err)
+	}
+	return &FileSpool{
+		path:     path,
+		file:     f,
+		size:     size,
+		pending:  pending,
+		maxBytes: maxBytes,
+	}, nil
+}
+
+// countLines returns the size of the file at path and the number of lines in it, reading
+// it in chunks.
+func countLines(path string) (int64, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	var size int64
+	lines := 0
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := f.Read(buf)
+		size += int64(n)
+		lines += bytes.Count(buf[:n], []byte{'\n'})
+		if err == io.EOF {
+			return size, lines, nil
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+}
+
+// Append durably stores an entry at the end of the spool.
+func (s *FileSpool) Append(entry Entry) error {
+	data, err := JSONCodec.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("audittrail: marshal spooled entry failed: %w", err)
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errSpoolClosed
+	}
+	if s.size+int64(len(data)) > s.maxBytes {
+		return ErrSpoolFull
+	}
+	if _, err := s.file.Write(data); err != nil {
+		return fmt.Errorf("audittrail: write spool failed: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("audittrail: sync spool failed: %w", err)
+	}
+	s.size += int64(len(data))
+	s.pending++
+	return nil
+}
+
+// Len reports how many entries are waiting in the spool.
+func (s *FileSpool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending
+}
+
+// Drain replays spooled entries in order through fn, reading the file one line at a time.
+// It stops at the first error and keeps that entry and everything after it for the next
+// attempt. Appends are not blocked while fn runs; entries appended during a Drain are left
+// for the next one.
+func (s *FileSpool) Drain(ctx context.Context, fn func(context.Context, Entry) error) error {
+	s.drainMu.Lock()
+	defer s.drainMu.Unlock()
+	s.mu.Lock()
+	pending, size, closed := s.pending, s.size, s.closed
+	s.mu.Unlock()
+	if closed {
+		return errSpoolClosed
+	}
+	if pending == 0 {
+		return nil
+	}
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		return fmt.Errorf("audittrail: read spool failed: %w", err)
+	}
+	defer f.Close()
+
+	var drainErr error
+	var consumed int64
+	lines := 0
+	// Only whole lines appended before the Drain started are read.
+	r := bufio.NewReader(io.LimitReader(f, size))
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			drainErr = fmt.Errorf("audittrail: read spool failed: %w", err)
+			break
+		}
+		if len(line) == 0 {
+			break
+		}
+		entry, uerr := JSONCodec.Unmarshal(bytes.TrimSuffix(line, []byte{'\n'}))
+		if uerr != nil {
+			// A torn write from a crash; skip it rather than blocking the spool forever.
+			logger().Warn("audittrail: dropping corrupt spool line", "error", uerr)
+		} else if err := fn(ctx, entry); err != nil {
+			drainErr = err
+			break
+		}
+		consumed += int64(len(line))
+		if line[len(line)-1] == '\n' {
+			lines++
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if consumed == 0 {
+		return drainErr
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errSpoolClosed
+	}
+	if err := s.rewrite(f, consumed, lines); err != nil {
+		return err
+	}
+	return drainErr
+}
+
+// rewrite atomically replaces the spool contents with those of src after offset, which
+// held lines entries. s.mu must be held, so no append is lost. The new file is synced before the rename and the directory after it,
+// so a crash leaves either the old or the new contents, never an empty or truncated spool.
+func (s *FileSpool) rewrite(src *os.File, offset int64, lines int) error {
+	if _, err := src.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("audittrail: rewrite spool failed: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := writeFileSync(tmp, src); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("audittrail: rewrite spool failed: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("audittrail: rewrite spool failed: %w", err)
+	}
+	if err := syncDir(filepath.Dir(s.path)); err != nil {
+		return fmt.Errorf("audittrail: sync spool directory failed: %w", err)
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("audittrail: reopen spool failed: %w", err)
+	}
+	_ = s.file.Close()
+	s.file = f
+	s.size -= offset
+	s.pending = max(s.pending-lines, 0)
+	return nil
+}
+
+// writeFileSync copies r to a new file at path and syncs it to disk.
+func writeFileSync(path string, r io.Reader) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir syncs a directory, making a rename within it durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Close closes the spool file. Pending entries remain on disk.
+func (s *FileSpool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return s.file.Close()
+}
+
+// SpoolingPublisher wraps a Publisher and diverts entries to a FileSpool while the broker is
+// unreachable. While the spool holds entries, new entries are appended behind them and the
+// spool is drained in the background, retrying with backoff, so broker downtime does not
+// lose or reorder audit data and callers do not wait for the broker.
+type SpoolingPublisher struct {
+	publisher Publisher
+	spool     *FileSpool
+
+	mu       sync.Mutex
+	draining bool // a background drain is running
+}
+
+// NewSpoolingPublisher creates a publisher that falls back to spool on failure.
+func NewSpoolingPublisher(publisher Publisher, spool *FileSpool) (*SpoolingPublisher, error) {
+	if publisher == nil {
+		return nil, errors.New("audittrail: publisher must not be nil")
+	}
+	if spool == nil {
+		return nil, errors.New("audittrail: spool must not be nil")
+	}
+	return &SpoolingPublisher{publisher: publisher, spool: spool}, nil
+}
+
+// Publish sends the entry, or spools it if the broker rejects it or earlier entries are still
+// spooled, and starts a background drain. An error is returned only when the entry could be
+// neither published nor spooled (e.g. ErrSpoolFull).
+func (p *SpoolingPublisher) Publish(ctx context.Context, entry Entry) error {
+	p.mu.Lock()
+	spooled := p.draining || p.spool.Len() > 0
+	p.mu.Unlock()
+
+	if !spooled {
+		err := p.publisher.Publish(ctx, entry)
+		if err == nil {
+			return nil
+		}
+		logger().Warn("audittrail: publish failed, spooling entry", "entry_id", entry.ID, "error", err)
+	}
+	if err := p.spool.Append(entry); err != nil {
+		reportDrop(entry, err)
+		return err
+	}
+	p.startDrain(ctx)
+	return nil
+}
+
+// startDrain starts a background drain unless one is running.
+func (p *SpoolingPublisher) startDrain(ctx context.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.draining {
+		return
+	}
+	p.draining = true
+	go p.drain(context.WithoutCancel(ctx))
+}
+
+// drain replays the spool until it is empty, backing off between failed attempts. It stops
+// early if the spool is closed.
+func (p *SpoolingPublisher) drain(ctx context.Context) {
+	interval := minSpoolRetryInterval
+	for {
+		err := p.spool.Drain(ctx, p.publisher.Publish)
+		p.mu.Lock()
+		if errors.Is(err, errSpoolClosed) || err == nil && p.spool.Len() == 0 {
+			p.draining = false
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+		if err == nil {
+			interval = minSpoolRetryInterval
+			continue
+		}
+		logger().Warn("audittrail: spool drain failed", "pending", p.spool.Len(), "retry_in", interval, "error", err)
+		time.Sleep(interval)
+		interval = min(interval*2, maxSpoolRetryInterval)
+	}
+}
+
+// Flush replays spooled entries now.
+func (p *SpoolingPublisher) Flush(ctx context.Context) error {
+	return p.spool.Drain(ctx, p.publisher.Publish)
+}
+
+// Pending reports how many entries are waiting to be replayed.
+func (p *SpoolingPublisher) Pending() int {
+	return p.spool.Len()
+}
+
+// Run periodically replays the spool until ctx is canceled, so entries left by a previous
+// process are delivered even when no new Publish call starts a background drain.
+func (p *SpoolingPublisher) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if p.Pending() > 0 {
+				_ = p.Flush(ctx)
+			}
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// recordingBroker is a Publisher that fails while down and records what it delivers.
+type recordingBroker struct {
+	down      atomic.Bool
+	mu        sync.Mutex
+	delivered []string
+}
+
+func (b *recordingBroker) Publish(_ context.Context, entry Entry) error {
+	if b.down.Load() {
+		return errors.New("broker unreachable")
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.delivered = append(b.delivered, entry.ID)
+	return nil
+}
+
+func (b *recordingBroker) ids() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.Join(b.delivered, ",")
+}
+
+// waitDrained waits for the background drain to empty the spool.
+func waitDrained(t *testing.T, pub *SpoolingPublisher) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for pub.Pending() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("spool not drained, %d entries pending", pub.Pending())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSpoolingPublisherReplaysInOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.spool")
+	spool, err := NewFileSpool(path, 0)
+	if err != nil {
+		t.Fatalf("NewFileSpool: %v", err)
+	}
+
+	broker := &recordingBroker{}
+	broker.down.Store(true)
+	pub, err := NewSpoolingPublisher(broker, spool)
+	if err != nil {
+		t.Fatalf("NewSpoolingPublisher: %v", err)
+	}
+
+	ctx := context.Background()
+	for _, id := range []string{"e1", "e2"} {
+		if err := pub.Publish(ctx, Entry{ID: id, Action: "a"}); err != nil {
+			t.Fatalf("Publish %s: %v", id, err)
+		}
+	}
+	if pub.Pending() != 2 || broker.ids() != "" {
+		t.Fatalf("expected 2 spooled entries, got pending=%d delivered=%v", pub.Pending(), broker.ids())
+	}
+
+	// Entries survive a restart.
+	_ = spool.Close()
+	spool, err = NewFileSpool(path, 0)
+	if err != nil {
+		t.Fatalf("reopen spool: %v", err)
+	}
+	defer spool.Close()
+	pub, _ = NewSpoolingPublisher(broker, spool)
+
+	broker.down.Store(false)
+	if err := pub.Publish(ctx, Entry{ID: "e3", Action: "a"}); err != nil {
+		t.Fatalf("Publish e3: %v", err)
+	}
+	waitDrained(t, pub)
+	if got := broker.ids(); got != "e1,e2,e3" {
+		t.Fatalf("unexpected replay order: %v", got)
+	}
+}
+
+func TestSpoolingPublisherDoesNotWaitForDrain(t *testing.T) {
+	spool, err := NewFileSpool(filepath.Join(t.TempDir(), "audit.spool"), 0)
+	if err != nil {
+		t.Fatalf("NewFileSpool: %v", err)
+	}
+	defer spool.Close()
+	var calls atomic.Int32
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var delivered []string
+	pub, _ := NewSpoolingPublisher(PublisherFunc(func(_ context.Context, e Entry) error {
+		if calls.Add(1) == 1 {
+			return errors.New("broker unreachable")
+		}
+		<-release // the broker hangs while the spool drains
+		mu.Lock()
+		defer mu.Unlock()
+		delivered = append(delivered, e.ID)
+		return nil
+	}), spool)
+
+	ctx := context.Background()
+	if err := pub.Publish(ctx, Entry{ID: "e1", Action: "a"}); err != nil {
+		t.Fatalf("Publish e1: %v", err)
+	}
+	done := make(chan error)
+	go func() {
+		for _, id := range []string{"e2", "e3"} {
+			if err := pub.Publish(ctx, Entry{ID: id, Action: "a"}); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish waited for the drain")
+	}
+	close(release)
+	waitDrained(t, pub)
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(delivered, ",") != "e1,e2,e3" {
+		t.Fatalf("unexpected delivery order: %v", delivered)
+	}
+}
+
+func TestFileSpoolRespectsSizeLimit(t *testing.T) {
+	spool, err := NewFileSpool(filepath.Join(t.TempDir(), "audit.spool"), 64)
+	if err != nil {
+		t.Fatalf("NewFileSpool: %v", err)
+	}
+	defer spool.Close()
+	if err := spool.Append(Entry{ID: "a-very-long-identifier-for-the-spool-limit", Action: "action"}); !errors.Is(err, ErrSpoolFull) {
+		t.Fatalf("expected ErrSpoolFull, got %v", err)
+	}
+}
+
+func TestFileSpoolDrainKeepsUndeliveredEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.spool")
+	spool, err := NewFileSpool(path, 0)
+	if err != nil {
+		t.Fatalf("NewFileSpool: %v", err)
+	}
+	if spool.maxBytes != DefaultSpoolMaxBytes {
+		t.Fatalf("expected the default size limit, got %d", spool.maxBytes)
+	}
+	for _, id := range []string{"e1", "e2", "e3"} {
+		if err := spool.Append(Entry{ID: id, Action: "a"}); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	var delivered []string
+	deliver := func(_ context.Context, e Entry) error {
+		if e.ID == "e2" {
+			return errors.New("broker unreachable")
+		}
+		delivered = append(delivered, e.ID)
+		return nil
+	}
+	if err := spool.Drain(context.Background(), deliver); err == nil || spool.Len() != 2 {
+		t.Fatalf("expected e2 and e3 kept, err=%v pending=%d", err, spool.Len())
+	}
+	_ = spool.Close()
+
+	// A torn write left by a crash is dropped on the next drain.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_, _ = f.WriteString(`{"id":"e4","act`)
+	_ = f.Close()
+
+	spool, err = NewFileSpool(path, 0)
+	if err != nil {
+		t.Fatalf("reopen spool: %v", err)
+	}
+	defer spool.Close()
+	if spool.Len() != 2 {
+		t.Fatalf("expected 2 entries after reopening, got %d", spool.Len())
+	}
+	err = spool.Drain(context.Background(), func(_ context.Context, e Entry) error {
+		delivered = append(delivered, e.ID)
+		return nil
+	})
+	if err != nil || spool.Len() != 0 || spool.size != 0 || strings.Join(delivered, ",") != "e1,e2,e3" {
+		t.Fatalf("unexpected drain: err=%v pending=%d size=%d delivered=%v", err, spool.Len(), spool.size, delivered)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() != 0 {
+		t.Fatalf("expected an empty spool file, got %v, %v", info, err)
+	}
+}
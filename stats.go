@@ -0,0 +1,123 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// StatsGroup selects the dimension Stats groups counts by.
+type StatsGroup string
+
+const (
+	StatsByAction   StatsGroup = "action"
+	StatsByActor    StatsGroup = "actor"
+	StatsByEndpoint StatsGroup = "endpoint"
+	StatsByService  StatsGroup = "service"
+	StatsByDay      StatsGroup = "day"
+)
+
+// StatsQuery describes an aggregation over audit entries.
+type StatsQuery struct {
+	Filter  Filter     // restricts which entries are counted (e.g. From/To for a time range)
+	GroupBy StatsGroup // default: StatsByAction
+	Limit   int        // max rows returned; 0 means no limit
+}
+
+// StatsRow is a single group and its entry count.
+// For StatsByDay, Key is formatted as YYYY-MM-DD.
+type StatsRow struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+// Stats returns entry counts grouped by the requested dimension.
+// Groups are ordered by count (highest first), except StatsByDay which is chronological.
+func (r *AuditTrail) Stats(ctx context.Context, q StatsQuery) ([]StatsRow, error) {
+	if r == nil || r.db == nil {
+		return nil, errors.New("audittrail: instance is not initialized")
+	}
+
+	groupBy := q.GroupBy
+	if groupBy == "" {
+		groupBy = StatsByAction
+	}
+	expr, err := r.statsExpr(groupBy)
+	if err != nil {
+		return nil, err
+	}
+	ctx = r.auditRead(ctx, "stats", q.Filter, map[string]any{"group_by": string(groupBy)})
+
+	b := &queryBuilder{placeholder: r.placeholder, dialect: r.dialect}
+	order := "n DESC, k"
+	if groupBy == StatsByDay {
+		order = "k"
+	}
+	query := fmt.Sprintf("SELECT %s AS k, COUNT(*) AS n FROM %s%s GROUP BY %s ORDER BY %s",
+		expr, r.tableRef, b.where(q.Filter), expr, order)
+	if q.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", q.Limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []StatsRow
+	for rows.Next() {
+		var key any
+		var row StatsRow
+		if err := rows.Scan(&key, &row.Count); err != nil {
+			return nil, err
+		}
+		row.Key = statsKey(key, groupBy)
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+func (r *AuditTrail) statsExpr(group StatsGroup) (string, error) {
+	switch group {
+	case StatsByAction:
+		return "log_action", nil
+	case StatsByActor:
+		return "COALESCE(log_actor, log_created_by)", nil
+	case StatsByEndpoint:
+		return "log_endpoint", nil
+	case StatsByService:
+		return "log_service_name", nil
+	case StatsByDay:
+		if r.placeholder == PlaceholderDollar {
+			return "CAST(log_created_date AS DATE)", nil
+		}
+		return "DATE(log_created_date)", nil
+	default:
+		return "", fmt.Errorf("audittrail: unsupported stats group: %s", group)
+	}
+}
+
+func statsKey(v any, group StatsGroup) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return val.UTC().Format("2006-01-02")
+	case []byte:
+		return truncateDay(string(val), group)
+	case string:
+		return truncateDay(val, group)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// truncateDay trims driver-specific time suffixes from textual dates.
+func truncateDay(s string, group StatsGroup) string {
+	if group == StatsByDay && len(s) > 10 {
+		return s[:10]
+	}
+	return s
+}
@@ -0,0 +1,63 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatsGroupsByDay(t *testing.T) {
+	var gotQuery string
+	var gotArgs []driver.NamedValue
+	db := openStubDB(t, &stubDriver{
+		queryFn: func(query string, args []driver.NamedValue) (driver.Rows, error) {
+			gotQuery, gotArgs = query, args
+			return &stubRows{
+				columns: []string{"k", "n"},
+				values: [][]driver.Value{
+					{time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), int64(3)},
+					{"2024-06-02 00:00:00", int64(5)},
+				},
+			}, nil
+		},
+	})
+
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+
+	from := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	rows, err := audit.Stats(context.Background(), StatsQuery{
+		Filter:  Filter{Actions: []string{"DELETE_ORDER"}, From: from, To: from.AddDate(0, 0, 7)},
+		GroupBy: StatsByDay,
+	})
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+
+	if !strings.Contains(gotQuery, "CAST(log_created_date AS DATE)") ||
+		!strings.Contains(gotQuery, "log_action IN ($1)") ||
+		!strings.Contains(gotQuery, "log_created_date < $3") {
+		t.Fatalf("unexpected query: %s", gotQuery)
+	}
+	if len(gotArgs) != 3 {
+		t.Fatalf("expected 3 args, got %d", len(gotArgs))
+	}
+	if len(rows) != 2 || rows[0].Key != "2024-06-01" || rows[1].Key != "2024-06-02" || rows[1].Count != 5 {
+		t.Fatalf("unexpected rows: %+v", rows)
+	}
+}
+
+func TestStatsRejectsUnknownGroup(t *testing.T) {
+	db := openStubDB(t, &stubDriver{})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderQuestion})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	if _, err := audit.Stats(context.Background(), StatsQuery{GroupBy: "tenant"}); err == nil {
+		t.Fatal("expected error for unsupported group")
+	}
+}
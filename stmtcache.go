@@ -0,0 +1,49 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql"
+)
+
+// execInsert runs an INSERT, through a cached prepared statement when
+// Config.PrepareStatements is set. Statements are keyed by query text, which already
+// encodes the placeholder style and duplicate handling. If the driver cannot prepare,
+// the query is executed directly.
+func (r *AuditTrail) execInsert(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if !r.prepare {
+		return r.db.ExecContext(ctx, query, args...)
+	}
+	stmt, err := r.stmt(ctx, query)
+	if err != nil {
+		logger().Warn("audittrail: prepare insert failed, executing directly", "error", err)
+		return r.db.ExecContext(ctx, query, args...)
+	}
+	return stmt.ExecContext(ctx, args...)
+}
+
+func (r *AuditTrail) stmt(ctx context.Context, query string) (*sql.Stmt, error) {
+	r.stmtMu.Lock()
+	defer r.stmtMu.Unlock()
+	if stmt, ok := r.stmts[query]; ok {
+		return stmt, nil
+	}
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if r.stmts == nil {
+		r.stmts = make(map[string]*sql.Stmt)
+	}
+	r.stmts[query] = stmt
+	return stmt, nil
+}
+
+// closeStmts releases the cached prepared statements.
+func (r *AuditTrail) closeStmts() {
+	r.stmtMu.Lock()
+	defer r.stmtMu.Unlock()
+	for query, stmt := range r.stmts {
+		_ = stmt.Close()
+		delete(r.stmts, query)
+	}
+}
@@ -0,0 +1,89 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// prepDriver supports Prepare and counts prepared and executed statements.
+type prepDriver struct {
+	prepared, executed, closed atomic.Int32
+}
+
+func (d *prepDriver) Open(string) (driver.Conn, error) { return &prepConn{d: d}, nil }
+
+type prepConn struct{ d *prepDriver }
+
+func (c *prepConn) Prepare(string) (driver.Stmt, error) {
+	c.d.prepared.Add(1)
+	return &prepStmt{d: c.d}, nil
+}
+func (c *prepConn) Close() error              { return nil }
+func (c *prepConn) Begin() (driver.Tx, error) { return stubTx{}, nil }
+
+type prepStmt struct{ d *prepDriver }
+
+func (s *prepStmt) Close() error  { s.d.closed.Add(1); return nil }
+func (s *prepStmt) NumInput() int { return -1 }
+func (s *prepStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.d.executed.Add(1)
+	return stubResult{}, nil
+}
+func (s *prepStmt) Query([]driver.Value) (driver.Rows, error) { return &stubRows{}, nil }
+
+func TestPrepareStatementsCachesInsert(t *testing.T) {
+	d := &prepDriver{}
+	name := fmt.Sprintf("audittrail_prep_%d", time.Now().UnixNano())
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	db.SetMaxOpenConns(1)
+
+	audit, err := NewAuditTrail(Config{DB: db, PrepareStatements: true})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := audit.Record(context.Background(), Entry{Action: "A"}); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	if d.prepared.Load() != 1 || d.executed.Load() != 5 {
+		t.Fatalf("prepared %d, executed %d", d.prepared.Load(), d.executed.Load())
+	}
+	if err := audit.Close(); err != nil || d.closed.Load() != 1 {
+		t.Fatalf("Close: %v, closed %d", err, d.closed.Load())
+	}
+}
+
+func TestPrepareStatementsFallsBackToExec(t *testing.T) {
+	var calls int
+	db := openStubDB(t, &stubDriver{execFn: func(string, []driver.NamedValue) (driver.Result, error) {
+		calls++
+		return stubResult{}, nil
+	}})
+	audit, err := NewAuditTrail(Config{DB: db, PrepareStatements: true})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	if err := audit.Record(context.Background(), Entry{Action: "A"}); err != nil || calls != 1 {
+		t.Fatalf("Record: %v, calls %d", err, calls)
+	}
+}
+
+func TestConfigurePool(t *testing.T) {
+	t.Setenv(envDBMaxOpenConns, "7")
+	db := openStubDB(t, &stubDriver{})
+	configurePool(db, &InitOptions{MaxIdleConns: 2, ConnMaxLifetime: time.Minute})
+	if got := db.Stats().MaxOpenConnections; got != 7 {
+		t.Fatalf("MaxOpenConnections = %d", got)
+	}
+}
@@ -0,0 +1,72 @@
+package audittrail
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+)
+
+// StreamSummary is recorded as the response payload of upgraded connections (websockets)
+// and streamed responses (server-sent events, flushed chunks) instead of their body, which
+// the middlewares neither buffer nor wait for.
+type StreamSummary struct {
+	Upgrade     string `json:"upgrade,omitempty"` // protocol of a hijacked connection, e.g. "websocket"
+	ContentType string `json:"content_type,omitempty"`
+	Bytes       int64  `json:"bytes"`             // body bytes written through the middleware
+	Flushes     int    `json:"flushes,omitempty"` // number of Flush calls
+}
+
+// streamState tracks what a response writer wrapper passed through.
+type streamState struct {
+	hijacked bool
+	flushes  int
+	written  int64
+}
+
+// streaming reports whether the response is streamed rather than a single body.
+func (s *streamState) streaming(h http.Header) bool {
+	return s.hijacked || s.flushes > 0 || isEventStream(h)
+}
+
+func (s *streamState) summary(r *http.Request, h http.Header) StreamSummary {
+	summary := StreamSummary{ContentType: h.Get("Content-Type"), Bytes: s.written, Flushes: s.flushes}
+	if s.hijacked {
+		summary.Upgrade = r.Header.Get("Upgrade")
+		if summary.Upgrade == "" {
+			summary.Upgrade = "hijacked"
+		}
+	}
+	return summary
+}
+
+func isEventStream(h http.Header) bool {
+	mediaType, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	return mediaType == "text/event-stream"
+}
+
+// flush flushes w if it supports it.
+func flush(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// hijack takes over the connection of w if it supports it.
+func hijack(w http.ResponseWriter) (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("audittrail: response writer does not support hijacking")
+	}
+	return h.Hijack()
+}
+
+// readFrom copies src to w, using w's own ReadFrom (e.g. sendfile) when it has one.
+func readFrom(w io.Writer, src io.Reader) (int64, error) {
+	if rf, ok := w.(io.ReaderFrom); ok {
+		return rf.ReadFrom(src)
+	}
+	return io.Copy(struct{ io.Writer }{w}, src)
+}
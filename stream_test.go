@@ -0,0 +1,124 @@
+package audittrail
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHTTPMiddlewareStreaming(t *testing.T) {
+	var got Entry
+	rec := RecorderFunc(func(_ context.Context, e Entry) error {
+		got = e
+		return nil
+	})
+	handler := HTTPMiddleware(rec, WithResponsePayload(func(status int) any { return status }))(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(w, "data: %d\n\n", i)
+			w.(http.Flusher).Flush()
+		}
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if !w.Flushed || w.Body.String() != "data: 0\n\ndata: 1\n\ndata: 2\n\n" {
+		t.Fatalf("flushes were not passed through: %v %q", w.Flushed, w.Body.String())
+	}
+	want := StreamSummary{ContentType: "text/event-stream", Bytes: 27, Flushes: 3}
+	if got.Response != want {
+		t.Fatalf("unexpected summary: %#v", got.Response)
+	}
+}
+
+// upgrade hijacks the connection the way a websocket library does.
+func upgrade(t *testing.T, w http.ResponseWriter) {
+	conn, rw, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		t.Errorf("Hijack: %v", err)
+		return
+	}
+	defer conn.Close()
+	_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\nhello")
+	_ = rw.Flush()
+}
+
+func dialUpgrade(t *testing.T, url string) string {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "GET /ws HTTP/1.1\r\nHost: example\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("ReadResponse: %v", err)
+	}
+	return resp.Status
+}
+
+func TestHTTPMiddlewareHijack(t *testing.T) {
+	entries := make(chan Entry, 1)
+	rec := RecorderFunc(func(_ context.Context, e Entry) error {
+		entries <- e
+		return nil
+	})
+	srv := httptest.NewServer(HTTPMiddleware(rec)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		upgrade(t, w)
+	})))
+	defer srv.Close()
+
+	if status := dialUpgrade(t, srv.URL); status != "101 Switching Protocols" {
+		t.Fatalf("unexpected status %q", status)
+	}
+	e := <-entries
+	if e.Response != (StreamSummary{Upgrade: "websocket"}) {
+		t.Fatalf("unexpected entry: %#v", e.Response)
+	}
+}
+
+func TestGinMiddlewareStreaming(t *testing.T) {
+	entries := make(chan Entry, 2)
+	useGlobalRecorder(t, RecorderFunc(func(_ context.Context, e Entry) error {
+		entries <- e
+		return nil
+	}))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinMiddleware(WithCaptureResponseBody(true)))
+	r.GET("/events", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		for i := 0; i < 2; i++ {
+			c.SSEvent("tick", i)
+			c.Writer.Flush()
+		}
+	})
+	r.GET("/ws", func(c *gin.Context) { upgrade(t, c.Writer) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+	e := <-entries
+	s, ok := e.Response.(StreamSummary)
+	if !w.Flushed || !ok || s.Flushes != 2 || s.Bytes != int64(w.Body.Len()) {
+		t.Fatalf("unexpected summary: %v %#v", w.Flushed, e.Response)
+	}
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	if status := dialUpgrade(t, srv.URL); status != "101 Switching Protocols" {
+		t.Fatalf("unexpected status %q", status)
+	}
+	e = <-entries
+	if e.Response != (StreamSummary{Upgrade: "websocket"}) {
+		t.Fatalf("unexpected entry: %#v", e.Response)
+	}
+}
@@ -0,0 +1,167 @@
+package audittrail
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	auditTagCache     sync.Map // reflect.Type -> bool
+)
+
+// applyAuditTags returns v marshaled as json.RawMessage with the fields tagged audit:"omit"
+// left out and those tagged audit:"mask" replaced by RedactedValue:
+//
+//	type Signup struct {
+//		Email    string `json:"email"`
+//		Password string `json:"password" audit:"omit"`
+//		Phone    string `json:"phone" audit:"mask"`
+//	}
+//
+// Tags are found through nested structs, pointers, slices and maps, but not through
+// interface-typed fields, and types with their own MarshalJSON are stored as they marshal.
+// Payloads without tagged fields are returned unchanged.
+func applyAuditTags(v any) (any, error) {
+	if v == nil || !hasAuditTags(reflect.TypeOf(v)) {
+		return v, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoded, ok := decodeJSONPayload(json.RawMessage(data))
+	if !ok {
+		return json.RawMessage(data), nil
+	}
+	return encodeJSONPayload(tagRedact(reflect.ValueOf(v), decoded), json.RawMessage(data)), nil
+}
+
+// hasAuditTags reports whether values of t can contain a field with an audit tag.
+func hasAuditTags(t reflect.Type) bool {
+	if cached, ok := auditTagCache.Load(t); ok {
+		return cached.(bool)
+	}
+	found := findAuditTags(t, map[reflect.Type]bool{})
+	auditTagCache.Store(t, found)
+	return found
+}
+
+// findAuditTags walks t; visiting breaks cycles in recursive types.
+func findAuditTags(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if visiting[t] || t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+		return false
+	}
+	visiting[t] = true
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return findAuditTags(t.Elem(), visiting)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if (f.IsExported() || f.Anonymous) && (f.Tag.Get("audit") != "" || findAuditTags(f.Type, visiting)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tagRedact applies the audit tags of rv's type to decoded, the JSON form of rv.
+func tagRedact(rv reflect.Value, decoded any) any {
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return decoded
+		}
+		rv = rv.Elem()
+	}
+	if !hasAuditTags(rv.Type()) {
+		return decoded
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		if m, ok := decoded.(map[string]any); ok {
+			tagRedactStruct(rv, m)
+		}
+	case reflect.Slice, reflect.Array:
+		if arr, ok := decoded.([]any); ok {
+			for i := 0; i < rv.Len() && i < len(arr); i++ {
+				arr[i] = tagRedact(rv.Index(i), arr[i])
+			}
+		}
+	case reflect.Map:
+		if m, ok := decoded.(map[string]any); ok {
+			iter := rv.MapRange()
+			for iter.Next() {
+				key := mapKeyString(iter.Key())
+				if child, ok := m[key]; ok {
+					m[key] = tagRedact(iter.Value(), child)
+				}
+			}
+		}
+	}
+	return decoded
+}
+
+// tagRedactStruct handles the fields of a struct, including those promoted from embedded
+// structs, named as encoding/json names them.
+func tagRedactStruct(rv reflect.Value, m map[string]any) {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		jsonTag := f.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(jsonTag, ",")
+		fv := rv.Field(i)
+		if f.Anonymous && name == "" {
+			for fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					break
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				tagRedactStruct(fv, m)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		switch f.Tag.Get("audit") {
+		case "omit":
+			delete(m, name)
+		case "mask":
+			if child, ok := m[name]; ok && child != nil {
+				m[name] = RedactedValue
+			}
+		default:
+			if child, ok := m[name]; ok {
+				m[name] = tagRedact(fv, child)
+			}
+		}
+	}
+}
+
+// mapKeyString formats a map key as encoding/json does.
+func mapKeyString(k reflect.Value) string {
+	if k.Kind() == reflect.String {
+		return k.String()
+	}
+	if k.Type().Implements(textMarshalerType) {
+		if text, err := k.Interface().(encoding.TextMarshaler).MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+	return fmt.Sprint(k.Interface())
+}
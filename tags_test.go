@@ -0,0 +1,74 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+)
+
+type taggedAddress struct {
+	Street string `json:"street" audit:"mask"`
+	City   string `json:"city"`
+}
+
+type taggedBase struct {
+	Token string `json:"token" audit:"omit"`
+}
+
+type taggedSignup struct {
+	taggedBase
+	Email     string                   `json:"email"`
+	Password  string                   `json:"password" audit:"omit"`
+	Phone     string                   `json:"phone,omitempty" audit:"mask"`
+	Address   *taggedAddress           `json:"address"`
+	Previous  []taggedAddress          `json:"previous"`
+	ByLabel   map[string]taggedAddress `json:"by_label"`
+	Nickname  string                   // no json tag
+	Reference *taggedSignup            `json:"reference,omitempty"`
+}
+
+func TestApplyAuditTags(t *testing.T) {
+	in := taggedSignup{
+		taggedBase: taggedBase{Token: "t"},
+		Email:      "a@b.c",
+		Password:   "hunter2",
+		Address:    &taggedAddress{Street: "Main 1", City: "Jakarta"},
+		Previous:   []taggedAddress{{Street: "Old 2", City: "Bandung"}},
+		ByLabel:    map[string]taggedAddress{"home": {Street: "Home 3", City: "Bogor"}},
+		Nickname:   "ab",
+	}
+	got, err := applyAuditTags(&in)
+	if err != nil {
+		t.Fatalf("applyAuditTags: %v", err)
+	}
+	want := `{"Nickname":"ab","address":{"city":"Jakarta","street":"[REDACTED]"},"by_label":{"home":{"city":"Bogor","street":"[REDACTED]"}},"email":"a@b.c","previous":[{"city":"Bandung","street":"[REDACTED]"}]}`
+	if string(got.(json.RawMessage)) != want {
+		t.Fatalf("unexpected payload:\n got %s\nwant %s", got, want)
+	}
+
+	plain := map[string]any{"password": "x"}
+	if got, _ := applyAuditTags(plain); got.(map[string]any)["password"] != "x" {
+		t.Fatalf("untagged payloads must be left alone: %v", got)
+	}
+}
+
+func TestRecordHonorsAuditTags(t *testing.T) {
+	var stored string
+	db := openStubDB(t, &stubDriver{
+		execFn: func(_ string, args []driver.NamedValue) (driver.Result, error) {
+			stored = stringArg(args, 4)
+			return stubResult{}, nil
+		},
+	})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar}, WithSensitiveDetection(SensitiveOff))
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	if err := audit.Record(context.Background(), Entry{Action: "SIGNUP", Request: taggedSignup{Email: "a@b.c", Password: "p", Phone: "555"}}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if stored != `{"Nickname":"","address":null,"by_label":null,"email":"a@b.c","phone":"[REDACTED]","previous":null}` {
+		t.Fatalf("unexpected stored payload: %s", stored)
+	}
+}
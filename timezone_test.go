@@ -0,0 +1,102 @@
+package audittrail
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimestampsRoundTripInUTC(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	created := time.Date(2024, 5, 1, 19, 30, 0, 123456000, jakarta)
+	var inserted []driver.NamedValue
+	db := openStubDB(t, &stubDriver{
+		execFn: func(_ string, args []driver.NamedValue) (driver.Result, error) {
+			inserted = args
+			return stubResult{}, nil
+		},
+		queryFn: func(string, []driver.NamedValue) (driver.Rows, error) {
+			// Read back the stored row as a driver configured with another zone returns it.
+			row := make([]driver.Value, insertColumnCount-1)
+			for i, arg := range inserted[:len(row)] {
+				if ts, ok := arg.Value.(time.Time); ok {
+					arg.Value = ts.In(time.FixedZone("EST", -5*60*60))
+				}
+				row[i] = arg.Value
+			}
+			return &stubRows{columns: strings.Split(EntryColumns, ", "), values: [][]driver.Value{row}}, nil
+		},
+	})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	ctx := context.Background()
+	if err := audit.Record(ctx, Entry{ID: "e1", Action: "PAY", CreatedDate: created, ExpiresAt: created.Add(time.Hour)}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	for _, i := range []int{6, 8} {
+		ts, ok := inserted[i].Value.(time.Time)
+		if !ok || ts.Location() != time.UTC {
+			t.Fatalf("expected argument %d in UTC, got %#v", i, inserted[i].Value)
+		}
+	}
+	if ts := inserted[6].Value.(time.Time); !ts.Equal(created) || ts.Hour() != 12 {
+		t.Fatalf("expected the same instant as UTC wall clock, got %v", ts)
+	}
+
+	entries, err := audit.queryEntries(ctx, "")
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("queryEntries: %v, %d entries", err, len(entries))
+	}
+	got := entries[0]
+	if got.CreatedDate.Location() != time.UTC || !got.CreatedDate.Equal(created) || !got.ExpiresAt.Equal(created.Add(time.Hour)) {
+		t.Fatalf("unexpected timestamps: %v, %v", got.CreatedDate, got.ExpiresAt)
+	}
+	if rowHash(got) != stringArg(inserted, insertColumnCount-1) {
+		t.Fatal("row hash changed across the round trip")
+	}
+}
+
+func TestTimestampColumnTypes(t *testing.T) {
+	for _, tc := range []struct {
+		placeholder PlaceholderStyle
+		want        string
+	}{
+		{PlaceholderDollar, "log_created_date TIMESTAMPTZ NOT NULL"},
+		{PlaceholderQuestion, "log_created_date TIMESTAMP NOT NULL"},
+	} {
+		var calls []string
+		db := openStubDB(t, &stubDriver{
+			execFn: func(query string, _ []driver.NamedValue) (driver.Result, error) {
+				calls = append(calls, query)
+				return stubResult{}, nil
+			},
+			queryFn: func(string, []driver.NamedValue) (driver.Rows, error) {
+				return &stubRows{columns: auditColumnNames()}, nil
+			},
+		})
+		audit, _ := NewAuditTrail(Config{DB: db, Placeholder: tc.placeholder})
+		if err := audit.EnsureTable(context.Background()); err != nil {
+			t.Fatalf("EnsureTable: %v", err)
+		}
+		if !strings.Contains(calls[0], tc.want) {
+			t.Fatalf("expected %q in %s", tc.want, calls[0])
+		}
+	}
+}
+
+func TestMySQLDSN(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"user:pass@tcp(db:3306)/audit", "user:pass@tcp(db:3306)/audit?parseTime=true&loc=UTC&time_zone=%27%2B00%3A00%27"},
+		{"user:p?ss@tcp(db)/audit?tls=true&loc=Local", "user:p?ss@tcp(db)/audit?tls=true&loc=Local&parseTime=true&time_zone=%27%2B00%3A00%27"},
+		{"u@/audit?parseTime=true&loc=UTC&time_zone=%27%2B00%3A00%27", "u@/audit?parseTime=true&loc=UTC&time_zone=%27%2B00%3A00%27"},
+		{"not a dsn", "not a dsn"},
+	} {
+		if got := mysqlDSN(tc.in); got != tc.want {
+			t.Errorf("mysqlDSN(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
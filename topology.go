@@ -0,0 +1,129 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Provisioner creates the broker resources a transport needs when they are missing.
+type Provisioner interface {
+	EnsureTopology(ctx context.Context) error
+}
+
+// ProvisionerFunc adapts a function to Provisioner, e.g. to create Kafka topics with your
+// admin client or SQS queues with the AWS SDK:
+//
+//	kafka := audittrail.ProvisionerFunc(func(ctx context.Context) error {
+//		_, err := admin.CreateTopic(ctx, 12, 3, map[string]*string{"retention.ms": &week}, "audit")
+//		if errors.Is(err, kerr.TopicAlreadyExists) {
+//			return nil
+//		}
+//		return err
+//	})
+type ProvisionerFunc func(ctx context.Context) error
+
+func (f ProvisionerFunc) EnsureTopology(ctx context.Context) error { return f(ctx) }
+
+// EnsureTopology runs every provisioner in order, e.g. at startup so a new environment
+// bootstraps itself. It stops at the first error.
+func EnsureTopology(ctx context.Context, provisioners ...Provisioner) error {
+	for _, p := range provisioners {
+		if p == nil {
+			continue
+		}
+		if err := p.EnsureTopology(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GCPTopology is the Pub/Sub topic and subscription used by the GCP publisher and
+// subscriber. Existing resources are left as they are; the settings only apply on creation.
+type GCPTopology struct {
+	Client       *pubsub.Client
+	Topic        string
+	Subscription string // optional; only the topic is created when empty
+
+	// MessageRetention keeps published messages on the topic, so subscriptions can seek
+	// back (see Consumer.Replay). Zero uses the Pub/Sub default (none).
+	MessageRetention time.Duration
+	// AckDeadline of the subscription; default 60s.
+	AckDeadline time.Duration
+	// SubscriptionRetention keeps unacknowledged messages; zero uses the Pub/Sub default (7 days).
+	SubscriptionRetention time.Duration
+	// MessageOrdering enables ordered delivery (see WithOrderingKey).
+	MessageOrdering bool
+	// DeadLetterTopic receives messages that failed MaxDeliveryAttempts (default 5) times.
+	// It must exist; the Pub/Sub service account needs publish rights on it.
+	DeadLetterTopic     string
+	MaxDeliveryAttempts int
+}
+
+// EnsureTopology creates the topic and subscription if they do not exist. Only reading is
+// needed when they do; creating needs the Pub/Sub Editor role.
+func (t GCPTopology) EnsureTopology(ctx context.Context) error {
+	if t.Client == nil {
+		return errors.New("audittrail: pubsub client must not be nil")
+	}
+	if t.Topic == "" {
+		return errors.New("audittrail: pubsub topic must not be empty")
+	}
+
+	topic := t.Client.Topic(t.Topic)
+	exists, err := topic.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("audittrail: check topic %s: %w", t.Topic, err)
+	}
+	if !exists {
+		cfg := &pubsub.TopicConfig{}
+		if t.MessageRetention > 0 {
+			cfg.RetentionDuration = t.MessageRetention
+		}
+		if _, err := t.Client.CreateTopicWithConfig(ctx, t.Topic, cfg); err != nil && status.Code(err) != codes.AlreadyExists {
+			return fmt.Errorf("audittrail: create topic %s: %w", t.Topic, err)
+		}
+		logger().Info("audittrail: created pubsub topic", "topic", t.Topic)
+	}
+	if t.Subscription == "" {
+		return nil
+	}
+
+	sub := t.Client.Subscription(t.Subscription)
+	if exists, err = sub.Exists(ctx); err != nil {
+		return fmt.Errorf("audittrail: check subscription %s: %w", t.Subscription, err)
+	}
+	if exists {
+		return nil
+	}
+	cfg := pubsub.SubscriptionConfig{
+		Topic:                 topic,
+		AckDeadline:           t.AckDeadline,
+		RetentionDuration:     t.SubscriptionRetention,
+		EnableMessageOrdering: t.MessageOrdering,
+	}
+	if cfg.AckDeadline <= 0 {
+		cfg.AckDeadline = time.Minute
+	}
+	if t.DeadLetterTopic != "" {
+		attempts := t.MaxDeliveryAttempts
+		if attempts <= 0 {
+			attempts = 5
+		}
+		cfg.DeadLetterPolicy = &pubsub.DeadLetterPolicy{
+			DeadLetterTopic:     t.Client.Topic(t.DeadLetterTopic).String(),
+			MaxDeliveryAttempts: attempts,
+		}
+	}
+	if _, err := t.Client.CreateSubscription(ctx, t.Subscription, cfg); err != nil && status.Code(err) != codes.AlreadyExists {
+		return fmt.Errorf("audittrail: create subscription %s: %w", t.Subscription, err)
+	}
+	logger().Info("audittrail: created pubsub subscription", "subscription", t.Subscription, "topic", t.Topic)
+	return nil
+}
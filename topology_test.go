@@ -0,0 +1,48 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGCPTopologyCreatesMissingResources(t *testing.T) {
+	ctx := context.Background()
+	_, client := newFakePubSub(t)
+	topology := GCPTopology{Client: client, Topic: "audit", Subscription: "audit-writer", AckDeadline: 45 * time.Second, MessageOrdering: true}
+
+	if err := EnsureTopology(ctx, topology); err != nil {
+		t.Fatalf("EnsureTopology: %v", err)
+	}
+	cfg, err := client.Subscription("audit-writer").Config(ctx)
+	if err != nil {
+		t.Fatalf("subscription config: %v", err)
+	}
+	if cfg.Topic.ID() != "audit" || cfg.AckDeadline != 45*time.Second || !cfg.EnableMessageOrdering {
+		t.Fatalf("unexpected subscription: %+v", cfg)
+	}
+
+	// Running again leaves existing resources alone.
+	topology.AckDeadline = 10 * time.Second
+	if err := topology.EnsureTopology(ctx); err != nil {
+		t.Fatalf("second EnsureTopology: %v", err)
+	}
+	if cfg, _ := client.Subscription("audit-writer").Config(ctx); cfg.AckDeadline != 45*time.Second {
+		t.Fatalf("existing subscription was changed: %v", cfg.AckDeadline)
+	}
+}
+
+func TestEnsureTopologyStopsAtFirstError(t *testing.T) {
+	calls := 0
+	failing := errors.New("queue service unavailable")
+	err := EnsureTopology(context.Background(),
+		ProvisionerFunc(func(context.Context) error { calls++; return failing }),
+		ProvisionerFunc(func(context.Context) error { calls++; return nil }))
+	if !errors.Is(err, failing) || calls != 1 {
+		t.Fatalf("expected first error, got %v after %d calls", err, calls)
+	}
+	if err := (GCPTopology{}).EnsureTopology(context.Background()); err == nil {
+		t.Fatal("expected error without a client")
+	}
+}
@@ -0,0 +1,311 @@
+package audittrail
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// TransportOption configures Transport behavior.
+type TransportOption func(*transportConfig)
+
+type transportConfig struct {
+	action          func(*http.Request) string
+	requestIDHeader string
+	maxBodySize     int64 // capture bodies up to this size; 0 disables
+	redaction       payloadRedaction
+	onError         func(error)
+	now             func() time.Time
+}
+
+func defaultTransportConfig() transportConfig {
+	return transportConfig{
+		action: func(r *http.Request) string {
+			return "OUTBOUND " + r.Method + " " + r.URL.Host
+		},
+		requestIDHeader: "X-Request-Id",
+		onError: func(err error) {
+			logger().Error("audittrail: transport record failed", "error", err)
+		},
+		now: time.Now,
+	}
+}
+
+// OutboundRequest is recorded as the request payload of an outbound call.
+type OutboundRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   any    `json:"body,omitempty"`
+}
+
+// OutboundResponse is recorded as the response payload of an outbound call. Status is 0
+// and Error set when no response was received.
+type OutboundResponse struct {
+	Status    int    `json:"status,omitempty"`
+	LatencyMS int64  `json:"latency_ms"` // until the response headers arrived
+	Body      any    `json:"body,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Transport wraps rt (http.DefaultTransport if nil) so every outbound call is recorded:
+// method, URL, status, latency and, with WithTransportBodies, the redacted bodies.
+//
+//	client := &http.Client{Transport: audittrail.Transport(nil, audit,
+//		audittrail.WithTransportBodies(64<<10),
+//		audittrail.WithTransportRedaction(audittrail.RedactFields("card_number"), nil))}
+//
+// Calls with a captured response body are recorded once the body is read to the end or
+// closed; the others as soon as the response headers arrive. Credentials in the URL and
+// sensitive query parameters are redacted.
+func Transport(rt http.RoundTripper, recorder Recorder, opts ...TransportOption) http.RoundTripper {
+	if recorder == nil {
+		panic("audittrail: Transport requires a non-nil Recorder")
+	}
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	cfg := defaultTransportConfig()
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+	return &auditTransport{next: rt, recorder: recorder, cfg: cfg}
+}
+
+type auditTransport struct {
+	next     http.RoundTripper
+	recorder Recorder
+	cfg      transportConfig
+}
+
+func (t *auditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := t.cfg.now().UTC()
+	call := &outboundCall{
+		t:   t,
+		ctx: context.WithoutCancel(req.Context()),
+		entry: Entry{
+			RequestID:   req.Header.Get(t.cfg.requestIDHeader),
+			Action:      t.cfg.action(req),
+			Endpoint:    redactURL(req.URL),
+			CreatedDate: start,
+		},
+		request: OutboundRequest{Method: req.Method, URL: redactURL(req.URL)},
+	}
+	if t.cfg.maxBodySize > 0 && req.Body != nil && req.Body != http.NoBody {
+		req, call.requestBody = captureOutboundBody(req, t.cfg.maxBodySize)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	call.response.LatencyMS = t.cfg.now().UTC().Sub(start).Milliseconds()
+	if err != nil {
+		call.response.Error = err.Error()
+		call.record()
+		return resp, err
+	}
+	call.response.Status = resp.StatusCode
+	if t.cfg.maxBodySize > 0 && resp.Body != nil && resp.Body != http.NoBody {
+		resp.Body = &outboundBody{ReadCloser: resp.Body, call: call, max: t.cfg.maxBodySize}
+		return resp, nil
+	}
+	call.record()
+	return resp, nil
+}
+
+// outboundCall collects one call's entry until it is recorded.
+type outboundCall struct {
+	t           *auditTransport
+	ctx         context.Context
+	entry       Entry
+	request     OutboundRequest
+	requestBody *limitedBuffer
+	response    OutboundResponse
+	once        sync.Once
+}
+
+func (c *outboundCall) record() {
+	c.once.Do(func() {
+		if c.requestBody != nil {
+			c.request.Body = c.requestBody.payload()
+		}
+		p := c.t.cfg.redaction
+		if p.request != nil && c.request.Body != nil {
+			c.request.Body = p.request(c.request.Body)
+		}
+		if p.response != nil && c.response.Body != nil {
+			c.response.Body = p.response(c.response.Body)
+		}
+		c.entry.Request = c.request
+		c.entry.Response = c.response
+		c.entry.Severity = DefaultSeverity(c.request.Method, c.response.Status)
+		if c.response.Error != "" {
+			c.entry.Severity = SeverityWarn
+		}
+		if err := c.t.recorder.Record(c.ctx, c.entry); err != nil {
+			reportDrop(c.entry, err)
+			if c.t.cfg.onError != nil {
+				c.t.cfg.onError(err)
+			}
+		}
+	})
+}
+
+// captureOutboundBody arranges for the first max bytes of the request body to be kept.
+// Bodies that can be replayed (GetBody) are read from a fresh copy; others are captured
+// as the transport sends them.
+func captureOutboundBody(req *http.Request, max int64) (*http.Request, *limitedBuffer) {
+	buf := &limitedBuffer{max: max}
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			_, _ = buf.ReadFrom(body)
+			_ = body.Close()
+			return req, buf
+		}
+	}
+	req = req.Clone(req.Context())
+	req.Body = &teeReadCloser{Reader: io.TeeReader(req.Body, buf), Closer: req.Body}
+	return req, buf
+}
+
+// limitedBuffer keeps the first max bytes written to it and discards the rest. It is
+// safe for concurrent use, as the transport may still be sending a body on its own
+// goroutine when the call is recorded.
+type limitedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+	max int64
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if room := b.max - int64(b.buf.Len()); room > 0 {
+		b.buf.Write(p[:min(int64(len(p)), room)])
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{b}, r)
+}
+
+func (b *limitedBuffer) payload() any {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return rawPayload(bytes.Clone(b.buf.Bytes()))
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// outboundBody captures a response body as the caller reads it and records the call at
+// EOF or Close, whichever comes first.
+type outboundBody struct {
+	io.ReadCloser
+	call *outboundCall
+	max  int64
+	buf  bytes.Buffer
+}
+
+func (b *outboundBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := b.max - int64(b.buf.Len()); room > 0 {
+		b.buf.Write(p[:min(int64(n), room)])
+	}
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *outboundBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish()
+	return err
+}
+
+func (b *outboundBody) finish() {
+	if b.call.response.Body == nil {
+		b.call.response.Body = rawPayload(bytes.Clone(b.buf.Bytes()))
+	}
+	b.call.record()
+}
+
+// redactURL returns u without user credentials and with the values of sensitive query
+// parameters (token, api_key, ...) replaced by RedactedValue.
+func redactURL(u *url.URL) string {
+	clean := *u
+	clean.User = nil
+	if clean.RawQuery != "" {
+		query := clean.Query()
+		changed := false
+		for key, values := range query {
+			if isSensitiveField(key) {
+				for i := range values {
+					values[i] = RedactedValue
+				}
+				changed = true
+			}
+		}
+		if changed {
+			clean.RawQuery = query.Encode()
+		}
+	}
+	return clean.String()
+}
+
+// WithTransportAction customizes the Action of outbound entries. Default:
+// "OUTBOUND <METHOD> <host>".
+func WithTransportAction(fn func(*http.Request) string) TransportOption {
+	return func(c *transportConfig) {
+		if fn != nil {
+			c.action = fn
+		}
+	}
+}
+
+// WithTransportRequestIDHeader sets which outbound header is recorded as the request ID.
+// Default: X-Request-Id.
+func WithTransportRequestIDHeader(name string) TransportOption {
+	return func(c *transportConfig) {
+		c.requestIDHeader = name
+	}
+}
+
+// WithTransportBodies captures request and response bodies up to maxBytes each. The
+// bodies sent and returned are never cut short.
+func WithTransportBodies(maxBytes int64) TransportOption {
+	return func(c *transportConfig) {
+		c.maxBodySize = maxBytes
+	}
+}
+
+// WithTransportRedaction sets the redactions applied to captured request and response
+// bodies; pass nil to keep a side as captured.
+func WithTransportRedaction(request, response Redaction) TransportOption {
+	return func(c *transportConfig) {
+		c.redaction = payloadRedaction{request: request, response: response}
+	}
+}
+
+// WithTransportNow overrides the clock used for timestamps and latency (useful in tests).
+func WithTransportNow(fn func() time.Time) TransportOption {
+	return func(c *transportConfig) {
+		if fn != nil {
+			c.now = fn
+		}
+	}
+}
+
+// WithTransportErrorHandler overrides how record errors are reported.
+func WithTransportErrorHandler(fn func(error)) TransportOption {
+	return func(c *transportConfig) {
+		c.onError = fn
+	}
+}
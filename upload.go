@@ -0,0 +1,168 @@
+package audittrail
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// UploadPolicy decides what the middlewares record for multipart and binary request bodies.
+type UploadPolicy int
+
+const (
+	UploadSummarize UploadPolicy = iota // record an UploadSummary (default)
+	UploadSkip                          // record no request payload
+	UploadRaw                           // capture the body like any other (text, truncated)
+)
+
+// UploadSummary is recorded as the request payload of file uploads and binary bodies
+// instead of their content.
+type UploadSummary struct {
+	ContentType string       `json:"content_type"`
+	Size        int64        `json:"size"`             // bytes the handler read
+	SHA256      string       `json:"sha256,omitempty"` // of the whole body; binary bodies only
+	Parts       []UploadPart `json:"parts,omitempty"`  // multipart bodies only
+	// Truncated is set when the handler stopped reading before the end of the body, so
+	// the last part's size and hash cover only what was read.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// UploadPart describes one part of a multipart body.
+type UploadPart struct {
+	Field       string `json:"field"`
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+}
+
+// WithUploadPolicy selects how multipart and binary request bodies are recorded when
+// WithRequestBody is set. Default: UploadSummarize.
+func WithUploadPolicy(p UploadPolicy) HTTPMiddlewareOption {
+	return func(c *httpMiddlewareConfig) { c.uploadPolicy = p }
+}
+
+// WithGinUploadPolicy selects how multipart and binary request bodies are recorded.
+// Default: UploadSummarize.
+func WithGinUploadPolicy(p UploadPolicy) GinMiddlewareOption {
+	return func(c *ginMiddlewareConfig) { c.uploadPolicy = p }
+}
+
+// isUpload reports whether a content type is a file upload or binary rather than text.
+func isUpload(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		return true
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "json"),
+		strings.HasSuffix(mediaType, "xml"),
+		mediaType == "application/x-www-form-urlencoded",
+		mediaType == "application/graphql":
+		return false
+	}
+	return true
+}
+
+// uploadCapture summarizes a request body as the handler reads it, so uploads of any size
+// are neither buffered nor cut short.
+type uploadCapture struct {
+	summary UploadSummary
+	body    io.ReadCloser
+	hash    hash.Hash
+	pw      *io.PipeWriter // multipart: feeds the part parser
+	done    chan struct{}
+	closed  bool  // multipart: the parser saw the closing boundary
+	length  int64 // Content-Length, -1 if unknown
+	eof     bool  // the handler read the whole body
+}
+
+// captureUpload wraps the body of r for summarizing when it is an upload and the policy
+// asks for it. It returns nil when the body should be handled as a regular payload.
+// With UploadSkip the body is left alone and an empty capture is returned.
+func captureUpload(r *http.Request, policy UploadPolicy) (*uploadCapture, io.ReadCloser) {
+	contentType := r.Header.Get("Content-Type")
+	if policy == UploadRaw || r.Body == nil || !isUpload(contentType) {
+		return nil, r.Body
+	}
+	u := &uploadCapture{summary: UploadSummary{ContentType: contentType}, body: r.Body, length: r.ContentLength}
+	if policy == UploadSkip {
+		return u, r.Body
+	}
+
+	_, params, _ := mime.ParseMediaType(contentType)
+	if boundary := params["boundary"]; boundary != "" {
+		pr, pw := io.Pipe()
+		u.pw, u.done = pw, make(chan struct{})
+		go u.parseParts(multipart.NewReader(pr, boundary), pr)
+	} else {
+		u.hash = sha256.New()
+	}
+	return u, u
+}
+
+func (u *uploadCapture) Read(p []byte) (int, error) {
+	n, err := u.body.Read(p)
+	u.summary.Size += int64(n)
+	if u.hash != nil {
+		u.hash.Write(p[:n])
+	}
+	if u.pw != nil && n > 0 {
+		_, _ = u.pw.Write(p[:n])
+	}
+	if err == io.EOF || (u.length > 0 && u.summary.Size == u.length) {
+		u.eof = true
+	}
+	return n, err
+}
+
+func (u *uploadCapture) Close() error { return u.body.Close() }
+
+// parseParts hashes every part the handler reads; it keeps draining the pipe after an
+// error so reads never block.
+func (u *uploadCapture) parseParts(mr *multipart.Reader, pr *io.PipeReader) {
+	defer close(u.done)
+	defer func() { _, _ = io.Copy(io.Discard, pr) }()
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			u.closed = err == io.EOF
+			return
+		}
+		h := sha256.New()
+		size, _ := io.Copy(h, part)
+		u.summary.Parts = append(u.summary.Parts, UploadPart{
+			Field:       part.FormName(),
+			Filename:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+			Size:        size,
+			SHA256:      hex.EncodeToString(h.Sum(nil)),
+		})
+	}
+}
+
+// finish returns the payload to record once the handler has returned.
+func (u *uploadCapture) finish() any {
+	if u.hash == nil && u.pw == nil {
+		return nil // UploadSkip
+	}
+	if u.pw != nil {
+		_ = u.pw.Close()
+		<-u.done
+		u.summary.Truncated = !u.closed
+		return u.summary
+	}
+	if u.eof {
+		u.summary.SHA256 = hex.EncodeToString(u.hash.Sum(nil))
+	}
+	u.summary.Truncated = !u.eof
+	return u.summary
+}
@@ -0,0 +1,136 @@
+package audittrail
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func multipartBody(t *testing.T) (*bytes.Buffer, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	_ = mw.WriteField("title", "Q3 report")
+	fw, err := mw.CreateFormFile("file", "report.pdf")
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	_, _ = fw.Write([]byte("%PDF-1.7 binary"))
+	_ = mw.Close()
+	return &buf, mw.FormDataContentType()
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestHTTPMiddlewareSummarizesUploads(t *testing.T) {
+	var got Entry
+	rec := RecorderFunc(func(_ context.Context, e Entry) error {
+		got = e
+		return nil
+	})
+	var form string
+	handler := HTTPMiddleware(rec, WithRequestBody(16))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err == nil {
+			form = r.FormValue("title")
+			return
+		}
+		_, _ = io.ReadAll(r.Body)
+	}))
+
+	body, contentType := multipartBody(t)
+	req := httptest.NewRequest(http.MethodPost, "/uploads", body)
+	req.Header.Set("Content-Type", contentType)
+	size := int64(body.Len())
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if form != "Q3 report" {
+		t.Fatalf("handler could not parse the form: %q", form)
+	}
+	want := UploadSummary{
+		ContentType: contentType,
+		Size:        size,
+		Parts: []UploadPart{
+			{Field: "title", Size: 9, SHA256: sha256Hex("Q3 report")},
+			{Field: "file", Filename: "report.pdf", ContentType: "application/octet-stream", Size: 15, SHA256: sha256Hex("%PDF-1.7 binary")},
+		},
+	}
+	if !reflect.DeepEqual(got.Request, want) {
+		t.Fatalf("unexpected summary:\n got %+v\nwant %+v", got.Request, want)
+	}
+
+	// A binary body larger than the capture limit still reaches the handler whole.
+	blob := strings.Repeat("\x00\x01", 64)
+	req = httptest.NewRequest(http.MethodPut, "/blobs/1", strings.NewReader(blob))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if s := got.Request.(UploadSummary); s.Size != 128 || s.SHA256 != sha256Hex(blob) || s.Truncated {
+		t.Fatalf("unexpected binary summary: %+v", s)
+	}
+
+	// A handler that rejects the upload without reading it.
+	reject := HTTPMiddleware(rec, WithRequestBody(16))(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	}))
+	req = httptest.NewRequest(http.MethodPut, "/blobs/1", strings.NewReader(blob))
+	req.Header.Set("Content-Type", "image/png")
+	reject.ServeHTTP(httptest.NewRecorder(), req)
+	if s := got.Request.(UploadSummary); s.Size != 0 || s.SHA256 != "" || !s.Truncated {
+		t.Fatalf("unexpected summary of unread body: %+v", s)
+	}
+
+	skip := HTTPMiddleware(rec, WithRequestBody(16), WithUploadPolicy(UploadSkip))(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	req = httptest.NewRequest(http.MethodPut, "/blobs/1", strings.NewReader(blob))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	skip.ServeHTTP(httptest.NewRecorder(), req)
+	if got.Request != nil {
+		t.Fatalf("expected no payload with UploadSkip, got %v", got.Request)
+	}
+}
+
+func TestGinMiddlewareSummarizesUploads(t *testing.T) {
+	entries := make(chan Entry, 1)
+	useGlobalRecorder(t, RecorderFunc(func(_ context.Context, e Entry) error {
+		entries <- e
+		return nil
+	}))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GinMiddleware())
+	r.POST("/uploads", func(c *gin.Context) {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.String(http.StatusOK, file.Filename)
+	})
+
+	body, contentType := multipartBody(t)
+	req := httptest.NewRequest(http.MethodPost, "/uploads", body)
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	e := <-entries
+	if w.Code != http.StatusOK || w.Body.String() != "report.pdf" {
+		t.Fatalf("handler failed: %d %s", w.Code, w.Body.String())
+	}
+	s, ok := e.Request.(UploadSummary)
+	if !ok || len(s.Parts) != 2 || s.Parts[1].Filename != "report.pdf" || s.Parts[1].SHA256 != sha256Hex("%PDF-1.7 binary") {
+		t.Fatalf("unexpected summary: %#v", e.Request)
+	}
+}
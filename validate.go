@@ -0,0 +1,78 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidEntry wraps the error of a Validator that rejected an entry.
+var ErrInvalidEntry = errors.New("audittrail: invalid entry")
+
+// errQuarantined signals that an entry was diverted to the quarantine recorder and must
+// not be stored; recorders report success to the caller.
+var errQuarantined = errors.New("audittrail: entry quarantined")
+
+// Validator checks an entry after enrichment, e.g. that an actor is set or the action
+// follows the organization's taxonomy. A non-nil error is a violation.
+type Validator func(entry Entry) error
+
+// ValidationPolicy decides what happens to entries that fail a Validator.
+type ValidationPolicy int
+
+const (
+	ValidationReject     ValidationPolicy = iota // return an error wrapping ErrInvalidEntry (default)
+	ValidationLog                                // log a warning and record the entry anyway
+	ValidationQuarantine                         // send the entry to the quarantine recorder instead
+)
+
+// WithValidator runs fns, in order, on every entry after the enrichers. The first
+// violation is handled according to the validation policy.
+func WithValidator(fns ...Validator) RecorderOption {
+	return func(h *recorderHooks) {
+		for _, fn := range fns {
+			if fn != nil {
+				h.validators = append(h.validators, fn)
+			}
+		}
+	}
+}
+
+// WithValidationPolicy selects how violations are handled. ValidationQuarantine without a
+// quarantine recorder (see WithQuarantine) behaves like ValidationReject.
+func WithValidationPolicy(policy ValidationPolicy) RecorderOption {
+	return func(h *recorderHooks) { h.policy = policy }
+}
+
+// WithQuarantine sends invalid entries to rec (e.g. a separate table or topic) for later
+// review and selects ValidationQuarantine. The caller's Record succeeds unless rec fails.
+func WithQuarantine(rec Recorder) RecorderOption {
+	return func(h *recorderHooks) {
+		h.quarantine = rec
+		h.policy = ValidationQuarantine
+	}
+}
+
+// validate runs the validators and applies the policy to the first violation.
+func (h *recorderHooks) validate(ctx context.Context, entry Entry) error {
+	for _, fn := range h.validators {
+		verr := fn(entry)
+		if verr == nil {
+			continue
+		}
+		switch {
+		case h.policy == ValidationLog:
+			logger().Warn("audittrail: invalid entry recorded", "action", entry.Action, "id", entry.ID, "error", verr)
+			return nil
+		case h.policy == ValidationQuarantine && h.quarantine != nil:
+			if err := h.quarantine.Record(ctx, entry); err != nil {
+				return fmt.Errorf("audittrail: quarantine entry failed: %w", err)
+			}
+			logger().Warn("audittrail: invalid entry quarantined", "action", entry.Action, "id", entry.ID, "error", verr)
+			return errQuarantined
+		default:
+			return fmt.Errorf("%w: %w", ErrInvalidEntry, verr)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package audittrail
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestValidatorPolicies(t *testing.T) {
+	requireActor := func(e Entry) error {
+		if e.CreatedBy == "" {
+			return errors.New("actor is required")
+		}
+		return nil
+	}
+	newRecorder := func(opts ...RecorderOption) (*PubSubRecorder, *[]Entry) {
+		var published []Entry
+		rec, err := NewPubSubRecorder(PublisherFunc(func(_ context.Context, e Entry) error {
+			published = append(published, e)
+			return nil
+		}), nil, opts...)
+		if err != nil {
+			t.Fatalf("NewPubSubRecorder: %v", err)
+		}
+		return rec, &published
+	}
+	ctx := context.Background()
+
+	rec, published := newRecorder(WithValidator(requireActor))
+	if err := rec.Record(ctx, Entry{Action: "A"}); !errors.Is(err, ErrInvalidEntry) || len(*published) != 0 {
+		t.Fatalf("expected rejection, err=%v published=%d", err, len(*published))
+	}
+	if err := rec.Record(ctx, Entry{Action: "A", CreatedBy: "u1"}); err != nil || len(*published) != 1 {
+		t.Fatalf("valid entry: err=%v published=%d", err, len(*published))
+	}
+
+	rec, published = newRecorder(WithValidator(requireActor), WithValidationPolicy(ValidationLog))
+	if err := rec.Record(ctx, Entry{Action: "A"}); err != nil || len(*published) != 1 {
+		t.Fatalf("log policy: err=%v published=%d", err, len(*published))
+	}
+
+	var quarantined []Entry
+	quarantine := RecorderFunc(func(_ context.Context, e Entry) error {
+		quarantined = append(quarantined, e)
+		return nil
+	})
+	rec, published = newRecorder(WithValidator(requireActor), WithQuarantine(quarantine))
+	batch := []Entry{{Action: "A"}, {Action: "B", CreatedBy: "u1"}}
+	if err := rec.RecordBatch(ctx, batch); err != nil {
+		t.Fatalf("RecordBatch: %v", err)
+	}
+	if len(*published) != 1 || (*published)[0].Action != "B" || len(quarantined) != 1 || quarantined[0].Action != "A" {
+		t.Fatalf("quarantine policy: published=%+v quarantined=%+v", *published, quarantined)
+	}
+
+	rec, published = newRecorder(WithValidator(requireActor), WithValidationPolicy(ValidationQuarantine))
+	if err := rec.Record(ctx, Entry{Action: "A"}); !errors.Is(err, ErrInvalidEntry) || len(*published) != 0 {
+		t.Fatalf("quarantine without recorder should reject, err=%v", err)
+	}
+}
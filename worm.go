@@ -0,0 +1,105 @@
+package audittrail
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ErrAppendOnly is returned by operations that would update or delete entries when
+// Config.AppendOnly is set: AnonymizeActor and MigrateIdentity always, and Purge,
+// PurgeExpired and archive truncation when no Config.PurgeToken is configured. Hold and
+// Release are allowed, as they only change whether entries may be deleted.
+var ErrAppendOnly = errors.New("audittrail: table is append-only")
+
+// purgeTokenSetting is the Postgres session setting the append-only trigger checks.
+const purgeTokenSetting = "audittrail.purge_token"
+
+// checkMutable rejects UPDATE statements on an append-only table.
+func (r *AuditTrail) checkMutable() error {
+	if r.appendOnly {
+		return ErrAppendOnly
+	}
+	return nil
+}
+
+// execDelete runs a retention DELETE. On an append-only table it requires the purge token
+// and, on Postgres, presents it to the trigger installed by InstallAppendOnlyTrigger
+// through a transaction-local setting.
+func (r *AuditTrail) execDelete(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if !r.appendOnly {
+		return r.db.ExecContext(ctx, query, args...)
+	}
+	if r.purgeToken == "" {
+		return nil, ErrAppendOnly
+	}
+	if r.placeholder != PlaceholderDollar {
+		return r.db.ExecContext(ctx, query, args...)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.ExecContext(ctx, "SELECT set_config($1, $2, true)", purgeTokenSetting, r.purgeToken); err != nil {
+		return nil, err
+	}
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return res, tx.Commit()
+}
+
+// InstallAppendOnlyTrigger installs a Postgres trigger that rejects UPDATE, DELETE and
+// TRUNCATE on the audit table for every client, not just this package. UPDATEs that only
+// change log_on_hold (Hold and Release) are allowed. DELETEs are let through only in
+// transactions that present Config.PurgeToken, which Purge, PurgeExpired
+// and the Archiver do when Config.AppendOnly is set. The trigger stores only the token's
+// SHA-256, so reading the function source does not reveal it; without a token nothing can
+// be deleted. Re-run it after rotating the token. Other databases return an error.
+func (r *AuditTrail) InstallAppendOnlyTrigger(ctx context.Context) error {
+	if r == nil || r.db == nil {
+		return errors.New("audittrail: instance is not initialized")
+	}
+	if r.placeholder != PlaceholderDollar {
+		return errors.New("audittrail: append-only trigger requires Postgres")
+	}
+
+	// An empty digest never matches, so deletes stay blocked without a token.
+	digest := ""
+	if r.purgeToken != "" {
+		sum := sha256.Sum256([]byte(r.purgeToken))
+		digest = hex.EncodeToString(sum[:])
+	}
+	fn := r.table + "_append_only"
+	statements := []string{
+		fmt.Sprintf(`CREATE OR REPLACE FUNCTION %s() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP = 'UPDATE' AND to_jsonb(NEW) - 'log_on_hold' = to_jsonb(OLD) - 'log_on_hold' THEN
+		RETURN NEW;
+	END IF;
+	IF TG_OP = 'DELETE' AND '%s' <> '' AND
+		encode(sha256(convert_to(coalesce(current_setting('%s', true), ''), 'UTF8')), 'hex') = '%s' THEN
+		RETURN OLD;
+	END IF;
+	RAISE EXCEPTION 'audittrail: %% on append-only table %%', TG_OP, TG_TABLE_NAME
+		USING ERRCODE = 'insufficient_privilege';
+END;
+$$ LANGUAGE plpgsql`, fn, digest, purgeTokenSetting, digest),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", fn, r.tableRef),
+		fmt.Sprintf("CREATE TRIGGER %s BEFORE UPDATE OR DELETE ON %s FOR EACH ROW EXECUTE FUNCTION %s()", fn, r.tableRef, fn),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s_truncate ON %s", fn, r.tableRef),
+		fmt.Sprintf("CREATE TRIGGER %s_truncate BEFORE TRUNCATE ON %s FOR EACH STATEMENT EXECUTE FUNCTION %s()", fn, r.tableRef, fn),
+	}
+	for _, stmt := range statements {
+		if _, err := r.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("audittrail: install append-only trigger failed: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package audittrail
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendOnlyRefusesMutations(t *testing.T) {
+	var calls []string
+	db := openStubDB(t, &stubDriver{
+		execFn: func(query string, _ []driver.NamedValue) (driver.Result, error) {
+			calls = append(calls, query)
+			return stubResult{}, nil
+		},
+	})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar, AppendOnly: true})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := audit.AnonymizeActor(ctx, "u1"); !errors.Is(err, ErrAppendOnly) {
+		t.Fatalf("AnonymizeActor: expected ErrAppendOnly, got %v", err)
+	}
+	if _, err := audit.Purge(ctx, time.Now()); !errors.Is(err, ErrAppendOnly) {
+		t.Fatalf("Purge: expected ErrAppendOnly, got %v", err)
+	}
+	if _, err := audit.PurgeExpired(ctx); !errors.Is(err, ErrAppendOnly) {
+		t.Fatalf("PurgeExpired: expected ErrAppendOnly, got %v", err)
+	}
+	if len(calls) != 0 {
+		t.Fatalf("expected no statements, got %q", calls)
+	}
+	if err := audit.Record(ctx, Entry{Action: "login"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+}
+
+func TestAppendOnlyAllowsLegalHold(t *testing.T) {
+	var calls []string
+	db := openStubDB(t, &stubDriver{
+		execFn: func(query string, _ []driver.NamedValue) (driver.Result, error) {
+			calls = append(calls, query)
+			return stubResult{}, nil
+		},
+	})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar, AppendOnly: true})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := audit.Hold(ctx, Filter{Actor: "u1"}); err != nil {
+		t.Fatalf("Hold: %v", err)
+	}
+	if _, err := audit.Release(ctx, Filter{Actor: "u1"}); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if len(calls) != 2 || !strings.HasPrefix(calls[0], "UPDATE audit_trail SET log_on_hold = $1 WHERE") {
+		t.Fatalf("unexpected statements: %q", calls)
+	}
+}
+
+func TestAppendOnlyPurgePresentsToken(t *testing.T) {
+	var calls []execCall
+	db := openStubDB(t, &stubDriver{
+		execFn: func(query string, args []driver.NamedValue) (driver.Result, error) {
+			calls = append(calls, execCall{query: query, args: args})
+			return stubResult{}, nil
+		},
+	})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar, AppendOnly: true, PurgeToken: "s3cret"})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	if _, err := audit.PurgeExpired(context.Background()); err != nil {
+		t.Fatalf("PurgeExpired: %v", err)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(calls))
+	}
+	if calls[0].query != "SELECT set_config($1, $2, true)" ||
+		calls[0].args[0].Value != purgeTokenSetting || calls[0].args[1].Value != "s3cret" {
+		t.Fatalf("unexpected token statement: %s %v", calls[0].query, calls[0].args)
+	}
+	if !strings.HasPrefix(calls[1].query, "DELETE FROM audit_trail WHERE") {
+		t.Fatalf("unexpected purge statement: %s", calls[1].query)
+	}
+}
+
+func TestInstallAppendOnlyTrigger(t *testing.T) {
+	var calls []string
+	db := openStubDB(t, &stubDriver{
+		execFn: func(query string, _ []driver.NamedValue) (driver.Result, error) {
+			calls = append(calls, query)
+			return stubResult{}, nil
+		},
+	})
+	audit, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderDollar, AppendOnly: true, PurgeToken: "s3cret"})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	if err := audit.InstallAppendOnlyTrigger(context.Background()); err != nil {
+		t.Fatalf("InstallAppendOnlyTrigger: %v", err)
+	}
+
+	sum := sha256.Sum256([]byte("s3cret"))
+	if len(calls) != 5 {
+		t.Fatalf("expected 5 statements, got %q", calls)
+	}
+	if !strings.HasPrefix(calls[0], "CREATE OR REPLACE FUNCTION audit_trail_append_only()") ||
+		!strings.Contains(calls[0], hex.EncodeToString(sum[:])) || strings.Contains(calls[0], "s3cret") ||
+		!strings.Contains(calls[0], "to_jsonb(NEW) - 'log_on_hold' = to_jsonb(OLD) - 'log_on_hold'") {
+		t.Fatalf("unexpected function: %s", calls[0])
+	}
+	if calls[2] != "CREATE TRIGGER audit_trail_append_only BEFORE UPDATE OR DELETE ON audit_trail FOR EACH ROW EXECUTE FUNCTION audit_trail_append_only()" ||
+		calls[4] != "CREATE TRIGGER audit_trail_append_only_truncate BEFORE TRUNCATE ON audit_trail FOR EACH STATEMENT EXECUTE FUNCTION audit_trail_append_only()" {
+		t.Fatalf("unexpected triggers: %q", calls)
+	}
+
+	mysql, err := NewAuditTrail(Config{DB: db, Placeholder: PlaceholderQuestion})
+	if err != nil {
+		t.Fatalf("NewAuditTrail: %v", err)
+	}
+	if err := mysql.InstallAppendOnlyTrigger(context.Background()); err == nil {
+		t.Fatal("expected error outside Postgres")
+	}
+}